@@ -36,6 +36,14 @@
 //			-tikv-config="" -tidb-config="" -pd-config=""
 //			-tidb-replicas="1" -pd-replicas="1" -storage-class="local-path"
 //
+//   Run with the fake S3 stand-in served by this program, the endpoint is this host if it's not given,
+//   which must be reachable from TiKV nodes:
+//		./bin/backup -fake-s3-addr ":9000" -fake-s3-dir /tmp/fake-s3 -backup-uri "s3://tipocket/backup"
+//
+// Every backup records the checksums of all tables read by tidb_snapshot at its backup ts,
+// and every restore of a full+incremental chain is checked against the snapshot of the backup restored,
+// the rows are compared with the snapshot after the whole chain is restored.
+//
 // This case is supposed to run forever, until an error occur or got killed
 // This case should tolerant with all kinds of nemesis with one exception:
 //   - when backup-uri refers to some local storage, nemesis which will kill
@@ -49,6 +57,8 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
+	"net"
 	"net/url"
 	"time"
 
@@ -64,6 +74,7 @@ import (
 	"github.com/pingcap/tipocket/pkg/cluster"
 	"github.com/pingcap/tipocket/pkg/control"
 	"github.com/pingcap/tipocket/pkg/test-infra/fixture"
+	"github.com/pingcap/tipocket/pkg/util/fakes3"
 	"github.com/pingcap/tipocket/tests/backup"
 )
 
@@ -76,6 +87,9 @@ var (
 	dbname          = flag.String("dbname", "test", "name of database to test")
 	retryLimit      = flag.Int("retry-limit", 20, "retry count")
 	backupURI       = flag.String("backup-uri", "local:///tmp/backup", "where the backup file should in")
+	gcLifeTime      = flag.String("gc-life-time", "1h", "tidb_gc_life_time, the snapshots of the backups must be readable until restored")
+	fakeS3Addr      = flag.String("fake-s3-addr", "", "serve a fake S3 on the address for the backup-uri, e.g. ':9000', empty means disabled")
+	fakeS3Dir       = flag.String("fake-s3-dir", "/tmp/fake-s3", "the directory of the fake S3")
 
 	pessimistic = flag.Bool("pessimistic", true, "use pessimistic transaction")
 	replicaRead = flag.String("tidb-replica-read", "leader", "tidb_replica_read mode, support values: leader / follower / leader-and-follower, default value: leader.")
@@ -97,6 +111,24 @@ func main() {
 	if err != nil {
 		log.Fatalf("invalid backupURI")
 	}
+	if *fakeS3Addr != "" {
+		go func() {
+			log.Fatal(fakes3.ListenAndServe(*fakeS3Addr, *fakeS3Dir))
+		}()
+		// point the s3 backup-uri without endpoint to the fake S3
+		if query := u.Query(); u.Scheme == "s3" && query.Get("endpoint") == "" {
+			endpoint, err := fakeS3Endpoint(*fakeS3Addr)
+			if err != nil {
+				log.Fatalf("resolve the fake S3 endpoint failed: %v", err)
+			}
+			query.Set("endpoint", endpoint)
+			query.Set("force-path-style", "true")
+			query.Set("access-key", "any")
+			query.Set("secret-access-key", "any")
+			u.RawQuery = query.Encode()
+			log.Infof("backup to %s", u)
+		}
+	}
 	suit := util.Suit{
 		Config:   &cfg,
 		Provider: cluster.NewDefaultClusterProvider(),
@@ -110,6 +142,7 @@ func main() {
 				Contention:      *contention,
 				DbName:          *dbname,
 				BackupURI:       *u,
+				GCLifeTime:      *gcLifeTime,
 			},
 			Features: backup.Features{
 				Pessimistic: *pessimistic,
@@ -124,4 +157,28 @@ func main() {
 			fixture.Context.TiDBClusterConfig),
 	}
 	suit.Run(context.Background())
+}
+
+// fakeS3Endpoint returns the endpoint of the fake S3 with the first non-loopback IP of this host if addr has no host
+func fakeS3Endpoint(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if host == "" {
+		addrs, err := net.InterfaceAddrs()
+		if err != nil {
+			return "", err
+		}
+		for _, a := range addrs {
+			if ipNet, ok := a.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+				host = ipNet.IP.String()
+				break
+			}
+		}
+		if host == "" {
+			return "", fmt.Errorf("no IP found for %s", addr)
+		}
+	}
+	return "http://" + net.JoinHostPort(host, port), nil
 }
\ No newline at end of file
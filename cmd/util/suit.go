@@ -33,6 +33,7 @@ import (
 	"github.com/pingcap/tipocket/pkg/logs"
 	"github.com/pingcap/tipocket/pkg/nemesis"
 	"github.com/pingcap/tipocket/pkg/test-infra/fixture"
+	"github.com/pingcap/tipocket/pkg/test-infra/tidb"
 	"github.com/pingcap/tipocket/pkg/verify"
 )
 
@@ -101,6 +102,9 @@ func (suit *Suit) Run(ctx context.Context) {
 	if suit.Config.RunRound == 0 {
 		suit.Config.RunRound = 1
 	}
+	if suit.Config.HealthGateTimeout == 0 {
+		suit.Config.HealthGateTimeout = fixture.Context.HealthGateTimeout
+	}
 	// fill clientNodes
 	retClientCount := len(suit.Config.ClientNodes)
 	for len(suit.Config.ClientNodes) < suit.Config.ClientCount {
@@ -324,6 +328,27 @@ func parseNemesisGenerator(name string) (g core.NemesisGenerator) {
 	// TODO: Change that name
 	case "leader-shuffle":
 		g = nemesis.NewLeaderShuffleGenerator(name)
+	case "failpoint_2pc", "failpoint_async_commit", "failpoint_region_split", "failpoint_raftstore",
+		"failpoint_tso", "failpoint_random":
+		g = nemesis.NewFailpointGenerator(name)
+	case "evict_leader_tikv_1node", "offline_tikv_1node":
+		g = nemesis.NewStoreGenerator(name)
+	case "store_limit_aggressive", "store_limit_throttle", "hot_region_aggressive":
+		g = nemesis.NewPDConfigGenerator(name)
+	case "rolling_upgrade", "rolling_upgrade_oneway", "rolling_restart_tikv_config":
+		var tikvConfig string
+		if fixture.Context.UpgradeTiKVConfig != "" {
+			var err error
+			if tikvConfig, err = tidb.ParseConfig(fixture.Context.UpgradeTiKVConfig); err != nil {
+				log.Fatalf("parse -upgrade.tikv-config failed: %v", err)
+			}
+		}
+		g = nemesis.NewRollingUpgradeGenerator(name, fixture.Context.UpgradeVersion, tikvConfig)
+	case "cpu_stress_tidb_1node", "cpu_stress_tikv_1node", "cpu_stress_pd_1node", "cpu_stress_tiflash_1node",
+		"memory_stress_tidb_1node", "memory_stress_tikv_1node", "memory_stress_pd_1node", "memory_stress_tiflash_1node":
+		g = nemesis.NewStressChaosGenerator(name)
+	case "disk_fill_tikv_1node", "disk_fill_pd_1node", "disk_fill_tiflash_1node":
+		g = nemesis.NewDiskFillGenerator(name, fixture.Context.DiskFillPercent)
 	default:
 		log.Fatalf("invalid nemesis generator %s", name)
 	}
@@ -0,0 +1,94 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package checkutil has the helpers shared by the checkers of the recorded histories.
+package checkutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ngaut/log"
+
+	"github.com/pingcap/tipocket/pkg/core"
+)
+
+// MaxReportedViolations limits the violations in the check error
+const MaxReportedViolations = 10
+
+// PairOps pairs the invocations and returns of each process in the history and calls fn with each pair,
+// the operations other than the invocations and returns, e.g. the nemesis events, are skipped
+func PairOps(ops []core.Operation, fn func(invoke, ret core.Operation)) error {
+	pending := make(map[int64]core.Operation)
+	for _, op := range ops {
+		switch op.Action {
+		case core.InvokeOperation:
+			pending[op.Proc] = op
+		case core.ReturnOperation:
+			invoke, ok := pending[op.Proc]
+			if !ok {
+				return fmt.Errorf("no request for the response of process %d", op.Proc)
+			}
+			delete(pending, op.Proc)
+			fn(invoke, op)
+		}
+	}
+	return nil
+}
+
+// Report logs the violations with the prefix of the checker name, and returns the check result,
+// the error has at most MaxReportedViolations of them
+func Report(name string, violations []string) (bool, error) {
+	if len(violations) == 0 {
+		return true, nil
+	}
+	for _, v := range violations {
+		log.Errorf("[%s] %s", name, v)
+	}
+	if len(violations) > MaxReportedViolations {
+		violations = violations[:MaxReportedViolations]
+	}
+	return false, fmt.Errorf("%s violations:\n%s", name, strings.Join(violations, "\n"))
+}
+
+// CommitWindow counts the transactions committed between two snapshots by their commit_ts
+type CommitWindow struct {
+	commits []uint64
+	unknown int64
+}
+
+// NewCommitWindow creates a CommitWindow of the commit_ts of the committed transactions,
+// and the number of the transactions whose outcomes are unknown
+func NewCommitWindow(commits []uint64, unknown int64) *CommitWindow {
+	sorted := append([]uint64(nil), commits...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return &CommitWindow{commits: sorted, unknown: unknown}
+}
+
+// CommittedBefore returns the number of the transactions committed before ts
+func (w *CommitWindow) CommittedBefore(ts uint64) int64 {
+	return int64(sort.Search(len(w.commits), func(i int) bool { return w.commits[i] >= ts }))
+}
+
+// Check checks the number of the things added between the snapshots of prevTS and ts is no less than
+// the transactions committed between them and no more than the ones which may be committed,
+// it returns the violation, or an empty string if there is none
+func (w *CommitWindow) Check(what string, added int64, prevTS, ts uint64) string {
+	committed := w.CommittedBefore(ts) - w.CommittedBefore(prevTS)
+	if added >= committed && added <= committed+w.unknown {
+		return ""
+	}
+	return fmt.Sprintf("%d %s are added between read_ts %d and %d, but %d transfers are committed and %d are unknown",
+		added, what, prevTS, ts, committed, w.unknown)
+}
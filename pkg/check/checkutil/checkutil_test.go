@@ -0,0 +1,61 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pingcap/tipocket/pkg/core"
+)
+
+func TestPairOps(t *testing.T) {
+	ops := []core.Operation{
+		{Action: core.InvokeOperation, Proc: 0, Data: "req0"},
+		{Action: core.InvokeOperation, Proc: 1, Data: "req1"},
+		{Action: core.InvokeNemesis, Data: "nemesis"},
+		{Action: core.ReturnOperation, Proc: 1, Data: "res1"},
+		{Action: core.RecoverNemesis, Data: "nemesis"},
+		{Action: core.ReturnOperation, Proc: 0, Data: "res0"},
+	}
+	var pairs []string
+	if err := PairOps(ops, func(invoke, ret core.Operation) {
+		pairs = append(pairs, fmt.Sprintf("%v-%v", invoke.Data, ret.Data))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(pairs) != "[req1-res1 req0-res0]" {
+		t.Fatalf("unexpected pairs %v", pairs)
+	}
+	if err := PairOps(ops[3:], func(invoke, ret core.Operation) {}); err == nil {
+		t.Fatal("expect the response without request is found")
+	}
+}
+
+func TestCommitWindow(t *testing.T) {
+	w := NewCommitWindow([]uint64{30, 10, 20}, 1)
+	if n := w.CommittedBefore(20); n != 1 {
+		t.Fatalf("expect 1 committed before 20, got %d", n)
+	}
+	for _, added := range []int64{2, 3} {
+		if v := w.Check("transactions", added, 15, 35); v != "" {
+			t.Fatalf("expect %d added transactions ok, got %s", added, v)
+		}
+	}
+	for _, added := range []int64{1, 4} {
+		if v := w.Check("transactions", added, 15, 35); v == "" {
+			t.Fatalf("expect %d added transactions violate", added)
+		}
+	}
+}
@@ -47,8 +47,16 @@ func (Checker) Name() string {
 	return "porcupine_checker"
 }
 
-// ConvertOperationsToEvents converts core.Operations to porcupine.Event.
+// ConvertOperationsToEvents converts core.Operations to porcupine.Event,
+// the nemesis, health gate and pod restart events in the history are skipped.
 func ConvertOperationsToEvents(ops []core.Operation) ([]porcupine.Event, error) {
+	clientOps := make([]core.Operation, 0, len(ops))
+	for _, op := range ops {
+		if op.Action == core.InvokeOperation || op.Action == core.ReturnOperation {
+			clientOps = append(clientOps, op)
+		}
+	}
+	ops = clientOps
 	if len(ops)%2 != 0 {
 		return nil, fmt.Errorf("history is not complete")
 	}
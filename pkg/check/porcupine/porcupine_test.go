@@ -72,3 +72,38 @@ func TestPorcupineChecker(t *testing.T) {
 		t.Fatal("must be linearizable")
 	}
 }
+
+func TestPorcupineCheckerSkipNemesis(t *testing.T) {
+	ops := []core.Operation{
+		{Action: core.InvokeOperation, Proc: 1, Data: noopRequest{Op: 0}},
+		{Action: core.HealthGateFailure, Data: core.HealthGateRecord{}},
+		{Action: core.ReturnOperation, Proc: 1, Data: noopResponse{Value: 10}},
+		{Action: core.InvokeNemesis, Data: core.NemesisGeneratorRecord{Name: "kill"}},
+		{Action: core.InvokeOperation, Proc: 2, Data: noopRequest{Op: 1, Value: 15}},
+		{Action: core.PodRestart, Data: core.PodRestartRecord{}},
+		{Action: core.ReturnOperation, Proc: 2, Data: noopResponse{Ok: true}},
+		{Action: core.RecoverNemesis, Data: "kill"},
+		{Action: core.InvokeNemesis, Data: core.NemesisGeneratorRecord{Name: "partition"}},
+		{Action: core.InvokeOperation, Proc: 3, Data: noopRequest{Op: 0}},
+		{Action: core.ReturnOperation, Proc: 3, Data: noopResponse{Value: 15}},
+	}
+
+	events, err := ConvertOperationsToEvents(ops)
+	if err != nil {
+		t.Fatalf("convert history failed %v", err)
+	}
+	if len(events) != 6 {
+		t.Fatalf("expect 6 events, got %d", len(events))
+	}
+	ok, err := Checker{}.Check(noop{}, ops)
+	if err != nil {
+		t.Fatalf("verify history failed %v", err)
+	}
+	if !ok {
+		t.Fatal("must be linearizable")
+	}
+
+	if _, err := ConvertOperationsToEvents(ops[:len(ops)-1]); err == nil {
+		t.Fatal("the history without the last return must be incomplete")
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FixSQLs returns the statements which make the downstream the same as the upstream
+func (d *TableDiff) FixSQLs() []string {
+	var (
+		t     = d.t
+		sqls  []string
+		names []int
+	)
+	// generated columns can't be written
+	for i, c := range t.columns {
+		if !c.generated {
+			names = append(names, i)
+		}
+	}
+	for _, row := range d.Rows {
+		if row.Downstream != nil {
+			// keyless rows are deleted one by one since there may be duplicated rows
+			var limit string
+			if t.keyless {
+				limit = " LIMIT 1"
+			}
+			sqls = append(sqls, fmt.Sprintf("DELETE FROM %s WHERE %s%s;", t.fullName(), t.match(row.Downstream), limit))
+		}
+		if row.Upstream != nil {
+			sqls = append(sqls, fmt.Sprintf("INSERT INTO %s (%s) VALUES %s;",
+				t.fullName(), t.columnNames(names), rowString(pick(row.Upstream, names))))
+		}
+	}
+	return sqls
+}
+
+// match returns the NULL-safe condition of the row by the keys
+func (t *table) match(row []*string) string {
+	conds := make([]string, len(t.keys))
+	for i, key := range t.keys {
+		conds[i] = fmt.Sprintf("%s <=> %s", quoteName(t.columns[key].name), literal(row[key]))
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// Report returns the human readable differences of the table
+func (d *TableDiff) Report() string {
+	var b strings.Builder
+	if d.StructDiff != "" {
+		fmt.Fprintf(&b, "table %s: different structure, %s\n", d.t.fullName(), d.StructDiff)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "table %s: %d chunks compared, %d mismatched, %d rows different\n",
+		d.t.fullName(), d.Chunks, len(d.Mismatched), len(d.Rows))
+	for _, chunk := range d.Mismatched {
+		fmt.Fprintf(&b, "  mismatched chunk %s\n", chunk)
+	}
+	for _, row := range d.Rows {
+		switch {
+		case row.Downstream == nil:
+			fmt.Fprintf(&b, "  key %s missing in downstream, upstream %s\n", rowString(row.Key), rowString(row.Upstream))
+		case row.Upstream == nil:
+			fmt.Fprintf(&b, "  key %s redundant in downstream, downstream %s\n", rowString(row.Key), rowString(row.Downstream))
+		default:
+			fmt.Fprintf(&b, "  key %s different, upstream %s, downstream %s\n",
+				rowString(row.Key), rowString(row.Upstream), rowString(row.Downstream))
+		}
+	}
+	if len(d.Rows) > 0 {
+		b.WriteString("  fix SQL:\n")
+		for _, sql := range d.FixSQLs() {
+			fmt.Fprintf(&b, "    %s\n", sql)
+		}
+	}
+	return b.String()
+}
+
+// Report returns the report of the different tables, empty if all the tables are the same
+func Report(diffs []*TableDiff) string {
+	var b strings.Builder
+	for _, d := range diffs {
+		if !d.Equal() {
+			b.WriteString(d.Report())
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,358 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncdiff
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+)
+
+// Querier is *sql.DB, *sql.Tx or anything else queries in the same way,
+// use a transaction or set tidb_snapshot to compare a consistent snapshot.
+type Querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// ContextQuerier is *sql.DB, *sql.Tx or *sql.Conn, which queries with a context
+type ContextQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// contextQuerier queries with the context, so that the comparison is canceled with it
+type contextQuerier struct {
+	ctx context.Context
+	db  ContextQuerier
+}
+
+// Query implements Querier interface
+func (q *contextQuerier) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return q.db.QueryContext(q.ctx, query, args...)
+}
+
+// WithContext returns the Querier which is canceled with the context
+func WithContext(ctx context.Context, db ContextQuerier) Querier {
+	return &contextQuerier{ctx: ctx, db: db}
+}
+
+// Config of the comparison
+type Config struct {
+	// ChunkSize is the row count of the chunks the table is split into
+	ChunkSize int
+	// RowThreshold is the row count under which a mismatched chunk is compared row by row instead of bisected
+	RowThreshold int
+}
+
+// DefaultConfig is the config used for the zero values
+var DefaultConfig = Config{
+	ChunkSize:    10000,
+	RowThreshold: 64,
+}
+
+// Chunk is the key range (Lower, Upper] of a table, nil bound is unbounded
+type Chunk struct {
+	Lower []string
+	Upper []string
+}
+
+func (c *Chunk) String() string {
+	bound := func(values []string, inf string) string {
+		if values == nil {
+			return inf
+		}
+		return "(" + strings.Join(values, ", ") + ")"
+	}
+	return fmt.Sprintf("(%s, %s]", bound(c.Lower, "-inf"), bound(c.Upper, "+inf"))
+}
+
+// RowDiff is a row which is different between upstream and downstream, the row missing in one side is nil
+type RowDiff struct {
+	Key        []*string
+	Upstream   []*string
+	Downstream []*string
+}
+
+// TableDiff is the result of comparing a table
+type TableDiff struct {
+	Schema string
+	Table  string
+	// StructDiff is the difference of the table structures, empty if they are the same,
+	// the data isn't compared if the structures are different
+	StructDiff string
+	// Chunks is the number of chunks compared, including the bisected ones
+	Chunks     int
+	Mismatched []*Chunk
+	Rows       []*RowDiff
+	t          *table
+}
+
+// Equal tells if the table is the same in upstream and downstream
+func (d *TableDiff) Equal() bool {
+	return d.StructDiff == "" && len(d.Mismatched) == 0 && len(d.Rows) == 0
+}
+
+type comparer struct {
+	up, down Querier
+	cfg      Config
+	t        *table
+	diff     *TableDiff
+}
+
+// CompareTables compares the tables of the schema in upstream and downstream
+func CompareTables(up, down Querier, schema string, tables []string, cfg Config) ([]*TableDiff, error) {
+	var diffs []*TableDiff
+	for _, table := range tables {
+		diff, err := CompareTable(up, down, schema, table, cfg)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
+
+// CompareTable checks the table structures are the same, then splits the table into key range chunks,
+// compares the row count and the checksum of each chunk,
+// and bisects the mismatched chunks until they are small enough to be compared row by row.
+func CompareTable(up, down Querier, schema, table string, cfg Config) (*TableDiff, error) {
+	if cfg.ChunkSize <= 0 {
+		cfg.ChunkSize = DefaultConfig.ChunkSize
+	}
+	if cfg.RowThreshold <= 0 {
+		cfg.RowThreshold = DefaultConfig.RowThreshold
+	}
+	t, err := fetchTable(up, schema, table)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	c := comparer{
+		up:   up,
+		down: down,
+		cfg:  cfg,
+		t:    t,
+		diff: &TableDiff{Schema: schema, Table: table, t: t},
+	}
+	downTable, err := fetchTable(down, schema, table)
+	if errors.Cause(err) == errTableNotFound {
+		c.diff.StructDiff = "table not found in downstream"
+		return c.diff, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if c.diff.StructDiff = t.structDiff(downTable); c.diff.StructDiff != "" {
+		log.Infof("[sync-diff] table %s structure different, %s", t.fullName(), c.diff.StructDiff)
+		return c.diff, nil
+	}
+	chunks, err := c.split()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, chunk := range chunks {
+		if err := c.compareChunk(chunk); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	log.Infof("[sync-diff] table %s compared in %d chunks, %d mismatched, %d rows different",
+		t.fullName(), c.diff.Chunks, len(c.diff.Mismatched), len(c.diff.Rows))
+	return c.diff, nil
+}
+
+// Checksum returns the row count and the checksum of the whole table,
+// which are the same as the chunk checksums of CompareTable
+func Checksum(db Querier, schema, table string) (count int, checksum string, err error) {
+	t, err := fetchTable(db, schema, table)
+	if err != nil {
+		return 0, "", errors.Trace(err)
+	}
+	c := comparer{t: t}
+	return c.checksum(db, &Chunk{})
+}
+
+// split splits the table into chunks of ChunkSize rows by the upstream keys,
+// the last chunk is unbounded so that the extra rows in downstream are included
+func (c *comparer) split() ([]*Chunk, error) {
+	if c.t.keyless {
+		return []*Chunk{{}}, nil
+	}
+	var (
+		chunks []*Chunk
+		lower  []string
+	)
+	for {
+		query, args := c.t.boundarySQL(&Chunk{Lower: lower}, c.cfg.ChunkSize-1)
+		rows, err := queryRows(c.up, query, args)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if len(rows) == 0 {
+			return append(chunks, &Chunk{Lower: lower}), nil
+		}
+		upper := values(rows[0])
+		chunks = append(chunks, &Chunk{Lower: lower, Upper: upper})
+		lower = upper
+	}
+}
+
+func (c *comparer) checksum(db Querier, chunk *Chunk) (count int, checksum string, err error) {
+	query, args := c.t.checksumSQL(chunk)
+	rows, err := queryRows(db, query, args)
+	if err != nil {
+		return 0, "", errors.Trace(err)
+	}
+	if len(rows) != 1 || rows[0][0] == nil || rows[0][1] == nil {
+		return 0, "", errors.Errorf("unexpected checksum result of %s", query)
+	}
+	_, err = fmt.Sscan(*rows[0][0], &count)
+	return count, *rows[0][1], errors.Trace(err)
+}
+
+func (c *comparer) compareChunk(chunk *Chunk) error {
+	c.diff.Chunks++
+	count1, checksum1, err := c.checksum(c.up, chunk)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	count2, checksum2, err := c.checksum(c.down, chunk)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if count1 == count2 && checksum1 == checksum2 {
+		return nil
+	}
+	log.Infof("[sync-diff] table %s chunk %s mismatched, count %d vs %d, checksum %s vs %s",
+		c.t.fullName(), chunk, count1, count2, checksum1, checksum2)
+
+	// bisect the chunk by the middle key of the side with more rows
+	db, count := c.up, count1
+	if count2 > count1 {
+		db, count = c.down, count2
+	}
+	if c.t.keyless || count <= c.cfg.RowThreshold {
+		c.diff.Mismatched = append(c.diff.Mismatched, chunk)
+		return errors.Trace(c.compareRows(chunk))
+	}
+	query, args := c.t.boundarySQL(chunk, count/2)
+	rows, err := queryRows(db, query, args)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(rows) == 0 {
+		// the data is changed, may be caused by comparing without a snapshot
+		return errors.Errorf("no middle key in chunk %s of table %s", chunk, c.t.fullName())
+	}
+	middle := values(rows[0])
+	if err := c.compareChunk(&Chunk{Lower: chunk.Lower, Upper: middle}); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(c.compareChunk(&Chunk{Lower: middle, Upper: chunk.Upper}))
+}
+
+// compareRows compares the rows of the chunk by their keys
+func (c *comparer) compareRows(chunk *Chunk) error {
+	query, args := c.t.rowsSQL(chunk)
+	rows1, err := queryRows(c.up, query, args)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	rows2, err := queryRows(c.down, query, args)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	c.diff.Rows = append(c.diff.Rows, diffRows(c.t.keys, rows1, rows2)...)
+	return nil
+}
+
+// diffRows matches the rows by their keys, rows with the same key are matched in order
+func diffRows(keys []int, rows1, rows2 [][]*string) []*RowDiff {
+	var (
+		order   []string
+		grouped = make(map[string][2][][]*string)
+	)
+	group := func(rows [][]*string, side int) {
+		for _, row := range rows {
+			k := rowString(pick(row, keys))
+			g, ok := grouped[k]
+			if !ok {
+				order = append(order, k)
+			}
+			g[side] = append(g[side], row)
+			grouped[k] = g
+		}
+	}
+	group(rows1, 0)
+	group(rows2, 1)
+	sort.Strings(order)
+
+	var diffs []*RowDiff
+	for _, k := range order {
+		g := grouped[k]
+		for i := 0; i < len(g[0]) || i < len(g[1]); i++ {
+			var d RowDiff
+			if i < len(g[0]) {
+				d.Upstream = g[0][i]
+				d.Key = pick(d.Upstream, keys)
+			}
+			if i < len(g[1]) {
+				d.Downstream = g[1][i]
+				d.Key = pick(d.Downstream, keys)
+			}
+			if d.Upstream != nil && d.Downstream != nil && rowString(d.Upstream) == rowString(d.Downstream) {
+				continue
+			}
+			diffs = append(diffs, &d)
+		}
+	}
+	return diffs
+}
+
+func pick(row []*string, indexes []int) []*string {
+	picked := make([]*string, len(indexes))
+	for i, index := range indexes {
+		picked[i] = row[index]
+	}
+	return picked
+}
+
+func values(row []*string) []string {
+	vs := make([]string, len(row))
+	for i, v := range row {
+		if v != nil {
+			vs[i] = *v
+		}
+	}
+	return vs
+}
+
+func rowString(row []*string) string {
+	strs := make([]string, len(row))
+	for i, v := range row {
+		strs[i] = literal(v)
+	}
+	return "(" + strings.Join(strs, ", ") + ")"
+}
+
+// literal quotes the value as a SQL string literal
+func literal(v *string) string {
+	if v == nil {
+		return "NULL"
+	}
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\x00", `\0`)
+	return "'" + r.Replace(*v) + "'"
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncdiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func str(s string) *string {
+	return &s
+}
+
+func testTable() *table {
+	return &table{
+		schema: "test",
+		name:   "t",
+		columns: []*column{
+			{name: "id", dataType: "bigint"},
+			{name: "name", dataType: "varchar"},
+			{name: "g", dataType: "int", generated: true},
+		},
+		keys: []int{0},
+	}
+}
+
+func TestChunkSQL(t *testing.T) {
+	tbl := testTable()
+	query, args := tbl.checksumSQL(&Chunk{Lower: []string{"9223372036854775806"}, Upper: []string{"18446744073709551615"}})
+	expect := "SELECT COUNT(*), COALESCE(BIT_XOR(CRC32(CONCAT_WS(',', `id`, `name`, `g`, ISNULL(`id`), ISNULL(`name`), ISNULL(`g`)))), 0) " +
+		"FROM `test`.`t` WHERE (`id`) > (?) AND (`id`) <= (?)"
+	if query != expect {
+		t.Fatalf("unexpected checksum SQL %s", query)
+	}
+	if !reflect.DeepEqual(args, []interface{}{int64(9223372036854775806), uint64(18446744073709551615)}) {
+		t.Fatalf("unexpected args %#v", args)
+	}
+	query, args = tbl.boundarySQL(&Chunk{}, 99)
+	if query != "SELECT `id` FROM `test`.`t` WHERE TRUE ORDER BY `id` LIMIT 1 OFFSET 99" || len(args) != 0 {
+		t.Fatalf("unexpected boundary SQL %s %v", query, args)
+	}
+}
+
+func TestApproximateValues(t *testing.T) {
+	tbl := testTable()
+	tbl.columns = append(tbl.columns, &column{name: "f", dataType: "float"}, &column{name: "d", dataType: "double"})
+	query, _ := tbl.rowsSQL(&Chunk{})
+	expect := "SELECT `id`, `name`, `g`, CAST(`f` AS DECIMAL(65, 5)), CAST(`d` AS DECIMAL(65, 10)) FROM `test`.`t` WHERE TRUE"
+	if query != expect {
+		t.Fatalf("unexpected rows SQL %s", query)
+	}
+}
+
+func TestStructDiff(t *testing.T) {
+	tbl := testTable()
+	if diff := tbl.structDiff(testTable()); diff != "" {
+		t.Fatalf("expect the same structure, got %s", diff)
+	}
+	other := testTable()
+	other.columns[1] = &column{name: "name", dataType: "text"}
+	if diff := tbl.structDiff(other); diff == "" {
+		t.Fatal("expect different column types")
+	}
+	other = testTable()
+	other.keys = []int{1}
+	if diff := tbl.structDiff(other); diff == "" {
+		t.Fatal("expect different keys")
+	}
+	other = testTable()
+	other.columns = other.columns[:2]
+	if diff := tbl.structDiff(other); diff == "" {
+		t.Fatal("expect different columns")
+	}
+	if d := (&TableDiff{StructDiff: "different", t: tbl}); d.Equal() || Report([]*TableDiff{d}) == "" {
+		t.Fatal("expect the structure difference reported")
+	}
+}
+
+func TestDiffRows(t *testing.T) {
+	tbl := testTable()
+	up := [][]*string{
+		{str("1"), str("a"), str("1")},
+		{str("2"), str("b"), str("2")},
+		{str("3"), nil, str("3")},
+	}
+	down := [][]*string{
+		{str("3"), str("c"), str("3")},
+		{str("1"), str("a"), str("1")},
+		{str("4"), str("d'"), str("4")},
+	}
+	diff := TableDiff{Rows: diffRows(tbl.keys, up, down), Mismatched: []*Chunk{{}}, t: tbl}
+	if len(diff.Rows) != 3 {
+		t.Fatalf("expect 3 different rows, got %d", len(diff.Rows))
+	}
+	expect := []string{
+		"INSERT INTO `test`.`t` (`id`, `name`) VALUES ('2', 'b');",
+		"DELETE FROM `test`.`t` WHERE `id` <=> '3';",
+		"INSERT INTO `test`.`t` (`id`, `name`) VALUES ('3', NULL);",
+		"DELETE FROM `test`.`t` WHERE `id` <=> '4';",
+	}
+	if sqls := diff.FixSQLs(); !reflect.DeepEqual(sqls, expect) {
+		t.Fatalf("unexpected fix SQLs %v", sqls)
+	}
+	if diff.Equal() {
+		t.Fatal("expect not equal")
+	}
+}
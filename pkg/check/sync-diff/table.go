@@ -0,0 +1,300 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncdiff
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+const (
+	columnsSQL = `SELECT COLUMN_NAME, DATA_TYPE, EXTRA FROM information_schema.columns
+WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION`
+	primaryKeySQL = `SELECT COLUMN_NAME FROM information_schema.key_column_usage
+WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY' ORDER BY ORDINAL_POSITION`
+	uniqueKeySQL = `SELECT INDEX_NAME, COLUMN_NAME, NULLABLE FROM information_schema.statistics
+WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND NON_UNIQUE = 0 ORDER BY INDEX_NAME, SEQ_IN_INDEX`
+
+	// floatScale and doubleScale are the decimals the approximate values are rounded to
+	floatScale  = 5
+	doubleScale = 10
+)
+
+// errTableNotFound is returned if the table doesn't exist
+var errTableNotFound = errors.New("table not found")
+
+// column of the compared table
+type column struct {
+	name      string
+	dataType  string
+	generated bool
+}
+
+// table is the compared table, rows are identified and ordered by its keys,
+// which is the primary key or a not null unique key, or all the columns if there is none
+type table struct {
+	schema  string
+	name    string
+	columns []*column
+	// keys are the indexes of the key columns
+	keys []int
+	// keyless table is compared as a multiset of rows in a single chunk
+	keyless bool
+}
+
+func fetchTable(db Querier, schema, name string) (*table, error) {
+	t := table{schema: schema, name: name}
+	rows, err := db.Query(columnsSQL, schema, name)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for rows.Next() {
+		var c column
+		var extra string
+		if err := rows.Scan(&c.name, &c.dataType, &extra); err != nil {
+			rows.Close()
+			return nil, errors.Trace(err)
+		}
+		c.generated = strings.Contains(strings.ToUpper(extra), "GENERATED")
+		t.columns = append(t.columns, &c)
+	}
+	rows.Close()
+	if len(t.columns) == 0 {
+		return nil, errors.Annotatef(errTableNotFound, "table %s.%s", schema, name)
+	}
+
+	keys, err := t.fetchKeys(db)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(keys) == 0 {
+		t.keyless = true
+		for i := range t.columns {
+			t.keys = append(t.keys, i)
+		}
+		return &t, nil
+	}
+	for _, key := range keys {
+		for i, c := range t.columns {
+			if strings.EqualFold(c.name, key) {
+				t.keys = append(t.keys, i)
+			}
+		}
+	}
+	return &t, nil
+}
+
+func (t *table) fetchKeys(db Querier) ([]string, error) {
+	rows, err := db.Query(primaryKeySQL, t.schema, t.name)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return nil, errors.Trace(err)
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if len(keys) > 0 {
+		return keys, nil
+	}
+
+	// the first unique key without nullable columns
+	rows, err = db.Query(uniqueKeySQL, t.schema, t.name)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer rows.Close()
+	var (
+		indexes  []string
+		columns  = make(map[string][]string)
+		nullable = make(map[string]bool)
+	)
+	for rows.Next() {
+		var index, key, null string
+		if err := rows.Scan(&index, &key, &null); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if _, ok := columns[index]; !ok {
+			indexes = append(indexes, index)
+		}
+		columns[index] = append(columns[index], key)
+		nullable[index] = nullable[index] || null == "YES"
+	}
+	for _, index := range indexes {
+		if !nullable[index] {
+			return columns[index], nil
+		}
+	}
+	return nil, nil
+}
+
+func quoteName(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+func (t *table) fullName() string {
+	return quoteName(t.schema) + "." + quoteName(t.name)
+}
+
+func (t *table) columnNames(indexes []int) string {
+	names := make([]string, len(indexes))
+	for i, index := range indexes {
+		names[i] = quoteName(t.columns[index].name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// keyArgs converts the key values to the arguments, integers are not passed as strings
+// since comparing integers and strings loses the precision of big integers
+func (t *table) keyArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+		if !strings.HasSuffix(t.columns[t.keys[i]].dataType, "int") {
+			continue
+		}
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			args[i] = n
+		} else if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+			args[i] = n
+		}
+	}
+	return args
+}
+
+// where returns the condition of the chunk
+func (t *table) where(c *Chunk) (string, []interface{}) {
+	var (
+		conds []string
+		args  []interface{}
+		keys  = "(" + t.columnNames(t.keys) + ")"
+		marks = "(" + strings.TrimSuffix(strings.Repeat("?, ", len(t.keys)), ", ") + ")"
+	)
+	if c.Lower != nil {
+		conds = append(conds, keys+" > "+marks)
+		args = append(args, t.keyArgs(c.Lower)...)
+	}
+	if c.Upper != nil {
+		conds = append(conds, keys+" <= "+marks)
+		args = append(args, t.keyArgs(c.Upper)...)
+	}
+	if len(conds) == 0 {
+		return "TRUE", nil
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// checksumSQL returns the row count and the checksum of the chunk,
+// NULL flags are concatenated since CONCAT_WS skips NULL values
+func (t *table) checksumSQL(c *Chunk) (string, []interface{}) {
+	var values []string
+	for i := range t.columns {
+		values = append(values, t.value(i))
+	}
+	for _, c := range t.columns {
+		values = append(values, "ISNULL("+quoteName(c.name)+")")
+	}
+	where, args := t.where(c)
+	return fmt.Sprintf("SELECT COUNT(*), COALESCE(BIT_XOR(CRC32(CONCAT_WS(',', %s))), 0) FROM %s WHERE %s",
+		strings.Join(values, ", "), t.fullName(), where), args
+}
+
+// structDiff returns the difference of the column names, the column types and the keys, empty if there is none
+func (t *table) structDiff(other *table) string {
+	if len(t.columns) != len(other.columns) {
+		return fmt.Sprintf("%d columns in upstream, %d columns in downstream", len(t.columns), len(other.columns))
+	}
+	for i, c := range t.columns {
+		o := other.columns[i]
+		if !strings.EqualFold(c.name, o.name) || c.dataType != o.dataType {
+			return fmt.Sprintf("column %d is %s %s in upstream, %s %s in downstream", i, c.name, c.dataType, o.name, o.dataType)
+		}
+	}
+	if t.columnNames(t.keys) != other.columnNames(other.keys) {
+		return fmt.Sprintf("keys are (%s) in upstream, (%s) in downstream", t.columnNames(t.keys), other.columnNames(other.keys))
+	}
+	return ""
+}
+
+// value returns the expression of the column to compare, approximate values are rounded to fixed decimals,
+// since their string formats of MySQL and TiDB are different, e.g. 1e+20 and 100000000000000000000
+func (t *table) value(index int) string {
+	name := quoteName(t.columns[index].name)
+	switch t.columns[index].dataType {
+	case "float":
+		return fmt.Sprintf("CAST(%s AS DECIMAL(65, %d))", name, floatScale)
+	case "double":
+		return fmt.Sprintf("CAST(%s AS DECIMAL(65, %d))", name, doubleScale)
+	}
+	return name
+}
+
+// boundarySQL returns the key of the offset-th row of the chunk
+func (t *table) boundarySQL(c *Chunk, offset int) (string, []interface{}) {
+	where, args := t.where(c)
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s LIMIT 1 OFFSET %d",
+		t.columnNames(t.keys), t.fullName(), where, t.columnNames(t.keys), offset), args
+}
+
+// rowsSQL returns all the rows of the chunk
+func (t *table) rowsSQL(c *Chunk) (string, []interface{}) {
+	values := make([]string, len(t.columns))
+	for i := range t.columns {
+		values[i] = t.value(i)
+	}
+	where, args := t.where(c)
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(values, ", "), t.fullName(), where), args
+}
+
+// queryRows returns the rows as strings, NULL values are nil
+func queryRows(db Querier, query string, args []interface{}) ([][]*string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, errors.Annotatef(err, "query %s", query)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var result [][]*string
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, errors.Trace(err)
+		}
+		row := make([]*string, len(columns))
+		for i, v := range values {
+			if v.Valid {
+				s := v.String
+				row[i] = &s
+			}
+		}
+		result = append(result, row)
+	}
+	return result, errors.Trace(rows.Err())
+}
@@ -0,0 +1,317 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tso checks the histories of TSO requests to the global and local TSO allocators of PD.
+package tso
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ngaut/log"
+
+	"github.com/pingcap/tipocket/pkg/check/checkutil"
+	"github.com/pingcap/tipocket/pkg/core"
+	"github.com/pingcap/tipocket/pkg/history"
+)
+
+// GlobalDCLocation is the dc-location of the global TSO allocator
+const GlobalDCLocation = "global"
+
+const (
+	physicalShiftBits = 18
+	logicalMask       = (1 << physicalShiftBits) - 1
+)
+
+// Kind is the kind of a request
+type Kind string
+
+// Kinds of requests
+const (
+	// KindTSO gets a TSO from the allocator of the dc-location
+	KindTSO Kind = "tso"
+	// KindTransfer transfers the allocator of the dc-location to the target PD member,
+	// the allocator of the global dc-location is the PD leader
+	KindTransfer Kind = "transfer"
+)
+
+// Request is a request to PD
+type Request struct {
+	Kind       Kind   `json:"kind"`
+	DCLocation string `json:"dc_location"`
+	Target     string `json:"target,omitempty"`
+}
+
+// Response is the response of a request, Start and End are the wall-clock bounds of the request
+type Response struct {
+	Physical int64     `json:"physical,omitempty"`
+	Logical  int64     `json:"logical,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Error    string    `json:"error,omitempty"`
+	Unknown  bool      `json:"unknown,omitempty"`
+}
+
+// IsUnknown implements core.UnknownResponse
+func (r Response) IsUnknown() bool {
+	return r.Unknown
+}
+
+// TS composes the physical and logical parts into a timestamp
+func (r Response) TS() uint64 {
+	return uint64(r.Physical)<<physicalShiftBits | uint64(r.Logical)&logicalMask
+}
+
+type parser struct{}
+
+// Parser parses a history of TSO requests
+func Parser() history.RecordParser {
+	return parser{}
+}
+
+// OnRequest implements history.RecordParser
+func (parser) OnRequest(data json.RawMessage) (interface{}, error) {
+	r := Request{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+// OnResponse implements history.RecordParser
+func (parser) OnResponse(data json.RawMessage) (interface{}, error) {
+	r := Response{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+// OnNoopResponse implements history.RecordParser
+func (parser) OnNoopResponse() interface{} {
+	return Response{Unknown: true}
+}
+
+// OnState implements history.RecordParser
+func (parser) OnState(state json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+type op struct {
+	Request
+	Response
+}
+
+func (o *op) String() string {
+	return fmt.Sprintf("%s tso %d (physical %d, logical %d) in [%s, %s]", o.DCLocation, o.TS(), o.Physical, o.Logical,
+		o.Start.Format(time.RFC3339Nano), o.End.Format(time.RFC3339Nano))
+}
+
+// mayTransferIn tells if the allocator transfer may take effect in [from, to],
+// a transfer with an unknown outcome may take effect at any time after it starts
+func (o *op) mayTransferIn(from, to time.Time) bool {
+	if o.Unknown {
+		return !o.Start.After(to)
+	}
+	return !o.Start.Before(from) && !o.End.After(to)
+}
+
+// timeline is the TSOs of a dc-location in the order of their end time
+type timeline struct {
+	ops []*op
+	// maxIdx[i] is the index of the max TSO in ops[:i+1]
+	maxIdx []int
+}
+
+func newTimeline(ops []*op) *timeline {
+	sort.Slice(ops, func(i, j int) bool { return ops[i].End.Before(ops[j].End) })
+	t := &timeline{ops: ops, maxIdx: make([]int, len(ops))}
+	for i, o := range ops {
+		t.maxIdx[i] = i
+		if i > 0 && ops[t.maxIdx[i-1]].TS() >= o.TS() {
+			t.maxIdx[i] = t.maxIdx[i-1]
+		}
+	}
+	return t
+}
+
+// maxBefore returns the max TSO returned before the time
+func (t *timeline) maxBefore(at time.Time) *op {
+	n := sort.Search(len(t.ops), func(i int) bool { return !t.ops[i].End.Before(at) })
+	if n == 0 {
+		return nil
+	}
+	return t.ops[t.maxIdx[n-1]]
+}
+
+type checker struct {
+	suffixBits uint
+}
+
+// Checker checks the TSOs of every dc-location increase in real time, the global TSO is greater than every
+// local TSO returned before it's requested and vice versa, every dc-location keeps a unique suffix in the
+// lowest suffixBits bits of the logical part, no TSO is returned twice across all the dc-locations,
+// and no TSO goes back across the allocator transfers.
+func Checker(suffixBits uint) core.Checker {
+	return checker{suffixBits: suffixBits}
+}
+
+// Name implements core.Checker
+func (checker) Name() string {
+	return "tso_checker"
+}
+
+// Check implements core.Checker
+func (c checker) Check(_ core.Model, ops []core.Operation) (bool, error) {
+	tsos, transfers, err := parseOps(ops)
+	if err != nil {
+		return false, err
+	}
+	timelines := make(map[string]*timeline, len(tsos))
+	for dc, dcOps := range tsos {
+		timelines[dc] = newTimeline(dcOps)
+	}
+	violations := checkMonotonic(timelines, transfers)
+	violations = append(violations, checkCausality(timelines)...)
+	violations = append(violations, c.checkSuffix(timelines)...)
+	violations = append(violations, checkUnique(timelines)...)
+
+	for dc, t := range timelines {
+		log.Infof("[tso] checked %d TSOs of %s and %d allocator transfers", len(t.ops), dc, len(transfers[dc]))
+	}
+	return checkutil.Report("tso", violations)
+}
+
+// checkMonotonic checks a TSO is greater than all the TSOs of the same dc-location returned before it's requested,
+// a violation is reported as a regression if the allocator is transferred between them
+func checkMonotonic(timelines map[string]*timeline, transfers map[string][]*op) []string {
+	var violations []string
+	for dc, t := range timelines {
+		for _, o := range t.ops {
+			prev := t.maxBefore(o.Start)
+			if prev == nil || o.TS() > prev.TS() {
+				continue
+			}
+			msg := fmt.Sprintf("%s isn't greater than %s", o, prev)
+			for _, tr := range transfers[dc] {
+				if tr.mayTransferIn(prev.End, o.Start) {
+					msg = fmt.Sprintf("%s regresses after the allocator is transferred to %s, it isn't greater than %s", o, tr.Target, prev)
+					break
+				}
+			}
+			violations = append(violations, msg)
+		}
+	}
+	return violations
+}
+
+// checkCausality checks a global TSO is greater than all the local TSOs returned before it's requested,
+// and a local TSO is greater than all the global TSOs returned before it's requested
+func checkCausality(timelines map[string]*timeline) []string {
+	var violations []string
+	global, ok := timelines[GlobalDCLocation]
+	if !ok {
+		return nil
+	}
+	for dc, local := range timelines {
+		if dc == GlobalDCLocation {
+			continue
+		}
+		for _, g := range global.ops {
+			if l := local.maxBefore(g.Start); l != nil && g.TS() <= l.TS() {
+				violations = append(violations, fmt.Sprintf("%s isn't greater than the previous %s", g, l))
+			}
+		}
+		for _, l := range local.ops {
+			if g := global.maxBefore(l.Start); g != nil && l.TS() <= g.TS() {
+				violations = append(violations, fmt.Sprintf("%s isn't greater than the previous %s", l, g))
+			}
+		}
+	}
+	return violations
+}
+
+// checkSuffix checks every dc-location keeps the same suffix, and the suffixes of different dc-locations are different
+func (c checker) checkSuffix(timelines map[string]*timeline) []string {
+	var violations []string
+	mask := int64(1)<<c.suffixBits - 1
+	owners := make(map[int64]string)
+	for _, dc := range sortedDCLocations(timelines) {
+		ops := timelines[dc].ops
+		if len(ops) == 0 {
+			continue
+		}
+		suffix := ops[0].Logical & mask
+		for _, o := range ops[1:] {
+			if o.Logical&mask != suffix {
+				violations = append(violations, fmt.Sprintf("the suffix of %s is %d, but it's %d in %s", o, o.Logical&mask, suffix, ops[0]))
+				break
+			}
+		}
+		if owner, ok := owners[suffix]; ok {
+			violations = append(violations, fmt.Sprintf("%s and %s have the same suffix %d", owner, dc, suffix))
+			continue
+		}
+		owners[suffix] = dc
+	}
+	return violations
+}
+
+// checkUnique checks no TSO is returned twice, even by different dc-locations
+func checkUnique(timelines map[string]*timeline) []string {
+	var violations []string
+	seen := make(map[uint64]*op)
+	for _, dc := range sortedDCLocations(timelines) {
+		for _, o := range timelines[dc].ops {
+			if prev, ok := seen[o.TS()]; ok {
+				violations = append(violations, fmt.Sprintf("%s is the same as %s", o, prev))
+				continue
+			}
+			seen[o.TS()] = o
+		}
+	}
+	return violations
+}
+
+func sortedDCLocations(timelines map[string]*timeline) []string {
+	dcs := make([]string, 0, len(timelines))
+	for dc := range timelines {
+		dcs = append(dcs, dc)
+	}
+	sort.Strings(dcs)
+	return dcs
+}
+
+// parseOps pairs the requests and responses of each process, it returns the successful TSOs and the allocator
+// transfers grouped by the dc-location, the transfers with unknown outcomes start at their invocations
+func parseOps(ops []core.Operation) (map[string][]*op, map[string][]*op, error) {
+	tsos := make(map[string][]*op)
+	transfers := make(map[string][]*op)
+	err := checkutil.PairOps(ops, func(invoke, ret core.Operation) {
+		o := &op{Request: invoke.Data.(Request), Response: ret.Data.(Response)}
+		switch o.Kind {
+		case KindTSO:
+			if !o.Unknown && o.Error == "" {
+				tsos[o.DCLocation] = append(tsos[o.DCLocation], o)
+			}
+		case KindTransfer:
+			// a failed or unknown transfer may also change the allocator
+			if o.Unknown {
+				o.Start = invoke.Time
+			}
+			transfers[o.DCLocation] = append(transfers[o.DCLocation], o)
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return tsos, transfers, nil
+}
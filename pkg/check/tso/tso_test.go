@@ -0,0 +1,161 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tso
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tipocket/pkg/core"
+)
+
+var base = time.Unix(1600000000, 0)
+
+type event struct {
+	proc  int64
+	req   Request
+	res   Response
+	start int
+	end   int
+}
+
+func tso(proc int64, dc string, physical, logical int64, start, end int) event {
+	return event{proc: proc, req: Request{Kind: KindTSO, DCLocation: dc},
+		res: Response{Physical: physical, Logical: logical}, start: start, end: end}
+}
+
+func transfer(proc int64, dc, target string, start, end int) event {
+	return event{proc: proc, req: Request{Kind: KindTransfer, DCLocation: dc, Target: target}, start: start, end: end}
+}
+
+func unknownTransfer(proc int64, dc, target string, start int) event {
+	return event{proc: proc, req: Request{Kind: KindTransfer, DCLocation: dc, Target: target},
+		res: Response{Unknown: true}, start: start, end: start}
+}
+
+// toOps converts the events to a history, the events of a process must not overlap
+func toOps(events ...event) []core.Operation {
+	var ops []core.Operation
+	for _, e := range events {
+		start := base.Add(time.Duration(e.start) * time.Second)
+		end := base.Add(time.Duration(e.end) * time.Second)
+		if !e.res.Unknown {
+			e.res.Start, e.res.End = start, end
+		}
+		ops = append(ops,
+			core.Operation{Action: core.InvokeOperation, Proc: e.proc, Data: e.req, Time: start},
+			core.Operation{Action: core.ReturnOperation, Proc: e.proc, Data: e.res, Time: end})
+	}
+	return ops
+}
+
+func TestCheck(t *testing.T) {
+	c := Checker(2)
+	for _, tc := range []struct {
+		name   string
+		events []event
+		err    string
+	}{
+		{
+			name: "valid",
+			events: []event{
+				tso(1, "dc-1", 10, 1<<2|1, 0, 1),
+				tso(2, "dc-2", 10, 1<<2|2, 0, 1),
+				// concurrent requests aren't ordered
+				tso(1, "dc-1", 12, 1<<2|1, 2, 5),
+				tso(3, "dc-1", 11, 1<<2|1, 3, 4),
+				tso(0, GlobalDCLocation, 13, 0, 6, 7),
+				tso(2, "dc-2", 13, 1<<2|2, 8, 9),
+			},
+		},
+		{
+			name: "local tso regresses after the allocator transfer",
+			events: []event{
+				tso(1, "dc-1", 10, 1<<2|1, 0, 1),
+				transfer(4, "dc-1", "pd-2", 2, 3),
+				tso(1, "dc-1", 9, 1<<2|1, 4, 5),
+			},
+			err: "regresses after the allocator is transferred to pd-2",
+		},
+		{
+			name: "global tso isn't greater than the previous local tso",
+			events: []event{
+				tso(1, "dc-1", 10, 1<<2|1, 0, 1),
+				tso(0, GlobalDCLocation, 10, 0, 2, 3),
+			},
+			err: "isn't greater than the previous dc-1 tso",
+		},
+		{
+			name: "local tso isn't greater than the previous global tso",
+			events: []event{
+				tso(0, GlobalDCLocation, 10, 1<<2, 0, 1),
+				tso(1, "dc-1", 10, 1, 2, 3),
+			},
+			err: "isn't greater than the previous global tso",
+		},
+		{
+			name: "dc-locations share a suffix",
+			events: []event{
+				tso(1, "dc-1", 10, 1<<2|1, 0, 1),
+				tso(2, "dc-2", 10, 2<<2|1, 0, 1),
+			},
+			err: "dc-1 and dc-2 have the same suffix 1",
+		},
+		{
+			name: "suffix changes in a dc-location",
+			events: []event{
+				tso(1, "dc-1", 10, 1<<2|1, 0, 1),
+				tso(1, "dc-1", 11, 1<<2|2, 2, 3),
+			},
+			err: "but it's 1 in dc-1 tso",
+		},
+		{
+			name: "tso is returned by two dc-locations",
+			events: []event{
+				tso(1, "dc-1", 10, 1<<2|1, 0, 1),
+				tso(2, "dc-2", 10, 1<<2|1, 0, 1),
+			},
+			err: "is the same as dc-1 tso",
+		},
+		{
+			name: "tso is returned to concurrent requests",
+			events: []event{
+				tso(1, "dc-1", 10, 1<<2|1, 0, 2),
+				tso(3, "dc-1", 10, 1<<2|1, 1, 3),
+			},
+			err: "is the same as dc-1 tso",
+		},
+		{
+			name: "local tso regresses after the allocator transfer with an unknown outcome",
+			events: []event{
+				tso(1, "dc-1", 10, 1<<2|1, 0, 1),
+				unknownTransfer(4, "dc-1", "pd-2", 2),
+				tso(1, "dc-1", 9, 1<<2|1, 4, 5),
+			},
+			err: "regresses after the allocator is transferred to pd-2",
+		},
+	} {
+		ok, err := c.Check(nil, toOps(tc.events...))
+		if tc.err == "" {
+			if !ok || err != nil {
+				t.Fatalf("%s: expect ok, got %v %v", tc.name, ok, err)
+			}
+			continue
+		}
+		if ok || err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("%s: expect error %q, got %v %v", tc.name, tc.err, ok, err)
+		}
+	}
+}
@@ -32,6 +32,10 @@ type Config struct {
 	// History file
 	History string
 
+	// HealthGateTimeout bounds how long to wait for the cluster to become healthy
+	// before injecting a nemesis and after recovering it, 0 disables the gate.
+	HealthGateTimeout time.Duration
+
 	// ClientConfig can be anything, use type assertion in your case
 	ClientConfig interface{}
 }
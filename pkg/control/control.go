@@ -45,6 +45,14 @@ type Controller struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
+	// recorder of the running round, nemesis events dispatched
+	// by dispatchNemesis are recorded to it.
+	recorderMu sync.Mutex
+	recorder   *history.Recorder
+	// recorderWg waits for the nemeses recorded by the running round, so
+	// the round doesn't close its recorder between a nemesis and its recovery.
+	recorderWg sync.WaitGroup
+
 	proc         int64
 	requestCount int64
 
@@ -138,6 +146,7 @@ ROUND:
 		if err := c.dumpState(ctx, recorder); err != nil {
 			log.Fatalf("dump state failed, %v", err)
 		}
+		c.setRecorder(recorder)
 
 		// requestCount for the round, shared by all clients.
 		requestCount := int64(c.cfg.RequestCount)
@@ -157,6 +166,8 @@ ROUND:
 		clientWg.Wait()
 		cancel()
 
+		c.setRecorder(nil)
+		c.recorderWg.Wait()
 		recorder.Close()
 		c.suit.Verify(historyFile)
 
@@ -414,23 +425,45 @@ loop:
 			}
 			continue
 		}
+		gen := gens.Next()
+		recorder := c.acquireRecorder()
+		if !c.passHealthGate(ctx, gen.Name(), core.HealthGateBeforeNemesis, recorder) {
+			log.Warnf("skip nemesis %s since the cluster is unhealthy", gen.Name())
+			c.releaseRecorder(recorder)
+			continue
+		}
 		var (
-			gen = gens.Next()
 			ops = gen.Generate(c.cfg.Nodes)
 			g   errgroup.Group
 		)
+		if recorder != nil {
+			if err := recorder.RecordInvokeNemesis(core.NemesisGeneratorRecord{Name: gen.Name(), Ops: ops}); err != nil {
+				log.Infof("record invoking nemesis %s failed: %v", gen.Name(), err)
+			}
+		}
 		for i := 0; i < len(ops); i++ {
 			op := ops[i]
 			g.Go(func() error {
-				c.onNemesis(ctx, op)
+				c.onNemesis(ctx, op, recorder)
 				return nil
 			})
 		}
 		_ = g.Wait()
+		if recorder != nil {
+			if err := recorder.RecordRecoverNemesis(gen.Name()); err != nil {
+				log.Infof("record recovering nemesis %s failed: %v", gen.Name(), err)
+			}
+		}
+		c.passHealthGate(ctx, gen.Name(), core.HealthGateAfterRecover, recorder)
+		c.releaseRecorder(recorder)
 	}
 }
 
 func (c *Controller) dispatchNemesisWithRecord(ctx context.Context, gen core.NemesisGenerator, recorder *history.Recorder) {
+	if !c.passHealthGate(ctx, gen.Name(), core.HealthGateBeforeNemesis, recorder) {
+		log.Warnf("skip nemesis %s since the cluster is unhealthy", gen.Name())
+		return
+	}
 	var (
 		ops = gen.Generate(c.cfg.Nodes)
 		g   errgroup.Group
@@ -442,7 +475,7 @@ func (c *Controller) dispatchNemesisWithRecord(ctx context.Context, gen core.Nem
 	for i := 0; i < len(ops); i++ {
 		op := ops[i]
 		g.Go(func() error {
-			c.onNemesis(ctx, op)
+			c.onNemesis(ctx, op, recorder)
 			return nil
 		})
 	}
@@ -450,12 +483,46 @@ func (c *Controller) dispatchNemesisWithRecord(ctx context.Context, gen core.Nem
 	if err := recorder.RecordRecoverNemesis(gen.Name()); err != nil {
 		log.Infof("record recovering nemesis %s failed: %v", gen.Name(), err)
 	}
+	c.passHealthGate(ctx, gen.Name(), core.HealthGateAfterRecover, recorder)
+}
+
+func (c *Controller) setRecorder(recorder *history.Recorder) {
+	c.recorderMu.Lock()
+	defer c.recorderMu.Unlock()
+	c.recorder = recorder
+}
+
+// acquireRecorder returns the recorder of the running round, which is kept open until it's released.
+func (c *Controller) acquireRecorder() *history.Recorder {
+	c.recorderMu.Lock()
+	defer c.recorderMu.Unlock()
+	if c.recorder != nil {
+		c.recorderWg.Add(1)
+	}
+	return c.recorder
+}
+
+func (c *Controller) releaseRecorder(recorder *history.Recorder) {
+	if recorder != nil {
+		c.recorderWg.Done()
+	}
 }
 
-func (c *Controller) onNemesis(ctx context.Context, op *core.NemesisOperation) {
+// onNemesis runs the nemesis operation, pods restarted by it are recorded if the recorder isn't nil.
+func (c *Controller) onNemesis(ctx context.Context, op *core.NemesisOperation, recorder *history.Recorder) {
 	if op == nil {
 		return
 	}
+	recoverCtx := context.TODO()
+	if recorder != nil {
+		report := func(restart core.PodRestartRecord) {
+			if err := recorder.RecordPodRestart(restart); err != nil {
+				log.Infof("record pod restart %s failed: %v", restart.Pod, err)
+			}
+		}
+		ctx = core.WithPodRestartReporter(ctx, report)
+		recoverCtx = core.WithPodRestartReporter(recoverCtx, report)
+	}
 	nemesis := core.GetNemesis(string(op.Type))
 	if nemesis == nil {
 		log.Errorf("nemesis %s is not registered", op.Type)
@@ -478,7 +545,7 @@ func (c *Controller) onNemesis(ctx context.Context, op *core.NemesisOperation) {
 	}
 	log.Infof("recover nemesis %s...", op.String())
 	err := util.RunWithRetry(ctx, 3, 10*time.Second, func() error {
-		return nemesis.Recover(context.TODO(), op.Node, op.RecoverArgs...)
+		return nemesis.Recover(recoverCtx, op.Node, op.RecoverArgs...)
 	})
 	if err != nil {
 		log.Errorf("recover nemesis %s failed: %v", op.String(), err)
@@ -0,0 +1,171 @@
+package control
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	// mysql driver
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/pingcap/tipocket/pkg/cluster"
+	"github.com/pingcap/tipocket/pkg/core"
+	"github.com/pingcap/tipocket/pkg/history"
+	"github.com/pingcap/tipocket/pkg/nemesis/fake_kvproto/metapb"
+	"github.com/pingcap/tipocket/pkg/util/pdutil"
+)
+
+const (
+	healthGateInterval = 5 * time.Second
+	// healthGateRequestTimeout bounds each request to PD, a hung PD mustn't block the gate
+	healthGateRequestTimeout = 10 * time.Second
+)
+
+// healthGate checks whether the cluster has recovered enough to take the next nemesis.
+// Chaos Mesh recovers asynchronously and TiKV needs time to rebalance, injecting
+// another nemesis on a cluster with a dead store only produces meaningless failures.
+type healthGate struct {
+	pdNodes    []cluster.Node
+	tidbNodes  []cluster.Node
+	httpClient *http.Client
+}
+
+func newHealthGate(nodes []cluster.Node) *healthGate {
+	h := &healthGate{httpClient: &http.Client{Timeout: healthGateRequestTimeout}}
+	for _, node := range nodes {
+		switch node.Component {
+		case cluster.PD:
+			h.pdNodes = append(h.pdNodes, node)
+		case cluster.TiDB:
+			h.tidbNodes = append(h.tidbNodes, node)
+		}
+	}
+	return h
+}
+
+// wait polls the cluster until it's healthy, or returns the last unhealthy reason after timeout.
+func (h *healthGate) wait(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var lastErr error
+	err := wait.PollImmediateUntil(healthGateInterval, func() (bool, error) {
+		if lastErr = h.check(ctx); lastErr != nil {
+			log.Infof("cluster is not healthy yet: %v", lastErr)
+			return false, nil
+		}
+		return true, nil
+	}, ctx.Done())
+	if err != nil && lastErr != nil {
+		return lastErr
+	}
+	return err
+}
+
+func (h *healthGate) check(ctx context.Context) error {
+	if err := h.checkPD(); err != nil {
+		return err
+	}
+	return h.checkTiDB(ctx)
+}
+
+// checkPD checks PD members, TiKV stores and region peers through PD.
+func (h *healthGate) checkPD() error {
+	if len(h.pdNodes) == 0 {
+		return nil
+	}
+	var (
+		client *pdutil.Client
+		health []*pdutil.MemberHealth
+		err    error
+	)
+	// any of PD members can serve the request
+	for _, node := range h.pdNodes {
+		client = pdutil.NewPDClient(h.httpClient, fmt.Sprintf("http://%s", node.Address()))
+		if health, err = client.GetMembersHealth(); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return errors.Annotate(err, "get pd members health failed")
+	}
+	for _, member := range health {
+		if !member.Health {
+			return errors.Errorf("pd member %s is unhealthy", member.Name)
+		}
+	}
+
+	stores, err := client.GetStores()
+	if err != nil {
+		return errors.Annotate(err, "get stores failed")
+	}
+	for _, store := range stores.Stores {
+		if store.GetState() == metapb.StoreState_Tombstone || store.StateName == metapb.StoreState_Tombstone.String() {
+			continue
+		}
+		// StateName is empty on old PD versions
+		if (store.StateName != "" && store.StateName != metapb.StoreState_Up.String()) || store.GetState() != metapb.StoreState_Up {
+			return errors.Errorf("store %d(%s) is %s(%s)", store.GetId(), store.GetAddress(), store.StateName, store.GetState())
+		}
+	}
+
+	for _, check := range []string{pdutil.RegionCheckDownPeer, pdutil.RegionCheckPendingPeer, pdutil.RegionCheckMissPeer} {
+		regions, err := client.GetRegionsByCheck(check)
+		if err != nil {
+			return errors.Annotatef(err, "check %s regions failed", check)
+		}
+		if len(regions) != 0 {
+			return errors.Errorf("%d regions have %s, eg. region %d", len(regions), check, regions[0].ID)
+		}
+	}
+	return nil
+}
+
+func (h *healthGate) checkTiDB(ctx context.Context) error {
+	for _, node := range h.tidbNodes {
+		if err := selectOne(ctx, node); err != nil {
+			return errors.Annotatef(err, "tidb %s is unavailable", node.Address())
+		}
+	}
+	return nil
+}
+
+func selectOne(ctx context.Context, node cluster.Node) error {
+	db, err := sql.Open("mysql", fmt.Sprintf("root@tcp(%s)/?timeout=10s&readTimeout=10s", node.Address()))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	var one int
+	return db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+}
+
+// passHealthGate waits for the cluster to be healthy at the given stage of the nemesis,
+// failures are recorded to the history as `health-gate-failure` events if recorder isn't nil.
+// It returns false if the cluster stays unhealthy.
+func (c *Controller) passHealthGate(ctx context.Context, name string, stage string, recorder *history.Recorder) bool {
+	if c.cfg.HealthGateTimeout <= 0 {
+		return true
+	}
+	log.Infof("wait cluster healthy %s %s...", stage, name)
+	err := newHealthGate(c.cfg.Nodes).wait(ctx, c.cfg.HealthGateTimeout)
+	if err == nil {
+		return true
+	}
+	// the test is finishing, it's not a failure of the cluster
+	if ctx.Err() != nil {
+		return false
+	}
+	log.Errorf("cluster is still unhealthy %s %s after %s: %v", stage, name, c.cfg.HealthGateTimeout, err)
+	if recorder != nil {
+		if err := recorder.RecordHealthGateFailure(core.HealthGateRecord{Name: name, Stage: stage, Err: err.Error()}); err != nil {
+			log.Infof("record health gate failure of %s failed: %v", name, err)
+		}
+	}
+	return false
+}
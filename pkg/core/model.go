@@ -30,6 +30,10 @@ const (
 	ReturnOperation = "return"
 	InvokeNemesis   = "inject"
 	RecoverNemesis  = "recover"
+	// HealthGateFailure marks the cluster didn't become healthy around a nemesis
+	HealthGateFailure = "health-gate-failure"
+	// PodRestart marks a pod restarted by a nemesis, eg. during a rolling update
+	PodRestart = "pod-restart"
 )
 
 // Operation of a data object.
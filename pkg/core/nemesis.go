@@ -33,6 +33,20 @@ const (
 	PDLeaderShuffler ChaosKind = "pd-leader-shuffler"
 	// Scaling scales cluster
 	Scaling ChaosKind = "scaling"
+	// Failpoint enables failpoints through HTTP APIs
+	Failpoint ChaosKind = "failpoint"
+	// EvictLeader evicts all leaders out of a store
+	EvictLeader ChaosKind = "evict-leader"
+	// StoreOffline makes a store tombstone, and brings a fresh one back on recovery
+	StoreOffline ChaosKind = "store-offline"
+	// PDSchedulingConfig changes store limits or scheduler configs of PD
+	PDSchedulingConfig ChaosKind = "pd-scheduling-config"
+	// RollingUpgrade rolls a new version or config through the cluster by TiDB Operator
+	RollingUpgrade ChaosKind = "rolling-upgrade"
+	// StressChaos stresses CPU or memory of a pod
+	StressChaos ChaosKind = "stress-chaos"
+	// DiskFill fills the data volume of a pod
+	DiskFill ChaosKind = "disk-fill"
 )
 
 // Nemesis injects failure and disturbs the database.
@@ -166,6 +180,47 @@ type NemesisGeneratorRecord struct {
 	Ops  []*NemesisOperation
 }
 
+// Health gate stages
+const (
+	// HealthGateBeforeNemesis is the gate checked before a nemesis is injected
+	HealthGateBeforeNemesis = "before-nemesis"
+	// HealthGateAfterRecover is the gate checked after a nemesis is recovered
+	HealthGateAfterRecover = "after-recover"
+)
+
+// HealthGateRecord is used to record a health gate which the cluster failed to pass
+type HealthGateRecord struct {
+	// Name of the nemesis generator
+	Name  string
+	Stage string
+	Err   string
+}
+
+// PodRestartRecord is used to record a pod restarted by a nemesis
+type PodRestartRecord struct {
+	Component cluster.Component
+	Pod       string
+	// Time is when the new pod is created
+	Time time.Time
+	// ReadyTime is when the new pod becomes ready
+	ReadyTime time.Time
+}
+
+type podRestartReporterKey struct{}
+
+// WithPodRestartReporter returns a context carrying the reporter,
+// nemeses report the pods they restart through it.
+func WithPodRestartReporter(ctx context.Context, report func(PodRestartRecord)) context.Context {
+	return context.WithValue(ctx, podRestartReporterKey{}, report)
+}
+
+// ReportPodRestart reports a restarted pod if the context carries a reporter.
+func ReportPodRestart(ctx context.Context, record PodRestartRecord) {
+	if report, ok := ctx.Value(podRestartReporterKey{}).(func(PodRestartRecord)); ok {
+		report(record)
+	}
+}
+
 // NemesisGenerator is used in control, it will generate a nemesis operation
 // and then the control can use it to disturb the cluster.
 type NemesisGenerator interface {
@@ -0,0 +1,141 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package listappend
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+
+	tipocketcore "github.com/pingcap/tipocket/pkg/core"
+	"github.com/pingcap/tipocket/pkg/elle/core"
+	"github.com/pingcap/tipocket/pkg/elle/txn"
+)
+
+// Response is the response of a list append transaction recorded in the history
+type Response struct {
+	Result core.Op `json:"result"`
+}
+
+func (r Response) String() string {
+	return r.Result.String()
+}
+
+// IsUnknown always returns false because we don't want to let it be resorted
+func (r Response) IsUnknown() bool {
+	return false
+}
+
+// Parser parses the history of list append transactions, the requests are core.Op and the responses are Response
+type Parser struct{}
+
+// OnRequest implements history.RecordParser
+func (Parser) OnRequest(data json.RawMessage) (interface{}, error) {
+	r := core.Op{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+// OnResponse implements history.RecordParser
+func (Parser) OnResponse(data json.RawMessage) (interface{}, error) {
+	r := Response{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+// OnNoopResponse implements history.RecordParser
+func (Parser) OnNoopResponse() interface{} {
+	panic("unreachable")
+}
+
+// OnState implements history.RecordParser
+func (Parser) OnState(state json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+// Checker checks the history of list append transactions for G-single, the history is also written to history.edn
+type Checker struct{}
+
+// Check implements core.Checker
+func (Checker) Check(_ tipocketcore.Model, ops []tipocketcore.Operation) (bool, error) {
+	h := ConvertOperationsToHistory(ops)
+	_ = WriteEdnHistory(h, "history.edn")
+
+	result := Check(txn.Opts{Anomalies: []string{"G-single"}}, h)
+	if result.Valid {
+		return true, nil
+	}
+	return false, result
+}
+
+// Name implements core.Checker
+func (Checker) Name() string {
+	return "list_append"
+}
+
+// ConvertOperationsToHistory converts the operations parsed by Parser to the elle history,
+// the operations other than the requests and responses, e.g. the nemesis events, are skipped
+func ConvertOperationsToHistory(events []tipocketcore.Operation) core.History {
+	var h core.History
+	for _, event := range events {
+		if event.Action != tipocketcore.InvokeOperation && event.Action != tipocketcore.ReturnOperation {
+			continue
+		}
+		var op core.Op
+		switch e := event.Data.(type) {
+		case core.Op:
+			op = e
+		case Response:
+			op = e.Result
+		default:
+			panic("unreachable")
+		}
+		op.Process.Set(int(event.Proc))
+		// the values decoded from JSON are float64
+		typedMops := make([]core.Mop, 0)
+		for _, mop := range *op.Value {
+			if mop.IsRead() {
+				var value []int
+				if mop.GetValue() != nil {
+					for _, v := range mop.GetValue().([]interface{}) {
+						value = append(value, int(v.(float64)))
+					}
+				}
+				typedMops = append(typedMops, core.Read(mop.GetKey(), value))
+			}
+			if mop.IsAppend() {
+				typedMops = append(typedMops, core.Append(mop.GetKey(), int(mop.GetValue().(float64))))
+			}
+		}
+		op.Value = &typedMops
+		h = append(h, op)
+	}
+	return h
+}
+
+// WriteEdnHistory writes the history to the file in edn format
+func WriteEdnHistory(h core.History, path string) error {
+	h.AttachIndexIfNoExists()
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	for _, op := range h {
+		w.WriteString(op.String())
+		w.WriteString("\n")
+	}
+	return w.Flush()
+}
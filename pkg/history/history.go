@@ -74,6 +74,16 @@ func (r *Recorder) RecordRecoverNemesis(op string) error {
 	return r.record(-1, core.RecoverNemesis, op)
 }
 
+// RecordHealthGateFailure records a failed cluster health gate on history file
+func (r *Recorder) RecordHealthGateFailure(gateRecord core.HealthGateRecord) error {
+	return r.record(-1, core.HealthGateFailure, gateRecord)
+}
+
+// RecordPodRestart records a pod restarted by a nemesis on history file
+func (r *Recorder) RecordPodRestart(restartRecord core.PodRestartRecord) error {
+	return r.record(-1, core.PodRestart, restartRecord)
+}
+
 func (r *Recorder) record(proc int64, action string, op interface{}) error {
 	// Marshal the op to json in order to store it in a history file.
 	data, err := json.Marshal(op)
@@ -168,6 +178,18 @@ func ReadHistory(historyFile string, p RecordParser) ([]core.Operation, interfac
 				return nil, nil, err
 			}
 			data = nemesis
+		} else if record.Action == core.HealthGateFailure {
+			var gate core.HealthGateRecord
+			if err := json.Unmarshal(record.Data, &gate); err != nil {
+				return nil, nil, err
+			}
+			data = gate
+		} else if record.Action == core.PodRestart {
+			var restart core.PodRestartRecord
+			if err := json.Unmarshal(record.Data, &restart); err != nil {
+				return nil, nil, err
+			}
+			data = restart
 		}
 
 		op := core.Operation{
@@ -5,7 +5,9 @@ import (
 	"os"
 	"path"
 	"testing"
+	"time"
 
+	"github.com/pingcap/tipocket/pkg/cluster"
 	"github.com/pingcap/tipocket/pkg/core"
 )
 
@@ -185,3 +187,86 @@ func TestCompleteOperation(t *testing.T) {
 		}
 	}
 }
+
+func TestRecordHealthGateFailure(t *testing.T) {
+	tmpDir, err := ioutil.TempDir(".", "var")
+	if err != nil {
+		t.Fatalf("create temp dir failed %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	name := path.Join(tmpDir, "history.log")
+	r, err := NewRecorder(name)
+	if err != nil {
+		t.Fatalf("create recorder failed %v", err)
+	}
+	defer r.Close()
+
+	gate := core.HealthGateRecord{Name: "kill_tikv_1node_5min", Stage: core.HealthGateAfterRecover, Err: "store 1 is Down"}
+	if err = r.RecordRequest(1, NoopRequest{Op: 0}); err != nil {
+		t.Fatalf("record request failed %v", err)
+	}
+	if err = r.RecordHealthGateFailure(gate); err != nil {
+		t.Fatalf("record health gate failure failed %v", err)
+	}
+	if err = r.RecordResponse(1, NoopResponse{Value: 10}); err != nil {
+		t.Fatalf("record response failed %v", err)
+	}
+
+	ops, _, err := ReadHistory(name, NoopParser{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 3 {
+		t.Fatalf("expect 3 ops, got %v", ops)
+	}
+	if ops[1].Action != core.HealthGateFailure || ops[1].Proc != -1 {
+		t.Fatalf("unexpected op %#v", ops[1])
+	}
+	if a, ok := ops[1].Data.(core.HealthGateRecord); !ok || a != gate {
+		t.Fatalf("expect %#v, got %#v", gate, ops[1].Data)
+	}
+
+	// health gate events are kept by CompleteOperations like nemesis events
+	compOps, err := CompleteOperations(ops, NoopParser{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(compOps) != 3 {
+		t.Fatalf("expect 3 ops, got %v", compOps)
+	}
+}
+
+func TestRecordPodRestart(t *testing.T) {
+	tmpDir, err := ioutil.TempDir(".", "var")
+	if err != nil {
+		t.Fatalf("create temp dir failed %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	name := path.Join(tmpDir, "history.log")
+	r, err := NewRecorder(name)
+	if err != nil {
+		t.Fatalf("create recorder failed %v", err)
+	}
+	defer r.Close()
+
+	now := time.Now().UTC()
+	restart := core.PodRestartRecord{Component: cluster.TiKV, Pod: "tc-tikv-0", Time: now, ReadyTime: now.Add(time.Minute)}
+	if err = r.RecordPodRestart(restart); err != nil {
+		t.Fatalf("record pod restart failed %v", err)
+	}
+
+	ops, _, err := ReadHistory(name, NoopParser{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 1 || ops[0].Action != core.PodRestart || ops[0].Proc != -1 {
+		t.Fatalf("unexpected ops %v", ops)
+	}
+	a, ok := ops[0].Data.(core.PodRestartRecord)
+	if !ok || a.Pod != restart.Pod || a.Component != restart.Component ||
+		!a.Time.Equal(restart.Time) || !a.ReadyTime.Equal(restart.ReadyTime) {
+		t.Fatalf("expect %#v, got %#v", restart, ops[0].Data)
+	}
+}
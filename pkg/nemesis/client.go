@@ -78,3 +78,18 @@ func (c *Chaos) ApplyTimeChaos(ctx context.Context, pc *v1alpha1.TimeChaos) erro
 func (c *Chaos) CancelTimeChaos(ctx context.Context, pc *v1alpha1.TimeChaos) error {
 	return c.cli.Delete(ctx, pc)
 }
+
+// ApplyStressChaos apply the stress chaos to cluster using Client.
+func (c *Chaos) ApplyStressChaos(ctx context.Context, sc *v1alpha1.StressChaos) error {
+	desired := sc.DeepCopy()
+	_, err := controllerutil.CreateOrUpdate(ctx, c.cli, sc, func() error {
+		sc.Spec = desired.Spec
+		return nil
+	})
+	return err
+}
+
+// CancelStressChaos Delete the stress chaos using Client.
+func (c *Chaos) CancelStressChaos(ctx context.Context, sc *v1alpha1.StressChaos) error {
+	return c.cli.Delete(ctx, sc)
+}
@@ -0,0 +1,195 @@
+package nemesis
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/ngaut/log"
+	"github.com/pingcap/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/pingcap/tipocket/pkg/cluster"
+	"github.com/pingcap/tipocket/pkg/core"
+	"github.com/pingcap/tipocket/pkg/test-infra/tests"
+)
+
+const (
+	diskFillFile = "tipocket-disk-fill"
+	// diskFillHelperImage runs the helper pod which removes the file on recovery
+	diskFillHelperImage   = "busybox:1.26.2"
+	diskFillHelperMount   = "/data"
+	diskFillHelperTimeout = 5 * time.Minute
+)
+
+// data volumes mounted by TiDB Operator
+var dataDirs = map[cluster.Component]string{
+	cluster.TiKV:    "/var/lib/tikv",
+	cluster.PD:      "/var/lib/pd",
+	cluster.TiFlash: "/data0",
+}
+
+type diskFillGenerator struct {
+	name    string
+	percent int
+}
+
+// NewDiskFillGenerator creates a generator which fills the data volume up to percent.
+// Name is like disk_fill_tikv_1node, the component can be tikv, pd or tiflash.
+func NewDiskFillGenerator(name string, percent int) core.NemesisGenerator {
+	return diskFillGenerator{name: name, percent: percent}
+}
+
+func (g diskFillGenerator) Generate(nodes []cluster.Node) []*core.NemesisOperation {
+	component := parseComponentNemesis(g.name, "disk_fill")
+	if _, ok := dataDirs[component]; !ok {
+		log.Fatalf("nemesis %s doesn't support component %s", g.name, component)
+	}
+	targets := filterComponent(nodes, component)
+	if len(targets) == 0 {
+		log.Warnf("no %s node for nemesis %s", component, g.name)
+		return nil
+	}
+	node := targets[r.Intn(len(targets))]
+	return []*core.NemesisOperation{{
+		Type:        core.DiskFill,
+		Node:        &node,
+		InvokeArgs:  []interface{}{g.percent},
+		RecoverArgs: nil,
+		RunTime:     time.Second * time.Duration(r.Intn(120)+60),
+	}}
+}
+
+func (g diskFillGenerator) Name() string {
+	return g.name
+}
+
+// diskFill fills the data volume by a file in the component container, and removes the file on recovery
+// by a helper pod mounting the same volume, since the container may be crash looping on the full disk.
+type diskFill struct {
+	cli *tests.TestCli
+}
+
+func (d diskFill) Invoke(ctx context.Context, node *cluster.Node, args ...interface{}) error {
+	if len(args) != 1 {
+		panic("args number error")
+	}
+	percent := args[0].(int)
+	file, err := diskFillPath(node)
+	if err != nil {
+		return err
+	}
+	log.Infof("apply nemesis %s %d%% on node %s(ns:%s)", core.DiskFill, percent, node.PodName, node.Namespace)
+	// compute the KiB to reach the percent by df, fallocate may be unsupported by the file system
+	script := fmt.Sprintf(`size=$(df -Pk %[1]s | awk 'NR==2 {n=int($2*%[2]d/100)-$3; if (n<0) n=0; print n}')
+fallocate -l ${size}K %[1]s/%[3]s || dd if=/dev/zero of=%[1]s/%[3]s bs=1M count=$((size/1024))
+df -Pk %[1]s`, dataDirs[node.Component], percent, diskFillFile)
+	output, err := d.cli.ExecInPod(ctx, node.Namespace, node.PodName, string(node.Component), "sh", "-c", script)
+	if err != nil {
+		return err
+	}
+	log.Infof("filled %s: %s", file, output)
+	return nil
+}
+
+func (d diskFill) Recover(ctx context.Context, node *cluster.Node, args ...interface{}) error {
+	file, err := diskFillPath(node)
+	if err != nil {
+		return err
+	}
+	pod := &corev1.Pod{}
+	if err := d.cli.Cli.Get(ctx, types.NamespacedName{Namespace: node.Namespace, Name: node.PodName}, pod); err != nil {
+		return errors.Annotatef(err, "get pod %s failed", node.PodName)
+	}
+	volume, err := dataVolume(pod, node.Component)
+	if err != nil {
+		return err
+	}
+	log.Infof("unapply nemesis %s on node %s(ns:%s)", core.DiskFill, node.PodName, node.Namespace)
+	helper := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-disk-fill-recover", node.PodName),
+			Namespace: node.Namespace,
+		},
+		Spec: corev1.PodSpec{
+			// the volume may be ReadWriteOnce, so the helper must be on the same node
+			NodeName:      pod.Spec.NodeName,
+			Tolerations:   pod.Spec.Tolerations,
+			RestartPolicy: corev1.RestartPolicyOnFailure,
+			Containers: []corev1.Container{{
+				Name:    "recover",
+				Image:   diskFillHelperImage,
+				Command: []string{"rm", "-f", path.Join(diskFillHelperMount, volume.subPath, diskFillFile)},
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      "data",
+					MountPath: diskFillHelperMount,
+				}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "data",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: volume.claim},
+				},
+			}},
+		},
+	}
+	if err := d.cli.Cli.Create(ctx, helper); err != nil && !apierrors.IsAlreadyExists(err) {
+		return errors.Annotatef(err, "create pod %s to remove %s failed", helper.Name, file)
+	}
+	defer func() {
+		if err := d.cli.Cli.Delete(context.TODO(), helper); err != nil && !apierrors.IsNotFound(err) {
+			log.Warnf("delete pod %s failed: %v", helper.Name, err)
+		}
+	}()
+	err = wait.PollImmediate(5*time.Second, diskFillHelperTimeout, func() (bool, error) {
+		p := &corev1.Pod{}
+		if err := d.cli.Cli.Get(ctx, types.NamespacedName{Namespace: helper.Namespace, Name: helper.Name}, p); err != nil {
+			log.Warnf("get pod %s failed: %v", helper.Name, err)
+			return false, nil
+		}
+		return p.Status.Phase == corev1.PodSucceeded, nil
+	})
+	return errors.Annotatef(err, "wait for pod %s to remove %s failed", helper.Name, file)
+}
+
+type volumeClaim struct {
+	claim   string
+	subPath string
+}
+
+// dataVolume finds the PVC mounted on the data dir of the component container.
+func dataVolume(pod *corev1.Pod, component cluster.Component) (volumeClaim, error) {
+	for _, container := range pod.Spec.Containers {
+		if container.Name != string(component) {
+			continue
+		}
+		for _, mount := range container.VolumeMounts {
+			if mount.MountPath != dataDirs[component] {
+				continue
+			}
+			for _, volume := range pod.Spec.Volumes {
+				if volume.Name == mount.Name && volume.PersistentVolumeClaim != nil {
+					return volumeClaim{claim: volume.PersistentVolumeClaim.ClaimName, subPath: mount.SubPath}, nil
+				}
+			}
+		}
+	}
+	return volumeClaim{}, errors.Errorf("no volume claim is mounted on %s of pod %s", dataDirs[component], pod.Name)
+}
+
+func (diskFill) Name() string {
+	return string(core.DiskFill)
+}
+
+func diskFillPath(node *cluster.Node) (string, error) {
+	dir, ok := dataDirs[node.Component]
+	if !ok {
+		return "", errors.Errorf("component %s doesn't support disk fill", node.Component)
+	}
+	return fmt.Sprintf("%s/%s", dir, diskFillFile), nil
+}
@@ -0,0 +1,201 @@
+package nemesis
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ngaut/log"
+	"github.com/pingcap/errors"
+
+	"github.com/pingcap/tipocket/pkg/cluster"
+	"github.com/pingcap/tipocket/pkg/core"
+	httputil "github.com/pingcap/tipocket/pkg/util/http"
+)
+
+const (
+	// TiDB serves failpoints on its status port only if
+	// `github.com/pingcap/tidb/server/enableTestAPI` is enabled, see `-failpoint.tidb`.
+	tidbStatusPort = 10080
+	tikvStatusPort = 20180
+
+	// failpointPrefix is served by the TiDB status server, and by the TiKV status server of most versions.
+	failpointPrefix = "/fail/"
+	// failpointAPIPrefix is the `/fail_point` API of PD and TiKV, which falls back to failpointPrefix if it's not found.
+	failpointAPIPrefix = "/fail_point/"
+)
+
+// FailpointSpec describes a failpoint and the terms it can be enabled with.
+type FailpointSpec struct {
+	Component cluster.Component
+	// Name is the full name of the failpoint, eg. github.com/pingcap/tidb/store/tikv/asyncCommitDoNothing
+	// for TiDB and PD, raft_before_save for TiKV.
+	Name string
+	// Terms are failpoint actions, like `return(true)`, `50%sleep(1000)` or `panic`, one of them is chosen randomly.
+	Terms []string
+}
+
+// failpointCatalog groups useful failpoints by the feature they disturb.
+var failpointCatalog = map[string][]FailpointSpec{
+	"2pc": {
+		{cluster.TiDB, "github.com/pingcap/tidb/store/tikv/rpcPrewriteTimeout", []string{"return(true)", "50%return(true)"}},
+		{cluster.TiDB, "github.com/pingcap/tidb/store/tikv/rpcCommitTimeout", []string{"return(true)", "50%return(true)"}},
+		{cluster.TiDB, "github.com/pingcap/tidb/store/tikv/rpcCommitResult", []string{`return("timeout")`, `return("notLeader")`, `return("keyError")`}},
+		{cluster.TiDB, "github.com/pingcap/tidb/store/tikv/beforeCommit", []string{`return("delay")`, "50%sleep(1000)"}},
+		{cluster.TiKV, "txn_before_process_write", []string{"50%sleep(1000)", "10%pause"}},
+		{cluster.TiKV, "scheduler_async_write_finish", []string{"50%sleep(1000)"}},
+	},
+	"async_commit": {
+		{cluster.TiDB, "github.com/pingcap/tidb/store/tikv/asyncCommitDoNothing", []string{"return", "50%return"}},
+		{cluster.TiDB, "github.com/pingcap/tidb/store/tikv/invalidMaxCommitTS", []string{"return"}},
+		{cluster.TiKV, "async_commit_1pc_force_fail", []string{"return", "50%return"}},
+		{cluster.TiKV, "after_prewrite_one_key", []string{"50%sleep(1000)"}},
+	},
+	"region_split": {
+		{cluster.TiDB, "github.com/pingcap/tidb/store/tikv/mockSplitRegionTimeout", []string{"return(true)"}},
+		{cluster.TiDB, "github.com/pingcap/tidb/store/tikv/mockScatterRegionTimeout", []string{"return(true)"}},
+		{cluster.TiKV, "apply_before_split", []string{"50%sleep(1000)", "pause"}},
+	},
+	"raftstore": {
+		{cluster.TiKV, "raft_before_save", []string{"50%sleep(1000)", "10%pause"}},
+		{cluster.TiKV, "raft_before_apply_snap", []string{"pause", "sleep(5000)"}},
+		{cluster.TiKV, "on_raft_gc_log_tick", []string{"return"}},
+	},
+	"tso": {
+		{cluster.PD, "github.com/tikv/pd/server/tso/delaySyncTimestamp", []string{"return(true)"}},
+	},
+}
+
+// failpointGenerator enables a random failpoint from its specs on a random node for a while.
+type failpointGenerator struct {
+	name  string
+	specs []FailpointSpec
+}
+
+// NewFailpointGenerator creates a generator.
+// Name is failpoint_2pc, failpoint_async_commit, failpoint_region_split, failpoint_raftstore,
+// failpoint_tso or failpoint_random which chooses from the whole catalog.
+func NewFailpointGenerator(name string) core.NemesisGenerator {
+	var specs []FailpointSpec
+	if group := strings.TrimPrefix(name, "failpoint_"); group == "random" {
+		for _, groupSpecs := range failpointCatalog {
+			specs = append(specs, groupSpecs...)
+		}
+	} else {
+		var ok bool
+		if specs, ok = failpointCatalog[group]; !ok {
+			log.Fatalf("unknown failpoint group %s", group)
+		}
+	}
+	return NewFailpointGeneratorWithSpecs(name, specs)
+}
+
+// NewFailpointGeneratorWithSpecs creates a generator chooses failpoints from the given specs,
+// it can be used by cases which need their own failpoints.
+func NewFailpointGeneratorWithSpecs(name string, specs []FailpointSpec) core.NemesisGenerator {
+	return failpointGenerator{name: name, specs: specs}
+}
+
+func (g failpointGenerator) Generate(nodes []cluster.Node) []*core.NemesisOperation {
+	duration := time.Second * time.Duration(rand.Intn(120)+60)
+	// try specs in random order in case the cluster lacks some components
+	for _, i := range shuffleIndices(len(g.specs)) {
+		spec := g.specs[i]
+		targets := filterComponent(nodes, spec.Component)
+		if len(targets) == 0 {
+			continue
+		}
+		node := targets[rand.Intn(len(targets))]
+		term := spec.Terms[rand.Intn(len(spec.Terms))]
+		return []*core.NemesisOperation{{
+			Type:        core.Failpoint,
+			Node:        &node,
+			InvokeArgs:  []interface{}{spec.Name, term},
+			RecoverArgs: []interface{}{spec.Name},
+			RunTime:     duration,
+		}}
+	}
+	log.Warnf("no node matches failpoints of %s", g.name)
+	return nil
+}
+
+func (g failpointGenerator) Name() string {
+	return g.name
+}
+
+// failpoint enables and disables failpoints through the HTTP APIs of TiDB, TiKV and PD.
+type failpoint struct{}
+
+func (f failpoint) Invoke(ctx context.Context, node *cluster.Node, args ...interface{}) error {
+	if len(args) != 2 {
+		panic("args number error")
+	}
+	name, term := args[0].(string), args[1].(string)
+	apiURLs, err := failpointURLs(node, name)
+	if err != nil {
+		return err
+	}
+	log.Infof("apply nemesis %s %s=%s on node %s", core.Failpoint, name, term, node)
+	client := httputil.NewHTTPClient(http.DefaultClient)
+	for _, apiURL := range apiURLs {
+		if _, err = client.Put(apiURL, "", strings.NewReader(term)); err == nil || !isNotFound(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (f failpoint) Recover(ctx context.Context, node *cluster.Node, args ...interface{}) error {
+	if len(args) != 1 {
+		panic("args number error")
+	}
+	name := args[0].(string)
+	apiURLs, err := failpointURLs(node, name)
+	if err != nil {
+		return err
+	}
+	log.Infof("unapply nemesis %s %s on node %s", core.Failpoint, name, node)
+	client := httputil.NewHTTPClient(http.DefaultClient)
+	for _, apiURL := range apiURLs {
+		if err = client.Delete(apiURL); err == nil || !isNotFound(err) {
+			return err
+		}
+	}
+	// the node may be restarted and lost its failpoints
+	log.Warnf("failpoint %s is not found on node %s: %v", name, node, err)
+	return nil
+}
+
+func (failpoint) Name() string {
+	return string(core.Failpoint)
+}
+
+// failpointURLs returns the failpoint APIs of the node in the order to try.
+func failpointURLs(node *cluster.Node, name string) ([]string, error) {
+	var (
+		port     int32
+		prefixes []string
+	)
+	switch node.Component {
+	case cluster.TiDB:
+		port, prefixes = tidbStatusPort, []string{failpointPrefix}
+	case cluster.TiKV:
+		port, prefixes = tikvStatusPort, []string{failpointAPIPrefix, failpointPrefix}
+	case cluster.PD:
+		port, prefixes = node.Port, []string{failpointAPIPrefix, failpointPrefix}
+	default:
+		return nil, errors.Errorf("component %s doesn't support failpoint", node.Component)
+	}
+	urls := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		urls = append(urls, fmt.Sprintf("http://%s:%d%s%s", node.IP, port, prefix, name))
+	}
+	return urls, nil
+}
+
+func isNotFound(err error) bool {
+	return strings.Contains(err.Error(), fmt.Sprintf("got %d", http.StatusNotFound))
+}
@@ -50,7 +50,14 @@ func init() {
 		core.RegisterNemesis(netem{client})
 		core.RegisterNemesis(timeChaos{client})
 		core.RegisterNemesis(scaling{client})
+		core.RegisterNemesis(storeOffline{client})
+		core.RegisterNemesis(newRollingUpgrade(client.cli))
+		core.RegisterNemesis(stressChaos{client})
+		core.RegisterNemesis(diskFill{tests.TestClient})
 	}
 	core.RegisterNemesis(scheduler{})
+	core.RegisterNemesis(failpoint{})
+	core.RegisterNemesis(newEvictLeader())
+	core.RegisterNemesis(newPDConfig())
 	core.RegisterNemesis(NewLeaderShuffler("", "0"))
 }
\ No newline at end of file
@@ -0,0 +1,176 @@
+package nemesis
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ngaut/log"
+	"github.com/pingcap/errors"
+
+	"github.com/pingcap/tipocket/pkg/cluster"
+	"github.com/pingcap/tipocket/pkg/core"
+	"github.com/pingcap/tipocket/pkg/util/pdutil"
+)
+
+const hotRegionScheduler = "balance-hot-region-scheduler"
+
+// pdConfigGenerator generates nemeses which change the scheduling configs of PD,
+// to make PD schedule aggressively or throttle it.
+type pdConfigGenerator struct {
+	name string
+}
+
+// NewPDConfigGenerator creates a generator.
+// Name is store_limit_aggressive, store_limit_throttle or hot_region_aggressive.
+func NewPDConfigGenerator(name string) core.NemesisGenerator {
+	return pdConfigGenerator{name: name}
+}
+
+func (g pdConfigGenerator) Generate(nodes []cluster.Node) []*core.NemesisOperation {
+	pds := filterComponent(nodes, cluster.PD)
+	if len(pds) == 0 {
+		log.Warnf("nemesis %s needs PD nodes", g.name)
+		return nil
+	}
+	// the id of the saved config, which is used to restore PD on recovery
+	id := fmt.Sprintf("%s-%s", g.name, randK8sObjectName())
+	return []*core.NemesisOperation{{
+		Type:        core.PDSchedulingConfig,
+		Node:        &pds[r.Intn(len(pds))],
+		InvokeArgs:  []interface{}{id, g.name},
+		RecoverArgs: []interface{}{id},
+		RunTime:     time.Second * time.Duration(r.Intn(120)+60),
+	}}
+}
+
+func (g pdConfigGenerator) Name() string {
+	return g.name
+}
+
+// pdSavedConfig is the PD config before a nemesis is applied.
+type pdSavedConfig struct {
+	storeLimits map[uint64]pdutil.StoreLimit
+	schedule    map[string]interface{}
+	hotRegion   map[string]interface{}
+}
+
+// pdConfig changes PD's store limits and scheduling configs, and restores the original ones on recovery.
+type pdConfig struct {
+	sync.Mutex
+	saved map[string]*pdSavedConfig
+}
+
+func newPDConfig() *pdConfig {
+	return &pdConfig{saved: make(map[string]*pdSavedConfig)}
+}
+
+func (p *pdConfig) Invoke(ctx context.Context, node *cluster.Node, args ...interface{}) error {
+	if len(args) != 2 {
+		panic("args number error")
+	}
+	id, name := args[0].(string), args[1].(string)
+	client := pdutil.NewPDClient(http.DefaultClient, fmt.Sprintf("http://%s", node.Address()))
+
+	saved := &pdSavedConfig{}
+	var err error
+	switch name {
+	case "store_limit_aggressive", "store_limit_throttle":
+		if saved.storeLimits, err = client.GetStoresLimit(); err != nil {
+			return err
+		}
+		if saved.schedule, err = client.GetScheduleConfig(); err != nil {
+			return err
+		}
+	case "hot_region_aggressive":
+		if saved.hotRegion, err = client.GetSchedulerConfig(hotRegionScheduler); err != nil {
+			return err
+		}
+	default:
+		return errors.Errorf("unknown pd config nemesis %s", name)
+	}
+	// save before changing anything, so that a partial failure can be recovered
+	p.Lock()
+	p.saved[id] = saved
+	p.Unlock()
+
+	log.Infof("apply nemesis %s %s on ns %s", core.PDSchedulingConfig, name, node.Namespace)
+	switch name {
+	case "store_limit_aggressive":
+		if err := setStoresLimit(client, saved.storeLimits, 200, 200); err != nil {
+			return err
+		}
+		return client.SetScheduleConfig(map[string]interface{}{
+			"leader-schedule-limit":     64,
+			"region-schedule-limit":     256,
+			"replica-schedule-limit":    256,
+			"merge-schedule-limit":      64,
+			"hot-region-schedule-limit": 64,
+		})
+	case "store_limit_throttle":
+		return setStoresLimit(client, saved.storeLimits, 1, 1)
+	default:
+		return client.SetSchedulerConfig(hotRegionScheduler, map[string]interface{}{
+			"min-hot-byte-rate": 1,
+			"min-hot-key-rate":  1,
+			"great-dec-ratio":   0.99,
+			"minor-dec-ratio":   0.99,
+		})
+	}
+}
+
+func (p *pdConfig) Recover(ctx context.Context, node *cluster.Node, args ...interface{}) error {
+	if len(args) != 1 {
+		panic("args number error")
+	}
+	id := args[0].(string)
+	p.Lock()
+	saved, ok := p.saved[id]
+	p.Unlock()
+	if !ok {
+		// Invoke failed before changing anything
+		return nil
+	}
+	client := pdutil.NewPDClient(http.DefaultClient, fmt.Sprintf("http://%s", node.Address()))
+	log.Infof("unapply nemesis %s %s on ns %s", core.PDSchedulingConfig, id, node.Namespace)
+	for storeID, limit := range saved.storeLimits {
+		if err := client.SetStoreLimit(storeID, pdutil.StoreLimitAddPeer, limit.AddPeer); err != nil {
+			return err
+		}
+		if err := client.SetStoreLimit(storeID, pdutil.StoreLimitRemovePeer, limit.RemovePeer); err != nil {
+			return err
+		}
+	}
+	if saved.schedule != nil {
+		if err := client.SetScheduleConfig(saved.schedule); err != nil {
+			return err
+		}
+	}
+	if saved.hotRegion != nil {
+		if err := client.SetSchedulerConfig(hotRegionScheduler, saved.hotRegion); err != nil {
+			return err
+		}
+	}
+	p.Lock()
+	delete(p.saved, id)
+	p.Unlock()
+	return nil
+}
+
+func (p *pdConfig) Name() string {
+	return string(core.PDSchedulingConfig)
+}
+
+func setStoresLimit(client *pdutil.Client, stores map[uint64]pdutil.StoreLimit, addPeer, removePeer float64) error {
+	for storeID := range stores {
+		if err := client.SetStoreLimit(storeID, pdutil.StoreLimitAddPeer, addPeer); err != nil {
+			return err
+		}
+		if err := client.SetStoreLimit(storeID, pdutil.StoreLimitRemovePeer, removePeer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,402 @@
+package nemesis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ngaut/log"
+	"github.com/pingcap/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/pingcap/tipocket/pkg/cluster"
+	"github.com/pingcap/tipocket/pkg/core"
+	"github.com/pingcap/tipocket/pkg/tidb-operator/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tipocket/pkg/tidb-operator/label"
+)
+
+const (
+	// changing the pod annotation makes TiDB Operator restart pods of the component one by one
+	restartedAtAnnotation = "tidb.pingcap.com/restartedAt"
+	rollingUpdateTimeout  = 30 * time.Minute
+
+	rollingModeVersion       = "version"
+	rollingModeVersionOneway = "version-oneway"
+	rollingModeTiKVConfig    = "tikv-config"
+)
+
+// TiDB Operator upgrades PD, TiKV and TiDB in order
+var rollingComponents = []cluster.Component{cluster.PD, cluster.TiKV, cluster.TiDB}
+
+type rollingUpgradeGenerator struct {
+	name       string
+	version    string
+	tikvConfig string
+}
+
+// NewRollingUpgradeGenerator creates a generator.
+// Name is rolling_upgrade which upgrades the cluster to version and downgrades it back on recovery,
+// rolling_upgrade_oneway which never downgrades, or rolling_restart_tikv_config which rolls
+// tikvConfig through TiKV and restores the original config on recovery.
+func NewRollingUpgradeGenerator(name, version, tikvConfig string) core.NemesisGenerator {
+	return rollingUpgradeGenerator{name: name, version: version, tikvConfig: tikvConfig}
+}
+
+func (g rollingUpgradeGenerator) Generate(nodes []cluster.Node) []*core.NemesisOperation {
+	var mode, value string
+	switch g.name {
+	case "rolling_upgrade", "rolling_upgrade_oneway":
+		mode, value = rollingModeVersion, g.version
+		if g.name == "rolling_upgrade_oneway" {
+			mode = rollingModeVersionOneway
+		}
+	case "rolling_restart_tikv_config":
+		mode, value = rollingModeTiKVConfig, g.tikvConfig
+	default:
+		log.Fatalf("unknown rolling upgrade nemesis %s", g.name)
+	}
+	if value == "" {
+		log.Warnf("nemesis %s has nothing to roll, see -upgrade.version and -upgrade.tikv-config", g.name)
+		return nil
+	}
+	// the id of the saved cluster spec, which is used to roll back on recovery
+	id := fmt.Sprintf("%s-%s", g.name, randK8sObjectName())
+	return []*core.NemesisOperation{{
+		Type:        core.RollingUpgrade,
+		Node:        &nodes[0],
+		InvokeArgs:  []interface{}{id, mode, value},
+		RecoverArgs: []interface{}{id},
+		RunTime:     time.Second * time.Duration(r.Intn(120)+60),
+	}}
+}
+
+func (g rollingUpgradeGenerator) Name() string {
+	return g.name
+}
+
+// rollingSaved is the cluster spec before a rolling upgrade.
+type rollingSaved struct {
+	mode        string
+	specVersion string
+	versions    map[cluster.Component]*string
+	// tikvConfig is nil if the config map of TiKV has no config file
+	tikvConfig *string
+}
+
+// rollingUpgrade patches the TidbCluster and waits for the rolling update of TiDB Operator,
+// pods restarted are reported into history.
+type rollingUpgrade struct {
+	k8sNemesisClient
+	sync.Mutex
+	saved map[string]*rollingSaved
+}
+
+func newRollingUpgrade(cli *Chaos) *rollingUpgrade {
+	return &rollingUpgrade{
+		k8sNemesisClient: k8sNemesisClient{cli},
+		saved:            make(map[string]*rollingSaved),
+	}
+}
+
+func (n *rollingUpgrade) Invoke(ctx context.Context, node *cluster.Node, args ...interface{}) error {
+	if len(args) != 3 {
+		panic("args number error")
+	}
+	id, mode, value := args[0].(string), args[1].(string), args[2].(string)
+	// the TidbCluster has the same name as its namespace
+	ns, name := node.Namespace, node.Namespace
+	tc, err := n.getTidbCluster(ctx, ns, name)
+	if err != nil {
+		return err
+	}
+	saved := &rollingSaved{mode: mode}
+	log.Infof("apply nemesis %s %s on ns %s", core.RollingUpgrade, mode, ns)
+	switch mode {
+	case rollingModeVersion, rollingModeVersionOneway:
+		// a oneway upgrade is never rolled back, so there is nothing to save
+		if mode == rollingModeVersion {
+			saved.specVersion, saved.versions = tc.Spec.Version, componentVersions(tc)
+			n.save(id, saved)
+		}
+		return n.rollVersion(ctx, ns, name, value, func(cluster.Component) *string { return &value })
+	case rollingModeTiKVConfig:
+		if saved.tikvConfig, err = n.getTiKVConfig(ctx, ns, name); err != nil {
+			return err
+		}
+		n.save(id, saved)
+		return n.rollTiKVConfig(ctx, ns, name, &value)
+	default:
+		return errors.Errorf("unknown rolling upgrade mode %s", mode)
+	}
+}
+
+func (n *rollingUpgrade) Recover(ctx context.Context, node *cluster.Node, args ...interface{}) error {
+	if len(args) != 1 {
+		panic("args number error")
+	}
+	id := args[0].(string)
+	n.Lock()
+	saved, ok := n.saved[id]
+	n.Unlock()
+	if !ok {
+		// Invoke failed before changing anything, or it's a oneway upgrade
+		return nil
+	}
+	ns, name := node.Namespace, node.Namespace
+	var err error
+	switch saved.mode {
+	case rollingModeVersion:
+		log.Infof("unapply nemesis %s %s on ns %s", core.RollingUpgrade, saved.mode, ns)
+		err = n.rollVersion(ctx, ns, name, saved.specVersion, func(component cluster.Component) *string {
+			return saved.versions[component]
+		})
+	case rollingModeTiKVConfig:
+		log.Infof("unapply nemesis %s %s on ns %s", core.RollingUpgrade, saved.mode, ns)
+		err = n.rollTiKVConfig(ctx, ns, name, saved.tikvConfig)
+	}
+	if err != nil {
+		return err
+	}
+	n.Lock()
+	delete(n.saved, id)
+	n.Unlock()
+	return nil
+}
+
+func (n *rollingUpgrade) Name() string {
+	return string(core.RollingUpgrade)
+}
+
+func (n *rollingUpgrade) save(id string, saved *rollingSaved) {
+	n.Lock()
+	defer n.Unlock()
+	n.saved[id] = saved
+}
+
+// rollVersion sets the versions of the cluster and components, then waits for components
+// whose version is changed to be rolled.
+func (n *rollingUpgrade) rollVersion(ctx context.Context, ns, name, specVersion string, version func(component cluster.Component) *string) error {
+	tc, err := n.getTidbCluster(ctx, ns, name)
+	if err != nil {
+		return err
+	}
+	var changed []cluster.Component
+	for _, component := range rollingComponents {
+		spec := componentSpec(tc, component)
+		if spec == nil {
+			continue
+		}
+		if stringValue(spec.Version, tc.Spec.Version) != stringValue(version(component), specVersion) {
+			changed = append(changed, component)
+		}
+	}
+	if len(changed) == 0 {
+		log.Infof("cluster %s/%s is already of version %s", ns, name, specVersion)
+		return nil
+	}
+	pods, err := n.listPodUIDs(ctx, ns, name, changed)
+	if err != nil {
+		return err
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, n.cli.cli, tc, func() error {
+		tc.Spec.Version = specVersion
+		for _, component := range rollingComponents {
+			if spec := componentSpec(tc, component); spec != nil {
+				spec.Version = version(component)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Annotatef(err, "update version of cluster %s/%s failed", ns, name)
+	}
+	for _, component := range changed {
+		if err := n.waitRolled(ctx, ns, name, component, pods[component]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollTiKVConfig writes the config into the config map of TiKV, and restarts TiKV to load it.
+// A nil config removes the config file from the config map.
+func (n *rollingUpgrade) rollTiKVConfig(ctx context.Context, ns, name string, config *string) error {
+	tc, err := n.getTidbCluster(ctx, ns, name)
+	if err != nil {
+		return err
+	}
+	if tc.Spec.TiKV == nil {
+		return errors.Errorf("cluster %s/%s has no TiKV", ns, name)
+	}
+	pods, err := n.listPodUIDs(ctx, ns, name, []cluster.Component{cluster.TiKV})
+	if err != nil {
+		return err
+	}
+	configMap := &corev1.ConfigMap{}
+	if err := n.cli.cli.Get(ctx, types.NamespacedName{Namespace: ns, Name: tikvConfigMapName(name)}, configMap); err != nil {
+		return errors.Annotatef(err, "get config map of TiKV failed")
+	}
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	if config == nil {
+		delete(configMap.Data, "config-file")
+	} else {
+		configMap.Data["config-file"] = *config
+	}
+	if err := n.cli.cli.Update(ctx, configMap); err != nil {
+		return errors.Annotatef(err, "update config map of TiKV failed")
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, n.cli.cli, tc, func() error {
+		if tc.Spec.TiKV.Annotations == nil {
+			tc.Spec.TiKV.Annotations = make(map[string]string)
+		}
+		tc.Spec.TiKV.Annotations[restartedAtAnnotation] = time.Now().Format(time.RFC3339)
+		return nil
+	})
+	if err != nil {
+		return errors.Annotatef(err, "restart TiKV of cluster %s/%s failed", ns, name)
+	}
+	return n.waitRolled(ctx, ns, name, cluster.TiKV, pods[cluster.TiKV])
+}
+
+// getTiKVConfig returns the config file in the config map of TiKV, or nil if there is no config file.
+func (n *rollingUpgrade) getTiKVConfig(ctx context.Context, ns, name string) (*string, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := n.cli.cli.Get(ctx, types.NamespacedName{Namespace: ns, Name: tikvConfigMapName(name)}, configMap); err != nil {
+		return nil, errors.Annotatef(err, "get config map of TiKV failed")
+	}
+	config, ok := configMap.Data["config-file"]
+	if !ok {
+		return nil, nil
+	}
+	return &config, nil
+}
+
+func (n *rollingUpgrade) getTidbCluster(ctx context.Context, ns, name string) (*v1alpha1.TidbCluster, error) {
+	var tc v1alpha1.TidbCluster
+	if err := n.cli.cli.Get(ctx, types.NamespacedName{Namespace: ns, Name: name}, &tc); err != nil {
+		return nil, errors.Annotatef(err, "get cluster %s/%s failed", ns, name)
+	}
+	return &tc, nil
+}
+
+func (n *rollingUpgrade) listPods(ctx context.Context, ns, name string, component cluster.Component) ([]corev1.Pod, error) {
+	var pods corev1.PodList
+	l := label.New().Instance(name).Component(string(component))
+	if err := n.cli.cli.List(ctx, &pods, client.InNamespace(ns), client.MatchingLabels(l.Labels())); err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
+
+// listPodUIDs lists UIDs of pods before rolling, a restarted pod has the same name but a new UID.
+func (n *rollingUpgrade) listPodUIDs(ctx context.Context, ns, name string, components []cluster.Component) (map[cluster.Component]map[string]types.UID, error) {
+	uids := make(map[cluster.Component]map[string]types.UID)
+	for _, component := range components {
+		pods, err := n.listPods(ctx, ns, name, component)
+		if err != nil {
+			return nil, errors.Annotatef(err, "list pods of %s failed", component)
+		}
+		uids[component] = make(map[string]types.UID)
+		for _, pod := range pods {
+			uids[component][pod.Name] = pod.UID
+		}
+	}
+	return uids, nil
+}
+
+// waitRolled waits for all pods of the component to be restarted and ready, and reports the restarts.
+func (n *rollingUpgrade) waitRolled(ctx context.Context, ns, name string, component cluster.Component, before map[string]types.UID) error {
+	ctx, cancel := context.WithTimeout(ctx, rollingUpdateTimeout)
+	defer cancel()
+	restarted := make(map[string]struct{})
+	err := wait.PollImmediateUntil(10*time.Second, func() (bool, error) {
+		pods, err := n.listPods(ctx, ns, name, component)
+		if err != nil {
+			log.Warnf("list pods of %s failed: %v", component, err)
+			return false, nil
+		}
+		for _, pod := range pods {
+			if _, ok := restarted[pod.Name]; ok {
+				continue
+			}
+			readyTime, ready := podReadyTime(&pod)
+			if uid, ok := before[pod.Name]; (ok && uid == pod.UID) || !ready {
+				continue
+			}
+			restarted[pod.Name] = struct{}{}
+			log.Infof("pod %s/%s is restarted at %s and ready at %s", ns, pod.Name, pod.CreationTimestamp.Time, readyTime)
+			core.ReportPodRestart(ctx, core.PodRestartRecord{
+				Component: component,
+				Pod:       pod.Name,
+				Time:      pod.CreationTimestamp.Time,
+				ReadyTime: readyTime,
+			})
+		}
+		for pod := range before {
+			if _, ok := restarted[pod]; !ok {
+				return false, nil
+			}
+		}
+		return true, nil
+	}, ctx.Done())
+	if err != nil {
+		return errors.Annotatef(err, "wait for rolling update of %s failed, %d/%d pods restarted", component, len(restarted), len(before))
+	}
+	return nil
+}
+
+func podReadyTime(pod *corev1.Pod) (time.Time, bool) {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.LastTransitionTime.Time, cond.Status == corev1.ConditionTrue
+		}
+	}
+	return time.Time{}, false
+}
+
+func componentSpec(tc *v1alpha1.TidbCluster, component cluster.Component) *v1alpha1.ComponentSpec {
+	switch component {
+	case cluster.PD:
+		if tc.Spec.PD != nil {
+			return &tc.Spec.PD.ComponentSpec
+		}
+	case cluster.TiKV:
+		if tc.Spec.TiKV != nil {
+			return &tc.Spec.TiKV.ComponentSpec
+		}
+	case cluster.TiDB:
+		if tc.Spec.TiDB != nil {
+			return &tc.Spec.TiDB.ComponentSpec
+		}
+	}
+	return nil
+}
+
+func componentVersions(tc *v1alpha1.TidbCluster) map[cluster.Component]*string {
+	versions := make(map[cluster.Component]*string)
+	for _, component := range rollingComponents {
+		if spec := componentSpec(tc, component); spec != nil && spec.Version != nil {
+			version := *spec.Version
+			versions[component] = &version
+		}
+	}
+	return versions
+}
+
+func stringValue(s *string, defaultValue string) string {
+	if s == nil {
+		return defaultValue
+	}
+	return *s
+}
+
+func tikvConfigMapName(tcName string) string {
+	return fmt.Sprintf("%s-tikv", tcName)
+}
@@ -0,0 +1,306 @@
+package nemesis
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ngaut/log"
+	"github.com/pingcap/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/pingcap/tipocket/pkg/cluster"
+	"github.com/pingcap/tipocket/pkg/core"
+	"github.com/pingcap/tipocket/pkg/nemesis/fake_kvproto/metapb"
+	"github.com/pingcap/tipocket/pkg/util/pdutil"
+)
+
+const (
+	evictLeaderScheduler = "evict-leader-scheduler"
+	// a store can't be tombstone unless there are more stores than max-replicas
+	minTiKVForOffline = 4
+
+	storeTombstoneTimeout = 30 * time.Minute
+	storeUpTimeout        = 20 * time.Minute
+)
+
+// storeGenerator generates nemeses on a single TiKV store through PD.
+type storeGenerator struct {
+	name string
+}
+
+// NewStoreGenerator creates a generator.
+// Name is evict_leader_tikv_1node or offline_tikv_1node.
+func NewStoreGenerator(name string) core.NemesisGenerator {
+	return storeGenerator{name: name}
+}
+
+func (g storeGenerator) Generate(nodes []cluster.Node) []*core.NemesisOperation {
+	pds := filterComponent(nodes, cluster.PD)
+	tikvs := filterComponent(nodes, cluster.TiKV)
+	if len(pds) == 0 || len(tikvs) == 0 {
+		log.Warnf("nemesis %s needs both PD and TiKV nodes", g.name)
+		return nil
+	}
+	var (
+		kind     core.ChaosKind
+		duration = time.Second * time.Duration(r.Intn(120)+60)
+	)
+	switch g.name {
+	case "evict_leader_tikv_1node":
+		kind = core.EvictLeader
+	case "offline_tikv_1node":
+		if len(tikvs) < minTiKVForOffline {
+			log.Warnf("nemesis %s needs at least %d TiKV nodes, got %d", g.name, minTiKVForOffline, len(tikvs))
+			return nil
+		}
+		kind = core.StoreOffline
+		// the store stays tombstone for a while before being brought back
+		duration = time.Minute
+	default:
+		log.Fatalf("unknown store nemesis %s", g.name)
+	}
+	pdAddr := fmt.Sprintf("http://%s", pds[r.Intn(len(pds))].Address())
+	node := tikvs[r.Intn(len(tikvs))]
+	return []*core.NemesisOperation{{
+		Type:        kind,
+		Node:        &node,
+		InvokeArgs:  []interface{}{pdAddr},
+		RecoverArgs: []interface{}{pdAddr},
+		RunTime:     duration,
+	}}
+}
+
+func (g storeGenerator) Name() string {
+	return g.name
+}
+
+// findStore finds the non-tombstone store served by the TiKV node.
+func findStore(client *pdutil.Client, node *cluster.Node) (*pdutil.StoreInfo, error) {
+	stores, err := client.GetStores()
+	if err != nil {
+		return nil, err
+	}
+	if store := matchStore(stores, node); store != nil {
+		return store, nil
+	}
+	return nil, errors.Errorf("store of node %s is not found", node)
+}
+
+// matchStore returns the non-tombstone store served by the TiKV node, or nil if there is none.
+func matchStore(stores *pdutil.Stores, node *cluster.Node) *pdutil.StoreInfo {
+	for _, store := range stores.Stores {
+		if store.GetState() == metapb.StoreState_Tombstone {
+			continue
+		}
+		// store address is like `{pod}.{cluster}-tikv-peer.{ns}.svc:20160` on K8s
+		if store.GetAddress() == node.Address() ||
+			(node.PodName != "" && strings.HasPrefix(store.GetAddress(), node.PodName+".")) {
+			return store
+		}
+	}
+	return nil
+}
+
+// evictLeader adds an evict-leader-scheduler for the store, so that all leaders are moved out of it.
+type evictLeader struct {
+	sync.Mutex
+	// added records the stores whose schedulers are added by the nemesis
+	added map[uint64]bool
+}
+
+func newEvictLeader() *evictLeader {
+	return &evictLeader{added: make(map[uint64]bool)}
+}
+
+func (e *evictLeader) Invoke(ctx context.Context, node *cluster.Node, args ...interface{}) error {
+	client := pdutil.NewPDClient(http.DefaultClient, extractPDAddr(args...))
+	store, err := findStore(client, node)
+	if err != nil {
+		return err
+	}
+	// if the store is evicting leaders already, recovery should not remove the scheduler.
+	evicting, err := evictingStores(client)
+	if err != nil {
+		return err
+	}
+	if evicting[store.GetId()] {
+		return errors.Errorf("store %d is evicting leaders before the nemesis", store.GetId())
+	}
+	log.Infof("apply nemesis %s on store %d(%s)", core.EvictLeader, store.GetId(), node)
+	// mark it before adding, so that a scheduler added by a timed out request can be removed as well
+	e.Lock()
+	e.added[store.GetId()] = true
+	e.Unlock()
+	return client.AddSchedulerWithArgs(evictLeaderScheduler, map[string]interface{}{"store_id": store.GetId()})
+}
+
+func (e *evictLeader) Recover(ctx context.Context, node *cluster.Node, args ...interface{}) error {
+	client := pdutil.NewPDClient(http.DefaultClient, extractPDAddr(args...))
+	store, err := findStore(client, node)
+	if err != nil {
+		return err
+	}
+	e.Lock()
+	added := e.added[store.GetId()]
+	e.Unlock()
+	if !added {
+		// Invoke failed before adding the scheduler, or the scheduler exists before, nothing to recover.
+		return nil
+	}
+	evicting, err := evictingStores(client)
+	if err != nil {
+		return err
+	}
+	if evicting[store.GetId()] {
+		log.Infof("unapply nemesis %s on store %d(%s)", core.EvictLeader, store.GetId(), node)
+		// it only removes the store from the scheduler, which is removed with its last store
+		if err := client.RemoveScheduler(fmt.Sprintf("%s-%d", evictLeaderScheduler, store.GetId())); err != nil {
+			return err
+		}
+	}
+	e.Lock()
+	delete(e.added, store.GetId())
+	e.Unlock()
+	return nil
+}
+
+// evictingStores returns the stores whose leaders are evicted. PD 4.0 and later runs a single
+// evict-leader-scheduler with the stores in its `store-id-ranges` config, while the earlier
+// versions run an evict-leader-scheduler-{store_id} for each store.
+func evictingStores(client *pdutil.Client) (map[uint64]bool, error) {
+	schedulers, err := client.GetSchedulers()
+	if err != nil {
+		return nil, err
+	}
+	stores := make(map[uint64]bool)
+	for _, name := range schedulers {
+		if name != evictLeaderScheduler {
+			if id := strings.TrimPrefix(name, evictLeaderScheduler+"-"); id != name {
+				storeID, err := strconv.ParseUint(id, 10, 64)
+				if err != nil {
+					return nil, errors.Annotatef(err, "invalid scheduler %s", name)
+				}
+				stores[storeID] = true
+			}
+			continue
+		}
+		config, err := client.GetSchedulerConfig(evictLeaderScheduler)
+		if err != nil {
+			return nil, err
+		}
+		ranges, _ := config["store-id-ranges"].(map[string]interface{})
+		for id := range ranges {
+			storeID, err := strconv.ParseUint(id, 10, 64)
+			if err != nil {
+				return nil, errors.Annotatef(err, "invalid store %s of %s", id, evictLeaderScheduler)
+			}
+			stores[storeID] = true
+		}
+	}
+	return stores, nil
+}
+
+func (e *evictLeader) Name() string {
+	return string(core.EvictLeader)
+}
+
+// storeOffline sets the store offline and waits for it to become tombstone,
+// recovery brings a fresh store back by rebuilding the TiKV pod with an empty volume.
+type storeOffline struct {
+	k8sNemesisClient
+}
+
+func (s storeOffline) Invoke(ctx context.Context, node *cluster.Node, args ...interface{}) error {
+	client := pdutil.NewPDClient(http.DefaultClient, extractPDAddr(args...))
+	store, err := findStore(client, node)
+	if err != nil {
+		return err
+	}
+	log.Infof("apply nemesis %s on store %d(%s)", core.StoreOffline, store.GetId(), node)
+	if err := client.DeleteStore(store.GetId()); err != nil {
+		return err
+	}
+	return wait.PollImmediate(10*time.Second, storeTombstoneTimeout, func() (bool, error) {
+		stores, err := client.GetStores()
+		if err != nil {
+			log.Warnf("get stores failed: %v", err)
+			return false, nil
+		}
+		for _, s := range stores.Stores {
+			if s.GetId() == store.GetId() {
+				return s.GetState() == metapb.StoreState_Tombstone, nil
+			}
+		}
+		// tombstone has been removed by others
+		return true, nil
+	})
+}
+
+func (s storeOffline) Recover(ctx context.Context, node *cluster.Node, args ...interface{}) error {
+	client := pdutil.NewPDClient(http.DefaultClient, extractPDAddr(args...))
+	// the volume is dropped only if the store is confirmed to be tombstone or gone
+	stores, err := client.GetStores()
+	if err != nil {
+		return errors.Annotatef(err, "get the store of %s failed", node)
+	}
+	if store := matchStore(stores, node); store != nil {
+		switch store.GetState() {
+		case metapb.StoreState_Offline:
+			// the store didn't become tombstone in time, it can be set up directly
+			log.Infof("unapply nemesis %s on store %d(%s)", core.StoreOffline, store.GetId(), node)
+			return client.SetStoreState(store.GetId(), metapb.StoreState_Up.String())
+		default:
+			// Invoke failed to set the store offline, nothing to recover
+			return nil
+		}
+	}
+	log.Infof("unapply nemesis %s on node %s", core.StoreOffline, node)
+	// A tombstone store can never be up again, so we drop its data and let the operator
+	// recreate the pod, which registers a new store with the same address.
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", cluster.TiKV, node.PodName),
+			Namespace: node.Namespace,
+		},
+	}
+	if err := s.cli.cli.Delete(ctx, pvc); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Annotatef(err, "delete pvc %s failed", pvc.Name)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      node.PodName,
+			Namespace: node.Namespace,
+		},
+	}
+	if err := s.cli.cli.Delete(ctx, pod); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Annotatef(err, "delete pod %s failed", pod.Name)
+	}
+	err = wait.PollImmediate(10*time.Second, storeUpTimeout, func() (bool, error) {
+		store, err := findStore(client, node)
+		if err != nil {
+			log.Infof("wait for the new store of %s: %v", node, err)
+			return false, nil
+		}
+		return store.GetState() == metapb.StoreState_Up, nil
+	})
+	if err != nil {
+		return errors.Annotatef(err, "wait for the new store of %s up failed", node)
+	}
+	return client.RemoveTombstone()
+}
+
+func (storeOffline) Name() string {
+	return string(core.StoreOffline)
+}
+
+func extractPDAddr(args ...interface{}) string {
+	return args[0].(string)
+}
@@ -0,0 +1,157 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nemesis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pingcap/tipocket/pkg/cluster"
+	"github.com/pingcap/tipocket/pkg/nemesis/fake_kvproto/metapb"
+	"github.com/pingcap/tipocket/pkg/util/pdutil"
+)
+
+// fakePD serves the stores and the evict-leader-scheduler like PD 4.0 and later,
+// which keeps all the evicted stores in the config of a single scheduler.
+type fakePD struct {
+	sync.Mutex
+	stores   []*metapb.Store
+	evicting map[uint64]bool
+}
+
+func (pd *fakePD) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	pd.Lock()
+	defer pd.Unlock()
+	var resp interface{}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/pd/api/v1/stores":
+		stores := &pdutil.Stores{Count: uint64(len(pd.stores))}
+		for _, store := range pd.stores {
+			stores.Stores = append(stores.Stores, &pdutil.StoreInfo{MetaStore: &pdutil.MetaStore{Store: store, StateName: store.State.String()}})
+		}
+		resp = stores
+	case r.Method == http.MethodGet && r.URL.Path == "/pd/api/v1/schedulers":
+		schedulers := []string{"balance-leader-scheduler"}
+		if len(pd.evicting) > 0 {
+			schedulers = append(schedulers, evictLeaderScheduler)
+		}
+		resp = schedulers
+	case r.Method == http.MethodGet && r.URL.Path == "/pd/api/v1/scheduler-config/evict-leader-scheduler/list":
+		if len(pd.evicting) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		ranges := make(map[string]interface{})
+		for id := range pd.evicting {
+			ranges[fmt.Sprint(id)] = []map[string]string{{"start-key": "", "end-key": ""}}
+		}
+		resp = map[string]interface{}{"store-id-ranges": ranges}
+	case r.Method == http.MethodPost && r.URL.Path == "/pd/api/v1/schedulers":
+		var input struct {
+			Name    string `json:"name"`
+			StoreID uint64 `json:"store_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Name != evictLeaderScheduler {
+			http.Error(w, "invalid scheduler", http.StatusBadRequest)
+			return
+		}
+		pd.evicting[input.StoreID] = true
+		resp = "The scheduler is created."
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/pd/api/v1/schedulers/"+evictLeaderScheduler+"-"):
+		var id uint64
+		fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/pd/api/v1/schedulers/"+evictLeaderScheduler+"-"), "%d", &id)
+		if !pd.evicting[id] {
+			http.NotFound(w, r)
+			return
+		}
+		delete(pd.evicting, id)
+		resp = "The scheduler is removed."
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func TestEvictLeader(t *testing.T) {
+	pd := &fakePD{
+		stores: []*metapb.Store{
+			{Id: 1, Address: "10.0.0.1:20160", State: metapb.StoreState_Up},
+			{Id: 4, Address: "10.0.0.2:20160", State: metapb.StoreState_Up},
+			{Id: 5, Address: "10.0.0.3:20160", State: metapb.StoreState_Up},
+		},
+		// store 5 is evicted by others
+		evicting: map[uint64]bool{5: true},
+	}
+	server := httptest.NewServer(pd)
+	defer server.Close()
+
+	var (
+		ctx      = context.Background()
+		e        = newEvictLeader()
+		node     = &cluster.Node{Component: cluster.TiKV, IP: "10.0.0.2", Port: 20160}
+		existing = &cluster.Node{Component: cluster.TiKV, IP: "10.0.0.3", Port: 20160}
+	)
+	if err := e.Invoke(ctx, node, server.URL); err != nil {
+		t.Fatalf("invoke failed %v", err)
+	}
+	if !pd.evicting[4] {
+		t.Fatalf("store 4 isn't evicted, got %v", pd.evicting)
+	}
+	if err := e.Recover(ctx, node, server.URL); err != nil {
+		t.Fatalf("recover failed %v", err)
+	}
+	if pd.evicting[4] || !pd.evicting[5] {
+		t.Fatalf("only store 4 should be removed from the scheduler, got %v", pd.evicting)
+	}
+
+	// the scheduler of the store isn't added by the nemesis, it must be kept
+	if err := e.Invoke(ctx, existing, server.URL); err == nil {
+		t.Fatal("store 5 is evicting leaders before the nemesis")
+	}
+	if err := e.Recover(ctx, existing, server.URL); err != nil {
+		t.Fatalf("recover failed %v", err)
+	}
+	if !pd.evicting[5] {
+		t.Fatalf("store 5 shouldn't be removed from the scheduler, got %v", pd.evicting)
+	}
+}
+
+func TestStoreOfflineRecoverKeepsVolume(t *testing.T) {
+	// storeOffline has no K8s client here, deleting the volume panics
+	var s storeOffline
+	node := &cluster.Node{Component: cluster.TiKV, IP: "10.0.0.1", Port: 20160}
+
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "pd is unavailable", http.StatusInternalServerError)
+	}))
+	defer unavailable.Close()
+	if err := s.Recover(context.Background(), node, unavailable.URL); err == nil {
+		t.Fatal("recover should fail if the stores are unknown")
+	}
+
+	server := httptest.NewServer(&fakePD{
+		stores: []*metapb.Store{{Id: 1, Address: "10.0.0.1:20160", State: metapb.StoreState_Up}},
+	})
+	defer server.Close()
+	if err := s.Recover(context.Background(), node, server.URL); err != nil {
+		t.Fatalf("recover failed %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package nemesis
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	chaosv1alpha1 "github.com/chaos-mesh/chaos-mesh/api/v1alpha1"
+	"github.com/ngaut/log"
+	"github.com/pingcap/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/pingcap/tipocket/pkg/cluster"
+	"github.com/pingcap/tipocket/pkg/core"
+)
+
+const (
+	stressCPU    = "cpu"
+	stressMemory = "memory"
+
+	stressWorkers = 4
+	// a container killed by OOM is expected to be back in time
+	containerReadyTimeout = 10 * time.Minute
+)
+
+// parseComponentNemesis parses names like `{prefix}_{component}_1node`.
+func parseComponentNemesis(name, prefix string) cluster.Component {
+	return cluster.Component(strings.TrimSuffix(strings.TrimPrefix(name, prefix+"_"), "_1node"))
+}
+
+type stressChaosGenerator struct {
+	name string
+}
+
+// NewStressChaosGenerator creates a generator.
+// Name is like cpu_stress_tikv_1node or memory_stress_tidb_1node, the component can be tidb, tikv, pd or tiflash.
+func NewStressChaosGenerator(name string) core.NemesisGenerator {
+	return stressChaosGenerator{name: name}
+}
+
+func (g stressChaosGenerator) Generate(nodes []cluster.Node) []*core.NemesisOperation {
+	var (
+		stressor  string
+		component cluster.Component
+	)
+	switch {
+	case strings.HasPrefix(g.name, "cpu_stress"):
+		stressor, component = stressCPU, parseComponentNemesis(g.name, "cpu_stress")
+	case strings.HasPrefix(g.name, "memory_stress"):
+		stressor, component = stressMemory, parseComponentNemesis(g.name, "memory_stress")
+	default:
+		log.Fatalf("unknown stress chaos %s", g.name)
+	}
+	targets := filterComponent(nodes, component)
+	if len(targets) == 0 {
+		log.Warnf("no %s node for nemesis %s", component, g.name)
+		return nil
+	}
+	node := targets[r.Intn(len(targets))]
+	return []*core.NemesisOperation{{
+		Type:        core.StressChaos,
+		Node:        &node,
+		InvokeArgs:  []interface{}{stressor},
+		RecoverArgs: []interface{}{stressor},
+		RunTime:     time.Second * time.Duration(r.Intn(120)+60),
+	}}
+}
+
+func (g stressChaosGenerator) Name() string {
+	return g.name
+}
+
+// stressChaos stresses CPU or memory of the component container by chaos-mesh,
+// memory stress may get the container killed by OOM, recovery waits for it to be back.
+type stressChaos struct {
+	k8sNemesisClient
+}
+
+func (s stressChaos) Invoke(ctx context.Context, node *cluster.Node, args ...interface{}) error {
+	if len(args) != 1 {
+		panic("args number error")
+	}
+	log.Infof("apply nemesis %s %s on node %s(ns:%s)", core.StressChaos, args[0], node.PodName, node.Namespace)
+	chaos := buildStressChaos(node, args[0].(string))
+	return s.cli.ApplyStressChaos(ctx, &chaos)
+}
+
+func (s stressChaos) Recover(ctx context.Context, node *cluster.Node, args ...interface{}) error {
+	if len(args) != 1 {
+		panic("args number error")
+	}
+	log.Infof("unapply nemesis %s %s on node %s(ns:%s)", core.StressChaos, args[0], node.PodName, node.Namespace)
+	chaos := buildStressChaos(node, args[0].(string))
+	var since time.Time
+	if err := s.cli.cli.Get(ctx, types.NamespacedName{Namespace: chaos.Namespace, Name: chaos.Name}, &chaos); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+	} else {
+		since = chaos.CreationTimestamp.Time
+		if err := s.cli.CancelStressChaos(ctx, &chaos); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return s.waitContainerReady(ctx, node, since)
+}
+
+func (stressChaos) Name() string {
+	return string(core.StressChaos)
+}
+
+func buildStressChaos(node *cluster.Node, stressor string) chaosv1alpha1.StressChaos {
+	stressors := &chaosv1alpha1.Stressors{}
+	switch stressor {
+	case stressCPU:
+		load := 100
+		stressors.CPUStressor = &chaosv1alpha1.CPUStressor{
+			Stressor: chaosv1alpha1.Stressor{Workers: stressWorkers},
+			Load:     &load,
+		}
+	case stressMemory:
+		// bigheap workers keep growing their heaps until the container is out of memory
+		stressors.MemoryStressor = &chaosv1alpha1.MemoryStressor{
+			Stressor: chaosv1alpha1.Stressor{Workers: stressWorkers},
+		}
+	}
+	containerName := string(node.Component)
+	return chaosv1alpha1.StressChaos{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      strings.Join([]string{node.PodName, string(core.StressChaos)}, "-"),
+			Namespace: node.Namespace,
+		},
+		Spec: chaosv1alpha1.StressChaosSpec{
+			Mode: chaosv1alpha1.OnePodMode,
+			Selector: chaosv1alpha1.SelectorSpec{
+				Pods: map[string][]string{node.Namespace: {node.PodName}},
+			},
+			Stressors:     stressors,
+			ContainerName: &containerName,
+		},
+	}
+}
+
+// waitContainerReady waits for the component container of the node to be ready,
+// and reports the restart if it's restarted after since, eg. killed by OOM.
+func (s stressChaos) waitContainerReady(ctx context.Context, node *cluster.Node, since time.Time) error {
+	var status *corev1.ContainerStatus
+	err := wait.PollImmediate(5*time.Second, containerReadyTimeout, func() (bool, error) {
+		var pod corev1.Pod
+		if err := s.cli.cli.Get(ctx, types.NamespacedName{Namespace: node.Namespace, Name: node.PodName}, &pod); err != nil {
+			log.Warnf("get pod %s/%s failed: %v", node.Namespace, node.PodName, err)
+			return false, nil
+		}
+		for i := range pod.Status.ContainerStatuses {
+			if pod.Status.ContainerStatuses[i].Name == string(node.Component) {
+				status = &pod.Status.ContainerStatuses[i]
+				return status.Ready && status.State.Running != nil, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return errors.Annotatef(err, "wait for container %s of pod %s/%s ready failed", node.Component, node.Namespace, node.PodName)
+	}
+	if last := status.LastTerminationState.Terminated; last != nil && !since.IsZero() && last.FinishedAt.After(since) {
+		log.Infof("container %s of pod %s/%s was terminated by %s at %s", node.Component, node.Namespace, node.PodName, last.Reason, last.FinishedAt)
+		core.ReportPodRestart(ctx, core.PodRestartRecord{
+			Component: node.Component,
+			Pod:       node.PodName,
+			Time:      status.State.Running.StartedAt.Time,
+			ReadyTime: time.Now(),
+		})
+	}
+	return nil
+}
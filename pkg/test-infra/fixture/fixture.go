@@ -48,6 +48,14 @@ type fixtureContext struct {
 	RunTime      time.Duration
 	RequestCount int
 	HistoryFile  string
+	// HealthGateTimeout is the timeout of waiting cluster healthy around nemesis
+	HealthGateTimeout time.Duration
+	// UpgradeVersion is the version which the rolling_upgrade nemesis upgrades the cluster to
+	UpgradeVersion string
+	// UpgradeTiKVConfig is the TiKV config which the rolling_restart_tikv_config nemesis rolls
+	UpgradeTiKVConfig string
+	// DiskFillPercent is the usage percentage which disk_fill nemeses fill the data volume up to
+	DiskFillPercent int
 	// Test-infra
 	Namespace                string
 	ClusterName              string
@@ -272,6 +280,10 @@ func init() {
 	flag.DurationVar(&Context.RunTime, "run-time", 100*time.Minute, "run time of client")
 	flag.IntVar(&Context.RequestCount, "request-count", 10000, "requests a client sends to the db")
 	flag.StringVar(&Context.HistoryFile, "history", "./history.log", "history file record client operation")
+	flag.DurationVar(&Context.HealthGateTimeout, "health-gate-timeout", 10*time.Minute, "max time to wait for the cluster to become healthy before a nemesis and after its recovery, 0 to disable")
+	flag.StringVar(&Context.UpgradeVersion, "upgrade.version", "", "image version which rolling_upgrade nemesis upgrades the cluster to")
+	flag.StringVar(&Context.UpgradeTiKVConfig, "upgrade.tikv-config", "", "path of tikv config file which rolling_restart_tikv_config nemesis rolls through TiKV")
+	flag.IntVar(&Context.DiskFillPercent, "disk-fill.percent", 95, "usage percentage which disk_fill nemeses fill the data volume up to")
 
 	flag.StringVar(&Context.Namespace, "namespace", "", "test namespace")
 	flag.StringVar(&Context.ClusterName, "cluster-name", "", "test cluster name")
@@ -14,6 +14,7 @@
 package tests
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
@@ -22,9 +23,12 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth" // auth in cluster
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/pingcap/tipocket/pkg/test-infra/fixture"
@@ -39,6 +43,8 @@ var TestClient *TestCli
 // TestCli contains clients
 type TestCli struct {
 	Cli client.Client
+
+	conf *rest.Config
 }
 
 func newTestCli(conf *rest.Config) *TestCli {
@@ -47,7 +53,8 @@ func newTestCli(conf *rest.Config) *TestCli {
 		log.Warnf("error creating kube-client: %v", err)
 	}
 	return &TestCli{
-		Cli: kubeCli,
+		Cli:  kubeCli,
+		conf: conf,
 	}
 }
 
@@ -110,6 +117,44 @@ func (e *TestCli) DeleteNamespace(name string) error {
 	return nil
 }
 
+// ExecInPod runs the command in the container of the pod, and returns its stdout.
+func (e *TestCli) ExecInPod(ctx context.Context, ns, pod, container string, command ...string) (string, error) {
+	clientset, err := kubernetes.NewForConfig(e.conf)
+	if err != nil {
+		return "", err
+	}
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(ns).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(e.conf, "POST", req.URL())
+	if err != nil {
+		return "", err
+	}
+	var stdout, stderr bytes.Buffer
+	// the executor of this client-go version doesn't accept a context
+	done := make(chan error, 1)
+	go func() {
+		done <- executor.Stream(remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr})
+	}()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	if err != nil {
+		return stdout.String(), fmt.Errorf("exec %v in pod %s/%s failed: %v, stderr: %s", command, ns, pod, err, stderr.String())
+	}
+	return stdout.String(), nil
+}
+
 func init() {
 	conf, err := clientcmd.BuildConfigFromFlags("", os.Getenv("KUBECONFIG"))
 	if err != nil {
@@ -294,7 +294,7 @@ func (o *Ops) applyTiDBConfigMap(tc *v1alpha1.TidbCluster, configString string)
 	if err != nil {
 		return err
 	}
-	configData, err := parseConfig(configString)
+	configData, err := ParseConfig(configString)
 	if err != nil {
 		return err
 	}
@@ -314,7 +314,7 @@ func (o *Ops) applyPDConfigMap(tc *v1alpha1.TidbCluster, configString string) er
 	if err != nil {
 		return err
 	}
-	configData, err := parseConfig(configString)
+	configData, err := ParseConfig(configString)
 	if err != nil {
 		return err
 	}
@@ -334,7 +334,7 @@ func (o *Ops) applyTiKVConfigMap(tc *v1alpha1.TidbCluster, configString string)
 	if err != nil {
 		return err
 	}
-	configData, err := parseConfig(configString)
+	configData, err := ParseConfig(configString)
 	if err != nil {
 		return err
 	}
@@ -406,7 +406,8 @@ func (o *Ops) GetPDMember(namespace, name string) (string, []string, error) {
 	return local.Status.PD.Leader.Name, members, nil
 }
 
-func parseConfig(config string) (string, error) {
+// ParseConfig reads a config which is a file path, base64://BASE64CONTENT or plaintext://CONTENT.
+func ParseConfig(config string) (string, error) {
 	if strings.HasPrefix(config, plaintextProtocolHeader) && len(config) > len(plaintextProtocolHeader) {
 		return extractRawConfig(config), nil
 	}
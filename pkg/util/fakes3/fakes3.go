@@ -0,0 +1,459 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fakes3 is a MinIO-compatible S3 stand-in which stores the objects in a local directory.
+// It serves the path-style requests used by BR, e.g. the storage URI
+// s3://bucket/prefix?endpoint=http://{host}:{port}&force-path-style=true&access-key=any&secret-access-key=any.
+// The requests are not authenticated and the buckets are created on demand.
+package fakes3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ngaut/log"
+)
+
+// uploadsDir keeps the parts of the multipart uploads, it's not a valid bucket name
+const uploadsDir = ".uploads"
+
+// defaultMaxKeys is the max keys of a list response
+const defaultMaxKeys = 1000
+
+// tmpPrefix is the prefix of the objects being written, they are not listed
+const tmpPrefix = ".tmp-"
+
+// Server serves the S3 API in a local directory, each bucket is a sub directory
+type Server struct {
+	dir          string
+	nextUploadID int64
+}
+
+// NewServer creates a Server which stores the objects in dir
+func NewServer(dir string) *Server {
+	return &Server{dir: dir}
+}
+
+// ListenAndServe serves the S3 API on addr
+func ListenAndServe(addr, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	log.Infof("[fake s3] serve %s on %s", dir, addr)
+	return http.ListenAndServe(addr, NewServer(dir))
+}
+
+type errorResponse struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+type initiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadID string   `xml:"UploadId"`
+}
+
+type completeMultipartUpload struct {
+	Parts []struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	} `xml:"Part"`
+}
+
+type completeMultipartUploadResult struct {
+	XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+	Bucket  string   `xml:"Bucket"`
+	Key     string   `xml:"Key"`
+	ETag    string   `xml:"ETag"`
+}
+
+type object struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type listBucketResult struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	Name                  string   `xml:"Name"`
+	Prefix                string   `xml:"Prefix"`
+	Marker                string   `xml:"Marker,omitempty"`
+	NextMarker            string   `xml:"NextMarker,omitempty"`
+	ContinuationToken     string   `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string   `xml:"NextContinuationToken,omitempty"`
+	KeyCount              int      `xml:"KeyCount"`
+	MaxKeys               int      `xml:"MaxKeys"`
+	IsTruncated           bool     `xml:"IsTruncated"`
+	Contents              []object `xml:"Contents"`
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, ok := splitPath(r.URL.Path)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "InvalidURI", "invalid path "+r.URL.Path)
+		return
+	}
+	query := r.URL.Query()
+	_, uploads := query["uploads"]
+	switch {
+	case key == "":
+		s.serveBucket(w, r, bucket)
+	case r.Method == http.MethodPost && uploads:
+		s.initiateMultipartUpload(w, bucket, key)
+	case r.Method == http.MethodPost && query.Get("uploadId") != "":
+		s.completeMultipartUpload(w, r, bucket, key, query.Get("uploadId"))
+	case r.Method == http.MethodPut && query.Get("uploadId") != "":
+		s.uploadPart(w, r, query.Get("uploadId"), query.Get("partNumber"))
+	case r.Method == http.MethodDelete && query.Get("uploadId") != "":
+		s.abortMultipartUpload(w, query.Get("uploadId"))
+	case r.Method == http.MethodPut:
+		s.putObject(w, r, bucket, key)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		s.getObject(w, r, bucket, key)
+	case r.Method == http.MethodDelete:
+		s.deleteObject(w, bucket, key)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method+" is not allowed")
+	}
+}
+
+func (s *Server) serveBucket(w http.ResponseWriter, r *http.Request, bucket string) {
+	switch r.Method {
+	case http.MethodHead, http.MethodPut:
+		if err := os.MkdirAll(filepath.Join(s.dir, bucket), 0755); err != nil {
+			writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		s.listObjects(w, r, bucket)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method+" is not allowed")
+	}
+}
+
+func (s *Server) listObjects(w http.ResponseWriter, r *http.Request, bucket string) {
+	query := r.URL.Query()
+	result := listBucketResult{
+		Name:    bucket,
+		Prefix:  query.Get("prefix"),
+		MaxKeys: defaultMaxKeys,
+	}
+	if maxKeys, err := strconv.Atoi(query.Get("max-keys")); err == nil && maxKeys > 0 && maxKeys < defaultMaxKeys {
+		result.MaxKeys = maxKeys
+	}
+	// the continuation token of v2 and the marker of v1 are both the last key listed
+	v2 := query.Get("list-type") == "2"
+	after := query.Get("marker")
+	if v2 {
+		result.ContinuationToken = query.Get("continuation-token")
+		after = result.ContinuationToken
+		if after == "" {
+			after = query.Get("start-after")
+		}
+	} else {
+		result.Marker = after
+	}
+
+	keys, err := s.keys(bucket, result.Prefix)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	for _, k := range keys {
+		if k <= after {
+			continue
+		}
+		if len(result.Contents) == result.MaxKeys {
+			result.IsTruncated = true
+			break
+		}
+		info, err := os.Stat(s.objectPath(bucket, k))
+		if err != nil {
+			continue
+		}
+		result.Contents = append(result.Contents, object{
+			Key:          k,
+			LastModified: info.ModTime().UTC().Format(time.RFC3339),
+			ETag:         etag(s.objectPath(bucket, k)),
+			Size:         info.Size(),
+			StorageClass: "STANDARD",
+		})
+	}
+	result.KeyCount = len(result.Contents)
+	if result.IsTruncated {
+		last := result.Contents[len(result.Contents)-1].Key
+		if v2 {
+			result.NextContinuationToken = last
+		} else {
+			result.NextMarker = last
+		}
+	}
+	writeXML(w, http.StatusOK, result)
+}
+
+// keys returns the sorted keys in the bucket with the prefix
+func (s *Server) keys(bucket, prefix string) ([]string, error) {
+	root := filepath.Join(s.dir, bucket)
+	var keys []string
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), tmpPrefix) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if k := filepath.ToSlash(rel); strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+		return nil
+	})
+	sort.Strings(keys)
+	return keys, err
+}
+
+func (s *Server) putObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	p := s.objectPath(bucket, key)
+	if source := r.Header.Get("x-amz-copy-source"); source != "" {
+		srcBucket, srcKey, ok := splitPath(source)
+		if !ok || srcKey == "" {
+			writeError(w, http.StatusBadRequest, "InvalidArgument", "invalid copy source "+source)
+			return
+		}
+		src, err := os.Open(s.objectPath(srcBucket, srcKey))
+		if err != nil {
+			writeError(w, http.StatusNotFound, "NoSuchKey", err.Error())
+			return
+		}
+		defer src.Close()
+		if err := writeFile(p, src); err != nil {
+			writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+			return
+		}
+		writeXML(w, http.StatusOK, struct {
+			XMLName xml.Name `xml:"CopyObjectResult"`
+			ETag    string   `xml:"ETag"`
+		}{ETag: etag(p)})
+		return
+	}
+	if err := writeFile(p, r.Body); err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	w.Header().Set("ETag", etag(p))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) getObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	p := s.objectPath(bucket, key)
+	f, err := os.Open(p)
+	if err != nil {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeError(w, http.StatusNotFound, "NoSuchKey", "the key "+key+" does not exist")
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		writeError(w, http.StatusNotFound, "NoSuchKey", "the key "+key+" does not exist")
+		return
+	}
+	w.Header().Set("ETag", etag(p))
+	// ServeContent handles the range requests and HEAD
+	http.ServeContent(w, r, path.Base(key), info.ModTime(), f)
+}
+
+func (s *Server) deleteObject(w http.ResponseWriter, bucket, key string) {
+	if err := os.Remove(s.objectPath(bucket, key)); err != nil && !os.IsNotExist(err) {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) initiateMultipartUpload(w http.ResponseWriter, bucket, key string) {
+	id := fmt.Sprintf("%d-%d", time.Now().UnixNano(), atomic.AddInt64(&s.nextUploadID, 1))
+	if err := os.MkdirAll(s.uploadPath(id), 0755); err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	writeXML(w, http.StatusOK, initiateMultipartUploadResult{Bucket: bucket, Key: key, UploadID: id})
+}
+
+func (s *Server) uploadPart(w http.ResponseWriter, r *http.Request, id, partNumber string) {
+	n, err := strconv.Atoi(partNumber)
+	if err != nil || n <= 0 || !validName(id) {
+		writeError(w, http.StatusBadRequest, "InvalidArgument", "invalid part "+partNumber+" of upload "+id)
+		return
+	}
+	if _, err := os.Stat(s.uploadPath(id)); err != nil {
+		writeError(w, http.StatusNotFound, "NoSuchUpload", "the upload "+id+" does not exist")
+		return
+	}
+	p := filepath.Join(s.uploadPath(id), strconv.Itoa(n))
+	if err := writeFile(p, r.Body); err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	w.Header().Set("ETag", etag(p))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) completeMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key, id string) {
+	if !validName(id) {
+		writeError(w, http.StatusBadRequest, "InvalidArgument", "invalid upload "+id)
+		return
+	}
+	var complete completeMultipartUpload
+	if err := xml.NewDecoder(r.Body).Decode(&complete); err != nil {
+		writeError(w, http.StatusBadRequest, "MalformedXML", err.Error())
+		return
+	}
+	sort.Slice(complete.Parts, func(i, j int) bool { return complete.Parts[i].PartNumber < complete.Parts[j].PartNumber })
+	readers := make([]io.Reader, 0, len(complete.Parts))
+	for _, part := range complete.Parts {
+		f, err := os.Open(filepath.Join(s.uploadPath(id), strconv.Itoa(part.PartNumber)))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidPart", err.Error())
+			return
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+	p := s.objectPath(bucket, key)
+	if err := writeFile(p, io.MultiReader(readers...)); err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	_ = os.RemoveAll(s.uploadPath(id))
+	writeXML(w, http.StatusOK, completeMultipartUploadResult{Bucket: bucket, Key: key, ETag: etag(p)})
+}
+
+func (s *Server) abortMultipartUpload(w http.ResponseWriter, id string) {
+	if validName(id) {
+		_ = os.RemoveAll(s.uploadPath(id))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) objectPath(bucket, key string) string {
+	return filepath.Join(s.dir, bucket, filepath.FromSlash(key))
+}
+
+func (s *Server) uploadPath(id string) string {
+	return filepath.Join(s.dir, uploadsDir, id)
+}
+
+// splitPath splits the path-style request path into the bucket and the key,
+// the paths escaping the bucket are rejected
+func splitPath(p string) (bucket, key string, ok bool) {
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return "", "", false
+	}
+	parts := strings.SplitN(p, "/", 2)
+	bucket = parts[0]
+	if len(parts) == 2 {
+		key = parts[1]
+	}
+	if !validName(bucket) || bucket == uploadsDir {
+		return "", "", false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "." || segment == ".." {
+			return "", "", false
+		}
+	}
+	return bucket, key, true
+}
+
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\")
+}
+
+// writeFile writes the file through a temporary file, so the readers never see a partial object
+func writeFile(p string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(p), tmpPrefix)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
+
+// etag returns the quoted md5 of the file
+func etag(p string) string {
+	f, err := os.Open(p)
+	if err != nil {
+		return `""`
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return `""`
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeXML(w, status, errorResponse{Code: code, Message: message})
+}
+
+func writeXML(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).Encode(v); err != nil {
+		log.Warnf("[fake s3] write response failed: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakes3
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func do(t *testing.T, method, url, body string, header map[string]string) (*http.Response, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(data)
+}
+
+func TestServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fakes3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	server := httptest.NewServer(NewServer(dir))
+	defer server.Close()
+	url := server.URL + "/bucket"
+
+	if resp, _ := do(t, http.MethodHead, url, "", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("head bucket: %s", resp.Status)
+	}
+	if resp, _ := do(t, http.MethodPut, url+"/backup/full-0/backupmeta", "meta", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("put object: %s", resp.Status)
+	}
+	if _, body := do(t, http.MethodGet, url+"/backup/full-0/backupmeta", "", map[string]string{"Range": "bytes=1-2"}); body != "et" {
+		t.Fatalf("unexpected range read %q", body)
+	}
+	if resp, _ := do(t, http.MethodGet, url+"/backup/none", "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("get missing object: %s", resp.Status)
+	}
+	if resp, _ := do(t, http.MethodGet, url+"/../secret", "", nil); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("escape the bucket: %s", resp.Status)
+	}
+
+	// multipart upload
+	_, body := do(t, http.MethodPost, url+"/backup/full-0/1.sst?uploads", "", nil)
+	var initiated initiateMultipartUploadResult
+	if err := xml.Unmarshal([]byte(body), &initiated); err != nil {
+		t.Fatal(err)
+	}
+	for n, part := range map[string]string{"2": "world", "1": "hello "} {
+		if resp, _ := do(t, http.MethodPut, url+"/backup/full-0/1.sst?partNumber="+n+"&uploadId="+initiated.UploadID, part, nil); resp.StatusCode != http.StatusOK {
+			t.Fatalf("upload part: %s", resp.Status)
+		}
+	}
+	complete := `<CompleteMultipartUpload><Part><PartNumber>2</PartNumber></Part><Part><PartNumber>1</PartNumber></Part></CompleteMultipartUpload>`
+	if resp, _ := do(t, http.MethodPost, url+"/backup/full-0/1.sst?uploadId="+initiated.UploadID, complete, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("complete multipart upload: %s", resp.Status)
+	}
+	if _, body := do(t, http.MethodGet, url+"/backup/full-0/1.sst", "", nil); body != "hello world" {
+		t.Fatalf("unexpected object %q", body)
+	}
+
+	// list in pages
+	var keys []string
+	token := ""
+	for {
+		_, body := do(t, http.MethodGet, url+"?list-type=2&prefix=backup/&max-keys=1&continuation-token="+token, "", nil)
+		var result listBucketResult
+		if err := xml.Unmarshal([]byte(body), &result); err != nil {
+			t.Fatal(err)
+		}
+		for _, o := range result.Contents {
+			keys = append(keys, o.Key)
+		}
+		if !result.IsTruncated {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	if strings.Join(keys, ",") != "backup/full-0/1.sst,backup/full-0/backupmeta" {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+
+	if resp, _ := do(t, http.MethodDelete, url+"/backup/full-0/backupmeta", "", nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete object: %s", resp.Status)
+	}
+	if resp, _ := do(t, http.MethodHead, url+"/backup/full-0/backupmeta", "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("head deleted object: %s", resp.Status)
+	}
+}
@@ -27,6 +27,12 @@ const (
 	transferAllocatorPrefix = "/pd/api/v1/tso/allocator/transfer"
 	storePrefix             = "/pd/api/v1/store"
 	transferLeaderPrefix    = "/pd/api/v1/leader/transfer"
+	healthPrefix            = "/pd/api/v1/health"
+	regionsCheckPrefix      = "/pd/api/v1/regions/check"
+	storesLimitPrefix       = "/pd/api/v1/stores/limit"
+	removeTombstonePrefix   = "/pd/api/v1/stores/remove-tombstone"
+	scheduleConfigPrefix    = "/pd/api/v1/config/schedule"
+	schedulerConfigPrefix   = "/pd/api/v1/scheduler-config"
 
 	contentJSON = "application/json"
 )
@@ -48,9 +54,25 @@ type Stores struct {
 
 // StoreInfo represents PD store info.
 type StoreInfo struct {
-	*metapb.Store `json:"store"`
+	*MetaStore `json:"store"`
 }
 
+// MetaStore is the store meta returned by PD, it carries a readable state
+// which distinguishes `Disconnected` and `Down` stores from `Up` ones.
+type MetaStore struct {
+	*metapb.Store
+	StateName string `json:"state_name"`
+}
+
+// Region health check kinds supported by PD.
+const (
+	RegionCheckMissPeer    = "miss-peer"
+	RegionCheckExtraPeer   = "extra-peer"
+	RegionCheckPendingPeer = "pending-peer"
+	RegionCheckDownPeer    = "down-peer"
+	RegionCheckOfflinePeer = "offline-peer"
+)
+
 // Client is a HTTP Client for PD.
 type Client struct {
 	c      *httputil.Client
@@ -73,6 +95,79 @@ func (p *Client) AddScheduler(schedulerName string) error {
 	return err
 }
 
+// AddSchedulerWithArgs adds the specified scheduler with its arguments to PD, eg. `store_id` of evict-leader-scheduler.
+func (p *Client) AddSchedulerWithArgs(schedulerName string, args map[string]interface{}) error {
+	input := map[string]interface{}{"name": schedulerName}
+	for k, v := range args {
+		input[k] = v
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	_, err = p.c.Post(p.pdAddr+schedulersPrefix, contentJSON, bytes.NewBuffer(data))
+	return err
+}
+
+// GetSchedulers lists the names of running schedulers.
+func (p *Client) GetSchedulers() ([]string, error) {
+	resp, err := p.c.Get(p.pdAddr + schedulersPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var schedulers []string
+	if err = json.Unmarshal(resp, &schedulers); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal `[]string` failed")
+	}
+	return schedulers, nil
+}
+
+// GetSchedulerConfig gets the config of the specified scheduler, eg. balance-hot-region-scheduler.
+func (p *Client) GetSchedulerConfig(schedulerName string) (map[string]interface{}, error) {
+	resp, err := p.c.Get(p.pdAddr + schedulerConfigPrefix + "/" + schedulerName + "/list")
+	if err != nil {
+		return nil, err
+	}
+	config := make(map[string]interface{})
+	if err = json.Unmarshal(resp, &config); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal scheduler config failed")
+	}
+	return config, nil
+}
+
+// SetSchedulerConfig updates the config of the specified scheduler.
+func (p *Client) SetSchedulerConfig(schedulerName string, config map[string]interface{}) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	_, err = p.c.Post(p.pdAddr+schedulerConfigPrefix+"/"+schedulerName+"/config", contentJSON, bytes.NewBuffer(data))
+	return err
+}
+
+// GetScheduleConfig gets the schedule config of PD, like leader-schedule-limit.
+func (p *Client) GetScheduleConfig() (map[string]interface{}, error) {
+	resp, err := p.c.Get(p.pdAddr + scheduleConfigPrefix)
+	if err != nil {
+		return nil, err
+	}
+	config := make(map[string]interface{})
+	if err = json.Unmarshal(resp, &config); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal schedule config failed")
+	}
+	return config, nil
+}
+
+// SetScheduleConfig updates the given items of PD's schedule config.
+func (p *Client) SetScheduleConfig(config map[string]interface{}) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	_, err = p.c.Post(p.pdAddr+scheduleConfigPrefix, contentJSON, bytes.NewBuffer(data))
+	return err
+}
+
 // RemoveScheduler removes the specified scheduler from PD.
 func (p *Client) RemoveScheduler(schedulerName string) error {
 	return p.c.Delete(p.pdAddr + schedulersPrefix + "/" + schedulerName)
@@ -108,6 +203,21 @@ func (p *Client) GetStores() (*Stores, error) {
 	return stores, nil
 }
 
+// GetRegionsByCheck lists the regions that fail the given health check, eg. RegionCheckDownPeer.
+func (p *Client) GetRegionsByCheck(check string) ([]*RegionInfo, error) {
+	resp, err := p.c.Get(p.pdAddr + regionsCheckPrefix + "/" + check)
+	if err != nil {
+		return nil, err
+	}
+	var body struct {
+		Regions []*RegionInfo `json:"regions"`
+	}
+	if err = json.Unmarshal(resp, &body); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal `[]RegionInfo` failed")
+	}
+	return body.Regions, nil
+}
+
 // GetRegionByKey gets the region info by region key.
 func (p *Client) GetRegionByKey(key string) (*RegionInfo, error) {
 	resp, err := p.c.Get(p.pdAddr + regionKeyPrefix + url.QueryEscape(key))
@@ -167,6 +277,19 @@ func (p *Client) GetMembers() (*MembersInfo, error) {
 	return members, nil
 }
 
+// GetMembersHealth gets the health status of every PD member
+func (p *Client) GetMembersHealth() ([]*MemberHealth, error) {
+	resp, err := p.c.Get(p.pdAddr + healthPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var healths []*MemberHealth
+	if err = json.Unmarshal(resp, &healths); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal `[]MemberHealth` failed")
+	}
+	return healths, nil
+}
+
 // TransferAllocator transfer tso allocator to the target pd member
 func (p *Client) TransferAllocator(name, dclocation string) error {
 	apiURL := fmt.Sprintf("%s%s/%s?dcLocation=%s", p.pdAddr, transferAllocatorPrefix, name, dclocation)
@@ -185,6 +308,57 @@ func (p *Client) SetStoreLabels(storeID uint64, labels map[string]string) error
 	return err
 }
 
+// DeleteStore sets the store offline, PD makes it tombstone after all its regions are moved out.
+func (p *Client) DeleteStore(storeID uint64) error {
+	return p.c.Delete(fmt.Sprintf("%s%s/%d", p.pdAddr, storePrefix, storeID))
+}
+
+// SetStoreState sets the state of the store, eg. an offline store can be set Up again.
+func (p *Client) SetStoreState(storeID uint64, state string) error {
+	_, err := p.c.Post(fmt.Sprintf("%s%s/%d/state?state=%s", p.pdAddr, storePrefix, storeID, state), contentJSON, nil)
+	return err
+}
+
+// RemoveTombstone removes all tombstone stores' records from PD.
+func (p *Client) RemoveTombstone() error {
+	return p.c.Delete(p.pdAddr + removeTombstonePrefix)
+}
+
+// GetStoresLimit gets the add-peer/remove-peer limits of all stores, indexed by the store id.
+func (p *Client) GetStoresLimit() (map[uint64]StoreLimit, error) {
+	resp, err := p.c.Get(p.pdAddr + storesLimitPrefix)
+	if err != nil {
+		return nil, err
+	}
+	limits := make(map[uint64]StoreLimit)
+	if err = json.Unmarshal(resp, &limits); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal `map[uint64]StoreLimit` failed")
+	}
+	return limits, nil
+}
+
+// SetStoreLimit sets the limit rate(operators per minute) of the given type, add-peer or remove-peer.
+func (p *Client) SetStoreLimit(storeID uint64, limitType string, rate float64) error {
+	data, err := json.Marshal(map[string]interface{}{"rate": rate, "type": limitType})
+	if err != nil {
+		return err
+	}
+	_, err = p.c.Post(fmt.Sprintf("%s%s/%d/limit", p.pdAddr, storePrefix, storeID), contentJSON, bytes.NewBuffer(data))
+	return err
+}
+
+// StoreLimit is the store limit info returned from PD
+type StoreLimit struct {
+	AddPeer    float64 `json:"add-peer"`
+	RemovePeer float64 `json:"remove-peer"`
+}
+
+// Store limit types
+const (
+	StoreLimitAddPeer    = "add-peer"
+	StoreLimitRemovePeer = "remove-peer"
+)
+
 // MembersInfo is PD members info returned from PD RESTful interface
 //type Members map[string][]*pdpb.Member
 type MembersInfo struct {
@@ -202,4 +376,12 @@ type Member struct {
 	MemberID uint64 `json:"member_id,omitempty"`
 	// dc_location is the dcLocation of the PD member
 	DcLocation string `json:"dc_location,omitempty"`
-}
\ No newline at end of file
+}
+
+// MemberHealth is the health status of a PD member
+type MemberHealth struct {
+	Name       string   `json:"name"`
+	MemberID   uint64   `json:"member_id"`
+	ClientUrls []string `json:"client_urls"`
+	Health     bool     `json:"health"`
+}
@@ -14,14 +14,28 @@
       '-read-lock=%s' % args.read_lock,
       '-txn-mode=%s' % args.txn_mode,
     ],
-  ledger(args={})::
+  ledger(args={ accounts: '1000000', interval: '2s' })::
     [
       '/bin/ledger',
+      '-accounts=%s' % args.accounts,
+      '-interval=%s' % args.interval,
     ],
   rawkv_linearizability(args={})::
     [
       '/bin/rawkv-linearizability',
     ],
+  'txnkv-register'(args={})::
+    [
+      '/bin/txnkv-register',
+    ],
+  'txnkv-append'(args={})::
+    [
+      '/bin/txnkv-append',
+    ],
+  'rawkv-cas'(args={})::
+    [
+      '/bin/rawkv-cas',
+    ],
   region_available(args={})::
     [
       '/bin/region-available',
@@ -30,10 +44,15 @@
     [
       '/bin/bank',
     ],
-  'bank2'(args={ concurrency: '200', accounts: '1000000', tidb_replica_read: 'leader-and-follower' })::
+  backup(args={ backup_uri: 'local:///tmp/backup', fake_s3_addr: '' })::
+    [
+      '/bin/backup',
+      '-backup-uri=%s' % args.backup_uri,
+      '-fake-s3-addr=%s' % args.fake_s3_addr,
+    ],
+  'bank2'(args={ accounts: '1000000', tidb_replica_read: 'leader-and-follower' })::
     [
       '/bin/bank2',
-      '-concurrency=%s' % args.concurrency,
       '-accounts=%s' % args.accounts,
       '-tidb-replica-read=%s' % args.tidb_replica_read,
     ],
@@ -67,13 +86,36 @@
     [
       '/bin/block-writer',
     ],
-  'stale-read'(args={})::
+  'stale-read'(args={ rows: '1000', staleness: '100', read_modes: 'as-of-timestamp,read-staleness,snapshot', replica_reads: 'leader,follower,leader-and-follower' })::
     [
       '/bin/stale-read',
+      '-rows=%s' % args.rows,
+      '-staleness=%s' % args.staleness,
+      '-read-modes=%s' % args.read_modes,
+      '-replica-reads=%s' % args.replica_reads,
     ],
   'ttl'(args={})::
     [
       '/bin/tll',
     ],
+  'causal-reverse'(args={ tablecount: '7', async_commit: 'true', one_pc: 'true', guarantee_linearizability: 'true' })::
+    [
+      '/bin/causal-reverse',
+      '-table-count=%s' % args.tablecount,
+      '-async-commit=%s' % args.async_commit,
+      '-one-pc=%s' % args.one_pc,
+      '-guarantee-linearizability=%s' % args.guarantee_linearizability,
+    ],
+  'unique-id'(args={ kinds: 'auto-increment,auto-id-cache-1,auto-random,sequence' })::
+    [
+      '/bin/unique-id',
+      '-kinds=%s' % args.kinds,
+    ],
+  workload(args={ workload: 'bank', checkers: '' })::
+    [
+      '/bin/workload',
+      '-workload=%s' % args.workload,
+      '-checkers=%s' % args.checkers,
+    ],
   // +tipocket:scaffold:case_decls
 }
\ No newline at end of file
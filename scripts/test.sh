@@ -7,17 +7,6 @@ for bin in $@; do
         cases=( bank multi_bank )
         nemeses=( random_kill random_drop )
         ;;
-    'rawkv' )
-        suit=chaos-rawkv
-        cases=( register )
-        # TODO: add random_drop, chaos can not heal drop nemesis sometime.
-        nemeses=( random_kill  )
-        ;;
-    'txnkv' )
-        suit=chaos-txnkv
-        cases=( register )
-        nemeses=( random_kill  )
-        ;;
     '--help' )
         HELP=1
         ;;
@@ -32,8 +21,6 @@ done
 if [ "$HELP" ]; then
     echo "usage: $0 [OPTION]"
     echo "  tidb                                           Chaos test TiDB"
-    echo "  rawkv                                          Chaos test RawKV"
-    echo "  txnkv                                          Chaos test TxnKV"
     echo "  --help                                         Display this message"
     exit 0
 fi
@@ -0,0 +1,204 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bank2
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/ngaut/log"
+
+	"github.com/pingcap/tipocket/pkg/check/checkutil"
+	"github.com/pingcap/tipocket/pkg/core"
+	"github.com/pingcap/tipocket/pkg/history"
+)
+
+type parser struct{}
+
+// Parser parses a history of bank2 test
+func Parser() history.RecordParser {
+	return parser{}
+}
+
+// OnRequest implements history.RecordParser
+func (parser) OnRequest(data json.RawMessage) (interface{}, error) {
+	r := request{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+// OnResponse implements history.RecordParser
+func (parser) OnResponse(data json.RawMessage) (interface{}, error) {
+	r := response{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+// OnNoopResponse implements history.RecordParser
+func (parser) OnNoopResponse() interface{} {
+	return response{Unknown: true}
+}
+
+// OnState implements history.RecordParser
+func (parser) OnState(state json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+type transfer struct {
+	request
+	response
+}
+
+// committed returns whether the transfer has written the accounts
+func (t *transfer) committed() bool {
+	return t.Ok && !t.Skipped
+}
+
+type read struct {
+	request
+	response
+}
+
+type checker struct{}
+
+// Checker checks the total balance is conserved in every verify read, and no balance is negative and
+// no transaction is posted twice or based on a stale balance over the transfers
+func Checker() core.Checker {
+	return checker{}
+}
+
+// Name implements core.Checker
+func (checker) Name() string {
+	return "bank2_checker"
+}
+
+// Check implements core.Checker
+func (checker) Check(_ core.Model, ops []core.Operation) (bool, error) {
+	transfers, reads, err := parseOps(ops)
+	if err != nil {
+		return false, err
+	}
+	violations := checkReads(reads, transfers)
+	violations = append(violations, checkTransfers(transfers)...)
+
+	log.Infof("[bank2] checked %d verify reads and %d transfers", len(reads), len(transfers))
+	return checkutil.Report("bank2", violations)
+}
+
+// checkReads checks the invariants in every snapshot, and the transactions between two snapshots
+// are no less than the transfers committed between them and no more than the ones which may be committed
+func checkReads(reads []read, transfers []*transfer) []string {
+	var (
+		violations []string
+		unknown    int64
+		commits    []uint64
+	)
+	for _, t := range transfers {
+		if t.committed() {
+			commits = append(commits, t.CommitTS)
+		} else if t.Unknown {
+			unknown++
+		}
+	}
+	window := checkutil.NewCommitWindow(commits, unknown)
+
+	sort.Slice(reads, func(i, j int) bool { return reads[i].ReadTS < reads[j].ReadTS })
+	for i, r := range reads {
+		if r.Total != r.Expected || r.TableScanTotal != r.Expected {
+			violations = append(violations, fmt.Sprintf("total balance at read_ts %d should be %d, but got %d by index and %d by table scan",
+				r.ReadTS, r.Expected, r.Total, r.TableScanTotal))
+		}
+		if r.TiFlashTotal != nil && *r.TiFlashTotal != r.Expected {
+			violations = append(violations, fmt.Sprintf("total balance at read_ts %d should be %d, but tiflash got %d", r.ReadTS, r.Expected, *r.TiFlashTotal))
+		}
+		if len(r.NegativeAccounts) > 0 {
+			violations = append(violations, fmt.Sprintf("accounts %v at read_ts %d have negative balances", r.NegativeAccounts, r.ReadTS))
+		}
+		if len(r.BadTxns) > 0 || r.Legs != 2*r.Txns {
+			violations = append(violations, fmt.Sprintf("%d transactions have %d legs at read_ts %d, the legs of transactions %v aren't a pair summing to 0",
+				r.Txns, r.Legs, r.ReadTS, r.BadTxns))
+		}
+		if r.AdminCheck != "" {
+			violations = append(violations, fmt.Sprintf("ADMIN CHECK TABLE bank2_accounts at read_ts %d fails: %s", r.ReadTS, r.AdminCheck))
+		}
+		if i == 0 {
+			continue
+		}
+		prev := reads[i-1]
+		if v := window.Check("transactions", r.Txns-prev.Txns, prev.ReadTS, r.ReadTS); v != "" {
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
+
+// checkTransfers checks the balances locked by the transfers are never negative, and each committed transfer
+// is based on the balance written by the previous one of the account, unless the account is written by
+// a transfer with an unknown outcome
+func checkTransfers(transfers []*transfer) []string {
+	type write struct {
+		t       *transfer
+		read    int
+		balance int
+	}
+	var violations []string
+	writes := make(map[int][]write)
+	uncertain := make(map[int]struct{})
+	for _, t := range transfers {
+		if t.Ok && t.Accounts != 2 {
+			violations = append(violations, fmt.Sprintf("%s locked %d accounts", t.request, t.Accounts))
+		}
+		if t.Ok && (t.FromBalance < 0 || t.ToBalance < 0) {
+			violations = append(violations, fmt.Sprintf("%s read negative balances %d and %d", t.request, t.FromBalance, t.ToBalance))
+		}
+		switch {
+		case t.committed():
+			writes[t.From] = append(writes[t.From], write{t: t, read: t.FromBalance, balance: t.FromBalance - t.Amount})
+			writes[t.To] = append(writes[t.To], write{t: t, read: t.ToBalance, balance: t.ToBalance + t.Amount})
+		case t.Unknown:
+			uncertain[t.From], uncertain[t.To] = struct{}{}, struct{}{}
+		}
+	}
+	for account, ws := range writes {
+		if _, ok := uncertain[account]; ok {
+			continue
+		}
+		sort.Slice(ws, func(i, j int) bool { return ws[i].t.CommitTS < ws[j].t.CommitTS })
+		for i := 1; i < len(ws); i++ {
+			if ws[i].read != ws[i-1].balance {
+				violations = append(violations, fmt.Sprintf("%s read balance %d of account %d, but %s has written %d",
+					ws[i].t.request, ws[i].read, account, ws[i-1].t.request, ws[i-1].balance))
+			}
+		}
+	}
+	return violations
+}
+
+// parseOps pairs the requests and responses of each process, it returns all the transfers and the ok reads
+func parseOps(ops []core.Operation) ([]*transfer, []read, error) {
+	var (
+		transfers []*transfer
+		reads     []read
+	)
+	err := checkutil.PairOps(ops, func(invoke, ret core.Operation) {
+		rq, rs := invoke.Data.(request), ret.Data.(response)
+		if rq.Kind == opTransfer {
+			transfers = append(transfers, &transfer{request: rq, response: rs})
+		} else if rs.Ok {
+			reads = append(reads, read{request: rq, response: rs})
+		}
+	})
+	return transfers, reads, err
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bank2
+
+import (
+	"testing"
+
+	"github.com/pingcap/tipocket/pkg/core"
+)
+
+func TestChecker(t *testing.T) {
+	history := func(secondRead int, last response) []core.Operation {
+		return []core.Operation{
+			{Action: core.InvokeOperation, Proc: 1, Data: request{Kind: opRead}},
+			{Action: core.ReturnOperation, Proc: 1, Data: response{Ok: true, ReadTS: 5, Expected: 100, Total: 100, TableScanTotal: 100}},
+			{Action: core.InvokeOperation, Proc: 2, Data: request{Kind: opTransfer, TxnID: 1, From: 1, To: 2, Amount: 10}},
+			{Action: core.ReturnOperation, Proc: 2, Data: response{Ok: true, Accounts: 2, FromBalance: 50, ToBalance: 50, CommitTS: 10}},
+			{Action: core.InvokeOperation, Proc: 3, Data: request{Kind: opTransfer, TxnID: 2, From: 2, To: 3, Amount: 5}},
+			{Action: core.ReturnOperation, Proc: 3, Data: response{Ok: true, Accounts: 2, FromBalance: secondRead, ToBalance: 0, CommitTS: 20}},
+			{Action: core.InvokeOperation, Proc: 4, Data: request{Kind: opTransfer, TxnID: 3, From: 3, To: 4, Amount: 80}},
+			{Action: core.ReturnOperation, Proc: 4, Data: response{Ok: true, Accounts: 2, FromBalance: 5, ToBalance: 0, Skipped: true}},
+			{Action: core.InvokeOperation, Proc: 5, Data: request{Kind: opRead}},
+			{Action: core.ReturnOperation, Proc: 5, Data: last},
+		}
+	}
+	read := func(total, txns, legs int64, negative ...int64) response {
+		return response{Ok: true, ReadTS: 25, Expected: 100, Total: total, TableScanTotal: total, Txns: txns, Legs: legs, NegativeAccounts: negative}
+	}
+	for _, c := range []struct {
+		secondRead  int
+		last        response
+		ok          bool
+		description string
+	}{
+		{60, read(100, 2, 4), true, "ok"},
+		{60, read(99, 2, 4), false, "the total isn't conserved"},
+		{60, read(100, 2, 4, 3), false, "a negative balance is read"},
+		{60, read(100, 2, 3), false, "a leg is missing"},
+		{60, read(100, 1, 2), false, "a committed transfer is missing"},
+		{60, read(100, 3, 6), false, "a transfer is posted twice"},
+		{50, read(100, 2, 4), false, "the update of the first transfer is lost"},
+	} {
+		ok, err := Checker().Check(nil, history(c.secondRead, c.last))
+		if ok != c.ok {
+			t.Fatalf("%s: expect %t, got %t, %v", c.description, c.ok, ok, err)
+		}
+	}
+}
@@ -4,6 +4,7 @@ package bank2
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"strings"
@@ -13,7 +14,6 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
-	"github.com/rogpeppe/fastuuid"
 
 	"github.com/pingcap/tipocket/pkg/cluster"
 	"github.com/pingcap/tipocket/pkg/core"
@@ -30,6 +30,11 @@ const (
 	insertBatchSize   = 100
 	maxTransfer       = 100
 	systemAccountID   = 0
+	// maxReportedRows limits the bad accounts and transactions returned by a verify read
+	maxReportedRows = 10
+
+	opTransfer = "transfer"
+	opRead     = "read"
 )
 
 var (
@@ -74,8 +79,7 @@ var (
 
 	// PadLength returns a random padding length for `remark` field within user
 	// specified bound.
-	baseLen      = [3]int{36, 48, 16}
-	tidbDatabase = true
+	baseLen = [3]int{36, 48, 16}
 )
 
 // Config ...
@@ -83,7 +87,6 @@ type Config struct {
 	// NumAccounts is total accounts
 	NumAccounts         int           `toml:"num_accounts"`
 	Interval            time.Duration `toml:"interval"`
-	Concurrency         int           `toml:"concurrency"`
 	RetryLimit          int           `toml:"retry_limit"`
 	EnableLongTxn       bool          `toml:"enable_long_txn"`
 	Contention          string        `toml:"contention"`
@@ -99,14 +102,66 @@ type Config struct {
 // ClientCreator ...
 type ClientCreator struct {
 	Cfg *Config
+
+	txnID int32
+	// lastRead is the unix nano time of the last verify read, which is shared by all the clients
+	lastRead int64
 }
 
 type bank2Client struct {
 	*Config
-	wg    sync.WaitGroup
-	stop  int32
-	txnID int32
-	db    *sql.DB
+	creator *ClientCreator
+	r       *rand.Rand
+	db      *sql.DB
+	conn    *sql.Conn
+}
+
+// request is a transfer, or a verify read which reads the accounts and the transactions in a snapshot
+type request struct {
+	Kind   string `json:"kind"`
+	TxnID  int32  `json:"txn_id,omitempty"`
+	From   int    `json:"from,omitempty"`
+	To     int    `json:"to,omitempty"`
+	Amount int    `json:"amount,omitempty"`
+}
+
+func (r request) String() string {
+	if r.Kind == opTransfer {
+		return fmt.Sprintf("txn %d transfers %d from %d to %d", r.TxnID, r.Amount, r.From, r.To)
+	}
+	return "verify read"
+}
+
+type response struct {
+	Ok      bool   `json:"ok"`
+	Unknown bool   `json:"unknown"`
+	Error   string `json:"error,omitempty"`
+	// Accounts is the number of the accounts locked by the transfer, FromBalance and ToBalance are their balances,
+	// Skipped means nothing is written since the balance isn't enough
+	Accounts    int    `json:"accounts,omitempty"`
+	FromBalance int    `json:"from_balance"`
+	ToBalance   int    `json:"to_balance"`
+	Skipped     bool   `json:"skipped,omitempty"`
+	CommitTS    uint64 `json:"commit_ts,omitempty"`
+	// ReadTS is the snapshot of a verify read, Expected is the total which all the reads must see
+	ReadTS         uint64 `json:"read_ts,omitempty"`
+	Expected       int64  `json:"expected,omitempty"`
+	Total          int64  `json:"total"`
+	TableScanTotal int64  `json:"table_scan_total"`
+	TiFlashTotal   *int64 `json:"tiflash_total,omitempty"`
+	// Txns and Legs are the numbers of the transactions and their legs
+	Txns int64 `json:"txns"`
+	Legs int64 `json:"legs"`
+	// NegativeAccounts and BadTxns are the accounts with negative balances and the transactions
+	// whose legs aren't a pair summing to 0
+	NegativeAccounts []int64 `json:"negative_accounts,omitempty"`
+	BadTxns          []int64 `json:"bad_txns,omitempty"`
+	AdminCheck       string  `json:"admin_check,omitempty"`
+}
+
+// IsUnknown implements core.UnknownResponse
+func (r response) IsUnknown() bool {
+	return r.Unknown
 }
 
 func (c *bank2Client) padLength(table int) int {
@@ -124,39 +179,39 @@ func (c *bank2Client) padLength(table int) int {
 }
 
 func (c *bank2Client) SetUp(ctx context.Context, _ []cluster.Node, clientNodes []cluster.ClientNode, idx int) error {
-	if idx != 0 {
-		return nil
-	}
-
 	var err error
 	node := clientNodes[idx]
 	dsn := fmt.Sprintf("root@tcp(%s:%d)/%s", node.IP, node.Port, c.DbName)
 	if len(c.ConnParams) > 0 {
 		dsn = dsn + "?" + c.ConnParams
 	}
+	if c.db, err = util.OpenDB(dsn, 1); err != nil {
+		return err
+	}
+	util.RandomlyChangeReplicaRead(c.String(), c.ReplicaRead, c.db)
+	// @@tidb_last_txn_info must be read in the connection of the transfer
+	if err = c.reconnect(ctx); err != nil {
+		return err
+	}
+	if idx != 0 {
+		return nil
+	}
 
 	log.Infof("start to init...")
-	db, err := util.OpenDB(dsn, 1)
+	db, err := util.OpenDB(dsn, insertConcurrency)
 	if err != nil {
 		log.Fatalf("[bank2Client] create db client error %v", err)
 	}
-	util.RandomlyChangeReplicaRead(c.String(), c.ReplicaRead, db)
+	defer db.Close()
 
 	_, err = db.Exec("set @@global.tidb_txn_mode = 'pessimistic';")
 	if err != nil {
 		log.Fatalf("[bank2Client] set txn_mode failed: %v", err)
 	}
 	time.Sleep(5 * time.Second)
-	c.db, err = util.OpenDB(dsn, c.Concurrency)
-	util.RandomlyChangeReplicaRead(c.String(), c.ReplicaRead, c.db)
-
-	if err != nil {
-		return err
-	}
 	defer func() {
 		log.Infof("init end...")
 	}()
-
 	for _, stmt := range stmtsCreate {
 		if _, err := db.Exec(stmt); err != nil {
 			log.Fatalf("execute statement %s error %v", stmt, err)
@@ -243,151 +298,154 @@ func (c *bank2Client) SetUp(ctx context.Context, _ []cluster.Node, clientNodes [
 		log.Fatalf("[%s] insert system account err: %v", c, err)
 	}
 
-	c.startVerify(ctx, db)
 	return nil
 }
 
 func (c *bank2Client) TearDown(ctx context.Context, nodes []cluster.ClientNode, idx int) error {
-	return nil
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	return c.db.Close()
 }
 
-func (c *bank2Client) Start(ctx context.Context, cfg interface{}, clientNodes []cluster.ClientNode) error {
-	log.Infof("[%s] start to test...", c)
-	defer func() {
-		log.Infof("[%s] test end...", c)
-	}()
-	var wg sync.WaitGroup
-	for i := 0; i < c.Config.Concurrency; i++ {
-		wg.Add(1)
-		go func(i int) {
-			defer wg.Done()
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-				}
-				if atomic.LoadInt32(&c.stop) != 0 {
-					log.Errorf("[%s] stopped", c)
-					return
-				}
-				c.wg.Add(1)
-				c.moveMoney(c.db)
-				c.wg.Done()
+// NextRequest implements the core.OnScheduleClientExtensions interface.
+// A verify read is issued by one of the clients every interval, the others are transfers.
+func (c *bank2Client) NextRequest() interface{} {
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&c.creator.lastRead)
+	if now-last >= int64(c.Interval) && atomic.CompareAndSwapInt64(&c.creator.lastRead, last, now) {
+		return request{Kind: opRead}
+	}
+	for {
+		from, to := c.r.Intn(c.Config.NumAccounts), c.r.Intn(c.Config.NumAccounts)
+		if c.Config.Contention == "high" {
+			// Use the first account number we generated as a coin flip to
+			// determine whether we're transferring money into or out of
+			// the system account.
+			if from > c.Config.NumAccounts/2 {
+				from = systemAccountID
+			} else {
+				to = systemAccountID
 			}
-		}(i)
+		}
+		if from == to {
+			continue
+		}
+		return request{
+			Kind:   opTransfer,
+			TxnID:  atomic.AddInt32(&c.creator.txnID, 1),
+			From:   from,
+			To:     to,
+			Amount: c.r.Intn(maxTransfer),
+		}
 	}
-	wg.Wait()
-	return nil
 }
 
-func (c *bank2Client) startVerify(ctx context.Context, db *sql.DB) {
-	c.verify(ctx, db)
-
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-time.After(c.Config.Interval):
-				c.verify(ctx, db)
-			}
+// Invoke implements the core.OnScheduleClientExtensions interface.
+func (c *bank2Client) Invoke(ctx context.Context, node cluster.ClientNode, r interface{}) core.UnknownResponse {
+	req := r.(request)
+	if c.conn == nil {
+		if err := c.reconnect(ctx); err != nil {
+			return response{Error: err.Error()}
 		}
-	}()
+	}
+	var res response
+	if req.Kind == opTransfer {
+		res = c.execTransaction(ctx, req)
+	} else {
+		res = c.verify(ctx)
+	}
+	if !res.Ok && isBadConn(res.Error) {
+		// the connection is broken, a new one is opened by the next request
+		c.conn.Close()
+		c.conn = nil
+	}
+	return res
+}
+
+// reconnect opens a new connection, @@tidb_last_txn_info is kept in it
+func (c *bank2Client) reconnect(ctx context.Context) (err error) {
+	c.conn, err = c.db.Conn(ctx)
+	return err
+}
+
+func isBadConn(err string) bool {
+	return strings.Contains(err, "bad connection") || strings.Contains(err, "invalid connection") ||
+		strings.Contains(err, "connection is already closed")
+}
+
+// DumpState implements the core.OnScheduleClientExtensions interface.
+func (c *bank2Client) DumpState(ctx context.Context) (interface{}, error) {
+	return nil, nil
 }
 
-func (c *bank2Client) verify(ctx context.Context, db *sql.DB) {
-	tx, err := db.Begin()
+// verify reads the total balance, the negative balances and the transactions in one snapshot
+func (c *bank2Client) verify(ctx context.Context) response {
+	tx, err := c.conn.BeginTx(ctx, nil)
 	if err != nil {
-		_ = errors.Trace(err)
-		return
+		return response{Error: err.Error()}
 	}
 	defer func() {
 		_ = tx.Rollback()
 	}()
 
-	if tidbDatabase {
-		var tso uint64
-		if err = tx.QueryRow("SELECT @@tidb_current_ts").Scan(&tso); err == nil {
-			log.Infof("[%s] SELECT SUM(balance) to verify use tso %d", c, tso)
-		}
+	res := response{Expected: (int64(c.Config.NumAccounts) * initialBalance) * 2}
+	if err = tx.QueryRowContext(ctx, "SELECT @@tidb_current_ts").Scan(&res.ReadTS); err != nil {
+		return response{Error: err.Error()}
 	}
 
-	var total int64
-	expectTotal := (int64(c.Config.NumAccounts) * initialBalance) * 2
-
 	// read from tikv
-	_, err = tx.Exec("set @@session.tidb_isolation_read_engines='tikv'")
-	if err != nil {
-		log.Warn(err)
-		return
-	}
-
-	// query with IndexScan
-	uuid := fastuuid.MustNewGenerator().Hex128()
-	query := fmt.Sprintf("SELECT SUM(balance) AS total, '%s' as uuid FROM bank2_accounts", uuid)
-	if err = tx.QueryRow(query).Scan(&total, &uuid); err != nil {
-		_ = errors.Trace(err)
-		return
-	}
-	if total != expectTotal {
-		log.Errorf("[%s] bank2_accounts total should be %d, but got %d, query uuid is %s", c, expectTotal, total, uuid)
-		atomic.StoreInt32(&c.stop, 1)
-		c.wg.Wait()
-		log.Fatalf("[%s] bank2_accounts total should be %d, but got %d, query uuid is %s", c, expectTotal, total, uuid)
+	if _, err = tx.ExecContext(ctx, "set @@session.tidb_isolation_read_engines='tikv'"); err != nil {
+		return response{Error: err.Error()}
+	}
+	for _, q := range []struct {
+		query string
+		dest  *int64
+	}{
+		// query with IndexScan
+		{"SELECT SUM(balance) AS total FROM bank2_accounts", &res.Total},
+		// query with TableScan
+		{"SELECT SUM(balance) AS total FROM bank2_accounts ignore index(byBalance)", &res.TableScanTotal},
+		{"SELECT COUNT(*) FROM bank2_transaction", &res.Txns},
+		{"SELECT COUNT(*) FROM bank2_transaction_leg", &res.Legs},
+	} {
+		if err = tx.QueryRowContext(ctx, q.query).Scan(q.dest); err != nil {
+			return response{Error: err.Error()}
+		}
 	}
-
-	// query with TableScan
-	uuid = fastuuid.MustNewGenerator().Hex128()
-	query = fmt.Sprintf("SELECT SUM(balance) AS total, '%s' as uuid FROM bank2_accounts ignore index(byBalance)", uuid)
-	if err = tx.QueryRow(query).Scan(&total, &uuid); err != nil {
-		_ = errors.Trace(err)
-		return
+	if res.NegativeAccounts, err = queryInt64s(ctx, tx, "SELECT id FROM bank2_accounts WHERE balance < 0 LIMIT ?", maxReportedRows); err != nil {
+		return response{Error: err.Error()}
 	}
-	if total != expectTotal {
-		log.Errorf("[%s] bank2_accounts total should be %d, but got %d, query uuid is %s", c, expectTotal, total, uuid)
-		atomic.StoreInt32(&c.stop, 1)
-		c.wg.Wait()
-		log.Fatalf("[%s] bank2_accounts total should be %d, but got %d, query uuid is %s", c, expectTotal, total, uuid)
+	if res.BadTxns, err = queryInt64s(ctx, tx, `SELECT txn_id FROM bank2_transaction_leg
+		GROUP BY txn_id HAVING COUNT(*) != 2 OR SUM(amount) != 0 LIMIT ?`, maxReportedRows); err != nil {
+		return response{Error: err.Error()}
 	}
 
 	// query with tiflash
 	if c.TiFlashDataReplicas > 0 {
-		_, err = tx.Exec("set @@session.tidb_isolation_read_engines='tiflash'")
-		if err != nil {
-			log.Warn(err)
-			return
+		if _, err = tx.ExecContext(ctx, "set @@session.tidb_isolation_read_engines='tiflash'"); err != nil {
+			return response{Error: err.Error()}
 		}
 
+		var total int64
 		readTiFlash := func() error {
-			uuid := fastuuid.MustNewGenerator().Hex128()
-			query := fmt.Sprintf("SELECT SUM(balance) AS total, '%s' as uuid FROM bank2_accounts", uuid)
-			if err = tx.QueryRow(query).Scan(&total, &uuid); err != nil {
+			if err := tx.QueryRowContext(ctx, "SELECT SUM(balance) AS total FROM bank2_accounts").Scan(&total); err != nil {
 				log.Errorf("[%s] select sum error %v", c, err)
 				return errors.Trace(err)
 			}
 			return nil
 		}
-		err = util.RunWithRetry(ctx, c.RetryLimit, time.Second, readTiFlash)
-		if err != nil {
-			_ = err
-			return
-		}
-		if total != expectTotal {
-			log.Errorf("[%s] bank2_accounts total should be %d, but tiflash got %d, query uuid is %s", c, expectTotal, total, uuid)
-			atomic.StoreInt32(&c.stop, 1)
-			c.wg.Wait()
-			log.Fatalf("[%s] bank2_accounts total should be %d, but tiflash got %d, query uuid is %s", c, expectTotal, total, uuid)
+		if err = util.RunWithRetry(ctx, c.RetryLimit, time.Second, readTiFlash); err != nil {
+			return response{Error: err.Error()}
 		}
+		res.TiFlashTotal = &total
 
-		_, err = tx.Exec("set @@session.tidb_isolation_read_engines='tikv'")
-		if err != nil {
-			log.Warn(err)
-			return
+		if _, err = tx.ExecContext(ctx, "set @@session.tidb_isolation_read_engines='tikv'"); err != nil {
+			return response{Error: err.Error()}
 		}
 	}
 
-	if _, err := tx.Exec("ADMIN CHECK TABLE bank2_accounts"); err != nil {
+	if _, err := tx.ExecContext(ctx, "ADMIN CHECK TABLE bank2_accounts"); err != nil {
 		log.Errorf("[%s] ADMIN CHECK TABLE bank2_accounts fails: %v", c, err)
 		errStr := err.Error()
 		if strings.Contains(errStr, "1105") &&
@@ -403,125 +461,116 @@ func (c *bank2Client) verify(ctx context.Context, db *sql.DB) {
 				strings.Contains(errStr, "raft proposal dropped") ||
 				strings.Contains(errStr, "no available connections") ||
 				(errStr == "Error 1105: ")) {
-			atomic.StoreInt32(&c.stop, 1)
-			c.wg.Wait()
-			log.Fatalf("[%s] ADMIN CHECK TABLE bank2_accounts fails: %v", c, err)
+			res.AdminCheck = errStr
 		}
 	}
+	res.Ok = true
+	log.Infof("[%s] verify read at %d", c, res.ReadTS)
+	return res
 }
 
-func (c *bank2Client) moveMoney(db *sql.DB) {
-	from, to := rand.Intn(c.Config.NumAccounts), rand.Intn(c.Config.NumAccounts)
-	if c.Config.Contention == "high" {
-		// Use the first account number we generated as a coin flip to
-		// determine whether we're transferring money into or out of
-		// the system account.
-		if from > c.Config.NumAccounts/2 {
-			from = systemAccountID
-		} else {
-			to = systemAccountID
-		}
-	}
-	if from == to {
-		return
-	}
-	amount := rand.Intn(maxTransfer)
-	// start := time.Now()
-	if err := c.execTransaction(db, from, to, amount); err != nil {
-		// bank2VerifyFailedCounter.Inc()
-		log.Errorf("[%s] move money err %v", c, err)
-		return
-	}
-	// bank2VerifyDuration.Observe(time.Since(start).Seconds())
-}
-
-func (c *bank2Client) execTransaction(db *sql.DB, from, to int, amount int) error {
-	tx, err := db.Begin()
+func (c *bank2Client) execTransaction(ctx context.Context, req request) response {
+	tx, err := c.conn.BeginTx(ctx, nil)
 	if err != nil {
-		return errors.Trace(err)
+		return response{Error: err.Error()}
 	}
 	defer func() {
 		_ = tx.Rollback()
 	}()
 
-	rows, err := tx.Query(fmt.Sprintf("SELECT id, balance FROM bank2_accounts WHERE id IN (%d, %d) FOR UPDATE", from, to))
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT id, balance FROM bank2_accounts WHERE id IN (%d, %d) FOR UPDATE", req.From, req.To))
 	if err != nil {
-		return errors.Trace(err)
+		return response{Error: err.Error()}
 	}
 	defer rows.Close()
 
-	var (
-		fromBalance int
-		toBalance   int
-		count       int
-	)
-
+	var res response
 	for rows.Next() {
 		var id, balance int
 		if err = rows.Scan(&id, &balance); err != nil {
-			return errors.Trace(err)
+			return response{Error: err.Error()}
 		}
 		switch id {
-		case from:
-			fromBalance = balance
-		case to:
-			toBalance = balance
+		case req.From:
+			res.FromBalance = balance
+		case req.To:
+			res.ToBalance = balance
 		default:
 			log.Fatalf("[%s] got unexpected account %d", c, id)
 		}
-		count++
+		res.Accounts++
 	}
 
 	if err = rows.Err(); err != nil {
-		return errors.Trace(err)
-	}
-
-	if count != 2 {
-		log.Fatalf("[%s] select %d(%d) -> %d(%d) invalid count %d", c, from, fromBalance, to, toBalance, count)
+		return response{Error: err.Error()}
 	}
 
-	if fromBalance < amount {
-		return nil
+	if res.Accounts != 2 || res.FromBalance < req.Amount {
+		res.Ok, res.Skipped = true, true
+		return res
 	}
 
 	insertTxn := `INSERT INTO bank2_transaction (id, txn_ref, remark) VALUES (?, ?, ?)`
 	insertTxnLeg := `INSERT INTO bank2_transaction_leg (account_id, amount, running_balance, txn_id, remark) VALUES (?, ?, ?, ?, ?)`
 	updateAcct := `UPDATE bank2_accounts SET balance = ? WHERE id = ?`
-	txnID := atomic.AddInt32(&c.txnID, 1)
-	if _, err := tx.Exec(insertTxn, txnID, fmt.Sprintf("txn %d", txnID), remark[:c.padLength(1)]); err != nil {
-		_ = tx.Rollback()
-		return errors.Trace(err)
+	for _, stmt := range []struct {
+		query string
+		args  []interface{}
+	}{
+		{insertTxn, []interface{}{req.TxnID, fmt.Sprintf("txn %d", req.TxnID), remark[:c.padLength(1)]}},
+		{insertTxnLeg, []interface{}{req.From, -req.Amount, res.FromBalance - req.Amount, req.TxnID, remark[:c.padLength(2)]}},
+		{insertTxnLeg, []interface{}{req.To, req.Amount, res.ToBalance + req.Amount, req.TxnID, remark[:c.padLength(2)]}},
+		{updateAcct, []interface{}{res.ToBalance + req.Amount, req.To}},
+		{updateAcct, []interface{}{res.FromBalance - req.Amount, req.From}},
+	} {
+		if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+			return response{Error: err.Error()}
+		}
 	}
-	if _, err = tx.Exec(insertTxnLeg, from, -amount, fromBalance-amount, txnID, remark[:c.padLength(2)]); err != nil {
-		_ = tx.Rollback()
-		return errors.Trace(err)
+
+	if err = tx.Commit(); err != nil {
+		res.Unknown, res.Error = true, err.Error()
+		return res
 	}
-	if _, err = tx.Exec(insertTxnLeg, to, amount, toBalance+amount, txnID, remark[:c.padLength(2)]); err != nil {
-		_ = tx.Rollback()
-		return errors.Trace(err)
+	var info string
+	if err := c.conn.QueryRowContext(ctx, "SELECT @@tidb_last_txn_info").Scan(&info); err != nil {
+		res.Unknown, res.Error = true, err.Error()
+		return res
 	}
-	if _, err = tx.Exec(updateAcct, toBalance+amount, to); err != nil {
-		_ = tx.Rollback()
-		return errors.Trace(err)
+	var txnInfo struct {
+		CommitTS uint64 `json:"commit_ts"`
 	}
-	if _, err = tx.Exec(updateAcct, fromBalance-amount, from); err != nil {
-		_ = tx.Rollback()
-		return errors.Trace(err)
+	if err := json.Unmarshal([]byte(info), &txnInfo); err != nil || txnInfo.CommitTS == 0 {
+		res.Unknown, res.Error = true, fmt.Sprintf("unexpected last txn info %s, err: %v", info, err)
+		return res
 	}
+	res.Ok, res.CommitTS = true, txnInfo.CommitTS
+	return res
+}
 
-	err = tx.Commit()
+func queryInt64s(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
-		log.Infof("[%s] %d(%d) failed to transfer %d to %d(%d), err: %v", c, from, fromBalance, amount, to, toBalance, err)
-	} else {
-		log.Infof("[%s] %d(%d) transfer %d to %d(%d)", c, from, fromBalance, amount, to, toBalance)
+		return nil, err
 	}
-	return nil
+	defer rows.Close()
+	var values []int64
+	for rows.Next() {
+		var v int64
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
 }
 
 // Create ...
-func (c ClientCreator) Create(_ cluster.ClientNode) core.Client {
+func (c *ClientCreator) Create(_ cluster.ClientNode) core.Client {
 	return &bank2Client{
-		Config: c.Cfg,
+		Config:  c.Cfg,
+		creator: c,
+		r:       rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 }
 
@@ -21,6 +21,7 @@ import (
 
 	// use mysql
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/ngaut/log"
 
 	logs "github.com/pingcap/tipocket/logsearch/pkg/logs"
 	test_infra "github.com/pingcap/tipocket/pkg/test-infra"
@@ -36,7 +37,6 @@ import (
 var (
 	accounts            = flag.Int("accounts", 1000000, "the number of accounts")
 	interval            = flag.Duration("interval", 2*time.Second, "the interval")
-	concurrency         = flag.Int("concurrency", 200, "concurrency worker count")
 	retryLimit          = flag.Int("retry-limit", 200, "retry count")
 	longTxn             = flag.Bool("long-txn", true, "enable long-term transactions")
 	contention          = flag.String("contention", "low", "contention level, support values: high / low, default value: low")
@@ -47,26 +47,32 @@ var (
 	dbname              = flag.String("dbname", "test", "name of database to test")
 	tiflashDataReplicas = flag.Int("tiflash-data-replicas", 0, "the number of the tiflash data replica")
 	connParams          = flag.String("conn_params", "", "connection parameters")
+	concurrency         = flag.Int("concurrency", 0, "deprecated, use -client instead")
 )
 
 func main() {
 	flag.Parse()
+	if *concurrency > 0 {
+		log.Warnf("-concurrency is deprecated, use -client instead")
+		fixture.Context.ClientCount = *concurrency
+	}
 
 	cfg := control.Config{
-		Mode:        control.ModeStandard,
-		ClientCount: 1,
-		RunTime:     fixture.Context.RunTime,
-		RunRound:    1,
+		Mode:         control.ModeOnSchedule,
+		ClientCount:  fixture.Context.ClientCount,
+		RequestCount: fixture.Context.RequestCount,
+		RunTime:      fixture.Context.RunTime,
+		RunRound:     fixture.Context.RunRound,
+		History:      fixture.Context.HistoryFile,
 	}
 
 	suit := util.Suit{
 		Config:   &cfg,
 		Provider: cluster.NewDefaultClusterProvider(),
-		ClientCreator: client.ClientCreator{
+		ClientCreator: &client.ClientCreator{
 			Cfg: &client.Config{
 				NumAccounts:         *accounts,
 				Interval:            *interval,
-				Concurrency:         *concurrency,
 				RetryLimit:          *retryLimit,
 				MinLength:           *minLength,
 				MaxLength:           *maxLength,
@@ -79,8 +85,12 @@ func main() {
 				ConnParams:          *connParams,
 			},
 		},
-		NemesisGens: util.ParseNemesisGenerators(fixture.Context.Nemesis),
-		VerifySuit:  verify.Suit{},
+		NemesisGens:      util.ParseNemesisGenerators(fixture.Context.Nemesis),
+		ClientRequestGen: util.OnClientLoop,
+		VerifySuit: verify.Suit{
+			Checker: client.Checker(),
+			Parser:  client.Parser(),
+		},
 		ClusterDefs: test_infra.NewDefaultCluster(fixture.Context.Namespace, fixture.Context.ClusterName,
 			fixture.Context.TiDBClusterConfig),
 		LogsClient: logs.NewDiagnosticLogClient(),
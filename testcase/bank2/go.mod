@@ -8,7 +8,6 @@ require (
 	github.com/ngaut/log v0.0.0-20180314031856-b8e36e7ba5ac
 	github.com/pingcap/tipocket v1.0.0
 	github.com/pingcap/tipocket/logsearch v1.0.0
-	github.com/rogpeppe/fastuuid v1.2.0
 )
 
 replace google.golang.org/grpc => google.golang.org/grpc v1.26.0
@@ -0,0 +1,172 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testcase
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ngaut/log"
+
+	"github.com/pingcap/tipocket/pkg/core"
+)
+
+// maxReportedAnomalies limits the anomalies in the check error
+const maxReportedAnomalies = 10
+
+// crWriteOp is a write with its real time interval, End is zero if the result is unknown
+type crWriteOp struct {
+	ID    int64
+	Start time.Time
+	End   time.Time
+	crResponse
+}
+
+type crReadOp struct {
+	Start time.Time
+	crResponse
+}
+
+type crChecker struct{}
+
+// Checker checks the history of causal reverse test, a read which observes a write must observe
+// all the writes committed strictly before the write began in real time
+func Checker() core.Checker {
+	return crChecker{}
+}
+
+// Check implements core.Checker
+func (crChecker) Check(_ core.Model, ops []core.Operation) (bool, error) {
+	writes, reads, err := crParseOps(ops)
+	if err != nil {
+		return false, err
+	}
+	logTxnStats(writes)
+
+	// the ok writes ordered by the end time
+	var committed []*crWriteOp
+	for _, w := range writes {
+		if w.Ok {
+			committed = append(committed, w)
+		}
+	}
+	sort.Slice(committed, func(i, j int) bool { return committed[i].End.Before(committed[j].End) })
+
+	var anomalies []string
+	for _, r := range reads {
+		observed := make(map[int64]struct{}, len(r.IDs))
+		// the latest start among the observed writes, all the writes ended before it must be observed
+		var latest *crWriteOp
+		for _, id := range r.IDs {
+			observed[id] = struct{}{}
+			w, ok := writes[id]
+			if !ok {
+				return false, fmt.Errorf("read observes id %d which is never written", id)
+			}
+			if latest == nil || w.Start.After(latest.Start) {
+				latest = w
+			}
+		}
+		if latest == nil {
+			continue
+		}
+		for _, w := range committed {
+			if !w.End.Before(latest.Start) {
+				break
+			}
+			if _, ok := observed[w.ID]; !ok {
+				anomalies = append(anomalies, fmt.Sprintf(
+					"read (start_ts %d) observes write %d (start_ts %d, commit_ts %d) but misses write %d (start_ts %d, commit_ts %d) which was committed before it began",
+					r.StartTS, latest.ID, latest.StartTS, latest.CommitTS, w.ID, w.StartTS, w.CommitTS))
+			}
+		}
+	}
+	if len(anomalies) == 0 {
+		return true, nil
+	}
+	for _, anomaly := range anomalies {
+		log.Errorf("[causal-reverse] %s", anomaly)
+	}
+	if len(anomalies) > maxReportedAnomalies {
+		anomalies = anomalies[:maxReportedAnomalies]
+	}
+	return false, fmt.Errorf("found causal reverse:\n%s", strings.Join(anomalies, "\n"))
+}
+
+// Name implements core.Checker
+func (crChecker) Name() string {
+	return "causal_reverse_checker"
+}
+
+// crParseOps pairs the requests and responses of each process, the unknown writes are kept since they may be observed
+func crParseOps(ops []core.Operation) (map[int64]*crWriteOp, []crReadOp, error) {
+	writes := make(map[int64]*crWriteOp)
+	var reads []crReadOp
+	pending := make(map[int64]core.Operation)
+	for _, op := range ops {
+		switch op.Action {
+		case core.InvokeOperation:
+			req := op.Data.(crRequest)
+			pending[op.Proc] = op
+			if req.Kind == crWrite {
+				if _, ok := writes[req.ID]; ok {
+					return nil, nil, fmt.Errorf("id %d is written twice", req.ID)
+				}
+				writes[req.ID] = &crWriteOp{ID: req.ID, Start: op.Time}
+			}
+		case core.ReturnOperation:
+			invoke, ok := pending[op.Proc]
+			if !ok {
+				return nil, nil, fmt.Errorf("no request for the response of process %d", op.Proc)
+			}
+			delete(pending, op.Proc)
+			req := invoke.Data.(crRequest)
+			res := op.Data.(crResponse)
+			if req.Kind == crWrite {
+				w := writes[req.ID]
+				w.crResponse = res
+				if res.Ok {
+					w.End = op.Time
+				}
+			} else if res.Ok {
+				reads = append(reads, crReadOp{Start: invoke.Time, crResponse: res})
+			}
+		}
+	}
+	return writes, reads, nil
+}
+
+// logTxnStats logs how many writes were committed by async commit and 1PC,
+// which makes the runs with different settings comparable
+func logTxnStats(writes map[int64]*crWriteOp) {
+	var ok, unknown, asyncCommit, onePC int
+	for _, w := range writes {
+		switch {
+		case w.Ok:
+			ok++
+		case w.Unknown:
+			unknown++
+		}
+		if w.AsyncCommit {
+			asyncCommit++
+		}
+		if w.OnePC {
+			onePC++
+		}
+	}
+	log.Infof("[causal-reverse] %d writes, %d ok, %d unknown, %d by async commit, %d by 1PC",
+		len(writes), ok, unknown, asyncCommit, onePC)
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testcase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pingcap/tipocket/pkg/core"
+)
+
+func TestChecker(t *testing.T) {
+	now := time.Now()
+	at := func(ms int) time.Time {
+		return now.Add(time.Duration(ms) * time.Millisecond)
+	}
+	history := func(observed []int64) []core.Operation {
+		return []core.Operation{
+			{Action: core.InvokeOperation, Proc: 1, Time: at(0), Data: crRequest{Kind: crWrite, ID: 1}},
+			{Action: core.ReturnOperation, Proc: 1, Time: at(10), Data: crResponse{Ok: true, txnInfo: txnInfo{StartTS: 1, CommitTS: 2}}},
+			{Action: core.InvokeOperation, Proc: 2, Time: at(20), Data: crRequest{Kind: crWrite, ID: 2}},
+			{Action: core.InvokeOperation, Proc: 3, Time: at(25), Data: crRequest{Kind: crRead}},
+			{Action: core.ReturnOperation, Proc: 2, Time: at(30), Data: crResponse{Ok: true, txnInfo: txnInfo{StartTS: 3, CommitTS: 4}}},
+			{Action: core.ReturnOperation, Proc: 3, Time: at(40), Data: crResponse{Ok: true, IDs: observed, txnInfo: txnInfo{StartTS: 5}}},
+		}
+	}
+	for _, c := range []struct {
+		observed []int64
+		ok       bool
+	}{
+		{nil, true},
+		{[]int64{1}, true},
+		{[]int64{1, 2}, true},
+		// write 2 began after write 1 was committed
+		{[]int64{2}, false},
+	} {
+		ok, err := Checker().Check(nil, history(c.observed))
+		if ok != c.ok {
+			t.Fatalf("observed %v, expect %t, got %t, %v", c.observed, c.ok, ok, err)
+		}
+	}
+}
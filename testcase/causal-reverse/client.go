@@ -0,0 +1,280 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testcase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/ngaut/log"
+
+	"github.com/pingcap/tipocket/pkg/cluster"
+	"github.com/pingcap/tipocket/pkg/core"
+	"github.com/pingcap/tipocket/pkg/history"
+	"github.com/pingcap/tipocket/pkg/test-infra/fixture"
+	"github.com/pingcap/tipocket/util"
+)
+
+const (
+	crRead  = "read"
+	crWrite = "write"
+)
+
+// Config is the config of the causal reverse test
+type Config struct {
+	TableCount int
+	// ReadRatio is the probability of a request being a read
+	ReadRatio float64
+	// AsyncCommit, OnePC and GuaranteeLinearizability set the session variables
+	// tidb_enable_async_commit, tidb_enable_1pc and tidb_guarantee_linearizability
+	AsyncCommit              bool
+	OnePC                    bool
+	GuaranteeLinearizability bool
+}
+
+type crRequest struct {
+	Kind string `json:"kind"`
+	// ID is the unique id to insert, it's only set for writes
+	ID int64 `json:"id,omitempty"`
+}
+
+func (r crRequest) String() string {
+	if r.Kind == crWrite {
+		return fmt.Sprintf("write %d", r.ID)
+	}
+	return r.Kind
+}
+
+// txnInfo is the result of @@tidb_last_txn_info
+type txnInfo struct {
+	StartTS     uint64 `json:"start_ts"`
+	CommitTS    uint64 `json:"commit_ts"`
+	AsyncCommit bool   `json:"async_commit"`
+	OnePC       bool   `json:"one_pc"`
+}
+
+type crResponse struct {
+	Ok      bool   `json:"ok"`
+	Unknown bool   `json:"unknown"`
+	Error   string `json:"error,omitempty"`
+	// IDs are the ids observed by a read
+	IDs []int64 `json:"ids,omitempty"`
+	txnInfo
+}
+
+// IsUnknown implements core.UnknownResponse
+func (r crResponse) IsUnknown() bool {
+	return r.Unknown
+}
+
+func (r crResponse) String() string {
+	if !r.Ok {
+		return fmt.Sprintf("ok: %t, unknown: %t, error: %s", r.Ok, r.Unknown, r.Error)
+	}
+	return fmt.Sprintf("ok: true, ids: %d, start_ts: %d, commit_ts: %d, async_commit: %t, one_pc: %t",
+		len(r.IDs), r.StartTS, r.CommitTS, r.AsyncCommit, r.OnePC)
+}
+
+// CaseCreator is a creator of test client
+type CaseCreator struct {
+	Config
+	nextID int64
+}
+
+// NewCaseCreator creates a CaseCreator
+func NewCaseCreator(cfg Config) *CaseCreator {
+	return &CaseCreator{Config: cfg}
+}
+
+// Create creates a test client
+func (c *CaseCreator) Create(node cluster.ClientNode) core.Client {
+	return &Client{
+		Config: c.Config,
+		nextID: &c.nextID,
+		r:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Client inserts rows with unique ids into tables spread across regions, and scans all the tables
+type Client struct {
+	Config
+	nextID *int64
+	r      *rand.Rand
+	db     *sql.DB
+}
+
+func tableName(i int) string {
+	return fmt.Sprintf("causal_reverse_%d", i)
+}
+
+// SetUp implements the core.Client interface.
+func (c *Client) SetUp(ctx context.Context, _ []cluster.Node, clientNodes []cluster.ClientNode, idx int) error {
+	node := clientNodes[idx]
+	// the session variables are set by the DSN, so they are kept on reconnecting
+	dsn := fmt.Sprintf("root@tcp(%s:%d)/test?tidb_enable_async_commit=%d&tidb_enable_1pc=%d&tidb_guarantee_linearizability=%d",
+		node.IP, node.Port, boolToInt(c.AsyncCommit), boolToInt(c.OnePC), boolToInt(c.GuaranteeLinearizability))
+	util.SetMySQLProxy(fixture.Context.MySQLProxy)
+	db, err := util.OpenDB(dsn, 1)
+	if err != nil {
+		return err
+	}
+	// @@tidb_last_txn_info is per session, so all the statements must run in one connection
+	db.SetMaxOpenConns(1)
+	c.db = db
+
+	if idx != 0 {
+		return nil
+	}
+	log.Infof("begin to create %d tables on node %s", c.TableCount, node)
+	for i := 0; i < c.TableCount; i++ {
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("drop table if exists %s", tableName(i))); err != nil {
+			return err
+		}
+		// each table has its own region since TiDB splits the new tables by default
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("create table %s (id bigint not null primary key, value bigint not null)", tableName(i))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TearDown implements the core.Client interface.
+func (c *Client) TearDown(ctx context.Context, nodes []cluster.ClientNode, idx int) error {
+	return c.db.Close()
+}
+
+// Invoke implements the core.OnScheduleClientExtensions interface.
+func (c *Client) Invoke(ctx context.Context, node cluster.ClientNode, r interface{}) core.UnknownResponse {
+	req := r.(crRequest)
+	txn, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return crResponse{Error: err.Error()}
+	}
+	var ids []int64
+	if req.Kind == crWrite {
+		query := fmt.Sprintf("insert into %s (id, value) values (?, ?)", tableName(int(req.ID)%c.TableCount))
+		if _, err := txn.ExecContext(ctx, query, req.ID, req.ID); err != nil {
+			_ = txn.Rollback()
+			return crResponse{Error: err.Error()}
+		}
+	} else {
+		ids = make([]int64, 0)
+		for i := 0; i < c.TableCount; i++ {
+			tableIDs, err := queryIDs(ctx, txn, tableName(i))
+			if err != nil {
+				_ = txn.Rollback()
+				return crResponse{Error: err.Error()}
+			}
+			ids = append(ids, tableIDs...)
+		}
+	}
+	if err := txn.Commit(); err != nil {
+		// the write may be committed or not, a read is just failed
+		return crResponse{Unknown: req.Kind == crWrite, Error: err.Error()}
+	}
+
+	res := crResponse{Ok: true, IDs: ids}
+	info, err := c.lastTxnInfo(ctx)
+	if err != nil {
+		log.Warnf("query last txn info failed: %v", err)
+	} else {
+		res.txnInfo = info
+	}
+	return res
+}
+
+func (c *Client) lastTxnInfo(ctx context.Context) (txnInfo, error) {
+	var (
+		raw  string
+		info txnInfo
+	)
+	if err := c.db.QueryRowContext(ctx, "select @@tidb_last_txn_info").Scan(&raw); err != nil {
+		return info, err
+	}
+	err := json.Unmarshal([]byte(raw), &info)
+	return info, err
+}
+
+func queryIDs(ctx context.Context, txn *sql.Tx, table string) ([]int64, error) {
+	rows, err := txn.QueryContext(ctx, fmt.Sprintf("select id from %s", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// NextRequest implements the core.OnScheduleClientExtensions interface.
+func (c *Client) NextRequest() interface{} {
+	if c.r.Float64() < c.ReadRatio {
+		return crRequest{Kind: crRead}
+	}
+	return crRequest{Kind: crWrite, ID: atomic.AddInt64(c.nextID, 1)}
+}
+
+// DumpState implements the core.OnScheduleClientExtensions interface.
+func (c *Client) DumpState(ctx context.Context) (interface{}, error) {
+	return nil, nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type crParser struct{}
+
+// OnRequest implements history.RecordParser
+func (crParser) OnRequest(data json.RawMessage) (interface{}, error) {
+	r := crRequest{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+// OnResponse implements history.RecordParser
+func (crParser) OnResponse(data json.RawMessage) (interface{}, error) {
+	r := crResponse{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+// OnNoopResponse implements history.RecordParser
+func (crParser) OnNoopResponse() interface{} {
+	return crResponse{Unknown: true}
+}
+
+// OnState implements history.RecordParser
+func (crParser) OnState(state json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+// Parser parses a history of causal reverse test
+func Parser() history.RecordParser {
+	return crParser{}
+}
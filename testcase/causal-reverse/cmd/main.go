@@ -0,0 +1,72 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+
+	// use mysql
+	_ "github.com/go-sql-driver/mysql"
+
+	"github.com/pingcap/tipocket/cmd/util"
+	logs "github.com/pingcap/tipocket/logsearch/pkg/logs"
+	"github.com/pingcap/tipocket/pkg/cluster"
+	"github.com/pingcap/tipocket/pkg/control"
+	test_infra "github.com/pingcap/tipocket/pkg/test-infra"
+	"github.com/pingcap/tipocket/pkg/test-infra/fixture"
+	"github.com/pingcap/tipocket/pkg/verify"
+
+	testcase "github.com/pingcap/tipocket/testcase/causal-reverse"
+)
+
+var (
+	tableCount               = flag.Int("table-count", 7, "Table count, each table is in its own region")
+	readRatio                = flag.Float64("read-ratio", 0.2, "The probability of a request being a read")
+	asyncCommit              = flag.Bool("async-commit", true, "Set tidb_enable_async_commit")
+	onePC                    = flag.Bool("one-pc", true, "Set tidb_enable_1pc")
+	guaranteeLinearizability = flag.Bool("guarantee-linearizability", true, "Set tidb_guarantee_linearizability")
+)
+
+func main() {
+	flag.Parse()
+	c := fixture.Context
+	suit := util.Suit{
+		Config: &control.Config{
+			Mode:         control.ModeOnSchedule,
+			ClientCount:  c.ClientCount,
+			RequestCount: c.RequestCount,
+			RunRound:     c.RunRound,
+			RunTime:      c.RunTime,
+			History:      c.HistoryFile,
+		},
+		Provider: cluster.NewDefaultClusterProvider(),
+		ClientCreator: testcase.NewCaseCreator(testcase.Config{
+			TableCount:               *tableCount,
+			ReadRatio:                *readRatio,
+			AsyncCommit:              *asyncCommit,
+			OnePC:                    *onePC,
+			GuaranteeLinearizability: *guaranteeLinearizability,
+		}),
+		NemesisGens:      util.ParseNemesisGenerators(c.Nemesis),
+		ClientRequestGen: util.OnClientLoop,
+		VerifySuit: verify.Suit{
+			Checker: testcase.Checker(),
+			Parser:  testcase.Parser(),
+		},
+		ClusterDefs: test_infra.NewDefaultCluster(c.Namespace, c.ClusterName, c.TiDBClusterConfig),
+		LogsClient:  logs.NewDiagnosticLogClient(),
+	}
+	suit.Run(context.Background())
+}
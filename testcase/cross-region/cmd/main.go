@@ -45,6 +45,7 @@ func main() {
 				DBName:      "test",
 				TSORequests: *tsoRequests,
 				Round:       *round,
+				History:     fixture.Context.HistoryFile,
 			},
 		},
 		NemesisGens: util.ParseNemesisGenerators(fixture.Context.Nemesis),
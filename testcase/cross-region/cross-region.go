@@ -11,9 +11,12 @@ import (
 	pdClient "github.com/tikv/pd/client"
 	"go.uber.org/zap"
 
+	"github.com/pingcap/tipocket/pkg/check/tso"
 	"github.com/pingcap/tipocket/pkg/cluster"
 	"github.com/pingcap/tipocket/pkg/core"
+	"github.com/pingcap/tipocket/pkg/history"
 	"github.com/pingcap/tipocket/pkg/util/pdutil"
+	"github.com/pingcap/tipocket/pkg/verify"
 	"github.com/pingcap/tipocket/util"
 )
 
@@ -27,6 +30,9 @@ type Config struct {
 	DBName      string
 	//TODO: support configure DCLocationNum instead of fixed 6
 	DCLocationNum int
+	// History records the TSO requests, the TSOs are checked over it after all the rounds,
+	// a temporary file is used if it's empty
+	History string
 }
 
 // ClientCreator creates crossRegionClient
@@ -48,6 +54,7 @@ type crossRegionClient struct {
 	pdClient     pdClient.Client
 	pdHTTPClient *pdutil.Client
 	db           *sql.DB
+	recorder     *history.Recorder
 	initialized  bool
 	closed       bool
 }
@@ -103,13 +110,18 @@ func (c *crossRegionClient) TearDown(ctx context.Context, _ []cluster.ClientNode
 
 // Start...
 func (c *crossRegionClient) Start(ctx context.Context, cfg interface{}, cnodes []cluster.ClientNode) error {
-	for i := 0; i < c.Round; i++ {
+	if err := c.openHistory(); err != nil {
+		return err
+	}
+	var err error
+	for i := 0; i < c.Round && err == nil; i++ {
 		log.Info("Start to test TSO", zap.Int("round", i))
-		if err := c.testTSO(ctx); err != nil {
-			return err
-		}
+		err = c.testTSO(ctx)
 	}
-	return nil
+	// the history is checked even if the test fails, the unfinished transfers are possible transfer points
+	c.recorder.Close()
+	verify.Suit{Checker: tso.Checker(suffixBits(len(DCLocations))), Parser: tso.Parser()}.Verify(c.History)
+	return err
 }
 
 func (c *crossRegionClient) setup() error {
@@ -0,0 +1,99 @@
+package crossregion
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/bits"
+	"time"
+
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/tipocket/pkg/check/tso"
+	"github.com/pingcap/tipocket/pkg/history"
+)
+
+// recordError means the history can't be recorded, so the TSOs can't be checked
+type recordError struct {
+	err error
+}
+
+func (e *recordError) Error() string {
+	return fmt.Sprintf("record history failed: %v", e.err)
+}
+
+// suffixBits returns the bits of the dc-location suffixes in the logical part of a TSO,
+// the global allocator takes the suffix 0 and the local allocators take the others.
+func suffixBits(dcLocationNum int) uint {
+	return uint(bits.Len(uint(dcLocationNum)))
+}
+
+// globalProc is the process requesting the Global TSO in the history.
+const globalProc int64 = 0
+
+// localProc is the process requesting the Local TSO of the dc-location in the history.
+func localProc(dcIdx int) int64 {
+	return int64(dcIdx + 1)
+}
+
+// controlProc is the process transferring the allocators and requesting TSOs after the transfers in the history.
+func controlProc() int64 {
+	return int64(len(DCLocations) + 1)
+}
+
+func (c *crossRegionClient) record(proc int64, req tso.Request, f func(res *tso.Response) error) error {
+	if err := c.recorder.RecordRequest(proc, req); err != nil {
+		return &recordError{err: err}
+	}
+	res := tso.Response{Start: time.Now()}
+	err := f(&res)
+	res.End = time.Now()
+	if err != nil {
+		res.Error = err.Error()
+	}
+	if recErr := c.recorder.RecordResponse(proc, res); recErr != nil {
+		return &recordError{err: recErr}
+	}
+	return err
+}
+
+// getTS requests a TSO from the allocator of the dc-location and records it in the history.
+func (c *crossRegionClient) getTS(ctx context.Context, proc int64, dcLocation string) (physical int64, logical int64, err error) {
+	err = c.record(proc, tso.Request{Kind: tso.KindTSO, DCLocation: dcLocation}, func(res *tso.Response) error {
+		var err error
+		if dcLocation == tso.GlobalDCLocation {
+			res.Physical, res.Logical, err = c.pdClient.GetTS(ctx)
+		} else {
+			res.Physical, res.Logical, err = c.pdClient.GetLocalTS(ctx, dcLocation)
+		}
+		physical, logical = res.Physical, res.Logical
+		return err
+	})
+	return
+}
+
+// recordTransfer records the transfer of the allocator of the dc-location, it ends when the target is ready.
+func (c *crossRegionClient) recordTransfer(dcLocation, target string, transfer func() error) error {
+	return c.record(controlProc(), tso.Request{Kind: tso.KindTransfer, DCLocation: dcLocation, Target: target}, func(*tso.Response) error {
+		return transfer()
+	})
+}
+
+func (c *crossRegionClient) openHistory() error {
+	if c.History == "" {
+		f, err := ioutil.TempFile("", "cross-region-history-")
+		if err != nil {
+			return err
+		}
+		f.Close()
+		c.History = f.Name()
+		log.Info("record the history in a temporary file", zap.String("file", c.History))
+	}
+	recorder, err := history.NewRecorder(c.History)
+	if err != nil {
+		return err
+	}
+	c.recorder = recorder
+	return nil
+}
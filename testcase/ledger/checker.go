@@ -0,0 +1,208 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ledger
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/ngaut/log"
+
+	"github.com/pingcap/tipocket/pkg/check/checkutil"
+	"github.com/pingcap/tipocket/pkg/core"
+	"github.com/pingcap/tipocket/pkg/history"
+)
+
+type parser struct{}
+
+// Parser parses a history of ledger test
+func Parser() history.RecordParser {
+	return parser{}
+}
+
+// OnRequest implements history.RecordParser
+func (parser) OnRequest(data json.RawMessage) (interface{}, error) {
+	r := request{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+// OnResponse implements history.RecordParser
+func (parser) OnResponse(data json.RawMessage) (interface{}, error) {
+	r := response{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+// OnNoopResponse implements history.RecordParser
+func (parser) OnNoopResponse() interface{} {
+	return response{Unknown: true}
+}
+
+// OnState implements history.RecordParser
+func (parser) OnState(state json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+type transfer struct {
+	request
+	response
+}
+
+type read struct {
+	request
+	response
+}
+
+// postingKey is a posting of an account
+type postingKey struct {
+	account     string
+	causalityID int64
+}
+
+type checker struct{}
+
+// Checker checks the balances sum to 0 and the postings are consistent in every verify read,
+// and no posting is doubled or based on a stale balance over the transfers
+func Checker() core.Checker {
+	return checker{}
+}
+
+// Name implements core.Checker
+func (checker) Name() string {
+	return "ledger_checker"
+}
+
+// Check implements core.Checker
+func (checker) Check(_ core.Model, ops []core.Operation) (bool, error) {
+	transfers, reads, err := parseOps(ops)
+	if err != nil {
+		return false, err
+	}
+	violations := checkReads(reads, transfers)
+	violations = append(violations, checkPostings(transfers)...)
+
+	log.Infof("[ledger] checked %d verify reads and %d transfers", len(reads), len(transfers))
+	return checkutil.Report("ledger", violations)
+}
+
+// checkReads checks the invariants in every snapshot, and the posting groups between two snapshots
+// are no less than the transfers committed between them and no more than the ones which may be committed
+func checkReads(reads []read, transfers []*transfer) []string {
+	var (
+		violations []string
+		unknown    int64
+		commits    []uint64
+	)
+	for _, t := range transfers {
+		if t.Ok {
+			commits = append(commits, t.CommitTS)
+		} else if t.Unknown {
+			unknown++
+		}
+	}
+	window := checkutil.NewCommitWindow(commits, unknown)
+
+	sort.Slice(reads, func(i, j int) bool { return reads[i].ReadTS < reads[j].ReadTS })
+	for i, r := range reads {
+		if r.Total != 0 {
+			violations = append(violations, fmt.Sprintf("total balance at read_ts %d is %d", r.ReadTS, r.Total))
+		}
+		if r.TiFlashTotal != nil && *r.TiFlashTotal != 0 {
+			violations = append(violations, fmt.Sprintf("total balance of tiflash at read_ts %d is %d", r.ReadTS, *r.TiFlashTotal))
+		}
+		if len(r.BadGroups) > 0 {
+			violations = append(violations, fmt.Sprintf("posting groups %v at read_ts %d aren't a pair of postings summing to 0", r.BadGroups, r.ReadTS))
+		}
+		if len(r.BadAccounts) > 0 {
+			violations = append(violations, fmt.Sprintf("accounts %v at read_ts %d have lost or doubled postings", r.BadAccounts, r.ReadTS))
+		}
+		if i == 0 {
+			continue
+		}
+		prev := reads[i-1]
+		if v := window.Check("posting groups", r.Groups-prev.Groups, prev.ReadTS, r.ReadTS); v != "" {
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
+
+// checkPostings checks every posting is written by at most one committed transfer, and a committed transfer
+// is based on the balance of the posting it follows
+func checkPostings(transfers []*transfer) []string {
+	type written struct {
+		t       *transfer
+		balance int64
+	}
+	var violations []string
+	writers := make(map[postingKey]written)
+	for _, t := range transfers {
+		if !t.Ok {
+			continue
+		}
+		for _, w := range []struct {
+			key     postingKey
+			balance int64
+		}{
+			{postingKey{t.AccountA, t.LastA.CausalityID + 1}, t.LastA.Balance + t.Amount},
+			{postingKey{t.AccountB, t.LastB.CausalityID + 1}, t.LastB.Balance - t.Amount},
+		} {
+			if prev, ok := writers[w.key]; ok {
+				violations = append(violations, fmt.Sprintf("posting %d of %s is written by both posting group %d and %d",
+					w.key.causalityID, w.key.account, prev.t.Group, t.Group))
+				continue
+			}
+			writers[w.key] = written{t: t, balance: w.balance}
+		}
+	}
+	for _, t := range transfers {
+		if !t.Ok {
+			continue
+		}
+		for _, last := range []struct {
+			account string
+			posting
+		}{{t.AccountA, t.LastA}, {t.AccountB, t.LastB}} {
+			expected, ok := int64(0), last.CausalityID == 0
+			if w, found := writers[postingKey{last.account, last.CausalityID}]; found {
+				expected, ok = w.balance, true
+			}
+			// the posting may be written by an unknown transfer or in the previous rounds
+			if ok && last.Balance != expected {
+				violations = append(violations, fmt.Sprintf("%s is based on posting %d of %s with balance %d, expect %d",
+					t.request, last.CausalityID, last.account, last.Balance, expected))
+			}
+		}
+	}
+	return violations
+}
+
+// parseOps pairs the requests and responses of each process, it returns all the transfers and the ok reads
+func parseOps(ops []core.Operation) ([]*transfer, []read, error) {
+	var (
+		transfers []*transfer
+		reads     []read
+	)
+	err := checkutil.PairOps(ops, func(invoke, ret core.Operation) {
+		rq, rs := invoke.Data.(request), ret.Data.(response)
+		if rq.Kind == opTransfer {
+			transfers = append(transfers, &transfer{request: rq, response: rs})
+		} else if rs.Ok {
+			reads = append(reads, read{request: rq, response: rs})
+		}
+	})
+	return transfers, reads, err
+}
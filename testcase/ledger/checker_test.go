@@ -0,0 +1,57 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ledger
+
+import (
+	"testing"
+
+	"github.com/pingcap/tipocket/pkg/core"
+)
+
+func TestChecker(t *testing.T) {
+	history := func(second posting, readTotal, readGroups int64) []core.Operation {
+		return []core.Operation{
+			{Action: core.InvokeOperation, Proc: 1, Data: request{Kind: opRead}},
+			{Action: core.ReturnOperation, Proc: 1, Data: response{Ok: true, ReadTS: 5}},
+			{Action: core.InvokeOperation, Proc: 2, Data: request{Kind: opTransfer, Group: 1, AccountA: "acc1", AccountB: "acc2", Amount: 10}},
+			{Action: core.ReturnOperation, Proc: 2, Data: response{Ok: true, LastA: posting{0, 0}, LastB: posting{0, 0}, CommitTS: 10}},
+			{Action: core.InvokeOperation, Proc: 3, Data: request{Kind: opTransfer, Group: 2, AccountA: "acc3", AccountB: "acc1", Amount: 3}},
+			{Action: core.ReturnOperation, Proc: 3, Data: response{Ok: true, LastA: posting{0, 0}, LastB: second, CommitTS: 20}},
+			{Action: core.InvokeOperation, Proc: 4, Data: request{Kind: opTransfer, Group: 3, AccountA: "acc4", AccountB: "acc5", Amount: 1}},
+			{Action: core.ReturnOperation, Proc: 4, Data: response{Unknown: true}},
+			{Action: core.InvokeOperation, Proc: 5, Data: request{Kind: opRead}},
+			{Action: core.ReturnOperation, Proc: 5, Data: response{Ok: true, ReadTS: 25, Total: readTotal, Groups: readGroups}},
+		}
+	}
+	for _, c := range []struct {
+		second      posting
+		total       int64
+		groups      int64
+		ok          bool
+		description string
+	}{
+		{posting{1, 10}, 0, 2, true, "ok"},
+		{posting{1, 10}, 0, 3, true, "the unknown transfer is committed"},
+		{posting{1, 10}, 1, 2, false, "the total isn't 0"},
+		{posting{1, 10}, 0, 1, false, "a committed transfer is missing"},
+		{posting{1, 10}, 0, 4, false, "more posting groups than the transfers"},
+		{posting{0, 0}, 0, 2, false, "posting 1 of acc1 is doubled"},
+		{posting{1, 7}, 0, 2, false, "the transfer is based on a wrong balance"},
+	} {
+		ok, err := Checker().Check(nil, history(c.second, c.total, c.groups))
+		if ok != c.ok {
+			t.Fatalf("%s: expect %t, got %t, %v", c.description, c.ok, ok, err)
+		}
+	}
+}
@@ -4,6 +4,7 @@ package ledger
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"strings"
@@ -24,6 +25,13 @@ var (
 	insertBatchSize   = 100
 	maxTransfer       = 100
 	retryLimit        = 200
+	// maxReportedRows limits the bad posting groups and accounts returned by a verify read
+	maxReportedRows = 10
+)
+
+const (
+	opTransfer = "transfer"
+	opRead     = "read"
 )
 
 const (
@@ -47,13 +55,26 @@ CREATE TABLE IF NOT EXISTS ledger_accounts (
 );
 TRUNCATE TABLE ledger_accounts;
 `
+	// stmtTotal sums the balances of the last postings of all the accounts
+	stmtTotal = `
+select sum(balance) total from
+  (select account_id, max(causality_id) max_causality_id from ledger_accounts group by account_id) last
+  join ledger_accounts
+    on last.account_id = ledger_accounts.account_id and last.max_causality_id = ledger_accounts.causality_id`
+	// stmtGroups counts the posting groups of the transfers, the initial postings have causality_id 0
+	stmtGroups = `select count(distinct posting_group_id) from ledger_accounts where causality_id > 0`
+	// stmtBadGroups finds the posting groups which aren't a pair of postings summing to 0
+	stmtBadGroups = `select posting_group_id from ledger_accounts where causality_id > 0
+  group by posting_group_id having count(*) != 2 or sum(amount) != 0 limit ?`
+	// stmtBadAccounts finds the accounts whose causality ids aren't 0, 1, ..., n-1, which means a posting is lost or doubled
+	stmtBadAccounts = `select account_id from ledger_accounts
+  group by account_id having count(*) != max(causality_id) + 1 or count(distinct causality_id) != count(*) limit ?`
 )
 
 // Config is for ledgerClient
 type Config struct {
 	NumAccounts         int           `toml:"num_accounts"`
 	Interval            time.Duration `toml:"interval"`
-	Concurrency         int           `toml:"concurrency"`
 	TxnMode             string        `toml:"txn_mode"`
 	TiFlashDataReplicas int
 }
@@ -61,12 +82,17 @@ type Config struct {
 // ClientCreator creates ledgerClient
 type ClientCreator struct {
 	Cfg *Config
+
+	// lastRead is the unix nano time of the last verify read, which is shared by all the clients
+	lastRead int64
 }
 
 // Create ...
-func (l ClientCreator) Create(node cluster.ClientNode) core.Client {
+func (l *ClientCreator) Create(node cluster.ClientNode) core.Client {
 	return &ledgerClient{
-		Config: l.Cfg,
+		Config:  l.Cfg,
+		creator: l,
+		r:       rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 }
 
@@ -74,45 +100,101 @@ func (l ClientCreator) Create(node cluster.ClientNode) core.Client {
 // life of a bank (or other company).
 type ledgerClient struct {
 	*Config
-	wg   sync.WaitGroup
-	stop int32
-	db   *sql.DB
+	creator *ClientCreator
+	r       *rand.Rand
+	db      *sql.DB
+	conn    *sql.Conn
 }
 
-func (c *ledgerClient) SetUp(ctx context.Context, _ []cluster.Node, clientNodes []cluster.ClientNode, idx int) error {
-	if idx != 0 {
-		return nil
+// request is a transfer, or a verify read which reads the ledger in a snapshot
+type request struct {
+	Kind string `json:"kind"`
+	// Amount is deposited on AccountA and removed from AccountB in the posting group
+	Group    int64  `json:"group,omitempty"`
+	AccountA string `json:"account_a,omitempty"`
+	AccountB string `json:"account_b,omitempty"`
+	Amount   int64  `json:"amount,omitempty"`
+}
+
+func (r request) String() string {
+	if r.Kind == opTransfer {
+		return fmt.Sprintf("posting group %d transfers %d from %s to %s", r.Group, r.Amount, r.AccountB, r.AccountA)
 	}
+	return "verify read"
+}
+
+// posting is the last posting of an account
+type posting struct {
+	CausalityID int64 `json:"causality_id"`
+	Balance     int64 `json:"balance"`
+}
+
+type response struct {
+	Ok      bool   `json:"ok"`
+	Unknown bool   `json:"unknown"`
+	Error   string `json:"error,omitempty"`
+	// LastA and LastB are the postings the transfer is based on, CommitTS is its commit_ts
+	LastA    posting `json:"last_a"`
+	LastB    posting `json:"last_b"`
+	CommitTS uint64  `json:"commit_ts,omitempty"`
+	// ReadTS is the snapshot of a verify read, Total is the sum of the last balances which must be 0,
+	// Groups is the number of the posting groups
+	ReadTS       uint64 `json:"read_ts,omitempty"`
+	Total        int64  `json:"total"`
+	TiFlashTotal *int64 `json:"tiflash_total,omitempty"`
+	Groups       int64  `json:"groups"`
+	// BadGroups and BadAccounts are the double or broken postings found in the snapshot
+	BadGroups   []int64  `json:"bad_groups,omitempty"`
+	BadAccounts []string `json:"bad_accounts,omitempty"`
+}
 
+// IsUnknown implements core.UnknownResponse
+func (r response) IsUnknown() bool {
+	return r.Unknown
+}
+
+func (c *ledgerClient) SetUp(ctx context.Context, _ []cluster.Node, clientNodes []cluster.ClientNode, idx int) error {
 	dbName := "test"
 
 	var err error
 	node := clientNodes[idx]
 	dsn := fmt.Sprintf("root@tcp(%s:%d)/%s?multiStatements=true", node.IP, node.Port, dbName)
+	if c.db, err = util.OpenDB(dsn, 1); err != nil {
+		return err
+	}
+	// @@tidb_last_txn_info must be read in the connection of the transfer
+	if err = c.reconnect(ctx); err != nil {
+		return err
+	}
+	if idx != 0 {
+		return nil
+	}
 
 	log.Infof("start to init...")
 	db, err := util.OpenDB(dsn, 1)
 	if err != nil {
 		log.Fatalf("[ledgerClient] create db client error %v", err)
 	}
+	defer db.Close()
 	_, err = db.Exec(fmt.Sprintf("set @@global.tidb_txn_mode = '%s';", c.TxnMode))
 	if err != nil {
 		log.Fatalf("[ledgerClient] set txn_mode failed: %v", err)
 	}
 	time.Sleep(5 * time.Second)
-	c.db, err = util.OpenDB(dsn, c.Concurrency)
+	loadDB, err := util.OpenDB(dsn, insertConcurrency)
 	if err != nil {
 		return err
 	}
 	defer func() {
+		loadDB.Close()
 		log.Infof("init end...")
 	}()
 
-	if _, err := c.db.Exec(stmtDrop); err != nil {
+	if _, err := loadDB.Exec(stmtDrop); err != nil {
 		log.Fatalf("execute statement %s error %v", stmtDrop, err)
 	}
 
-	if _, err := c.db.Exec(stmtCreate); err != nil {
+	if _, err := loadDB.Exec(stmtCreate); err != nil {
 		log.Fatalf("execute statement %s error %v", stmtCreate, err)
 	}
 
@@ -147,7 +229,7 @@ func (c *ledgerClient) SetUp(ctx context.Context, _ []cluster.Node, clientNodes
 
 				query := fmt.Sprintf(`INSERT INTO ledger_accounts (posting_group_id, amount,account_id, causality_id, balance) VALUES %s`, strings.Join(args, ","))
 				err := util.RunWithRetry(ctx, retryLimit, 3*time.Second, func() error {
-					_, err := c.db.Exec(query)
+					_, err := loadDB.Exec(query)
 					if util.IsErrDupEntry(err) {
 						return nil
 					}
@@ -180,107 +262,100 @@ func (c *ledgerClient) SetUp(ctx context.Context, _ []cluster.Node, clientNodes
 	select {
 	case <-ctx.Done():
 		log.Warnf("ledgerClient initialize is cancel")
-		return nil
 	default:
 	}
-
-	c.startVerify(ctx, c.db)
 	return nil
 }
 
 func (c *ledgerClient) TearDown(ctx context.Context, nodes []cluster.ClientNode, idx int) error {
-	return nil
-}
-
-func (c *ledgerClient) Start(ctx context.Context, cfg interface{}, clientNodes []cluster.ClientNode) error {
-	log.Infof("start to test...")
-	defer func() {
-		log.Infof("test end...")
-	}()
-	var wg sync.WaitGroup
-	for i := 0; i < c.Concurrency; i++ {
-		wg.Add(1)
-		go func(i int) {
-			defer wg.Done()
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-				}
-				if err := c.ExecuteLedger(c.db); err != nil {
-					log.Errorf("exec failed %v", err)
-				}
-			}
-		}(i)
+	if c.conn != nil {
+		c.conn.Close()
 	}
-
-	wg.Wait()
-	return nil
-}
-
-type postingRequest struct {
-	Group               int64
-	AccountA, AccountB  string
-	Amount              int    // deposited on AccountA, removed from AccountB
-	Transaction, Scheme string // opaque
+	return c.db.Close()
 }
 
-// ExecuteLedger is run case
-func (c *ledgerClient) ExecuteLedger(db *sql.DB) error {
-	if atomic.LoadInt32(&c.stop) != 0 {
-		return errors.New("ledgerClient stopped")
+// NextRequest implements the core.OnScheduleClientExtensions interface.
+// A verify read is issued by one of the clients every interval, the others are transfers.
+func (c *ledgerClient) NextRequest() interface{} {
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&c.creator.lastRead)
+	if now-last >= int64(c.Interval) && atomic.CompareAndSwapInt64(&c.creator.lastRead, last, now) {
+		return request{Kind: opRead}
 	}
-
-	c.wg.Add(1)
-	defer c.wg.Done()
-
-	req := postingRequest{
-		Group:    rand.Int63(),
-		AccountA: fmt.Sprintf("acc%d", rand.Intn(c.NumAccounts)+1),
-		AccountB: fmt.Sprintf("acc%d", rand.Intn(c.NumAccounts)+1),
-		Amount:   rand.Intn(maxTransfer),
+	req := request{
+		Kind:     opTransfer,
+		Group:    c.r.Int63(),
+		AccountA: fmt.Sprintf("acc%d", c.r.Intn(c.NumAccounts)+1),
+		AccountB: fmt.Sprintf("acc%d", c.r.Intn(c.NumAccounts)+1),
+		Amount:   int64(c.r.Intn(maxTransfer)),
 	}
-
-	if req.AccountA == req.AccountB {
+	for req.AccountA == req.AccountB {
 		// The code we use throws a unique constraint violation since we
 		// try to insert two conflicting primary keys. This isn't the
 		// interesting case.
-		return nil
+		req.AccountB = fmt.Sprintf("acc%d", c.r.Intn(c.NumAccounts)+1)
 	}
+	return req
+}
 
-	tx, err := db.Begin()
-	if err != nil {
-		return errors.Trace(err)
+// Invoke implements the core.OnScheduleClientExtensions interface.
+func (c *ledgerClient) Invoke(ctx context.Context, node cluster.ClientNode, r interface{}) core.UnknownResponse {
+	req := r.(request)
+	if c.conn == nil {
+		if err := c.reconnect(ctx); err != nil {
+			return response{Error: err.Error()}
+		}
 	}
-	defer tx.Rollback()
-
-	if err = c.doPosting(tx, req); err != nil {
-		log.Errorf("doPosting error: %v", err)
-		return errors.Trace(err)
+	var res response
+	if req.Kind == opTransfer {
+		res = c.doPosting(ctx, req)
+	} else {
+		res = c.verify(ctx)
 	}
+	if !res.Ok && isBadConn(res.Error) {
+		// the connection is broken, a new one is opened by the next request
+		c.conn.Close()
+		c.conn = nil
+	}
+	return res
+}
 
-	return nil
+// reconnect opens a new connection, @@tidb_last_txn_info is kept in it
+func (c *ledgerClient) reconnect(ctx context.Context) (err error) {
+	c.conn, err = c.db.Conn(ctx)
+	return err
+}
+
+func isBadConn(err string) bool {
+	return strings.Contains(err, "bad connection") || strings.Contains(err, "invalid connection") ||
+		strings.Contains(err, "connection is already closed")
 }
 
-func getLast(tx *sql.Tx, accountID string) (lastCID int64, lastBalance int64, err error) {
-	err = tx.QueryRow(fmt.Sprintf(`SELECT causality_id, balance FROM ledger_accounts
-		WHERE account_id = "%v" ORDER BY causality_id DESC LIMIT 1 FOR UPDATE`, accountID)).
-		Scan(&lastCID, &lastBalance)
+// DumpState implements the core.OnScheduleClientExtensions interface.
+func (c *ledgerClient) DumpState(ctx context.Context) (interface{}, error) {
+	return nil, nil
+}
+
+func getLast(ctx context.Context, tx *sql.Tx, accountID string) (last posting, err error) {
+	err = tx.QueryRowContext(ctx, `SELECT causality_id, balance FROM ledger_accounts
+		WHERE account_id = ? ORDER BY causality_id DESC LIMIT 1 FOR UPDATE`, accountID).
+		Scan(&last.CausalityID, &last.Balance)
 	return
 }
 
-func (c *ledgerClient) doPosting(tx *sql.Tx, req postingRequest) error {
-	//start := time.Now()
-	var cidA, balA, cidB, balB int64
-	var err error
-	cidA, balA, err = getLast(tx, req.AccountA)
+func (c *ledgerClient) doPosting(ctx context.Context, req request) response {
+	tx, err := c.conn.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return response{Error: err.Error()}
 	}
-	cidB, balB, err = getLast(tx, req.AccountB)
-	if err != nil {
-		return err
+	defer tx.Rollback()
+
+	var res response
+	if res.LastA, err = getLast(ctx, tx, req.AccountA); err != nil {
+		return response{Error: err.Error()}
+	}
+	if res.LastB, err = getLast(ctx, tx, req.AccountB); err != nil {
+		return response{Error: err.Error()}
 	}
 	query := fmt.Sprintf(`
 INSERT INTO ledger_accounts (
@@ -303,103 +378,117 @@ VALUES (
   %[7]v,   -- causality_id
   %[8]v-%[2]v -- (new) balance
 )`, req.Group, req.Amount,
-		req.AccountA, cidA+1, balA,
-		req.AccountB, cidB+1, balB)
-	if _, err := tx.Exec(query); err != nil {
-		return errors.Trace(err)
+		req.AccountA, res.LastA.CausalityID+1, res.LastA.Balance,
+		req.AccountB, res.LastB.CausalityID+1, res.LastB.Balance)
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return response{Error: err.Error()}
 	}
 	if err := tx.Commit(); err != nil {
-		return errors.Trace(err)
+		res.Unknown, res.Error = true, err.Error()
+		return res
 	}
-
-	//ledgerTxnDuration.Observe(time.Since(start).Seconds())
-	return nil
-}
-
-func (c *ledgerClient) startVerify(ctx context.Context, db *sql.DB) {
-	c.verify(ctx, db)
-
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-time.After(c.Interval):
-				c.verify(ctx, db)
-			}
-		}
-	}()
+	var info string
+	if err := c.conn.QueryRowContext(ctx, "SELECT @@tidb_last_txn_info").Scan(&info); err != nil {
+		res.Unknown, res.Error = true, err.Error()
+		return res
+	}
+	var txnInfo struct {
+		CommitTS uint64 `json:"commit_ts"`
+	}
+	if err := json.Unmarshal([]byte(info), &txnInfo); err != nil || txnInfo.CommitTS == 0 {
+		res.Unknown, res.Error = true, fmt.Sprintf("unexpected last txn info %s, err: %v", info, err)
+		return res
+	}
+	res.Ok, res.CommitTS = true, txnInfo.CommitTS
+	return res
 }
 
-func (c *ledgerClient) verify(ctx context.Context, db *sql.DB) error {
+// verify reads the total balance and the postings in one snapshot
+func (c *ledgerClient) verify(ctx context.Context) response {
 	start := time.Now()
-	tx, err := db.Begin()
+	tx, err := c.conn.BeginTx(ctx, nil)
 	if err != nil {
-		//ledgerVerifyFailedCounter.Inc()
-		return errors.Trace(err)
+		return response{Error: err.Error()}
 	}
 	defer tx.Rollback()
 
 	// read from tikv
-	_, err = tx.Exec("set @@session.tidb_isolation_read_engines='tikv'")
-	if err != nil {
-		return errors.Trace(err)
+	if _, err = tx.ExecContext(ctx, "set @@session.tidb_isolation_read_engines='tikv'"); err != nil {
+		return response{Error: err.Error()}
 	}
 
-	var total int64
-	err = tx.QueryRow(`
-select sum(balance) total from
-  (select account_id, max(causality_id) max_causality_id from ledger_accounts group by account_id) last
-  join ledger_accounts
-    on last.account_id = ledger_accounts.account_id and last.max_causality_id = ledger_accounts.causality_id`).Scan(&total)
-	if err != nil {
-		//ledgerVerifyFailedCounter.Inc()
-		return errors.Trace(err)
+	var res response
+	if err = tx.QueryRowContext(ctx, "SELECT @@tidb_current_ts").Scan(&res.ReadTS); err != nil {
+		return response{Error: err.Error()}
 	}
-
-	//ledgerVerifyDuration.Observe(time.Since(start).Seconds())
-	if total != 0 {
-		log.Errorf("check total balance got %v", total)
-		atomic.StoreInt32(&c.stop, 1)
-		c.wg.Wait()
-		log.Fatalf("check total balance got %v", total)
+	if err = tx.QueryRowContext(ctx, stmtTotal).Scan(&res.Total); err != nil {
+		return response{Error: err.Error()}
+	}
+	if err = tx.QueryRowContext(ctx, stmtGroups).Scan(&res.Groups); err != nil {
+		return response{Error: err.Error()}
+	}
+	if res.BadGroups, err = queryInt64s(ctx, tx, stmtBadGroups, maxReportedRows); err != nil {
+		return response{Error: err.Error()}
+	}
+	if res.BadAccounts, err = queryStrings(ctx, tx, stmtBadAccounts, maxReportedRows); err != nil {
+		return response{Error: err.Error()}
 	}
 
 	// query with tiflash
 	if c.TiFlashDataReplicas > 0 {
-		_, err = tx.Exec("set @@session.tidb_isolation_read_engines='tiflash'")
-		if err != nil {
-			return errors.Trace(err)
+		if _, err = tx.ExecContext(ctx, "set @@session.tidb_isolation_read_engines='tiflash'"); err != nil {
+			return response{Error: err.Error()}
 		}
 
+		var total int64
 		readTiFlash := func() error {
-			err = tx.QueryRow(`
-select sum(balance) total from
-  (select account_id, max(causality_id) max_causality_id from ledger_accounts group by account_id) last
-  join ledger_accounts
-    on last.account_id = ledger_accounts.account_id and last.max_causality_id = ledger_accounts.causality_id`).Scan(&total)
-			if err != nil {
-				return errors.Trace(err)
-			}
-			return nil
+			return errors.Trace(tx.QueryRowContext(ctx, stmtTotal).Scan(&total))
 		}
-		err = util.RunWithRetry(ctx, retryLimit, time.Second, readTiFlash)
-		if err != nil {
-			return err
+		if err = util.RunWithRetry(ctx, retryLimit, time.Second, readTiFlash); err != nil {
+			return response{Error: err.Error()}
 		}
-		if total != 0 {
-			log.Errorf("check total balance tiflash got %v", total)
-			atomic.StoreInt32(&c.stop, 1)
-			c.wg.Wait()
-			log.Fatalf("check total balance tiflash got %v", total)
+		res.TiFlashTotal = &total
+
+		if _, err = tx.ExecContext(ctx, "set @@session.tidb_isolation_read_engines='tikv'"); err != nil {
+			return response{Error: err.Error()}
 		}
+	}
 
-		_, err = tx.Exec("set @@session.tidb_isolation_read_engines='tikv'")
-		if err != nil {
-			return err
+	res.Ok = true
+	log.Infof("verify read at %d, cost time: %v", res.ReadTS, time.Since(start))
+	return res
+}
+
+func queryInt64s(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var values []int64
+	for rows.Next() {
+		var v int64
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
 		}
+		values = append(values, v)
 	}
+	return values, rows.Err()
+}
 
-	log.Infof("verify ok, cost time: %v", time.Since(start))
-	return nil
+func queryStrings(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
 }
\ No newline at end of file
@@ -20,6 +20,7 @@ import (
 
 	// use mysql
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/ngaut/log"
 
 	logs "github.com/pingcap/tipocket/logsearch/pkg/logs"
 	test_infra "github.com/pingcap/tipocket/pkg/test-infra"
@@ -30,35 +31,47 @@ import (
 	"github.com/pingcap/tipocket/pkg/cluster"
 	"github.com/pingcap/tipocket/pkg/control"
 	"github.com/pingcap/tipocket/pkg/test-infra/fixture"
+	"github.com/pingcap/tipocket/pkg/verify"
 )
 
 var (
 	accounts            = flag.Int("accounts", 1000000, "the number of accounts")
 	interval            = flag.Duration("interval", 2*time.Second, "check interval")
-	concurrency         = flag.Int("concurrency", 200, "concurrency of worker")
 	txnMode             = flag.String("txn-mode", "pessimistic", "TiDB txn mode")
 	tiflashDataReplicas = flag.Int("tiflash-data-replicas", 0, "the number of the tiflash data replica")
+	concurrency         = flag.Int("concurrency", 0, "deprecated, use -client instead")
 )
 
 func main() {
 	flag.Parse()
+	if *concurrency > 0 {
+		log.Warnf("-concurrency is deprecated, use -client instead")
+		fixture.Context.ClientCount = *concurrency
+	}
+
 	cfg := control.Config{
-		Mode:        control.ModeStandard,
-		ClientCount: 1,
-		RunTime:     fixture.Context.RunTime,
-		RunRound:    1,
+		Mode:         control.ModeOnSchedule,
+		ClientCount:  fixture.Context.ClientCount,
+		RequestCount: fixture.Context.RequestCount,
+		RunTime:      fixture.Context.RunTime,
+		RunRound:     fixture.Context.RunRound,
+		History:      fixture.Context.HistoryFile,
 	}
 	suit := util.Suit{
 		Config:   &cfg,
 		Provider: cluster.NewDefaultClusterProvider(),
-		ClientCreator: ledger.ClientCreator{Cfg: &ledger.Config{
+		ClientCreator: &ledger.ClientCreator{Cfg: &ledger.Config{
 			NumAccounts:         *accounts,
-			Concurrency:         *concurrency,
 			Interval:            *interval,
 			TxnMode:             *txnMode,
 			TiFlashDataReplicas: *tiflashDataReplicas,
 		}},
-		NemesisGens: util.ParseNemesisGenerators(fixture.Context.Nemesis),
+		NemesisGens:      util.ParseNemesisGenerators(fixture.Context.Nemesis),
+		ClientRequestGen: util.OnClientLoop,
+		VerifySuit: verify.Suit{
+			Checker: ledger.Checker(),
+			Parser:  ledger.Parser(),
+		},
 		ClusterDefs: test_infra.NewDefaultCluster(fixture.Context.Namespace, fixture.Context.ClusterName,
 			fixture.Context.TiDBClusterConfig),
 		LogsClient: logs.NewDiagnosticLogClient(),
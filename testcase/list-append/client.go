@@ -1,14 +1,11 @@
 package listappend
 
 import (
-	"bufio"
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"log"
 	"math/rand"
-	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -21,19 +18,6 @@ import (
 	elletxn "github.com/pingcap/tipocket/pkg/elle/txn"
 )
 
-type appendResponse struct {
-	Result ellecore.Op `json:result`
-}
-
-func (c appendResponse) String() string {
-	return c.Result.String()
-}
-
-// IsUnknown always returns false because we don't want to let it be resorted
-func (c appendResponse) IsUnknown() bool {
-	return false
-}
-
 type client struct {
 	tableCount  int
 	useIndex    bool
@@ -95,7 +79,7 @@ func (c *client) Invoke(ctx context.Context, node cluster.ClientNode, r interfac
 	request := r.(ellecore.Op)
 	txn, err := c.db.Begin()
 	if err != nil {
-		return appendResponse{
+		return elleappend.Response{
 			Result: ellecore.Op{
 				Time:  time.Now(),
 				Type:  ellecore.OpTypeFail,
@@ -116,7 +100,7 @@ func (c *client) Invoke(ctx context.Context, node cluster.ClientNode, r interfac
 				table), k, k, v, v)
 			if err != nil {
 				_ = txn.Rollback()
-				return appendResponse{
+				return elleappend.Response{
 					Result: ellecore.Op{
 						Time:  time.Now(),
 						Type:  ellecore.OpTypeFail,
@@ -143,7 +127,7 @@ func (c *client) Invoke(ctx context.Context, node cluster.ClientNode, r interfac
 			rows, err := txn.QueryContext(ctx, query, k)
 			if err != nil {
 				_ = txn.Rollback()
-				return appendResponse{
+				return elleappend.Response{
 					Result: ellecore.Op{
 						Time:  time.Now(),
 						Type:  ellecore.OpTypeFail,
@@ -156,7 +140,7 @@ func (c *client) Invoke(ctx context.Context, node cluster.ClientNode, r interfac
 			if rows.Next() {
 				if err := rows.Scan(&value); err != nil {
 					rows.Close()
-					return appendResponse{
+					return elleappend.Response{
 						Result: ellecore.Op{
 							Time:  time.Now(),
 							Type:  ellecore.OpTypeFail,
@@ -185,7 +169,7 @@ func (c *client) Invoke(ctx context.Context, node cluster.ClientNode, r interfac
 		if strings.Contains(err.Error(), "invalid connection") {
 			tp = ellecore.OpTypeUnknown
 		}
-		return appendResponse{
+		return elleappend.Response{
 			Result: ellecore.Op{
 				Time:  time.Now(),
 				Type:  tp,
@@ -195,7 +179,7 @@ func (c *client) Invoke(ctx context.Context, node cluster.ClientNode, r interfac
 		}
 	}
 
-	return appendResponse{
+	return elleappend.Response{
 		Result: ellecore.Op{
 			Time:  time.Now(),
 			Type:  ellecore.OpTypeOk,
@@ -270,56 +254,11 @@ func (a *appendClientCreator) Create(_ cluster.ClientNode) core.Client {
 	}
 }
 
-// AppendParser ...
-type AppendParser struct{}
-
-// OnRequest ...
-func (a AppendParser) OnRequest(data json.RawMessage) (interface{}, error) {
-	r := ellecore.Op{}
-	str := string(data)
-	_ = str
-	err := json.Unmarshal(data, &r)
-	return r, err
-}
-
-// OnResponse ...
-func (a AppendParser) OnResponse(data json.RawMessage) (interface{}, error) {
-	r := appendResponse{}
-	err := json.Unmarshal(data, &r)
-	return r, err
-}
+// AppendParser parses the history of list append
+type AppendParser = elleappend.Parser
 
-// OnNoopResponse ...
-func (a AppendParser) OnNoopResponse() interface{} {
-	panic("unreachable")
-}
-
-// OnState ...
-func (a AppendParser) OnState(state json.RawMessage) (interface{}, error) {
-	return nil, nil
-}
-
-// AppendChecker ...
-type AppendChecker struct{}
-
-// Check ...
-func (a AppendChecker) Check(_ core.Model, ops []core.Operation) (bool, error) {
-	history := convertOperationsToHistory(ops)
-	_ = writeEdnHistory(history)
-
-	result := elleappend.Check(
-		elletxn.Opts{Anomalies: []string{"G-single"}},
-		history)
-	if result.Valid {
-		return true, nil
-	}
-	return false, result
-}
-
-// Name ...
-func (a AppendChecker) Name() string {
-	return "list_append"
-}
+// AppendChecker checks the history of list append
+type AppendChecker = elleappend.Checker
 
 func mustAtoi(s string) int {
 	i, err := strconv.ParseInt(s, 10, 64)
@@ -328,55 +267,3 @@ func mustAtoi(s string) int {
 	}
 	return int(i)
 }
-
-func convertOperationsToHistory(events []core.Operation) ellecore.History {
-	var history ellecore.History
-	for _, event := range events {
-		var op ellecore.Op
-		switch e := event.Data.(type) {
-		case ellecore.Op:
-			op = e
-			op.Process.Set(int(event.Proc))
-		case appendResponse:
-			op = e.Result
-			op.Process.Set(int(event.Proc))
-		default:
-			panic("unreachable")
-		}
-		mops := op.Value
-		typedMops := make([]ellecore.Mop, 0)
-		for _, mop := range *mops {
-			if mop.IsRead() {
-				var value []int
-				if mop.GetValue() != nil {
-					for _, v := range mop.GetValue().([]interface{}) {
-						value = append(value, int(v.(float64)))
-					}
-					typedMops = append(typedMops, ellecore.Read(mop.GetKey(), value))
-				} else {
-					typedMops = append(typedMops, ellecore.Read(mop.GetKey(), nil))
-				}
-			}
-			if mop.IsAppend() {
-				typedMops = append(typedMops, ellecore.Append(mop.GetKey(), int(mop.GetValue().(float64))))
-			}
-		}
-		op.Value = &typedMops
-		history = append(history, op)
-	}
-	return history
-}
-
-func writeEdnHistory(history ellecore.History) error {
-	history.AttachIndexIfNoExists()
-	f, err := os.Create("history.edn")
-	if err != nil {
-		return err
-	}
-	w := bufio.NewWriter(f)
-	for _, op := range history {
-		w.WriteString(op.String())
-		w.WriteString("\n")
-	}
-	return w.Flush()
-}
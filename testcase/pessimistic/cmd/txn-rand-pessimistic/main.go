@@ -47,6 +47,7 @@ var (
 	ignoreO            = flag.String("ignore-o", "9007,1105", "ignored error code for optimistic transaction, separated by comma")
 	ignoreP            = flag.String("ignore-p", "1213", "ignored error code for pessimistic transaction, separated by comma")
 	insertDelete       = flag.Bool("insert-delete", false, "run insert delete transactions")
+	lockCheck          = flag.Bool("lock-check", true, "record the random transactions and check the pessimistic lock semantics")
 
 	hongbaoDBName      = flag.String("hongbao-db-name", "hongbao", "database name for hongbao case")
 	userNum            = flag.Int("user-num", 1000, "number of users in total")
@@ -77,6 +78,10 @@ func main() {
 	if err != nil {
 		log.Fatalf("[%s] parse argment error: %v", caseName, err)
 	}
+	var history string
+	if *lockCheck {
+		history = fixture.Context.HistoryFile
+	}
 	suit := util.Suit{
 		Config:   &cfg,
 		Provider: cluster.NewDefaultClusterProvider(),
@@ -92,6 +97,7 @@ func main() {
 				IgnoreCodesO:   ignoreCodesO,
 				IgnoreCodesP:   ignoreCodesP,
 				UsePrepareStmt: *prepareStmt,
+				History:        history,
 			},
 			HongbaoClientConfig: hongbao.ClientConfig{
 				DBName:         *hongbaoDBName,
@@ -0,0 +1,471 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ngaut/log"
+
+	"github.com/pingcap/tipocket/pkg/core"
+)
+
+const (
+	// maxReportedViolations limits the violations in the check error
+	maxReportedViolations = 10
+	// clockSlack is the allowed difference between the clock of the client and the physical time of TSO
+	clockSlack = time.Second
+	// unknownOutcomeWindow is how long a transaction with an unknown outcome may be committed after it,
+	// the rows it writes aren't checked until then
+	unknownOutcomeWindow = time.Minute
+	// longWait is the statement duration which is counted as a long lock wait
+	longWait = time.Second
+)
+
+// txn is a transaction of the history with its real time interval
+type txn struct {
+	begin time.Time
+	end   time.Time
+	resp  txnResponse
+}
+
+func (t *txn) String() string {
+	return fmt.Sprintf("session %d (outcome %s, commit_ts %d)", t.resp.Session, t.resp.Outcome, t.resp.CommitTS)
+}
+
+// version is a committed value of a row, which is the latest one since start
+type version struct {
+	start time.Time
+	value int64
+	known bool
+}
+
+// rowState is the value of a row when replaying the committed transactions in the order of commit_ts
+type rowState struct {
+	value int64
+	known bool
+	// unknownUntil is the time before which the row may be written by a transaction with an unknown effect
+	unknownUntil time.Time
+	// uncertain are the commit start times of the writes with unknown effects, sorted
+	uncertain []time.Time
+	next      int
+	versions  []version
+}
+
+// advance makes the row unknown if an uncertain write may be committed before p
+func (s *rowState) advance(p time.Time) {
+	for ; s.next < len(s.uncertain) && !s.uncertain[s.next].After(p.Add(clockSlack)); s.next++ {
+		s.known = false
+		if until := s.uncertain[s.next].Add(unknownOutcomeWindow); until.After(s.unknownUntil) {
+			s.unknownUntil = until
+		}
+	}
+}
+
+// localValue is the value of a row in a transaction after its own writes
+type localValue struct {
+	value int64
+	known bool
+}
+
+type checker struct {
+	tableSize uint64
+}
+
+// Checker checks FOR UPDATE reads the latest committed value, no update of the lock holders is lost,
+// and every deadlock error is a real wait-for cycle among the transactions
+func Checker(tableSize uint64) core.Checker {
+	return checker{tableSize: tableSize}
+}
+
+// Name implements core.Checker
+func (checker) Name() string {
+	return "pessimistic_lock_checker"
+}
+
+// Check implements core.Checker
+func (c checker) Check(_ core.Model, ops []core.Operation) (bool, error) {
+	txns, err := parseTxns(ops)
+	if err != nil {
+		return false, err
+	}
+	rows := c.buildRows(txns)
+	violations := c.checkChain(txns, rows)
+	violations = append(violations, c.checkLockReads(txns, rows)...)
+	violations = append(violations, checkDeadlocks(txns)...)
+	logWaits(txns)
+
+	if len(violations) == 0 {
+		log.Infof("[pessimistic] %d transactions are checked", len(txns))
+		return true, nil
+	}
+	for _, v := range violations {
+		log.Errorf("[pessimistic] %s", v)
+	}
+	if len(violations) > maxReportedViolations {
+		violations = violations[:maxReportedViolations]
+	}
+	return false, fmt.Errorf("pessimistic lock violations:\n%s", strings.Join(violations, "\n"))
+}
+
+// parseTxns pairs the requests and responses of each session
+func parseTxns(ops []core.Operation) ([]*txn, error) {
+	begins := make(map[int64]time.Time)
+	var txns []*txn
+	for _, op := range ops {
+		switch op.Action {
+		case core.InvokeOperation:
+			begins[op.Proc] = op.Time
+		case core.ReturnOperation:
+			resp, ok := op.Data.(txnResponse)
+			if !ok {
+				return nil, fmt.Errorf("unexpected response %v of proc %d", op.Data, op.Proc)
+			}
+			txns = append(txns, &txn{begin: begins[op.Proc], end: op.Time, resp: resp})
+		}
+	}
+	return txns, nil
+}
+
+// uncertainEffect returns whether the writes of the transaction may or may not take effect at an unknown commit_ts
+func uncertainEffect(t *txn) bool {
+	return t.resp.Outcome == outcomeUnknown || (t.resp.Outcome == outcomeCommitted && t.resp.CommitTS == 0)
+}
+
+// buildRows collects the uncertain writes of each row
+func (c checker) buildRows(txns []*txn) map[string]*rowState {
+	rows := make(map[string]*rowState)
+	for _, t := range txns {
+		if t.resp.Final || !uncertainEffect(t) {
+			continue
+		}
+		start := t.resp.CommitStart
+		if start.IsZero() {
+			start = t.begin
+		}
+		for _, stmt := range t.resp.Stmts {
+			if stmt.Write == nil || stmt.Error != "" {
+				continue
+			}
+			for _, row := range stmt.Rows {
+				if row < c.tableSize {
+					s := getRow(rows, rowKey(stmt.Table, row))
+					s.uncertain = append(s.uncertain, start)
+				}
+			}
+		}
+	}
+	for _, s := range rows {
+		sort.Slice(s.uncertain, func(i, j int) bool { return s.uncertain[i].Before(s.uncertain[j]) })
+	}
+	return rows
+}
+
+// getRow returns the state of the row, all the rows are 0 at first
+func getRow(rows map[string]*rowState, key string) *rowState {
+	s, ok := rows[key]
+	if !ok {
+		s = &rowState{known: true, versions: []version{{known: true}}}
+		rows[key] = s
+	}
+	return s
+}
+
+// tsTime returns the physical time of a TSO
+func tsTime(ts uint64) time.Time {
+	ms := int64(ts >> 18)
+	return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond))
+}
+
+// checkChain replays the committed transactions in the order of commit_ts, every lock read of a pessimistic transaction
+// must return the value written by the previous ones, which also means no update is lost. The final reads are checked
+// against the values after all of them.
+func (c checker) checkChain(txns []*txn, rows map[string]*rowState) []string {
+	var (
+		committed  []*txn
+		final      *txn
+		violations []string
+	)
+	for _, t := range txns {
+		if t.resp.Final {
+			final = t
+		} else if t.resp.Outcome == outcomeCommitted && t.resp.CommitTS != 0 {
+			committed = append(committed, t)
+		}
+	}
+	sort.Slice(committed, func(i, j int) bool { return committed[i].resp.CommitTS < committed[j].resp.CommitTS })
+	for _, t := range committed {
+		violations = append(violations, c.replay(t, tsTime(t.resp.CommitTS), rows)...)
+	}
+	if final != nil {
+		violations = append(violations, c.replay(final, final.begin, rows)...)
+		for _, stmt := range final.resp.Stmts {
+			for row := uint64(0); row < c.tableSize; row++ {
+				if _, ok := stmt.Values[row]; !ok {
+					violations = append(violations, fmt.Sprintf("row %s is missing in the final read", rowKey(stmt.Table, row)))
+				}
+			}
+		}
+	}
+	return violations
+}
+
+// replay applies the transaction committed at p to the rows
+func (c checker) replay(t *txn, p time.Time, rows map[string]*rowState) []string {
+	var violations []string
+	// the lock reads of optimistic transactions are not the latest, but their writes are still applied
+	checkRead := t.resp.Pessimistic || t.resp.Final
+	local := make(map[string]*localValue)
+	for _, stmt := range t.resp.Stmts {
+		if stmt.LockRead || t.resp.Final {
+			for row, value := range stmt.Values {
+				if row >= c.tableSize {
+					continue
+				}
+				key := rowKey(stmt.Table, row)
+				if l, ok := local[key]; ok {
+					if l.known && l.value != value {
+						violations = append(violations, fmt.Sprintf("%s %s read c = %d of row %s after its own writes, expect %d",
+							t, stmt.Name, value, key, l.value))
+					}
+					continue
+				}
+				s := getRow(rows, key)
+				s.advance(p)
+				if !s.known {
+					// the row is known again since the uncertain writes can't be committed later
+					if p.After(s.unknownUntil) {
+						s.value, s.known = value, true
+					}
+					continue
+				}
+				if checkRead && s.value != value {
+					violations = append(violations, fmt.Sprintf("%s %s read c = %d of row %s, expect %d",
+						t, stmt.Name, value, key, s.value))
+				}
+			}
+		}
+		if stmt.Write == nil {
+			continue
+		}
+		for _, row := range stmt.Rows {
+			if row >= c.tableSize {
+				continue
+			}
+			key := rowKey(stmt.Table, row)
+			l, ok := local[key]
+			if !ok {
+				s := getRow(rows, key)
+				s.advance(p)
+				l = &localValue{value: s.value, known: s.known}
+				local[key] = l
+			}
+			if stmt.Write.Set {
+				l.value = stmt.Write.Value
+			} else {
+				l.value += stmt.Write.Delta
+			}
+			if stmt.Uncertain {
+				l.known = false
+			}
+		}
+	}
+	for key, l := range local {
+		s := rows[key]
+		s.value, s.known = l.value, l.known
+		if !l.known && p.After(s.unknownUntil) {
+			s.unknownUntil = p
+		}
+		s.versions = append(s.versions, version{start: p, value: l.value, known: l.known})
+	}
+	return violations
+}
+
+// checkLockReads checks the lock reads of the pessimistic transactions which aren't committed, they must return
+// a version which is the latest one during the statement
+func (c checker) checkLockReads(txns []*txn, rows map[string]*rowState) []string {
+	var violations []string
+	for _, t := range txns {
+		if t.resp.Final || !t.resp.Pessimistic || (t.resp.Outcome == outcomeCommitted && t.resp.CommitTS != 0) {
+			continue
+		}
+		written := make(map[string]struct{})
+		for _, stmt := range t.resp.Stmts {
+			if stmt.LockRead && stmt.Error == "" {
+				for row, value := range stmt.Values {
+					key := rowKey(stmt.Table, row)
+					if _, ok := written[key]; ok || row >= c.tableSize {
+						continue
+					}
+					if !readable(getRow(rows, key), stmt.Start, stmt.End, value) {
+						violations = append(violations, fmt.Sprintf("%s %s read c = %d of row %s in [%s, %s], which is never the latest value",
+							t, stmt.Name, value, key, stmt.Start.Format(time.RFC3339Nano), stmt.End.Format(time.RFC3339Nano)))
+					}
+				}
+			}
+			if stmt.Write != nil {
+				for _, row := range stmt.Rows {
+					written[rowKey(stmt.Table, row)] = struct{}{}
+				}
+			}
+		}
+	}
+	return violations
+}
+
+// readable returns whether the value may be the latest committed value in [start, end]
+func readable(s *rowState, start, end time.Time, value int64) bool {
+	for _, u := range s.uncertain {
+		if !u.After(end.Add(clockSlack)) {
+			return true
+		}
+	}
+	for i, v := range s.versions {
+		if v.start.After(end.Add(clockSlack)) {
+			break
+		}
+		if i+1 < len(s.versions) && s.versions[i+1].start.Before(start.Add(-clockSlack)) {
+			continue
+		}
+		if !v.known || v.value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDeadlocks checks there is a wait-for cycle through every statement which fails with a deadlock error.
+// During the statement, a transaction waits for the locks of its statements running in it,
+// and holds the locks of the statements finished before they start.
+func checkDeadlocks(txns []*txn) []string {
+	var violations []string
+	for _, t := range txns {
+		for i := range t.resp.Stmts {
+			stmt := &t.resp.Stmts[i]
+			if stmt.Code != deadlockErrorCode {
+				continue
+			}
+			if !inWaitForCycle(t, txns, stmt.Start, stmt.End) {
+				violations = append(violations, fmt.Sprintf("%s %s got a deadlock error in [%s, %s], but there is no wait-for cycle",
+					t, stmt.Name, stmt.Start.Format(time.RFC3339Nano), stmt.End.Format(time.RFC3339Nano)))
+			}
+		}
+	}
+	return violations
+}
+
+func inWaitForCycle(target *txn, txns []*txn, start, end time.Time) bool {
+	type lockSet struct {
+		held    map[string]struct{}
+		waiting map[string]struct{}
+	}
+	var (
+		active []*txn
+		locks  []lockSet
+		self   = -1
+	)
+	for _, t := range txns {
+		if t.resp.Final || !t.resp.Pessimistic || !t.begin.Before(end) || t.end.Before(start) {
+			continue
+		}
+		ls := lockSet{held: make(map[string]struct{}), waiting: make(map[string]struct{})}
+		// the statements running during [start, end] wait for their locks,
+		// only the statements finished before they start hold the locks
+		waitStart := end
+		for _, stmt := range t.resp.Stmts {
+			if !stmt.Start.Before(end) || !stmt.End.After(start) {
+				continue
+			}
+			if stmt.Start.Before(waitStart) {
+				waitStart = stmt.Start
+			}
+			for _, key := range stmt.lockKeys() {
+				ls.waiting[key] = struct{}{}
+			}
+		}
+		for _, stmt := range t.resp.Stmts {
+			if stmt.End.After(waitStart) {
+				continue
+			}
+			for _, key := range stmt.lockKeys() {
+				ls.held[key] = struct{}{}
+			}
+		}
+		if t == target {
+			self = len(active)
+		}
+		active = append(active, t)
+		locks = append(locks, ls)
+	}
+	if self < 0 {
+		return false
+	}
+	waitsFor := func(i, j int) bool {
+		for key := range locks[i].waiting {
+			if _, ok := locks[j].held[key]; ok {
+				return true
+			}
+		}
+		return false
+	}
+	// search a path back to the target
+	visited := make([]bool, len(active))
+	stack := []int{self}
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for j := range active {
+			if j == i || !waitsFor(i, j) {
+				continue
+			}
+			if j == self {
+				return true
+			}
+			if !visited[j] {
+				visited[j] = true
+				stack = append(stack, j)
+			}
+		}
+	}
+	return false
+}
+
+// logWaits logs the statistics of the lock waits
+func logWaits(txns []*txn) {
+	var (
+		stmts, long int
+		max         time.Duration
+		maxName     string
+	)
+	for _, t := range txns {
+		if t.resp.Final || !t.resp.Pessimistic {
+			continue
+		}
+		for _, stmt := range t.resp.Stmts {
+			wait := stmt.End.Sub(stmt.Start)
+			stmts++
+			if wait >= longWait {
+				long++
+			}
+			if wait > max {
+				max, maxName = wait, stmt.Name
+			}
+		}
+	}
+	log.Infof("[pessimistic] %d statements of pessimistic transactions, %d of them wait over %s, the longest is %s %s",
+		stmts, long, longWait, maxName, max)
+}
@@ -0,0 +1,151 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pingcap/tipocket/pkg/core"
+)
+
+var base = time.Unix(1600000000, 0)
+
+func at(sec int) time.Time {
+	return base.Add(time.Duration(sec) * time.Second)
+}
+
+func tsAt(sec int) uint64 {
+	return uint64(at(sec).UnixNano()/int64(time.Millisecond)) << 18
+}
+
+func lockRead(row uint64, value int64, start, end int) stmtRecord {
+	return stmtRecord{Name: "select_for_update", Rows: []uint64{row}, LockRead: true,
+		Values: map[uint64]int64{row: value}, Start: at(start), End: at(end)}
+}
+
+func add(row uint64, delta int64, start, end int) stmtRecord {
+	return stmtRecord{Name: "update", Rows: []uint64{row}, Write: &writeEffect{Delta: delta}, Start: at(start), End: at(end)}
+}
+
+func committed(session uint64, commit int, stmts ...stmtRecord) txnResponse {
+	return txnResponse{Session: session, Pessimistic: true, Stmts: stmts, Outcome: outcomeCommitted, CommitTS: tsAt(commit)}
+}
+
+func finalRead(values ...int64) txnResponse {
+	stmt := stmtRecord{Name: "final_read", Values: make(map[uint64]int64)}
+	for row, v := range values {
+		stmt.Values[uint64(row)] = v
+	}
+	return txnResponse{Outcome: outcomeCommitted, Final: true, Stmts: []stmtRecord{stmt}}
+}
+
+// runTxns runs the transactions one by one in the order of the responses
+func runTxns(resps ...txnResponse) []core.Operation {
+	var ops []core.Operation
+	for i, resp := range resps {
+		ops = append(ops,
+			core.Operation{Action: core.InvokeOperation, Proc: int64(resp.Session), Data: txnRequest{}, Time: at(i * 10)},
+			core.Operation{Action: core.ReturnOperation, Proc: int64(resp.Session), Data: resp, Time: at(i*10 + 5)})
+	}
+	return ops
+}
+
+func TestCheckValueChain(t *testing.T) {
+	c := Checker(2)
+	ops := runTxns(
+		committed(0, 5, lockRead(0, 0, 1, 2), add(0, -1, 2, 3), add(1, 1, 3, 4)),
+		committed(1, 15, lockRead(1, 1, 11, 12), add(1, -1, 12, 13), lockRead(1, 0, 13, 14)),
+		// an aborted transaction reads the latest value
+		txnResponse{Session: 2, Pessimistic: true, Stmts: []stmtRecord{lockRead(0, -1, 21, 22)}, Outcome: outcomeAborted},
+		finalRead(-1, 0),
+	)
+	if ok, err := c.Check(nil, ops); !ok || err != nil {
+		t.Fatalf("expect ok, got %v %v", ok, err)
+	}
+
+	// the update of session 1 is lost
+	ops = runTxns(
+		committed(0, 5, add(0, 1, 1, 2)),
+		committed(1, 15, add(0, 1, 11, 12)),
+		finalRead(1, 0),
+	)
+	if ok, _ := c.Check(nil, ops); ok {
+		t.Fatal("expect the lost update is found")
+	}
+
+	// the lock read doesn't return the latest committed value
+	ops = runTxns(
+		committed(0, 5, add(0, 1, 1, 2)),
+		committed(1, 15, lockRead(0, 0, 11, 12), add(0, 1, 12, 13)),
+		finalRead(2, 0),
+	)
+	if ok, _ := c.Check(nil, ops); ok {
+		t.Fatal("expect the stale lock read is found")
+	}
+
+	// the row written by a transaction with an unknown outcome isn't checked
+	ops = runTxns(
+		txnResponse{Session: 0, Pessimistic: true, Stmts: []stmtRecord{add(0, 1, 1, 2)}, Outcome: outcomeUnknown, CommitStart: at(3)},
+		finalRead(1, 0),
+	)
+	if ok, err := c.Check(nil, ops); !ok || err != nil {
+		t.Fatalf("expect ok, got %v %v", ok, err)
+	}
+}
+
+func TestCheckDeadlocks(t *testing.T) {
+	c := Checker(2)
+	deadlock := func(row uint64, start, end int) stmtRecord {
+		return stmtRecord{Name: "update", Rows: []uint64{row}, Write: &writeEffect{Delta: 1},
+			Start: at(start), End: at(end), Error: "deadlock", Code: deadlockErrorCode}
+	}
+	aborted := func(session uint64, stmts ...stmtRecord) txnResponse {
+		return txnResponse{Session: session, Pessimistic: true, Stmts: stmts, Outcome: outcomeAborted}
+	}
+	// session 0 locks row 0 and waits for row 1, session 1 locks row 1 and waits for row 0
+	ops := []core.Operation{
+		{Action: core.InvokeOperation, Proc: 0, Data: txnRequest{}, Time: at(0)},
+		{Action: core.InvokeOperation, Proc: 1, Data: txnRequest{}, Time: at(0)},
+		{Action: core.ReturnOperation, Proc: 0, Data: aborted(0, add(0, 1, 1, 2), deadlock(1, 3, 5)), Time: at(6)},
+		{Action: core.ReturnOperation, Proc: 1, Data: committed(1, 7, add(1, 1, 1, 2), add(0, 1, 3, 6)), Time: at(7)},
+	}
+	if ok, err := c.Check(nil, ops); !ok || err != nil {
+		t.Fatalf("expect ok, got %v %v", ok, err)
+	}
+
+	// session 1 never waits for session 0
+	ops = []core.Operation{
+		{Action: core.InvokeOperation, Proc: 0, Data: txnRequest{}, Time: at(0)},
+		{Action: core.InvokeOperation, Proc: 1, Data: txnRequest{}, Time: at(0)},
+		{Action: core.ReturnOperation, Proc: 0, Data: aborted(0, add(0, 1, 1, 2), deadlock(1, 3, 5)), Time: at(6)},
+		{Action: core.ReturnOperation, Proc: 1, Data: committed(1, 7, add(1, 1, 1, 2)), Time: at(7)},
+	}
+	if ok, _ := c.Check(nil, ops); ok {
+		t.Fatal("expect the fake deadlock is found")
+	}
+
+	// session 0 and session 1 both wait for row 0 held by session 2, neither holds a lock the other waits for
+	ops = []core.Operation{
+		{Action: core.InvokeOperation, Proc: 0, Data: txnRequest{}, Time: at(0)},
+		{Action: core.InvokeOperation, Proc: 1, Data: txnRequest{}, Time: at(0)},
+		{Action: core.InvokeOperation, Proc: 2, Data: txnRequest{}, Time: at(0)},
+		{Action: core.ReturnOperation, Proc: 0, Data: aborted(0, deadlock(0, 3, 5)), Time: at(6)},
+		{Action: core.ReturnOperation, Proc: 2, Data: committed(2, 6, add(0, 1, 1, 2)), Time: at(7)},
+		{Action: core.ReturnOperation, Proc: 1, Data: committed(1, 8, add(0, 1, 3, 7)), Time: at(8)},
+	}
+	if ok, _ := c.Check(nil, ops); ok {
+		t.Fatal("expect the fake deadlock of the queued waiters is found")
+	}
+}
@@ -122,24 +122,26 @@ func (c *pessimisticClient) TearDown(ctx context.Context, nodes []cluster.Client
 }
 
 func (c *pessimisticClient) Start(ctx context.Context, cfg interface{}, clientNodes []cluster.ClientNode) error {
-	ch := make(chan error)
+	randTxnCh := make(chan error, 1)
+	hongbaoCh := make(chan error, 1)
 
 	go func() {
-		ch <- c.PessimisticClient.Execute(ctx, c.randTxnDB)
+		randTxnCh <- c.PessimisticClient.Execute(ctx, c.randTxnDB)
 	}()
 
 	go func() {
-		ch <- c.HongbaoClient.Execute(ctx, c.hongbaoDB)
+		hongbaoCh <- c.HongbaoClient.Execute(ctx, c.hongbaoDB)
 	}()
 
+	var err error
 	select {
 	case <-ctx.Done():
-		return nil
-	case err := <-ch:
-		if err != nil {
-			return errors.Trace(err)
+		// the random transactions stop on ctx and check the history then
+		if c.cfg.PessimisticClientConfig.History != "" {
+			err = <-randTxnCh
 		}
+	case err = <-randTxnCh:
+	case err = <-hongbaoCh:
 	}
-
-	return nil
+	return errors.Trace(err)
 }
\ No newline at end of file
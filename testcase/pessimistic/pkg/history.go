@@ -0,0 +1,146 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pingcap/tipocket/pkg/history"
+)
+
+// The outcomes of a transaction
+const (
+	outcomeCommitted = "committed"
+	outcomeAborted   = "aborted"
+	// outcomeUnknown means the commit failed with an error which doesn't tell whether it's committed
+	outcomeUnknown = "unknown"
+)
+
+const deadlockErrorCode = 1213
+
+// abortedCommitCodes are the commit errors which mean the transaction is rolled back
+var abortedCommitCodes = map[int]struct{}{
+	// write conflict
+	9007: {},
+	// can't retry the optimistic transaction
+	8002: {},
+	// transaction too large
+	8004: {},
+	// entry too large
+	8025: {},
+	// information schema is changed
+	8028:              {},
+	deadlockErrorCode: {},
+}
+
+// writeEffect is how a statement changes the c column of its rows, c = Value if Set, otherwise c = c + Delta
+type writeEffect struct {
+	Set   bool  `json:"set,omitempty"`
+	Value int64 `json:"value,omitempty"`
+	Delta int64 `json:"delta,omitempty"`
+}
+
+// stmtRecord is a statement of a transaction, the statements are recorded in the order of execution,
+// so a pessimistic transaction acquires its locks in the order of the statements
+type stmtRecord struct {
+	Name  string `json:"name"`
+	Table int    `json:"table"`
+	// Rows are the rows the statement locks, Keys are the other keys it locks, like unique index entries
+	Rows []uint64 `json:"rows,omitempty"`
+	Keys []string `json:"keys,omitempty"`
+	// LockRead means a SELECT ... FOR UPDATE, Values are the c columns it read by row
+	LockRead bool             `json:"lock_read,omitempty"`
+	Values   map[uint64]int64 `json:"values,omitempty"`
+	Write    *writeEffect     `json:"write,omitempty"`
+	// Uncertain means the affected rows aren't as expected, so the effect of the write is unknown
+	Uncertain bool `json:"uncertain,omitempty"`
+	// Start and End are when the statement is sent and returned, the time between them is waiting for the locks
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Error string    `json:"error,omitempty"`
+	Code  int       `json:"code,omitempty"`
+
+	// expectAffected is the expected affected rows of the write
+	expectAffected int64
+}
+
+// lockKeys returns the keys locked by the statement
+func (s *stmtRecord) lockKeys() []string {
+	keys := make([]string, 0, len(s.Rows)+len(s.Keys))
+	for _, row := range s.Rows {
+		keys = append(keys, rowKey(s.Table, row))
+	}
+	for _, key := range s.Keys {
+		keys = append(keys, fmt.Sprintf("%d/%s", s.Table, key))
+	}
+	return keys
+}
+
+func rowKey(table int, row uint64) string {
+	return fmt.Sprintf("%d/%d", table, row)
+}
+
+// txnRequest is the begin of a transaction
+type txnRequest struct {
+	Pessimistic bool `json:"pessimistic"`
+}
+
+// txnResponse is a finished transaction with all its statements
+type txnResponse struct {
+	Session     uint64       `json:"session"`
+	Pessimistic bool         `json:"pessimistic"`
+	Stmts       []stmtRecord `json:"stmts"`
+	Outcome     string       `json:"outcome"`
+	Error       string       `json:"error,omitempty"`
+	Code        int          `json:"code,omitempty"`
+	CommitStart time.Time    `json:"commit_start"`
+	CommitTS    uint64       `json:"commit_ts,omitempty"`
+	// Final means the reads of all the rows after the test
+	Final bool `json:"final,omitempty"`
+}
+
+// IsUnknown implements core.UnknownResponse, the checker handles the unknown outcomes itself
+func (t txnResponse) IsUnknown() bool {
+	return false
+}
+
+// txnParser implements history.RecordParser
+type txnParser struct{}
+
+// Parser parses a history of the random transactions
+func Parser() history.RecordParser {
+	return txnParser{}
+}
+
+func (txnParser) OnRequest(data json.RawMessage) (interface{}, error) {
+	r := txnRequest{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+func (txnParser) OnResponse(data json.RawMessage) (interface{}, error) {
+	r := txnResponse{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+func (txnParser) OnNoopResponse() interface{} {
+	return txnResponse{Outcome: outcomeUnknown}
+}
+
+func (txnParser) OnState(state json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
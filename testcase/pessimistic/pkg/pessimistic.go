@@ -2,6 +2,7 @@ package pkg
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"sync"
@@ -10,6 +11,9 @@ import (
 
 	"github.com/ngaut/log"
 	"golang.org/x/net/context"
+
+	"github.com/pingcap/tipocket/pkg/history"
+	"github.com/pingcap/tipocket/pkg/verify"
 )
 
 // Client is for pessimistic transaction test
@@ -17,6 +21,7 @@ type Client struct {
 	cfg        ClientConfig
 	successTxn uint64
 	failTxn    uint64
+	recorder   *history.Recorder
 }
 
 // ClientConfig is for pessimistic test case.
@@ -31,6 +36,8 @@ type ClientConfig struct {
 	IgnoreCodesO   []int  `toml:"ignore_codes_o"`
 	IgnoreCodesP   []int  `toml:"ignore_codes_p"`
 	UsePrepareStmt bool   `toml:"use_prepare_stmt"`
+	// History is the file to record the transactions and check the pessimistic lock semantics, no check if it's empty
+	History string `toml:"history"`
 }
 
 const (
@@ -77,6 +84,13 @@ func (c *Client) Execute(ctx context.Context, db *sql.DB) error {
 	defer func() {
 		log.Infof("[%s] test end...", c)
 	}()
+	if c.cfg.History != "" && !c.cfg.InsertDelete {
+		recorder, err := history.NewRecorder(c.cfg.History)
+		if err != nil {
+			return err
+		}
+		c.recorder = recorder
+	}
 	wg := new(sync.WaitGroup)
 	wg.Add(c.cfg.Concurrency)
 	for i := 0; i < c.cfg.Concurrency; i++ {
@@ -84,16 +98,60 @@ func (c *Client) Execute(ctx context.Context, db *sql.DB) error {
 		if err != nil {
 			log.Fatal(err)
 		}
-		go se.Run(wg)
+		go se.Run(ctx, wg)
 	}
 	if !c.cfg.InsertDelete {
 		go c.checkLoop(db)
 	}
 	go c.statsLoop()
 	wg.Wait()
+	if c.recorder == nil {
+		return nil
+	}
+	if err := c.recordFinal(db); err != nil {
+		return err
+	}
+	c.recorder.Close()
+	verify.Suit{Checker: Checker(c.cfg.TableSize), Parser: Parser()}.Verify(c.cfg.History)
 	return nil
 }
 
+// recordFinal records the rows after all the transactions as a final transaction
+func (c *Client) recordFinal(db *sql.DB) error {
+	ctx := context.Background()
+	proc := int64(c.cfg.Concurrency)
+	if err := c.recorder.RecordRequest(proc, txnRequest{}); err != nil {
+		return err
+	}
+	txn := txnResponse{Session: uint64(proc), Outcome: outcomeCommitted, Final: true}
+	for i := 0; i < c.cfg.TableNum; i++ {
+		stmt := stmtRecord{Name: "final_read", Table: i, Values: make(map[uint64]int64), Start: time.Now()}
+		rows, err := db.QueryContext(ctx, fmt.Sprintf("select id, c from %s%d", tableNamePrefix, i))
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var (
+				id uint64
+				v  int64
+			)
+			if err := rows.Scan(&id, &v); err != nil {
+				rows.Close()
+				return err
+			}
+			stmt.Values[id] = v
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return err
+		}
+		rows.Close()
+		stmt.End = time.Now()
+		txn.Stmts = append(txn.Stmts, stmt)
+	}
+	return c.recorder.RecordResponse(proc, txn)
+}
+
 func (c *Client) String() string {
 	return caseName
 }
@@ -121,6 +179,8 @@ type Session struct {
 	stmtCache      map[string]*sql.Stmt
 	pCase          *Client
 	scores         map[int]int
+	// txn records the running transaction
+	txn txnResponse
 }
 
 // NewSession ...
@@ -183,8 +243,10 @@ func (se *Session) runTransaction(parent context.Context) error {
 	if se.isPessimistic {
 		beginSQL = "begin /*!90000 pessimistic */"
 	}
+	se.beginRecord()
 	_, err := se.conn.ExecContext(ctx, beginSQL)
 	if err != nil {
+		se.finishRecord(ctx, outcomeAborted, err)
 		return err
 	}
 	numStmts := 1 + se.ran.uniform.Intn(5)
@@ -194,19 +256,64 @@ func (se *Session) runTransaction(parent context.Context) error {
 		err = f(ctx)
 		if err != nil {
 			se.handleError(ctx, err, false)
+			se.finishRecord(ctx, outcomeAborted, err)
 			return nil
 		}
 	}
 	se.commitStart = time.Now()
+	se.txn.CommitStart = se.commitStart
 	_, err = se.conn.ExecContext(ctx, "commit")
 	if err != nil {
 		se.handleError(ctx, err, true)
+		outcome := outcomeUnknown
+		if _, ok := abortedCommitCodes[getErrorCode(err)]; ok {
+			outcome = outcomeAborted
+		}
+		se.finishRecord(ctx, outcome, err)
 	} else {
 		atomic.AddUint64(&se.pCase.successTxn, 1)
+		se.finishRecord(ctx, outcomeCommitted, nil)
 	}
 	return nil
 }
 
+// beginRecord records the begin of a transaction
+func (se *Session) beginRecord() {
+	se.txn = txnResponse{Session: se.seID, Pessimistic: se.isPessimistic}
+	if se.pCase.recorder == nil {
+		return
+	}
+	if err := se.pCase.recorder.RecordRequest(int64(se.seID), txnRequest{Pessimistic: se.isPessimistic}); err != nil {
+		log.Fatalf("[%s] record request failed %v", caseName, err)
+	}
+}
+
+// finishRecord records the transaction with its statements
+func (se *Session) finishRecord(ctx context.Context, outcome string, err error) {
+	if se.pCase.recorder == nil {
+		return
+	}
+	se.txn.Outcome = outcome
+	if err != nil {
+		se.txn.Error, se.txn.Code = err.Error(), getErrorCode(err)
+	}
+	if outcome == outcomeCommitted {
+		// the commit ts orders the committed transactions, it's 0 if it's unknown
+		var raw string
+		if err := se.conn.QueryRowContext(ctx, "select @@tidb_last_txn_info").Scan(&raw); err == nil {
+			var info struct {
+				CommitTS uint64 `json:"commit_ts"`
+			}
+			if json.Unmarshal([]byte(raw), &info) == nil {
+				se.txn.CommitTS = info.CommitTS
+			}
+		}
+	}
+	if err := se.pCase.recorder.RecordResponse(int64(se.seID), se.txn); err != nil {
+		log.Fatalf("[%s] record response failed %v", caseName, err)
+	}
+}
+
 func (se *Session) runInsertDeleteTransaction(parent context.Context) error {
 	se.reset()
 	ctx, cancel := context.WithTimeout(parent, time.Minute)
@@ -284,8 +391,8 @@ func (se *Session) handleError(ctx context.Context, err error, isCommit bool) {
 	}
 }
 
-// Run ...
-func (se *Session) Run(wg *sync.WaitGroup) {
+// Run runs the transactions until the operation count is reached or stopCtx is done
+func (se *Session) Run(stopCtx context.Context, wg *sync.WaitGroup) {
 	defer wg.Done()
 	ctx := context.Background()
 	runFunc := se.runTransaction
@@ -293,6 +400,11 @@ func (se *Session) Run(wg *sync.WaitGroup) {
 		runFunc = se.runInsertDeleteTransaction
 	}
 	for i := uint64(0); i < se.operationCount; i++ {
+		select {
+		case <-stopCtx.Done():
+			return
+		default:
+		}
 		if err := runFunc(ctx); err != nil {
 			log.Info("begin error", err)
 			return
@@ -300,9 +412,13 @@ func (se *Session) Run(wg *sync.WaitGroup) {
 	}
 }
 
-func (se *Session) executeDML(ctx context.Context, sqlFormat string, args ...interface{}) error {
+// executeDML executes the DML, the statement is recorded by rec if it's not nil
+func (se *Session) executeDML(ctx context.Context, rec *stmtRecord, sqlFormat string, args ...interface{}) (err error) {
+	if rec != nil {
+		rec.Start = time.Now()
+		defer func() { se.recordStmt(rec, err) }()
+	}
 	var res sql.Result
-	var err error
 	if se.pCase.cfg.UsePrepareStmt {
 		stmt, err := se.getAndCacheStmt(ctx, sqlFormat)
 		if err != nil {
@@ -322,6 +438,9 @@ func (se *Session) executeDML(ctx context.Context, sqlFormat string, args ...int
 	if err != nil {
 		return err
 	}
+	if rec != nil && rec.Write != nil && affected != rec.expectAffected {
+		rec.Uncertain = true
+	}
 	if affected == 0 {
 		msg := fmt.Sprintf("affected row is 0, sqlFormat: %s, %v", sqlFormat, args)
 		if len(msg) > 100 {
@@ -359,6 +478,77 @@ func (se *Session) executeSelect(ctx context.Context, sqlFormat string, args ...
 	return rows.Close()
 }
 
+// executeLockRead executes the SELECT ... FOR UPDATE and records the c columns of the rows
+func (se *Session) executeLockRead(ctx context.Context, rec *stmtRecord, sqlFormat string, args ...interface{}) (err error) {
+	rec.LockRead = true
+	rec.Values = make(map[uint64]int64)
+	rec.Start = time.Now()
+	defer func() { se.recordStmt(rec, err) }()
+
+	var rows *sql.Rows
+	if se.pCase.cfg.UsePrepareStmt {
+		stmt, err := se.getAndCacheStmt(ctx, sqlFormat)
+		if err != nil {
+			return err
+		}
+		if rows, err = stmt.QueryContext(ctx, args...); err != nil {
+			return err
+		}
+	} else if rows, err = se.conn.QueryContext(ctx, sqlFormat, args...); err != nil {
+		return err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	// scan the id and c columns by name
+	var (
+		id   uint64
+		c    int64
+		dest = make([]interface{}, len(cols))
+	)
+	for i, col := range cols {
+		switch col {
+		case "id":
+			dest[i] = &id
+		case "c":
+			dest[i] = &c
+		default:
+			dest[i] = new(sql.RawBytes)
+		}
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		rec.Values[id] = c
+	}
+	return rows.Err()
+}
+
+// lockReadRow reads the c column of the row by SELECT ... FOR UPDATE, it returns sql.ErrNoRows if the row doesn't exist
+func (se *Session) lockReadRow(ctx context.Context, name string, tableID int, rowID uint64) (int64, error) {
+	rec := &stmtRecord{Name: name, Table: tableID, Rows: []uint64{rowID}}
+	query := fmt.Sprintf("select id, c from %s%d where id = ? for update", tableNamePrefix, tableID)
+	if err := se.executeLockRead(ctx, rec, query, rowID); err != nil {
+		return 0, err
+	}
+	c, ok := rec.Values[rowID]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return c, nil
+}
+
+func (se *Session) recordStmt(rec *stmtRecord, err error) {
+	rec.End = time.Now()
+	if err != nil {
+		rec.Error, rec.Code = err.Error(), getErrorCode(err)
+	}
+	se.txn.Stmts = append(se.txn.Stmts, *rec)
+}
+
 func (se *Session) executeCommit(ctx context.Context) error {
 	_, err := se.conn.ExecContext(ctx, "commit")
 	return err
@@ -19,13 +19,15 @@ func (se *Session) plainSelect(ctx context.Context) error {
 
 func (se *Session) selectForUpdate(ctx context.Context) error {
 	sql := fmt.Sprintf("select * from %s%d where id in (?, ?) for update", tableNamePrefix, 0)
-	return se.executeSelect(ctx, sql, se.ran.nextRowID(), se.ran.nextRowID())
+	rows := []uint64{se.ran.nextRowID(), se.ran.nextRowID()}
+	return se.executeLockRead(ctx, &stmtRecord{Name: "select_for_update", Table: 0, Rows: rows}, sql, rows[0], rows[1])
 }
 
 func (se *Session) updateMultiTableSimple(ctx context.Context) error {
 	fromTableID, toTableID, fromRowID, toRowID := se.ran.nextTableAndRowPairs()
 	err := se.executeDML(
 		ctx,
+		deltaStmt("update_multi_table_simple", fromTableID, []uint64{fromRowID}, -1),
 		fmt.Sprintf("update %s%d set c = c - 1 where id = ?", tableNamePrefix, fromTableID),
 		fromRowID)
 	if err != nil {
@@ -33,6 +35,7 @@ func (se *Session) updateMultiTableSimple(ctx context.Context) error {
 	}
 	return se.executeDML(
 		ctx,
+		deltaStmt("update_multi_table_simple", toTableID, []uint64{toRowID}, 1),
 		fmt.Sprintf("update %s%d set c = c + 1 where id = ?", tableNamePrefix, toTableID),
 		toRowID)
 }
@@ -41,6 +44,7 @@ func (se *Session) updateMultiTableIndex(ctx context.Context) error {
 	fromTableID, toTableID, fromRowID, toRowID := se.ran.nextTableAndRowPairs()
 	err := se.executeDML(
 		ctx,
+		deltaStmt("update_multi_table_index", fromTableID, []uint64{fromRowID}, -1),
 		fmt.Sprintf("update %s%d set i = i + 1, c = c - 1 where id = ?", tableNamePrefix, fromTableID),
 		fromRowID)
 	if err != nil {
@@ -48,21 +52,26 @@ func (se *Session) updateMultiTableIndex(ctx context.Context) error {
 	}
 	return se.executeDML(
 		ctx,
+		deltaStmt("update_multi_table_index", toTableID, []uint64{toRowID}, 1),
 		fmt.Sprintf("update %s%d set i = i + 1, c = c + 1 where id = ?", tableNamePrefix, toTableID),
 		toRowID)
 }
 
 // updateUniqueIndex make sure there is no conflict on the unique index by randomly generate the unique index value
 func (se *Session) updateMultiTableUniqueIndex(ctx context.Context) error {
-	sql := fmt.Sprintf("update %s%d set u = ?, c = c where id = ?", tableNamePrefix, se.ran.nextTableID())
-	return se.executeDML(ctx, sql, se.ran.nextUniqueIndex(), se.ran.nextRowID())
+	tableID, rowID := se.ran.nextTableID(), se.ran.nextRowID()
+	sql := fmt.Sprintf("update %s%d set u = ?, c = c where id = ?", tableNamePrefix, tableID)
+	rec := &stmtRecord{Name: "update_multi_table_unique_index", Table: tableID, Rows: []uint64{rowID}}
+	return se.executeDML(ctx, rec, sql, se.ran.nextUniqueIndex(), rowID)
 }
 
 func (se *Session) updateMultiTableRange(ctx context.Context) error {
 	fromTableID, toTableID, fromRowID, toRowID := se.ran.nextTableAndRowPairs()
 	beginRowID := mathutil.MinUint64(fromRowID, toRowID) + 2
+	rows := se.rowRange(beginRowID, beginRowID+8)
 	err := se.executeDML(
 		ctx,
+		deltaStmt("update_multi_table_range", fromTableID, rows, -1),
 		fmt.Sprintf("update %s%d set c = c - 1 where id between ? and ?", tableNamePrefix, fromTableID),
 		beginRowID,
 		beginRowID+8)
@@ -71,6 +80,7 @@ func (se *Session) updateMultiTableRange(ctx context.Context) error {
 	}
 	return se.executeDML(
 		ctx,
+		deltaStmt("update_multi_table_range", toTableID, rows, 1),
 		fmt.Sprintf("update %s%d set c = c + 1 where id between ? and ?", tableNamePrefix, toTableID),
 		beginRowID,
 		beginRowID+8)
@@ -79,8 +89,10 @@ func (se *Session) updateMultiTableRange(ctx context.Context) error {
 func (se *Session) updateMultiTableIndexRange(ctx context.Context) error {
 	fromTableID, toTableID, fromRowID, toRowID := se.ran.nextTableAndRowPairs()
 	beginRowID := mathutil.MinUint64(fromRowID, toRowID) + 2
+	rows := se.rowRange(beginRowID, beginRowID+8)
 	err := se.executeDML(
 		ctx,
+		deltaStmt("update_multi_table_index_range", fromTableID, rows, -1),
 		fmt.Sprintf("update %s%d set i = i + 1, c = c - 1 where id between ? and ?", tableNamePrefix, fromTableID),
 		beginRowID,
 		beginRowID+8)
@@ -89,55 +101,46 @@ func (se *Session) updateMultiTableIndexRange(ctx context.Context) error {
 	}
 	return se.executeDML(
 		ctx,
+		deltaStmt("update_multi_table_index_range", toTableID, rows, 1),
 		fmt.Sprintf("update %s%d set i = i + 1, c = c + 1 where id between ? and ?", tableNamePrefix, toTableID),
 		beginRowID,
 		beginRowID+8)
 }
 
 func (se *Session) replace(ctx context.Context) error {
-	var fromCnt, toCnt int64
 	fromTableID, toTableID, fromRowID, toRowID := se.ran.nextTableAndRowPairs()
-	sql := fmt.Sprintf("select c from %s%d where id = ? for update", tableNamePrefix, fromTableID)
-	row := se.conn.QueryRowContext(ctx, sql, fromRowID)
-	err := row.Scan(&fromCnt)
+	fromCnt, err := se.lockReadRow(ctx, "replace", fromTableID, fromRowID)
 	if err != nil {
 		return err
 	}
-	sql = fmt.Sprintf("select c from %s%d where id = ? for update", tableNamePrefix, toTableID)
-	row = se.conn.QueryRowContext(ctx, sql, toRowID)
-	err = row.Scan(&toCnt)
+	toCnt, err := se.lockReadRow(ctx, "replace", toTableID, toRowID)
 	if err != nil {
 		return err
 	}
-	sql = fmt.Sprintf("replace into %s%d values (?, ?, ?, ?, ?)", tableNamePrefix, fromTableID)
-	if err = se.executeDML(ctx, sql, fromRowID, se.ran.nextUniqueIndex(), fromRowID, toCnt, randExceededLargeEntry()); err != nil {
+	sql := fmt.Sprintf("replace into %s%d values (?, ?, ?, ?, ?)", tableNamePrefix, fromTableID)
+	if err = se.executeDML(ctx, setStmt("replace", fromTableID, fromRowID, toCnt), sql, fromRowID, se.ran.nextUniqueIndex(), fromRowID, toCnt, randExceededLargeEntry()); err != nil {
 		return err
 	}
 	sql = fmt.Sprintf("replace into %s%d values (?, ?, ?, ?, ?)", tableNamePrefix, toTableID)
-	return se.executeDML(ctx, sql, toRowID, se.ran.nextUniqueIndex(), toRowID, fromCnt, randExceededLargeEntry())
+	return se.executeDML(ctx, setStmt("replace", toTableID, toRowID, fromCnt), sql, toRowID, se.ran.nextUniqueIndex(), toRowID, fromCnt, randExceededLargeEntry())
 }
 
 func (se *Session) insertOnDuplicateUpdate(ctx context.Context) error {
-	var fromCnt, toCnt int64
 	fromTableID, toTableID, fromRowID, toRowID := se.ran.nextTableAndRowPairs()
-	sql := fmt.Sprintf("select c from %s%d where id = ? for update", tableNamePrefix, fromTableID)
-	row := se.conn.QueryRowContext(ctx, sql, fromRowID)
-	err := row.Scan(&fromCnt)
+	fromCnt, err := se.lockReadRow(ctx, "insert_on_duplicate_update", fromTableID, fromRowID)
 	if err != nil {
 		return err
 	}
-	sql = fmt.Sprintf("select c from %s%d where id = ? for update", tableNamePrefix, toTableID)
-	row = se.conn.QueryRowContext(ctx, sql, toRowID)
-	err = row.Scan(&toCnt)
+	toCnt, err := se.lockReadRow(ctx, "insert_on_duplicate_update", toTableID, toRowID)
 	if err != nil {
 		return err
 	}
-	sql = fmt.Sprintf("insert into %s%d values (?, ?, ?, ?, ?) on duplicate key update id=?, u=?, i=?, c=?", tableNamePrefix, fromTableID)
-	if err = se.executeDML(ctx, sql, fromRowID, se.ran.nextUniqueIndex(), fromRowID, toCnt, randExceededLargeEntry(), fromRowID, se.ran.nextUniqueIndex(), fromRowID, toCnt); err != nil {
+	sql := fmt.Sprintf("insert into %s%d values (?, ?, ?, ?, ?) on duplicate key update id=?, u=?, i=?, c=?", tableNamePrefix, fromTableID)
+	if err = se.executeDML(ctx, setStmt("insert_on_duplicate_update", fromTableID, fromRowID, toCnt), sql, fromRowID, se.ran.nextUniqueIndex(), fromRowID, toCnt, randExceededLargeEntry(), fromRowID, se.ran.nextUniqueIndex(), fromRowID, toCnt); err != nil {
 		return err
 	}
 	sql = fmt.Sprintf("insert into %s%d values (?, ?, ?, ?, ?) on duplicate key update id=?, u=?, i=?, c=?", tableNamePrefix, toTableID)
-	return se.executeDML(ctx, sql, toRowID, se.ran.nextUniqueIndex(), toRowID, fromCnt, randExceededLargeEntry(), toRowID, se.ran.nextUniqueIndex(), toRowID, fromCnt)
+	return se.executeDML(ctx, setStmt("insert_on_duplicate_update", toTableID, toRowID, fromCnt), sql, toRowID, se.ran.nextUniqueIndex(), toRowID, fromCnt, randExceededLargeEntry(), toRowID, se.ran.nextUniqueIndex(), toRowID, fromCnt)
 }
 
 func (se *Session) deleteInsert(ctx context.Context) error {
@@ -150,7 +153,7 @@ func (se *Session) deleteInsert(ctx context.Context) error {
 		return err
 	}
 	sql = fmt.Sprintf("delete from %s%d where id = ?", tableNamePrefix, fromTableID)
-	if err = se.executeDML(ctx, sql, fromRowID); err != nil {
+	if err = se.executeDML(ctx, nil, sql, fromRowID); err != nil {
 		return err
 	}
 
@@ -161,59 +164,65 @@ func (se *Session) deleteInsert(ctx context.Context) error {
 		return err
 	}
 	sql = fmt.Sprintf("delete from %s%d where id = ?", tableNamePrefix, toTableID)
-	if err = se.executeDML(ctx, sql, toRowID); err != nil {
+	if err = se.executeDML(ctx, nil, sql, toRowID); err != nil {
 		return err
 	}
 
 	sql = fmt.Sprintf("insert into %s%d values (?, ?, ?, ?, ?)", tableNamePrefix, fromTableID)
-	if err = se.executeDML(ctx, sql, fromRowID, se.ran.nextUniqueIndex(), fromRowID, cntTo, randExceededLargeEntry()); err != nil {
+	if err = se.executeDML(ctx, nil, sql, fromRowID, se.ran.nextUniqueIndex(), fromRowID, cntTo, randExceededLargeEntry()); err != nil {
 		return err
 	}
 	sql = fmt.Sprintf("insert into %s%d values (?, ?, ?, ?, ?)", tableNamePrefix, toTableID)
-	return se.executeDML(ctx, sql, toRowID, se.ran.nextUniqueIndex(), toRowID, cntFrom, randExceededLargeEntry())
+	return se.executeDML(ctx, nil, sql, toRowID, se.ran.nextUniqueIndex(), toRowID, cntFrom, randExceededLargeEntry())
 }
 
 func (se *Session) insertThenDelete(ctx context.Context) error {
 	tableID := se.ran.nextTableID()
 	rowID := se.ran.nextNonExistRowID()
+	rec := func() *stmtRecord {
+		return &stmtRecord{Name: "insert_then_delete", Table: tableID, Rows: []uint64{rowID}}
+	}
 	sql := fmt.Sprintf("insert into %s%d values (?, ?, ?, ?, ?)", tableNamePrefix, tableID)
-	if err := se.executeDML(ctx, sql, rowID, se.ran.nextUniqueIndex(), rowID, 0, randExceededLargeEntry()); err != nil {
+	if err := se.executeDML(ctx, rec(), sql, rowID, se.ran.nextUniqueIndex(), rowID, 0, randExceededLargeEntry()); err != nil {
 		return err
 	}
 	sql = fmt.Sprintf("delete from %s%d where id = ?", tableNamePrefix, tableID)
-	return se.executeDML(ctx, sql, rowID)
+	return se.executeDML(ctx, rec(), sql, rowID)
 }
 
 func (se *Session) insertIgnore(ctx context.Context) error {
 	fromTableID, toTableID, fromRowID, toRowID := se.ran.nextTableAndRowPairs()
-	var cntFrom, cntTo int64
+	// the inserts check the unique index entry u = 0 as well as the rows
+	insertIgnore := func(tableID int, rowID uint64) *stmtRecord {
+		return &stmtRecord{Name: "insert_ignore", Table: tableID, Rows: []uint64{rowID}, Keys: []string{"u/0"}}
+	}
+	insertUpdate := func(tableID int, rowID uint64, delta int64) *stmtRecord {
+		rec := deltaStmt("insert_ignore", tableID, []uint64{rowID}, delta)
+		// the affected rows of an updated duplicate row is 2
+		rec.Keys, rec.expectAffected = []string{"u/0"}, 2
+		return rec
+	}
 
 	sql := fmt.Sprintf("insert ignore into %s%d values (?, ?, ?, ?, ?)", tableNamePrefix, fromTableID)
-	if err := se.executeDML(ctx, sql, fromRowID, 0, fromRowID, 0, randExceededLargeEntry()); err != nil {
+	if err := se.executeDML(ctx, insertIgnore(fromTableID, fromRowID), sql, fromRowID, 0, fromRowID, 0, randExceededLargeEntry()); err != nil {
 		return err
 	}
 	sql = fmt.Sprintf("insert ignore into %s%d values (?, ?, ?, ?, ?)", tableNamePrefix, toTableID)
-	if err := se.executeDML(ctx, sql, toRowID, 0, toRowID, 0, randExceededLargeEntry()); err != nil {
+	if err := se.executeDML(ctx, insertIgnore(toTableID, toRowID), sql, toRowID, 0, toRowID, 0, randExceededLargeEntry()); err != nil {
 		return err
 	}
-	sql = fmt.Sprintf("select c from %s%d where id = ? for update", tableNamePrefix, fromTableID)
-	row := se.conn.QueryRowContext(ctx, sql, fromRowID)
-	err := row.Scan(&cntFrom)
-	if err != nil {
+	if _, err := se.lockReadRow(ctx, "insert_ignore", fromTableID, fromRowID); err != nil {
 		return err
 	}
-	sql = fmt.Sprintf("select c from %s%d where id = ? for update", tableNamePrefix, toTableID)
-	row = se.conn.QueryRowContext(ctx, sql, toRowID)
-	err = row.Scan(&cntTo)
-	if err != nil {
+	if _, err := se.lockReadRow(ctx, "insert_ignore", toTableID, toRowID); err != nil {
 		return err
 	}
 	sql = fmt.Sprintf("insert into %s%d values (?, ?, ?, ?, ?) on duplicate key update c=c+1", tableNamePrefix, fromTableID)
-	if err := se.executeDML(ctx, sql, fromRowID, 0, fromRowID, 0, randExceededLargeEntry()); err != nil {
+	if err := se.executeDML(ctx, insertUpdate(fromTableID, fromRowID, 1), sql, fromRowID, 0, fromRowID, 0, randExceededLargeEntry()); err != nil {
 		return err
 	}
 	sql = fmt.Sprintf("insert into %s%d values (?, ?, ?, ?, ?) on duplicate key update c=c-1", tableNamePrefix, toTableID)
-	return se.executeDML(ctx, sql, toRowID, 0, toRowID, 0, randExceededLargeEntry())
+	return se.executeDML(ctx, insertUpdate(toTableID, toRowID, -1), sql, toRowID, 0, toRowID, 0, randExceededLargeEntry())
 }
 
 func (se *Session) insertIntoSelect(ctx context.Context) error {
@@ -237,12 +246,12 @@ func (se *Session) insertIntoSelect(ctx context.Context) error {
 	}
 
 	sql = fmt.Sprintf("delete from %s%d where id = ?", tableNamePrefix, toTableID)
-	if err = se.executeDML(ctx, sql, rowID); err != nil {
+	if err = se.executeDML(ctx, nil, sql, rowID); err != nil {
 		return err
 	}
 
 	sql = fmt.Sprintf("insert into %s%d select * from %s%d where id=?", tableNamePrefix, toTableID, tableNamePrefix, fromTableID)
-	if err = se.executeDML(ctx, sql, rowID); err != nil {
+	if err = se.executeDML(ctx, nil, sql, rowID); err != nil {
 		return err
 	}
 
@@ -250,7 +259,7 @@ func (se *Session) insertIntoSelect(ctx context.Context) error {
 		return nil
 	}
 	sql = fmt.Sprintf("update %s%d set c = c + ? where id=?", tableNamePrefix, toTableID)
-	return se.executeDML(ctx, sql, cntTo-cntFrom, rowID)
+	return se.executeDML(ctx, nil, sql, cntTo-cntFrom, rowID)
 }
 
 func (se *Session) randSizeExceededTransaciton(ctx context.Context) error {
@@ -270,7 +279,8 @@ func (se *Session) randSizeExceededTransaciton(ctx context.Context) error {
 		rowID := se.ran.nextNonExistRowID()
 		// Transaction is expected to fail
 		sql := fmt.Sprintf("insert into %s%d values(?, ?, ?, ?, ?)", tableNamePrefix, tableID)
-		if err = se.executeDML(ctx, sql, rowID, rowID, rowID, i, m); err != nil {
+		rec := &stmtRecord{Name: "rand_size_exceeded_transaction", Table: tableID, Rows: []uint64{rowID}}
+		if err = se.executeDML(ctx, rec, sql, rowID, rowID, rowID, i, m); err != nil {
 			return err
 		}
 	}
@@ -289,3 +299,35 @@ func randExceededLargeEntry() string {
 	}
 	return ""
 }
+
+// deltaStmt records an update of c = c + delta on the rows
+func deltaStmt(name string, tableID int, rows []uint64, delta int64) *stmtRecord {
+	return &stmtRecord{
+		Name:           name,
+		Table:          tableID,
+		Rows:           rows,
+		Write:          &writeEffect{Delta: delta},
+		expectAffected: int64(len(rows)),
+	}
+}
+
+// setStmt records a replace of the row which sets c = value
+func setStmt(name string, tableID int, rowID uint64, value int64) *stmtRecord {
+	return &stmtRecord{
+		Name:  name,
+		Table: tableID,
+		Rows:  []uint64{rowID},
+		Write: &writeEffect{Set: true, Value: value},
+		// the affected rows of a replaced row is 2
+		expectAffected: 2,
+	}
+}
+
+// rowRange returns the initial rows between begin and end
+func (se *Session) rowRange(begin, end uint64) []uint64 {
+	var rows []uint64
+	for row := begin; row <= end && row < se.ran.max; row++ {
+		rows = append(rows, row)
+	}
+	return rows
+}
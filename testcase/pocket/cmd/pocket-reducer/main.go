@@ -0,0 +1,86 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"regexp"
+	"time"
+
+	"github.com/ngaut/log"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/comparator"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/core"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/reducer"
+)
+
+var (
+	logDir           = flag.String("log-dir", "", "directory of the executor logs, e.g. the log path of pocket")
+	mode             = flag.String("mode", "abtest", "mode of the logs, abtest compares 2 databases, single, binlog or oracle replays on 1 database")
+	dsn1             = flag.String("dsn1", "", "DSN of the database to replay on, the database is recreated")
+	dsn2             = flag.String("dsn2", "", "DSN of the other database in abtest mode, the database is recreated")
+	errPattern       = flag.String("error", "", "regexp of the error to reduce, required if there is only 1 database")
+	output           = flag.String("output", "reduced", "directory of the reduced session-N.sql scripts")
+	maxRuns          = flag.Int("max-runs", 0, "max replay times, 0 means no limitation")
+	timeout          = flag.Duration("timeout", time.Hour, "max time to reduce")
+	floatTolerance   = flag.Float64("float-tolerance", 1e-9, "relative tolerance of FLOAT and DOUBLE values")
+	decimalTolerance = flag.Float64("decimal-tolerance", 0, "absolute tolerance of DECIMAL values")
+)
+
+func main() {
+	flag.Parse()
+	logs, err := core.ReadLogDir(*logDir, *mode)
+	if err != nil {
+		log.Fatalf("read logs error %v", err)
+	}
+	stmts := reducer.FromLogs(logs)
+	if len(stmts) == 0 {
+		log.Fatalf("no statement found in %s", *logDir)
+	}
+
+	dsns := []string{*dsn1}
+	if *mode == "abtest" {
+		dsns = append(dsns, *dsn2)
+	}
+	var pattern *regexp.Regexp
+	if *errPattern != "" {
+		pattern = regexp.MustCompile(*errPattern)
+	}
+	cmp := comparator.New(comparator.Option{
+		FloatTolerance:   *floatTolerance,
+		DecimalTolerance: *decimalTolerance,
+	})
+	runner, err := reducer.NewDBRunner(dsns, cmp, pattern)
+	if err != nil {
+		log.Fatalf("create runner error %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	r := reducer.New(runner)
+	r.MaxRuns = *maxRuns
+	reduced, failure, err := r.Reduce(ctx, stmts)
+	if err != nil && reduced == nil {
+		log.Fatalf("reduce error %v", err)
+	}
+	if err != nil {
+		log.Errorf("reduce is stopped by %v, write the current result", err)
+	}
+	files, err := reducer.WriteSessions(*output, reduced, failure)
+	if err != nil {
+		log.Fatalf("write scripts error %v", err)
+	}
+	log.Infof("reduced %d statements to %d, failure: %s, scripts: %v", len(stmts), len(reduced), failure.Message, files)
+}
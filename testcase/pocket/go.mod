@@ -16,6 +16,7 @@ require (
 	github.com/pkg/errors v0.9.1
 	github.com/satori/go.uuid v1.2.0
 	github.com/stretchr/testify v1.5.1
+	golang.org/x/text v0.3.3
 	k8s.io/apimachinery v0.17.0
 )
 
@@ -0,0 +1,411 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package comparator
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/parser"
+	"github.com/pingcap/parser/ast"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	// register the value expression driver for parsing
+	_ "github.com/pingcap/tidb/types/parser_driver"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/connection"
+)
+
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// Option of the comparator
+type Option struct {
+	// FloatTolerance is the relative tolerance of FLOAT and DOUBLE values, 0 means exactly the same
+	FloatTolerance float64
+	// DecimalTolerance is the absolute tolerance of DECIMAL values, 0 means exactly the same
+	DecimalTolerance float64
+	// Location1 and Location2 are the session time zones of the 2 results,
+	// TIMESTAMP values are compared as instants in them, nil means UTC
+	Location1 *time.Location
+	Location2 *time.Location
+}
+
+// CollationLookup looks up the collation of the column of the table in the current database,
+// it returns "" if the column doesn't exist or has no collation.
+type CollationLookup func(table, column string) (string, error)
+
+// Comparator compares the results of the same query,
+// the values are compared by their column types returned by the driver,
+// and the strings are compared by the collations of their source columns if they can be looked up.
+type Comparator struct {
+	opt        Option
+	collations CollationLookup
+}
+
+// New creates a Comparator
+func New(opt Option) *Comparator {
+	if opt.Location1 == nil {
+		opt.Location1 = time.UTC
+	}
+	if opt.Location2 == nil {
+		opt.Location2 = time.UTC
+	}
+	return &Comparator{opt: opt}
+}
+
+// WithCollations returns a comparator which looks up the collations of the selected columns by the lookup
+func (c *Comparator) WithCollations(lookup CollationLookup) *Comparator {
+	return &Comparator{opt: c.opt, collations: lookup}
+}
+
+// CompareQuery compares the results of the statement,
+// they are compared as multisets if the statement has no deterministic order.
+func (c *Comparator) CompareQuery(stmt string, res1, res2 [][]*connection.QueryItem) error {
+	return c.compare(res1, res2, Ordered(stmt), c.columnCollations(stmt))
+}
+
+// Compare compares 2 results, the rows are compared by their positions if ordered, otherwise as multisets
+func (c *Comparator) Compare(res1, res2 [][]*connection.QueryItem, ordered bool) error {
+	return c.compare(res1, res2, ordered, nil)
+}
+
+// compare compares 2 results, collations are the collations of the columns, nil if they are unknown
+func (c *Comparator) compare(res1, res2 [][]*connection.QueryItem, ordered bool, collations []string) error {
+	if len(res1) != len(res2) {
+		return errors.Errorf("row number not match res1: %d, res2: %d", len(res1), len(res2))
+	}
+	if !ordered {
+		res1 = c.sortRows(res1, c.opt.Location1, collations)
+		res2 = c.sortRows(res2, c.opt.Location2, collations)
+	}
+	for index := range res1 {
+		var (
+			row1 = res1[index]
+			row2 = res2[index]
+		)
+		if len(row1) != len(row2) {
+			return errors.Errorf("column number not match row1: %d, row2: %d, row index %d", len(row1), len(row2), index)
+		}
+		for rIndex := range row1 {
+			if err := c.compareItem(row1[rIndex], row2[rIndex], columnCollation(collations, rIndex)); err != nil {
+				return errors.Errorf("%s, row index %d, column index %d", err.Error(), index, rIndex)
+			}
+		}
+	}
+	return nil
+}
+
+// CompareItem compares 2 values of the same column
+func (c *Comparator) CompareItem(item1, item2 *connection.QueryItem) error {
+	return c.compareItem(item1, item2, "")
+}
+
+func (c *Comparator) compareItem(item1, item2 *connection.QueryItem, collation string) error {
+	if (item1 == nil) != (item2 == nil) {
+		return errors.Errorf("one is nil but another is not, self: %t, another: %t", item1 == nil, item2 == nil)
+	}
+	if item1 == nil {
+		return nil
+	}
+	if item1.Null != item2.Null {
+		return errors.Errorf("one is NULL but another is not, self: %t, another: %t", item1.Null, item2.Null)
+	}
+	if item1.Null {
+		return nil
+	}
+	if !c.equal(typeName(item1), collation, item1.ValString, item2.ValString) {
+		return errors.Errorf("column data diff, self: %s, another: %s", item1.ValString, item2.ValString)
+	}
+	return nil
+}
+
+func (c *Comparator) equal(tp, collation, v1, v2 string) bool {
+	if v1 == v2 {
+		return true
+	}
+	switch tp {
+	case "FLOAT", "DOUBLE":
+		f1, err1 := strconv.ParseFloat(v1, 64)
+		f2, err2 := strconv.ParseFloat(v2, 64)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		return f1 == f2 || math.Abs(f1-f2) <= c.opt.FloatTolerance*math.Max(math.Abs(f1), math.Abs(f2))
+	case "DECIMAL":
+		d1, ok1 := new(big.Rat).SetString(v1)
+		d2, ok2 := new(big.Rat).SetString(v2)
+		if !ok1 || !ok2 {
+			return false
+		}
+		diff := new(big.Rat).Sub(d1, d2)
+		tolerance := new(big.Rat)
+		tolerance.SetFloat64(c.opt.DecimalTolerance)
+		return diff.Abs(diff).Cmp(tolerance) <= 0
+	case "CHAR", "VARCHAR", "TEXT", "TINYTEXT", "MEDIUMTEXT", "LONGTEXT", "ENUM", "SET":
+		return foldString(v1, collation) == foldString(v2, collation)
+	case "TIMESTAMP":
+		t1, err1 := parseTime(v1, c.opt.Location1)
+		t2, err2 := parseTime(v2, c.opt.Location2)
+		return err1 == nil && err2 == nil && t1.Equal(t2)
+	case "DATETIME", "DATE":
+		t1, err1 := parseTime(v1, time.UTC)
+		t2, err2 := parseTime(v2, time.UTC)
+		return err1 == nil && err2 == nil && t1.Equal(t2)
+	case "JSON":
+		var j1, j2 interface{}
+		if json.Unmarshal([]byte(v1), &j1) != nil || json.Unmarshal([]byte(v2), &j2) != nil {
+			return false
+		}
+		return reflect.DeepEqual(j1, j2)
+	}
+	return false
+}
+
+// normalize formats the value to its canonical form, which is used for sorting the rows
+func normalize(tp, collation, v string, loc *time.Location) string {
+	switch tp {
+	case "FLOAT", "DOUBLE":
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return strconv.FormatFloat(f, 'e', 6, 64)
+		}
+	case "DECIMAL":
+		if d, ok := new(big.Rat).SetString(v); ok {
+			return d.RatString()
+		}
+	case "CHAR", "VARCHAR", "TEXT", "TINYTEXT", "MEDIUMTEXT", "LONGTEXT", "ENUM", "SET":
+		return foldString(v, collation)
+	case "TIMESTAMP":
+		if t, err := parseTime(v, loc); err == nil {
+			return t.UTC().Format(time.RFC3339Nano)
+		}
+	case "DATETIME", "DATE":
+		if t, err := parseTime(v, time.UTC); err == nil {
+			return t.Format(time.RFC3339Nano)
+		}
+	case "JSON":
+		var j interface{}
+		if json.Unmarshal([]byte(v), &j) == nil {
+			// map keys are sorted by Marshal
+			if b, err := json.Marshal(j); err == nil {
+				return string(b)
+			}
+		}
+	}
+	return v
+}
+
+func (c *Comparator) sortRows(res [][]*connection.QueryItem, loc *time.Location, collations []string) [][]*connection.QueryItem {
+	type keyedRow struct {
+		key []string
+		row []*connection.QueryItem
+	}
+	rows := make([]keyedRow, 0, len(res))
+	for _, row := range res {
+		key := make([]string, 0, len(row))
+		for i, item := range row {
+			switch {
+			case item == nil:
+				key = append(key, "")
+			case item.Null:
+				// NULL is the first
+				key = append(key, "\x00")
+			default:
+				key = append(key, "\x01"+normalize(typeName(item), columnCollation(collations, i), item.ValString, loc))
+			}
+		}
+		rows = append(rows, keyedRow{key: key, row: row})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		k1, k2 := rows[i].key, rows[j].key
+		for n := 0; n < len(k1) && n < len(k2); n++ {
+			if k1[n] != k2[n] {
+				return k1[n] < k2[n]
+			}
+		}
+		return len(k1) < len(k2)
+	})
+	sorted := make([][]*connection.QueryItem, 0, len(rows))
+	for _, row := range rows {
+		sorted = append(sorted, row.row)
+	}
+	return sorted
+}
+
+// Ordered reports whether the rows of the statement are in a deterministic order,
+// which means the statement is ordered by all its fields.
+func Ordered(stmt string) bool {
+	node, err := parser.New().ParseOneStmt(stmt, "", "")
+	if err != nil {
+		return false
+	}
+	sel, ok := node.(*ast.SelectStmt)
+	if !ok || sel.OrderBy == nil || sel.Fields == nil {
+		return false
+	}
+	// the ORDER BY columns are keyed by their qualified names, eg. t1.c
+	orderBy := make(map[string]struct{})
+	for _, item := range sel.OrderBy.Items {
+		if column, ok := item.Expr.(*ast.ColumnNameExpr); ok {
+			orderBy[columnKey(column.Name)] = struct{}{}
+		}
+	}
+	for _, field := range sel.Fields.Fields {
+		if field.WildCard != nil {
+			return false
+		}
+		if _, ok := orderBy[field.AsName.L]; ok && field.AsName.L != "" {
+			continue
+		}
+		column, ok := field.Expr.(*ast.ColumnNameExpr)
+		if !ok {
+			return false
+		}
+		if _, ok := orderBy[columnKey(column.Name)]; ok {
+			continue
+		}
+		// an unqualified ORDER BY column refers to the selected column of the name
+		if _, ok := orderBy[column.Name.Name.L]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func columnKey(name *ast.ColumnName) string {
+	if name.Table.L == "" {
+		return name.Name.L
+	}
+	return name.Table.L + "." + name.Name.L
+}
+
+// columnCollations looks up the collations of the selected columns of the statement,
+// the collation of a column is "" if it's not a column of a table or can't be looked up.
+func (c *Comparator) columnCollations(stmt string) []string {
+	if c.collations == nil {
+		return nil
+	}
+	node, err := parser.New().ParseOneStmt(stmt, "", "")
+	if err != nil {
+		return nil
+	}
+	sel, ok := node.(*ast.SelectStmt)
+	if !ok || sel.Fields == nil {
+		return nil
+	}
+	// tables maps the names and aliases in the FROM clause to the tables
+	tables := make(map[string]string)
+	var names []string
+	if sel.From != nil {
+		collectTables(sel.From.TableRefs, tables, &names)
+	}
+	collations := make([]string, 0, len(sel.Fields.Fields))
+	for _, field := range sel.Fields.Fields {
+		if field.WildCard != nil {
+			// the columns of the wildcard are unknown
+			return nil
+		}
+		column, ok := field.Expr.(*ast.ColumnNameExpr)
+		if !ok {
+			collations = append(collations, "")
+			continue
+		}
+		candidates := names
+		if t := column.Name.Table.L; t != "" {
+			candidates = []string{tables[t]}
+		}
+		collation := ""
+		for _, table := range candidates {
+			if table == "" {
+				continue
+			}
+			if collation, err = c.collations(table, column.Name.Name.O); err == nil && collation != "" {
+				break
+			}
+		}
+		collations = append(collations, collation)
+	}
+	return collations
+}
+
+func collectTables(node ast.ResultSetNode, tables map[string]string, names *[]string) {
+	switch n := node.(type) {
+	case *ast.Join:
+		collectTables(n.Left, tables, names)
+		if n.Right != nil {
+			collectTables(n.Right, tables, names)
+		}
+	case *ast.TableSource:
+		table, ok := n.Source.(*ast.TableName)
+		if !ok {
+			return
+		}
+		*names = append(*names, table.Name.O)
+		tables[table.Name.L] = table.Name.O
+		if n.AsName.L != "" {
+			tables[n.AsName.L] = table.Name.O
+		}
+	}
+}
+
+func columnCollation(collations []string, i int) string {
+	if i < len(collations) {
+		return collations[i]
+	}
+	return ""
+}
+
+// foldString folds the string by the collation, the trailing spaces are trimmed since the collations pad spaces,
+// and the case and accents are folded for the case insensitive collations, eg. utf8mb4_general_ci and utf8mb4_unicode_ci
+func foldString(v, collation string) string {
+	v = strings.TrimRight(v, " ")
+	if !strings.HasSuffix(strings.ToLower(collation), "_ci") {
+		return v
+	}
+	// the transformer isn't safe for concurrent use
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	if folded, _, err := transform.String(t, v); err == nil {
+		v = folded
+	}
+	return strings.ToLower(v)
+}
+
+func typeName(item *connection.QueryItem) string {
+	if item.ValType == nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToUpper(item.ValType.DatabaseTypeName()), "UNSIGNED ")
+}
+
+func parseTime(v string, loc *time.Location) (time.Time, error) {
+	var err error
+	for _, layout := range timeLayouts {
+		var t time.Time
+		if t, err = time.ParseInLocation(layout, v, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
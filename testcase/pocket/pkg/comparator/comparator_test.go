@@ -0,0 +1,157 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package comparator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/connection"
+)
+
+func TestOrdered(t *testing.T) {
+	cases := []struct {
+		stmt    string
+		ordered bool
+	}{
+		{"SELECT a, b FROM t ORDER BY a, b", true},
+		{"SELECT a, b FROM t ORDER BY b DESC, a", true},
+		{"SELECT t.a AS c0, t.b AS c1 FROM t ORDER BY c1, c0", true},
+		{"SELECT t1.c, t2.c AS d FROM t1 JOIN t2 ON t1.a = t2.a ORDER BY t1.c, t2.c", true},
+		{"SELECT t1.c, t2.c FROM t1 JOIN t2 ON t1.a = t2.a ORDER BY t2.c", false},
+		{"SELECT t1.c FROM t1 JOIN t2 ON t1.a = t2.a ORDER BY t2.c", false},
+		{"SELECT t1.c FROM t1 ORDER BY c", true},
+		{"SELECT a, b FROM t ORDER BY a", false},
+		{"SELECT a, b FROM t", false},
+		{"SELECT * FROM t ORDER BY a", false},
+		{"SELECT COUNT(1) FROM t", false},
+		{"not a statement", false},
+	}
+	for _, c := range cases {
+		if got := Ordered(c.stmt); got != c.ordered {
+			t.Errorf("Ordered(%s) = %t, expect %t", c.stmt, got, c.ordered)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip(err)
+	}
+	c := New(Option{
+		FloatTolerance:   1e-9,
+		DecimalTolerance: 0.001,
+		Location1:        time.UTC,
+		Location2:        shanghai,
+	})
+	cases := []struct {
+		tp        string
+		collation string
+		v1, v2    string
+		equal     bool
+	}{
+		{"DOUBLE", "", "0.30000000000000004", "0.3", true},
+		{"FLOAT", "", "1.5", "1.6", false},
+		{"DECIMAL", "", "1.50", "1.5", true},
+		{"DECIMAL", "", "1.5004", "1.5", true},
+		{"DECIMAL", "", "1.502", "1.5", false},
+		{"VARCHAR", "", "abc  ", "abc", true},
+		{"VARCHAR", "", "abc", "ABC", false},
+		{"VARCHAR", "utf8mb4_bin", "abc", "ABC", false},
+		{"VARCHAR", "utf8mb4_general_ci", "abc ", "ABC", true},
+		{"VARCHAR", "utf8mb4_unicode_ci", "résumé", "RESUME", true},
+		{"CHAR", "utf8mb4_general_ci", "abc", "abd", false},
+		{"VARBINARY", "", "abc ", "abc", false},
+		{"TIMESTAMP", "", "2021-01-01 00:00:00", "2021-01-01 08:00:00", true},
+		{"TIMESTAMP", "", "2021-01-01 00:00:00", "2021-01-01 00:00:00.000000", false},
+		{"DATETIME", "", "2021-01-01 00:00:00", "2021-01-01 00:00:00.000", true},
+		{"JSON", "", `{"a": 1, "b": [1, 2]}`, `{"b":[1,2],"a":1}`, true},
+		{"JSON", "", `{"a": 1}`, `{"a": 2}`, false},
+		{"INT", "", "1", "01", false},
+	}
+	for _, cs := range cases {
+		if got := c.equal(cs.tp, cs.collation, cs.v1, cs.v2); got != cs.equal {
+			t.Errorf("equal(%s, %s, %s, %s) = %t, expect %t", cs.tp, cs.collation, cs.v1, cs.v2, got, cs.equal)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	row := func(values ...string) []*connection.QueryItem {
+		var r []*connection.QueryItem
+		for _, v := range values {
+			if v == "NULL" {
+				r = append(r, &connection.QueryItem{Null: true})
+			} else {
+				r = append(r, &connection.QueryItem{ValString: v})
+			}
+		}
+		return r
+	}
+	c := New(Option{})
+	res1 := [][]*connection.QueryItem{row("1", "a"), row("2", "NULL"), row("1", "a")}
+	res2 := [][]*connection.QueryItem{row("2", "NULL"), row("1", "a"), row("1", "a")}
+
+	if err := c.Compare(res1, res2, false); err != nil {
+		t.Fatalf("unordered results should be the same, %v", err)
+	}
+	if err := c.Compare(res1, res2, true); err == nil {
+		t.Fatal("ordered results should not be the same")
+	}
+	if err := c.Compare(res1, res2[:2], false); err == nil {
+		t.Fatal("results with different row numbers should not be the same")
+	}
+	res3 := [][]*connection.QueryItem{row("2", "NULL"), row("1", "a"), row("2", "a")}
+	if err := c.Compare(res1, res3, false); err == nil {
+		t.Fatal("results with different rows should not be the same")
+	}
+	if err := c.CompareQuery("SELECT a, b FROM t ORDER BY a", res1, res2); err != nil {
+		t.Fatalf("results not totally ordered should be compared as multisets, %v", err)
+	}
+}
+
+func TestColumnCollations(t *testing.T) {
+	columns := map[string]string{
+		"t.a":  "utf8mb4_general_ci",
+		"t.b":  "utf8mb4_bin",
+		"t2.c": "utf8mb4_unicode_ci",
+	}
+	c := New(Option{}).WithCollations(func(table, column string) (string, error) {
+		return columns[table+"."+column], nil
+	})
+	cases := []struct {
+		stmt       string
+		collations []string
+	}{
+		{"SELECT a, b FROM t", []string{"utf8mb4_general_ci", "utf8mb4_bin"}},
+		{"SELECT x.b, c, a + 1 FROM t AS x JOIN t2 ON x.a = t2.c", []string{"utf8mb4_bin", "utf8mb4_unicode_ci", ""}},
+		{"SELECT * FROM t", nil},
+		{"SELECT a FROM (SELECT a FROM t) s", []string{""}},
+	}
+	for _, cs := range cases {
+		got := c.columnCollations(cs.stmt)
+		if len(got) != len(cs.collations) {
+			t.Fatalf("columnCollations(%s) = %v, expect %v", cs.stmt, got, cs.collations)
+		}
+		for i := range got {
+			if got[i] != cs.collations[i] {
+				t.Fatalf("columnCollations(%s) = %v, expect %v", cs.stmt, got, cs.collations)
+			}
+		}
+	}
+	if got := New(Option{}).columnCollations("SELECT a FROM t"); got != nil {
+		t.Fatalf("collations without lookup should be unknown, got %v", got)
+	}
+}
@@ -34,10 +34,37 @@ type Options struct {
 	Duration      types.Duration `toml:"duration"`
 	CheckDuration types.Duration `toml:"check-duration"`
 	OnlineDDL     bool           `toml:"online-ddl"`
+	// ConcurrentDDL runs DDLs in a dedicated connection concurrently with the DMLs and transactions,
+	// and checks the tables after each DDL every DDLInterval
+	ConcurrentDDL bool           `toml:"concurrent-ddl"`
+	DDLInterval   types.Duration `toml:"ddl-interval"`
 	Serialize     bool           `toml:"serialize"`
 	GeneralLog    bool           `toml:"general-log"`
 	SyncTimeout   types.Duration `toml:"check-duration"`
 	EnableHint    bool           `toml:"enable-hint"`
+	// FloatTolerance and DecimalTolerance are the tolerances when comparing results of FLOAT/DOUBLE and DECIMAL values
+	FloatTolerance   float64 `toml:"float-tolerance"`
+	DecimalTolerance float64 `toml:"decimal-tolerance"`
+	// TimeZone1 and TimeZone2 are the time zones of DSN1 and DSN2, TIMESTAMP values are compared in them
+	TimeZone1 string `toml:"time-zone1"`
+	TimeZone2 string `toml:"time-zone2"`
+	// CheckChunkSize is the row count of the chunks when comparing the checksums of the binlog and DM upstream and downstream
+	CheckChunkSize int `toml:"check-chunk-size"`
+	// PreparedStmt also executes the generated DMLs as prepared statements in abtest
+	PreparedStmt bool `toml:"prepared-stmt"`
+	// PlanDiff explains each generated select statement in both sides of abtest and reports the plan differences,
+	// B is significantly slower if it takes PlanSlowRatio times of A's execution time and at least PlanSlowThreshold more
+	PlanDiff          bool           `toml:"plan-diff"`
+	PlanAnalyze       bool           `toml:"plan-analyze"`
+	PlanSlowRatio     float64        `toml:"plan-slow-ratio"`
+	PlanSlowThreshold types.Duration `toml:"plan-slow-threshold"`
+	// Feedback adapts the generator weights toward the statements which produced new coverage signals,
+	// and mutates the interesting statements by FeedbackMutateRatio instead of generating new ones,
+	// CoverageURL is the Go coverage profile of the target, which is polled every FeedbackInterval
+	Feedback            bool           `toml:"feedback"`
+	FeedbackMutateRatio float64        `toml:"feedback-mutate-ratio"`
+	FeedbackInterval    types.Duration `toml:"feedback-interval"`
+	CoverageURL         string         `toml:"coverage-url"`
 }
 
 // Generator Config
@@ -71,13 +98,34 @@ var initConfig = Config{
 		CheckDuration: types.Duration{
 			Duration: time.Minute,
 		},
-		OnlineDDL:  true,
+		OnlineDDL:     true,
+		ConcurrentDDL: false,
+		DDLInterval: types.Duration{
+			Duration: 10 * time.Second,
+		},
 		Serialize:  true,
 		GeneralLog: false,
 		SyncTimeout: types.Duration{
 			Duration: 10 * time.Minute,
 		},
-		EnableHint: false,
+		CheckChunkSize:   10000,
+		EnableHint:       false,
+		FloatTolerance:   1e-9,
+		DecimalTolerance: 0,
+		TimeZone1:        "UTC",
+		TimeZone2:        "UTC",
+		PreparedStmt:     false,
+		PlanDiff:         false,
+		PlanAnalyze:      true,
+		PlanSlowRatio:    2,
+		PlanSlowThreshold: types.Duration{
+			Duration: 100 * time.Millisecond,
+		},
+		Feedback:            false,
+		FeedbackMutateRatio: 0.2,
+		FeedbackInterval: types.Duration{
+			Duration: time.Minute,
+		},
 	},
 	Generator: Generator{
 		SQLSmith: SQLSmith{
@@ -93,6 +141,14 @@ var initConfig = Config{
 			DMLUpdate:          120,
 			DMLInsert:          120,
 			Sleep:              10,
+			SelectPlain:        40,
+			SelectAggregation:  15,
+			SelectWindow:       10,
+			SelectJoin:         10,
+			SelectSemiJoin:     10,
+			SelectDerived:      5,
+			SelectSetOperation: 5,
+			SelectCTE:          5,
 		},
 	},
 }
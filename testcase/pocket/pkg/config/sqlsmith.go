@@ -14,4 +14,13 @@ type SQLSmith struct {
 	DMLUpdate          int `toml:"dml-update"`
 	DMLInsert          int `toml:"dml-insert"`
 	Sleep              int `toml:"sleep"`
+	// weights of the select statement shapes, see generator.SelectOptions
+	SelectPlain        int `toml:"select-plain"`
+	SelectAggregation  int `toml:"select-aggregation"`
+	SelectWindow       int `toml:"select-window"`
+	SelectJoin         int `toml:"select-join"`
+	SelectSemiJoin     int `toml:"select-semi-join"`
+	SelectDerived      int `toml:"select-derived"`
+	SelectSetOperation int `toml:"select-set-operation"`
+	SelectCTE          int `toml:"select-cte"`
 }
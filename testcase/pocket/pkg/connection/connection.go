@@ -14,6 +14,8 @@
 package connection
 
 import (
+	"sync"
+
 	"github.com/juju/errors"
 
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/logger"
@@ -34,6 +36,9 @@ type Connection struct {
 	logger *logger.Logger
 	db     *mysql.DBConnect
 	opt    *Option
+	// lastErr is the error of the last statement
+	errMutex sync.Mutex
+	lastErr  error
 }
 
 // New create Connection instance from dsn
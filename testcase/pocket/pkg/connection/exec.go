@@ -15,7 +15,9 @@
 package connection
 
 import (
+	"database/sql"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/ngaut/log"
@@ -30,6 +32,13 @@ func (c *Connection) Select(stmt string, args ...interface{}) ([][]*QueryItem, e
 		return [][]*QueryItem{}, err
 	}
 
+	result := scanRows(rows)
+	// TODO: make args and stmt together
+	c.logSQL(stmt, time.Now().Sub(start), nil)
+	return result, nil
+}
+
+func scanRows(rows *sql.Rows) [][]*QueryItem {
 	columnTypes, _ := rows.ColumnTypes()
 	var result [][]*QueryItem
 
@@ -50,8 +59,7 @@ func (c *Connection) Select(stmt string, args ...interface{}) ([][]*QueryItem, e
 				ValType: columnTypes[index],
 			}
 			if r != nil {
-				bytes := r.([]byte)
-				item.ValString = string(bytes)
+				item.ValString = valueString(r)
 			} else {
 				item.Null = true
 			}
@@ -60,9 +68,31 @@ func (c *Connection) Select(stmt string, args ...interface{}) ([][]*QueryItem, e
 		result = append(result, resultRow)
 	}
 
-	// TODO: make args and stmt together
-	c.logSQL(stmt, time.Now().Sub(start), nil)
-	return result, nil
+	return result
+}
+
+// valueString formats the value scanned from the text or binary protocol as the text protocol
+func valueString(v interface{}) string {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999")
+	}
+	return fmt.Sprint(v)
+}
+
+// Query runs the query in the transaction if there is one, it implements syncdiff.Querier
+func (c *Connection) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.db.Query(query, args...)
 }
 
 // Update run update statement and return error
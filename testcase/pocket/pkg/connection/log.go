@@ -21,6 +21,9 @@ import (
 )
 
 func (c *Connection) logSQL(sql string, duration time.Duration, err error, args ...interface{}) {
+	c.errMutex.Lock()
+	c.lastErr = err
+	c.errMutex.Unlock()
 	line := fmt.Sprintf("Success: %t, Duration: %s", err == nil, duration)
 	for index, arg := range args {
 		if index == 0 {
@@ -33,3 +36,10 @@ func (c *Connection) logSQL(sql string, duration time.Duration, err error, args
 		log.Fatalf("fail to log to file %v", err)
 	}
 }
+
+// LastError returns the error of the last statement executed by the connection
+func (c *Connection) LastError() error {
+	c.errMutex.Lock()
+	defer c.errMutex.Unlock()
+	return c.lastErr
+}
@@ -0,0 +1,78 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/mysql"
+)
+
+// PreparedStmt is a server-side prepared statement, it's executed by the binary protocol
+type PreparedStmt struct {
+	c     *Connection
+	stmt  *mysql.Stmt
+	query string
+}
+
+// PrepareStmt prepares the statement with the prepared plan cache enabled or disabled in the session,
+// mysql.ErrVarNotApplied is returned if the server can't switch the plan cache by the session variable
+func (c *Connection) PrepareStmt(query string, planCache bool) (*PreparedStmt, error) {
+	vars := map[string]string{"tidb_enable_prepared_plan_cache": "OFF"}
+	if planCache {
+		vars["tidb_enable_prepared_plan_cache"] = "ON"
+	}
+	start := time.Now()
+	stmt, err := c.db.Prepare(query, vars)
+	c.logSQL(fmt.Sprintf("PREPARE %s", query), time.Now().Sub(start), err)
+	if err != nil {
+		return nil, err
+	}
+	return &PreparedStmt{c: c, stmt: stmt, query: query}, nil
+}
+
+// Select executes the prepared select statement with the arguments and returns the result
+func (s *PreparedStmt) Select(args ...interface{}) ([][]*QueryItem, error) {
+	start := time.Now()
+	rows, err := s.stmt.Query(args...)
+	if err != nil {
+		s.c.logSQL(s.executeSQL(args), time.Now().Sub(start), err)
+		return [][]*QueryItem{}, err
+	}
+	result := scanRows(rows)
+	s.c.logSQL(s.executeSQL(args), time.Now().Sub(start), nil)
+	return result, nil
+}
+
+// Exec executes the prepared DML statement with the arguments and returns the affected rows
+func (s *PreparedStmt) Exec(args ...interface{}) (int64, error) {
+	var affectedRows int64
+	start := time.Now()
+	result, err := s.stmt.Exec(args...)
+	if err == nil {
+		affectedRows, _ = result.RowsAffected()
+	}
+	s.c.logSQL(s.executeSQL(args), time.Now().Sub(start), err, affectedRows)
+	return affectedRows, err
+}
+
+// Close deallocates the prepared statement
+func (s *PreparedStmt) Close() error {
+	return s.stmt.Close()
+}
+
+func (s *PreparedStmt) executeSQL(args []interface{}) string {
+	return fmt.Sprintf("EXECUTE %s USING %#v", s.query, args)
+}
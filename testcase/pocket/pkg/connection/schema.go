@@ -15,10 +15,14 @@
 package connection
 
 import (
+	"database/sql"
 	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/juju/errors"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/mysql"
 )
 
 const (
@@ -68,6 +72,23 @@ func (c *Connection) FetchTables(db string) ([]string, error) {
 	return tableSlice, nil
 }
 
+// FetchCreateTable get the CREATE TABLE statement of given table by SHOW CREATE TABLE
+func (c *Connection) FetchCreateTable(table string) (string, error) {
+	res, err := c.db.Query(fmt.Sprintf("SHOW CREATE TABLE `%s`", table))
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	defer res.Close()
+	if !res.Next() {
+		return "", errors.Errorf("table %s not found", table)
+	}
+	var name, createStmt string
+	if err := res.Scan(&name, &createStmt); err != nil {
+		return "", errors.Trace(err)
+	}
+	return createStmt, nil
+}
+
 // FetchSchema get schema of given database from database
 func (c *Connection) FetchSchema(db string) ([][5]string, error) {
 	var (
@@ -110,7 +131,8 @@ func (c *Connection) FetchSchema(db string) ([][5]string, error) {
 	return schema, nil
 }
 
-// FetchColumns get columns for given table
+// FetchColumns get columns for given table,
+// the column type is followed by "not null" and "virtual generated" or "stored generated" if there are
 func (c *Connection) FetchColumns(db, table string) ([][2]string, error) {
 	var columns [][2]string
 	res, err := c.db.Query(fmt.Sprintf(tableSQL, db, table))
@@ -119,15 +141,38 @@ func (c *Connection) FetchColumns(db, table string) ([][2]string, error) {
 	}
 	for res.Next() {
 		var columnName, columnType string
-		var col1, col2, col3, col4 interface{}
-		if err = res.Scan(&columnName, &columnType, &col1, &col2, &col3, &col4); err != nil {
+		var columnNull, columnExtra sql.NullString
+		var col2, col3 interface{}
+		if err = res.Scan(&columnName, &columnType, &columnNull, &col2, &col3, &columnExtra); err != nil {
 			return [][2]string{}, errors.Trace(err)
 		}
+		if columnNull.String == "NO" {
+			columnType += " not null"
+		}
+		if extra := strings.ToLower(columnExtra.String); strings.Contains(extra, "generated") {
+			columnType += " " + extra
+		}
 		columns = append(columns, [2]string{columnName, columnType})
 	}
 	return columns, nil
 }
 
+// FetchCollation gets the collation of the column in the current database, it's "" if the column has no collation
+func (c *Connection) FetchCollation(table, column string) (string, error) {
+	res, err := c.Query("SELECT COLLATION_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?", table, column)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	defer res.Close()
+	var collation sql.NullString
+	if res.Next() {
+		if err := res.Scan(&collation); err != nil {
+			return "", errors.Trace(err)
+		}
+	}
+	return collation.String, errors.Trace(res.Err())
+}
+
 // FetchIndexes get indexes for given table
 func (c *Connection) FetchIndexes(db, table string) ([]string, error) {
 	var indexes []string
@@ -171,6 +216,97 @@ func (c *Connection) FetchIndexes(db, table string) ([]string, error) {
 	return indexes, nil
 }
 
+// FetchPartitions get the range partitions of tables in given database,
+// it maps table name to its partitions in order, partition[0] is name and partition[1] is description
+func (c *Connection) FetchPartitions(db string) (map[string][][2]string, error) {
+	partitions := make(map[string][][2]string)
+	res, err := c.db.Query(partitionSQL, db)
+	if err != nil {
+		return partitions, errors.Trace(err)
+	}
+	defer res.Close()
+	for res.Next() {
+		var (
+			tableName, partitionName string
+			description              sql.NullString
+		)
+		if err := res.Scan(&tableName, &partitionName, &description); err != nil {
+			return partitions, errors.Trace(err)
+		}
+		partitions[tableName] = append(partitions[tableName], [2]string{partitionName, description.String})
+	}
+	return partitions, errors.Trace(res.Err())
+}
+
+// DDLJob is a DDL job in information_schema.ddl_jobs
+type DDLJob struct {
+	ID    int64
+	Table string
+	Type  string
+	State string
+}
+
+// Done tells if the job is finished, both the synced and the cancelled jobs are done
+func (j *DDLJob) Done() bool {
+	switch strings.ToLower(j.State) {
+	case "synced", "cancelled", "rollback done":
+		return true
+	}
+	return false
+}
+
+// FetchDDLJobs get the latest DDL jobs of given database in descending order,
+// the jobs are nil if the database is not TiDB, whose DDLs are executed synchronously
+func (c *Connection) FetchDDLJobs(db string) ([]*DDLJob, error) {
+	res, err := c.db.Query(ddlJobsSQL, db)
+	if err != nil {
+		if mysql.IsErrUnknownTable(err) {
+			return nil, nil
+		}
+		return nil, errors.Trace(err)
+	}
+	defer res.Close()
+	jobs := []*DDLJob{}
+	for res.Next() {
+		var job DDLJob
+		if err := res.Scan(&job.ID, &job.Table, &job.Type, &job.State); err != nil {
+			return nil, errors.Trace(err)
+		}
+		jobs = append(jobs, &job)
+	}
+	return jobs, errors.Trace(res.Err())
+}
+
+// FetchSchemaVersion get the schema version of TiDB by ADMIN SHOW DDL, whose first column is SCHEMA_VER
+func (c *Connection) FetchSchemaVersion() (int64, error) {
+	res, err := c.db.Query(adminShowDDLSQL)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	defer res.Close()
+	columnTypes, err := res.ColumnTypes()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if !res.Next() {
+		return 0, errors.Errorf("no result of %s", adminShowDDLSQL)
+	}
+	var version int64
+	rowResultSets := []interface{}{&version}
+	for range columnTypes[1:] {
+		rowResultSets = append(rowResultSets, new(interface{}))
+	}
+	if err := res.Scan(rowResultSets...); err != nil {
+		return 0, errors.Trace(err)
+	}
+	return version, nil
+}
+
+// AdminCheckTable checks the indexes of given table are consistent with the rows
+func (c *Connection) AdminCheckTable(table string) error {
+	return errors.Trace(c.ExecDDL(fmt.Sprintf(adminCheckTableSQL, table)))
+}
+
 func ifBinlogSyncTable(t string) bool {
 	return binlogSyncTablePattern.MatchString(t)
 }
\ No newline at end of file
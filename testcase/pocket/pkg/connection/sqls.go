@@ -21,4 +21,10 @@ const (
 	schemaSQL         = "SELECT TABLE_SCHEMA, TABLE_NAME, TABLE_TYPE FROM information_schema.tables"
 	tableSQL          = "DESC %s.%s"
 	indexSQL          = "SHOW INDEX FROM %s.%s"
+	partitionSQL      = "SELECT TABLE_NAME, PARTITION_NAME, PARTITION_DESCRIPTION FROM information_schema.partitions " +
+		"WHERE TABLE_SCHEMA = ? AND PARTITION_NAME IS NOT NULL ORDER BY TABLE_NAME, PARTITION_ORDINAL_POSITION"
+	ddlJobsSQL = "SELECT JOB_ID, TABLE_NAME, JOB_TYPE, STATE FROM information_schema.ddl_jobs " +
+		"WHERE DB_NAME = ? ORDER BY JOB_ID DESC LIMIT 32"
+	adminCheckTableSQL = "ADMIN CHECK TABLE %s"
+	adminShowDDLSQL    = "ADMIN SHOW DDL"
 )
\ No newline at end of file
@@ -36,6 +36,7 @@ func (c *Core) startCheckConsistency(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
+			c.flushPlanReport()
 			return
 		case <-ticker.C:
 			var (
@@ -58,6 +59,7 @@ func (c *Core) startCheckConsistency(ctx context.Context) {
 				return
 			}
 			log.Infof("test %d compare data result %t\n", round, result)
+			c.flushPlanReport()
 			round++
 		}
 	}
@@ -166,7 +168,15 @@ func (c *Core) abTestCompareData(delay bool) (bool, error) {
 		time.Sleep(time.Duration(rand.Intn(5)) * time.Second)
 	}
 
-	return c.compareData(compareExecutor, schema)
+	result, err := c.compareData(compareExecutor, schema)
+	if err != nil || c.planReporter == nil {
+		return result, errors.Trace(err)
+	}
+	// both sides have the same data now, analyze the tables for the plan diff
+	if err := compareExecutor.ABTestTxnRollback(); err != nil {
+		return result, errors.Trace(err)
+	}
+	return result, errors.Trace(c.analyzeTables(compareExecutor))
 }
 
 func (c *Core) binlogTestCompareData(delay bool) (bool, error) {
@@ -231,12 +241,6 @@ SYNC:
 		}
 	}
 
-	schema, err := compareExecutor.GetConn().FetchSchema(c.dbname)
-	for err != nil {
-		schema, err = compareExecutor.GetConn().FetchSchema(c.dbname)
-		// c.Unlock()
-		// return false, errors.Trace(err)
-	}
 	if err := compareExecutor.ABTestTxnBegin(); err != nil {
 		return false, errors.Trace(err)
 	}
@@ -246,7 +250,15 @@ SYNC:
 		time.Sleep(time.Duration(rand.Intn(5)) * time.Second)
 	}
 
-	return c.compareData(compareExecutor, schema)
+	result, err := c.checksumCompareData(compareExecutor)
+	if err != nil || c.planReporter == nil {
+		return result, errors.Trace(err)
+	}
+	// both sides have the same data now, analyze the tables for the plan diff
+	if err := compareExecutor.ABTestTxnRollback(); err != nil {
+		return result, errors.Trace(err)
+	}
+	return result, errors.Trace(c.analyzeTables(compareExecutor))
 }
 
 func (c *Core) compareData(beganConnect *executor.Executor, schema [][5]string) (bool, error) {
@@ -0,0 +1,51 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"io/ioutil"
+	"path"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+
+	syncdiff "github.com/pingcap/tipocket/pkg/check/sync-diff"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/executor"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/util"
+)
+
+const syncDiffReportFile = "sync-diff.log"
+
+// checksumCompareData compares the chunk checksums of the tables in the snapshots of the began executor,
+// instead of pulling the whole tables, the report and the fix SQLs are written to the log path if not equal
+func (c *Core) checksumCompareData(beganConnect *executor.Executor) (bool, error) {
+	tables, err := beganConnect.GetConn1().FetchTables(c.dbname)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	diffs, err := syncdiff.CompareTables(beganConnect.GetConn1(), beganConnect.GetConn2(), c.dbname, tables,
+		syncdiff.Config{ChunkSize: c.cfg.Options.CheckChunkSize})
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	if report := syncdiff.Report(diffs); report != "" {
+		if err := ioutil.WriteFile(path.Join(c.cfg.Options.Path, syncDiffReportFile), []byte(report), 0644); err != nil {
+			log.Errorf("write sync diff report error %v", err)
+		}
+		log.Fatalf("inconsistency when compare data, begin: %s\n%s",
+			util.FormatTimeStrAsLog(beganConnect.GetConn().GetBeginTime()), report)
+	}
+	log.Info("consistency check pass")
+	return true, nil
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/juju/errors"
 
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/executor"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/generator/generator"
 )
 
 func (c *Core) generateExecutorOption(id int) *executor.Option {
@@ -37,6 +38,29 @@ func (c *Core) generateExecutorOption(id int) *executor.Option {
 		OnlineDDL:  c.cfg.Options.OnlineDDL,
 		GeneralLog: c.cfg.Options.GeneralLog,
 		Hint:       c.cfg.Options.EnableHint,
+		Oracle:     c.cfg.Mode == "oracle",
+		Partition:  c.cfg.Options.ConcurrentDDL,
+
+		FloatTolerance:   c.cfg.Options.FloatTolerance,
+		DecimalTolerance: c.cfg.Options.DecimalTolerance,
+		TimeZone1:        c.cfg.Options.TimeZone1,
+		TimeZone2:        c.cfg.Options.TimeZone2,
+
+		PlanReporter: c.planReporter,
+		PlanAnalyze:  c.cfg.Options.PlanAnalyze,
+		Prepare:      c.cfg.Options.PreparedStmt,
+		Feedback:     c.feedback,
+
+		Select: generator.SelectOptions{
+			Plain:        c.cfg.Generator.SQLSmith.SelectPlain,
+			Aggregation:  c.cfg.Generator.SQLSmith.SelectAggregation,
+			Window:       c.cfg.Generator.SQLSmith.SelectWindow,
+			Join:         c.cfg.Generator.SQLSmith.SelectJoin,
+			SemiJoin:     c.cfg.Generator.SQLSmith.SelectSemiJoin,
+			Derived:      c.cfg.Generator.SQLSmith.SelectDerived,
+			SetOperation: c.cfg.Generator.SQLSmith.SelectSetOperation,
+			CTE:          c.cfg.Generator.SQLSmith.SelectCTE,
+		},
 	}
 	return &opt
 }
@@ -54,7 +78,7 @@ func (c *Core) initConnectionWithoutSchema(id int) (*executor.Executor, error) {
 	}
 
 	switch c.cfg.Mode {
-	case "single", "tiflash":
+	case "single", "tiflash", "oracle":
 		e, err = executor.New(removeDSNSchema(c.cfg.DSN1), c.generateExecutorOption(id), tiFlash)
 		if err != nil {
 			return nil, errors.Trace(err)
@@ -87,7 +111,7 @@ func (c *Core) initConnection(id int) (*executor.Executor, error) {
 	)
 
 	switch c.cfg.Mode {
-	case "single", "tiflash":
+	case "single", "tiflash", "oracle":
 		mode = "single"
 	case "abtest", "tiflash-abtest":
 		mode = "abtest"
@@ -162,6 +186,24 @@ func (c *Core) initSubConnection() error {
 	return nil
 }
 
+func (c *Core) initDDLConnection() error {
+	if c.cfg.Mode == "dm" {
+		return errors.New("concurrent DDL is not supported in dm mode")
+	}
+	e, err := c.initConnection(len(c.executors) + 1)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	c.ddlExec = e
+	switch c.cfg.Mode {
+	case "abtest", "binlog", "tiflash-abtest", "tiflash-binlog":
+		if c.checkExec, err = c.initCompareConnection(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
 func (c *Core) initCompareConnection() (*executor.Executor, error) {
 	var tiFlash bool
 	if strings.HasPrefix(c.cfg.Mode, "tiflash") {
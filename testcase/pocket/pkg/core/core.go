@@ -22,6 +22,8 @@ import (
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/config"
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/connection"
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/executor"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/feedback"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/plan"
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/types"
 )
 
@@ -36,8 +38,15 @@ type Core struct {
 	dbname      string
 	nowExec     *executor.Executor
 	executors   []*executor.Executor
+	ddlExec     *executor.Executor
+	checkExec   *executor.Executor
 	lockWatchCh chan int
 	order       *types.Order
+
+	// planReporter collects the plan diffs of abtest executors
+	planReporter *plan.Reporter
+	// feedback adapts the generation of all the executors by the coverage signals
+	feedback *feedback.Feedback
 	// lock
 	mutex     sync.Mutex
 	execMutex sync.Mutex
@@ -46,11 +55,24 @@ type Core struct {
 
 // New creates a Core struct
 func New(cfg *config.Config) *Core {
-	return &Core{
+	c := Core{
 		cfg:         cfg,
 		lockWatchCh: make(chan int),
 		order:       types.NewOrder(),
 	}
+	// plans are only compared in abtest modes
+	if cfg.Options.PlanDiff && (cfg.Mode == "abtest" || cfg.Mode == "tiflash-abtest") {
+		c.planReporter = plan.NewReporter(plan.Option{
+			SlowRatio:     cfg.Options.PlanSlowRatio,
+			SlowThreshold: cfg.Options.PlanSlowThreshold.Duration,
+		})
+	}
+	if cfg.Options.Feedback {
+		c.feedback = feedback.New(feedback.Option{
+			MutateRatio: cfg.Options.FeedbackMutateRatio,
+		})
+	}
+	return &c
 }
 
 // Start test
@@ -67,6 +89,11 @@ func (c *Core) Start(ctx context.Context) error {
 	if err := c.initSubConnection(); err != nil {
 		return errors.Trace(err)
 	}
+	if c.cfg.Options.ConcurrentDDL {
+		if err := c.initDDLConnection(); err != nil {
+			return errors.Trace(err)
+		}
+	}
 	go c.watchLock()
 	if c.cfg.Options.Reproduce {
 		return c.reproduce(ctx)
@@ -75,6 +102,12 @@ func (c *Core) Start(ctx context.Context) error {
 	go func() {
 		<-initTableReadyCh
 		go c.startCheckConsistency(ctx)
+		if c.ddlExec != nil {
+			go c.startConcurrentDDL(ctx)
+		}
+		if c.feedback != nil {
+			go c.startFeedback(ctx)
+		}
 	}()
 	return errors.Trace(c.generate(ctx, &initTableReadyCh))
 }
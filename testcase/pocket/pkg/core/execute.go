@@ -61,7 +61,7 @@ func (c *Core) executeByID(id int, sql *types.SQL) {
 // and the manipulated db should be c.dbname
 func (c *Core) coreInitDatabaseExecute(sql *types.SQL) error {
 	switch c.cfg.Mode {
-	case "single", "tiflash":
+	case "single", "tiflash", "oracle":
 		return errors.Trace(c.coreExec.GetConn().Exec(sql.SQLStmt))
 	case "binlog", "tiflash-binlog":
 		if err := c.coreExec.GetConn().Exec(sql.SQLStmt); err != nil {
@@ -0,0 +1,92 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/ngaut/log"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/feedback"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/types"
+)
+
+// nextSQLType picks the type of the next statement, the weights are adapted by the feedback if it's enabled
+func (c *Core) nextSQLType() (types.SQLType, feedback.Trace) {
+	if c.feedback == nil {
+		return c.randSQLType(), nil
+	}
+	cfg := c.cfg.Generator.SQLSmith
+	sqlTypes := []types.SQLType{
+		types.SQLTypeTxnBegin,
+		types.SQLTypeTxnCommit,
+		types.SQLTypeTxnRollback,
+		types.SQLTypeDDLCreateTable,
+		types.SQLTypeDDLAlterTable,
+		types.SQLTypeDDLCreateIndex,
+		types.SQLTypeDMLSelect,
+		types.SQLTypeDMLSelectForUpdate,
+		types.SQLTypeDMLDelete,
+		types.SQLTypeDMLUpdate,
+		types.SQLTypeDMLInsert,
+		types.SQLTypeSleep,
+	}
+	weights := []int{
+		cfg.TxnBegin,
+		cfg.TxnCommit,
+		cfg.TxnRollback,
+		cfg.DDLCreateTable,
+		cfg.DDLAlterTable,
+		cfg.DDLCreateIndex,
+		cfg.DMLSelect,
+		cfg.DMLSelectForUpdate,
+		cfg.DMLDelete,
+		cfg.DMLUpdate,
+		cfg.DMLInsert,
+		cfg.Sleep,
+	}
+	index := c.feedback.Choose("stmt", weights)
+	if index < 0 {
+		return types.SQLTypeUnknown, nil
+	}
+	return sqlTypes[index], feedback.Trace{{Kind: "stmt", Index: index}}
+}
+
+// startFeedback polls the coverage of the target if the coverage URL is set,
+// and logs the feedback statistics every feedback interval
+func (c *Core) startFeedback(ctx context.Context) {
+	var coverage *feedback.Coverage
+	if c.cfg.Options.CoverageURL != "" {
+		coverage = feedback.NewCoverage(c.cfg.Options.CoverageURL)
+	}
+	ticker := time.NewTicker(c.cfg.Options.FeedbackInterval.Duration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if coverage != nil {
+			covered, err := coverage.Poll()
+			if err != nil {
+				log.Errorf("[feedback] poll coverage error %v", err)
+			} else {
+				c.feedback.ObserveCoverage(covered)
+			}
+		}
+		log.Info(c.feedback.Report())
+	}
+}
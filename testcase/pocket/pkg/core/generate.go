@@ -64,6 +64,12 @@ func (c *Core) beforeGenerate() error {
 		}
 	}
 
+	if c.planReporter != nil {
+		if err := c.analyzeTables(c.coreExec); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
 	for _, e := range c.executors {
 		if err := e.ReloadSchema(); err != nil {
 			return errors.Trace(err)
@@ -200,7 +206,8 @@ func (c *Core) generateSQLWithExecutor(e *executor.Executor) {
 		err error
 	)
 
-	switch c.randSQLType() {
+	tp, trace := c.nextSQLType()
+	switch tp {
 	case types.SQLTypeDDLCreateTable:
 		sql, err = e.GenerateDDLCreateTable()
 	case types.SQLTypeDDLAlterTable:
@@ -232,6 +239,9 @@ func (c *Core) generateSQLWithExecutor(e *executor.Executor) {
 		log.Errorf("generate SQL error, %+v", errors.ErrorStack(err))
 		return
 	}
+	if sql != nil && trace != nil {
+		sql.Trace = append(trace, sql.Trace...)
+	}
 	if sql != nil {
 		c.execute(e, sql)
 	}
@@ -247,7 +257,8 @@ func (c *Core) serializeGenerateSQL() {
 		e   *executor.Executor
 	)
 
-	switch c.randSQLType() {
+	tp, trace := c.nextSQLType()
+	switch tp {
 	case types.SQLTypeDDLCreateTable:
 		sql, e, err = c.generateDDLCreateTable()
 	case types.SQLTypeDDLAlterTable:
@@ -279,6 +290,9 @@ func (c *Core) serializeGenerateSQL() {
 		log.Errorf("generate SQL error, %+v", errors.ErrorStack(err))
 		return
 	}
+	if sql != nil && trace != nil {
+		sql.Trace = append(trace, sql.Trace...)
+	}
 	c.nowExec = e
 	if e != nil && sql != nil {
 		c.execute(e, sql)
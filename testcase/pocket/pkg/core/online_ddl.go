@@ -0,0 +1,222 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/connection"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/generator/generator"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/mysql"
+)
+
+// startConcurrentDDL runs DDLs by ddlExec without execMutex,
+// so they are executed concurrently with the DMLs and transactions of other executors
+func (c *Core) startConcurrentDDL(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.Options.DDLInterval.Duration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.concurrentDDL(); err != nil {
+				log.Errorf("concurrent DDL error %+v", errors.ErrorStack(err))
+			}
+		}
+	}
+}
+
+func (c *Core) concurrentDDL() error {
+	e := c.ddlExec
+	if err := e.ReloadSchema(); err != nil {
+		return errors.Trace(err)
+	}
+	versions, err := c.schemaVersions()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	sqls, table, err := e.GenerateDDLOnline(&generator.DDLOptions{
+		OnlineDDL:       true,
+		ShowCreateTable: e.GetConn().FetchCreateTable,
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, sql := range sqls {
+		// the error of DDL is logged by executor, a failed DDL still needs the check
+		if err := e.ExecSQL(sql); err != nil {
+			break
+		}
+	}
+
+	conns, err := c.waitDDLJobsSynced(table, versions)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	tables, err := e.GetConn().FetchTables(c.dbname)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, t := range tables {
+		// the table may be renamed
+		if t != table {
+			continue
+		}
+		for _, conn := range conns {
+			if err := c.checkTableIndexes(conn, table); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	_, err = c.checkConsistency(false)
+	return errors.Trace(err)
+}
+
+// ddlConns returns the connections the DDLs are executed or replicated to
+func (c *Core) ddlConns() []*connection.Connection {
+	if c.checkExec != nil {
+		return []*connection.Connection{c.checkExec.GetConn1(), c.checkExec.GetConn2()}
+	}
+	return []*connection.Connection{c.ddlExec.GetConn1()}
+}
+
+// schemaVersions returns the schema versions of the connections to TiDB
+func (c *Core) schemaVersions() (map[*connection.Connection]int64, error) {
+	versions := make(map[*connection.Connection]int64)
+	for _, conn := range c.ddlConns() {
+		jobs, err := conn.FetchDDLJobs(c.dbname)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if jobs == nil {
+			continue
+		}
+		version, err := conn.FetchSchemaVersion()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		versions[conn] = version
+	}
+	return versions, nil
+}
+
+// waitDDLJobsSynced waits until all the DDL jobs of the database are done,
+// and the schema versions of both sides grow the same since the versions before the DDLs,
+// which means both sides reach the same schema.
+// It returns the connections to TiDB, since information_schema.ddl_jobs only exists in TiDB.
+func (c *Core) waitDDLJobsSynced(table string, versions map[*connection.Connection]int64) ([]*connection.Connection, error) {
+	var tidbConns []*connection.Connection
+	err := wait.PollImmediate(time.Second, c.cfg.Options.SyncTimeout.Duration, func() (bool, error) {
+		var deltas []int64
+		tidbConns = tidbConns[:0]
+		for _, conn := range c.ddlConns() {
+			jobs, err := conn.FetchDDLJobs(c.dbname)
+			if err != nil {
+				// may be caused by chaos, wait for sync timeout before throwing an error
+				log.Error(err)
+				return false, nil
+			}
+			if jobs == nil {
+				continue
+			}
+			tidbConns = append(tidbConns, conn)
+			for _, job := range jobs {
+				if !job.Done() {
+					return false, nil
+				}
+			}
+			before, ok := versions[conn]
+			if !ok {
+				return false, errors.New("schema version before the DDLs is not fetched")
+			}
+			version, err := conn.FetchSchemaVersion()
+			if err != nil {
+				log.Error(err)
+				return false, nil
+			}
+			deltas = append(deltas, version-before)
+		}
+		for _, delta := range deltas {
+			if delta != deltas[0] {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, errors.Annotatef(err, "wait DDL jobs of table %s synced timeout in %s", table, c.cfg.Options.SyncTimeout.Duration)
+	}
+	return tidbConns, nil
+}
+
+// checkTableIndexes checks the table by ADMIN CHECK TABLE,
+// and then checks the row count read from each index is the same as the one read from the table
+func (c *Core) checkTableIndexes(conn *connection.Connection, table string) error {
+	if err := conn.AdminCheckTable(table); err != nil {
+		if mysql.IsErrDataInconsistent(err) {
+			log.Fatalf("admin check table %s failed, %v", table, err)
+		}
+		return errors.Trace(err)
+	}
+	indexes, err := conn.FetchIndexes(c.dbname, table)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	// read in the same snapshot
+	if err := conn.Begin(); err != nil {
+		return errors.Trace(err)
+	}
+	defer func() {
+		_ = conn.Rollback()
+	}()
+	rowCount, err := selectCount(conn, fmt.Sprintf("SELECT COUNT(*) FROM %s USE INDEX()", table))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	checked := make(map[string]bool)
+	for _, index := range indexes {
+		if checked[index] {
+			continue
+		}
+		checked[index] = true
+		indexCount, err := selectCount(conn, fmt.Sprintf("SELECT COUNT(*) FROM %s FORCE INDEX(`%s`)", table, index))
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if indexCount != rowCount {
+			log.Fatalf("inconsistent index %s of table %s, row count %s but index count %s", index, table, rowCount, indexCount)
+		}
+	}
+	log.Infof("index check of table %s pass", table)
+	return nil
+}
+
+func selectCount(conn *connection.Connection, sql string) (string, error) {
+	res, err := conn.Select(sql)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if len(res) != 1 || len(res[0]) != 1 {
+		return "", errors.Errorf("unexpected result of %s", sql)
+	}
+	return res[0][0].ValString, nil
+}
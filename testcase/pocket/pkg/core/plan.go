@@ -0,0 +1,56 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/executor"
+)
+
+const planReportFile = "plan-diff.md"
+
+// analyzeTables synchronizes the statistics of both sides,
+// it should be called when both sides have the same data so that the plans are compared with the same statistics
+func (c *Core) analyzeTables(e *executor.Executor) error {
+	tables, err := e.GetConn1().FetchTables(c.dbname)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, table := range tables {
+		sql := fmt.Sprintf("ANALYZE TABLE %s", table)
+		if err := e.GetConn1().ExecDDL(sql); err != nil {
+			return errors.Trace(err)
+		}
+		if err := e.GetConn2().ExecDDL(sql); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	log.Infof("%d tables analyzed in both sides", len(tables))
+	return nil
+}
+
+// flushPlanReport writes the plan diff report to the log path
+func (c *Core) flushPlanReport() {
+	if c.planReporter == nil {
+		return
+	}
+	if err := c.planReporter.Flush(path.Join(c.cfg.Options.Path, planReportFile)); err != nil {
+		log.Errorf("write plan diff report error %v", err)
+	}
+}
@@ -56,24 +56,7 @@ func (c *Core) reproduce(ctx context.Context) error {
 }
 
 func (c *Core) reproduceFromDir(dir, table string) error {
-	var logFiles []string
-	files, err := ioutil.ReadDir(dir)
-	if err != nil {
-		log.Fatal(err)
-	}
-	for _, f := range files {
-		match := false
-		if c.cfg.Mode == "abtest" && abTestLogPattern.MatchString(f.Name()) {
-			match = true
-		} else if c.cfg.Mode == "binlog" && binlogTestLogPattern.MatchString(f.Name()) {
-			match = true
-		}
-		if match {
-			logFiles = append(logFiles, path.Join(dir, f.Name()))
-		}
-	}
-
-	logs, err := c.readLogs(logFiles)
+	logs, err := ReadLogDir(dir, c.cfg.Mode)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -104,7 +87,28 @@ func (c *Core) reproduceFromDir(dir, table string) error {
 	return nil
 }
 
-func (c *Core) readLogs(logFiles []string) ([]*types.Log, error) {
+// ReadLogDir reads the executed SQLs from the executor logs of the mode in dir, sorted by their time
+func ReadLogDir(dir, mode string) ([]*types.Log, error) {
+	var logFiles []string
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, f := range files {
+		match := false
+		if mode == "abtest" && abTestLogPattern.MatchString(f.Name()) {
+			match = true
+		} else if (mode == "binlog" || mode == "single" || mode == "oracle") && binlogTestLogPattern.MatchString(f.Name()) {
+			match = true
+		}
+		if match {
+			logFiles = append(logFiles, path.Join(dir, f.Name()))
+		}
+	}
+	return readLogs(logFiles)
+}
+
+func readLogs(logFiles []string) ([]*types.Log, error) {
 	var serilizedLogs []*types.Log
 	for _, file := range logFiles {
 		logs, err := readLogFile(file)
@@ -182,7 +186,7 @@ func parseLog(line string, node int) (*types.Log, error) {
 
 func parseSQLType(sql string) types.SQLType {
 	sql = strings.ToLower(sql)
-	if strings.HasPrefix(sql, "select") {
+	if strings.HasPrefix(sql, "select") || strings.HasPrefix(sql, "with") {
 		return types.SQLTypeDMLSelect
 	}
 	if strings.HasPrefix(sql, "update") {
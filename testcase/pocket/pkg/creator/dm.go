@@ -24,9 +24,9 @@ import (
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb-tools/pkg/dbutil"
-	"github.com/pingcap/tidb-tools/pkg/diff"
 	"k8s.io/apimachinery/pkg/util/wait"
 
+	syncdiff "github.com/pingcap/tipocket/pkg/check/sync-diff"
 	"github.com/pingcap/tipocket/pkg/cluster"
 	"github.com/pingcap/tipocket/pkg/util/dmutil"
 	pocketCore "github.com/pingcap/tipocket/testcase/pocket/pkg/core"
@@ -192,49 +192,24 @@ func dmSyncDiffSingleTask(ctx context.Context, mysqlDB, tidbDB *sql.DB, schema s
 		return errors.Errorf("fail to get tables for schema %s, %v", schema, err)
 	}
 
-	for _, table := range tables {
-		sourceTables := []*diff.TableInstance{
-			{
-				Conn:       mysqlDB,
-				Schema:     schema,
-				Table:      table,
-				InstanceID: "source-1",
-			},
-		}
-
-		targetTable := &diff.TableInstance{
-			Conn:       tidbDB,
-			Schema:     schema,
-			Table:      table,
-			InstanceID: "target",
-		}
-
-		td := &diff.TableDiff{
-			SourceTables:     sourceTables,
-			TargetTable:      targetTable,
-			ChunkSize:        1000,
-			Sample:           100,
-			CheckThreadCount: 1,
-			UseChecksum:      true,
-			TiDBStatsSource:  targetTable,
-			CpDB:             tidbDB,
-		}
-
-		structEqual, dataEqual, err := td.Equal(ctx, func(dml string) error {
-			fmt.Println("fix SQL:", dml) // output to stdout
-			return nil
-		})
-
+	diffs, err := syncdiff.CompareTables(syncdiff.WithContext(ctx, mysqlDB), syncdiff.WithContext(ctx, tidbDB),
+		schema, tables, syncdiff.Config{ChunkSize: 1000})
+	if err != nil {
 		if errors.Cause(err) == context.Canceled || errors.Cause(err) == context.DeadlineExceeded {
 			return nil
 		}
-		if !structEqual {
-			return errors.Errorf("different struct for table %s", table)
-		} else if !dataEqual {
-			return errors.Errorf("different data for table %s", table)
+		return errors.Errorf("fail to compare tables for schema %s, %v", schema, err)
+	}
+	for _, diff := range diffs {
+		if diff.StructDiff != "" {
+			return errors.Errorf("different struct for table %s, %s", diff.Table, diff.StructDiff)
 		}
-		log.Infof("struct and data are equal for table %s", table)
 	}
+	if report := syncdiff.Report(diffs); report != "" {
+		fmt.Println(report) // output the diff and fix SQL to stdout
+		return errors.Errorf("different data for schema %s", schema)
+	}
+	log.Infof("struct and data are equal for schema %s", schema)
 
 	return nil
 }
\ No newline at end of file
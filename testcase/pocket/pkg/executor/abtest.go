@@ -38,12 +38,31 @@ func (e *Executor) execABTestSQL(sql *types.SQL) error {
 	switch sql.SQLType {
 	case types.SQLTypeDMLSelect, types.SQLTypeDMLSelectForUpdate:
 		err = e.abTestSelect(sql.SQLStmt)
+		e.observeFeedback(sql)
+		if err == nil && sql.SQLType == types.SQLTypeDMLSelect && e.opt.PlanReporter != nil {
+			e.abTestPlan(sql.SQLStmt)
+		}
+		if err == nil && e.opt.Prepare {
+			err = e.abTestPrepared(sql.SQLStmt, true)
+		}
 	case types.SQLTypeDMLUpdate:
 		err = e.abTestUpdate(sql.SQLStmt)
+		e.observeFeedback(sql)
+		if err == nil && e.opt.Prepare {
+			err = e.abTestPrepared(sql.SQLStmt, false)
+		}
 	case types.SQLTypeDMLInsert:
 		err = e.abTestInsert(sql.SQLStmt)
+		e.observeFeedback(sql)
+		if err == nil && e.opt.Prepare {
+			err = e.abTestPrepared(sql.SQLStmt, false)
+		}
 	case types.SQLTypeDMLDelete:
 		err = e.abTestDelete(sql.SQLStmt)
+		e.observeFeedback(sql)
+		if err == nil && e.opt.Prepare {
+			err = e.abTestPrepared(sql.SQLStmt, false)
+		}
 	case types.SQLTypeDDLCreateTable:
 		err = e.abTestExecDDL(sql.SQLStmt)
 		if e.TiFlash {
@@ -53,6 +72,9 @@ func (e *Executor) execABTestSQL(sql *types.SQL) error {
 		}
 	case types.SQLTypeDDLAlterTable, types.SQLTypeDDLCreateIndex:
 		err = e.abTestExecDDL(sql.SQLStmt)
+	case types.SQLTypeDDLTiFlashReplica:
+		// TiFlash is only on the first side
+		err = e.singleTestExecDDL(sql.SQLStmt)
 	case types.SQLTypeTxnBegin:
 		if err := e.reloadSchema(); err != nil {
 			log.Error(err)
@@ -169,30 +191,9 @@ func (e *Executor) abTestSelect(sql string) error {
 		return err
 	}
 
-	if len(res1) != len(res2) {
-		return util.WrapErrExactlyNotSame("row number not match res1: %d, res2: %d", len(res1), len(res2))
+	if err := e.cmp.CompareQuery(sql, res1, res2); err != nil {
+		return util.WrapErrExactlyNotSame("%s", err.Error())
 	}
-	for index := range res1 {
-		var (
-			row1 = res1[index]
-			row2 = res2[index]
-		)
-
-		if len(row1) != len(row1) {
-			return util.WrapErrExactlyNotSame("column number not match res1: %d, res2: %d", len(res1), len(res2))
-		}
-
-		for rIndex := range row1 {
-			var (
-				item1 = row1[rIndex]
-				item2 = row2[rIndex]
-			)
-			if err := item1.MustSame(item2); err != nil {
-				return util.WrapErrExactlyNotSame("%s, row index %d, column index %d", err.Error(), index, rIndex)
-			}
-		}
-	}
-
 	return nil
 }
 
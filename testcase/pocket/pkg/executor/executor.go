@@ -26,6 +26,7 @@ import (
 
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith"
 
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/comparator"
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/connection"
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/generator/generator"
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/logger"
@@ -47,6 +48,7 @@ type Executor struct {
 	conn2       *connection.Connection
 	conn3       *connection.Connection
 	ss          generator.Generator
+	cmp         *comparator.Comparator
 	dbname      string
 	mode        string
 	opt         *Option
@@ -89,6 +91,11 @@ func New(dsn string, opt *Option, tiFlash bool) (*Executor, error) {
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	cmp, err := opt.Comparator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	cmp = cmp.WithCollations(conn.FetchCollation)
 	e := Executor{
 		id:         opt.ID,
 		dsn1:       dsn,
@@ -98,6 +105,7 @@ func New(dsn string, opt *Option, tiFlash bool) (*Executor, error) {
 		TxnReadyCh: make(chan struct{}, 1),
 		ErrCh:      make(chan error, 1),
 		ss:         sqlsmith.New(),
+		cmp:        cmp,
 		dbname:     dbnameRegex.FindString(dsn),
 		opt:        opt,
 		logger:     l,
@@ -139,6 +147,11 @@ func NewABTest(dsn1, dsn2 string, opt *Option, tiFlash bool) (*Executor, error)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	cmp, err := opt.Comparator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	cmp = cmp.WithCollations(conn1.FetchCollation)
 	e := Executor{
 		id:         opt.ID,
 		dsn1:       dsn1,
@@ -146,6 +159,7 @@ func NewABTest(dsn1, dsn2 string, opt *Option, tiFlash bool) (*Executor, error)
 		conn1:      conn1,
 		conn2:      conn2,
 		ss:         sqlsmith.New(),
+		cmp:        cmp,
 		mode:       "abtest",
 		SQLCh:      make(chan *types.SQL, 1),
 		TxnReadyCh: make(chan struct{}, 1),
@@ -0,0 +1,97 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/feedback"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/plan"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/types"
+)
+
+var (
+	// the quoted names and values and the numbers are stripped from the error messages
+	errorQuotedRegex = regexp.MustCompile("'[^']*'|\"[^\"]*\"|`[^`]*`")
+	errorNumberRegex = regexp.MustCompile(`\d+`)
+)
+
+// maxErrorSignalLength limits the length of the normalized error messages
+const maxErrorSignalLength = 80
+
+// mutateStmt returns a mutation of an interesting statement of the type from the feedback corpus,
+// the statements are limited to the online tables if there are
+func (e *Executor) mutateStmt(tp types.SQLType) *types.SQL {
+	if e.opt.Feedback == nil {
+		return nil
+	}
+	entry, ok := e.opt.Feedback.Mutate(tp.String(), e.OnlineTable)
+	if !ok {
+		return nil
+	}
+	return &types.SQL{
+		SQLType:  tp,
+		SQLStmt:  entry.SQL,
+		SQLTable: entry.Table,
+		Trace:    entry.Trace,
+	}
+}
+
+// observeFeedback collects the coverage signals of the executed statement in the first side,
+// which are the error it returned, or the operators and join types of its plan
+func (e *Executor) observeFeedback(sql *types.SQL) {
+	if e.opt.Feedback == nil {
+		return
+	}
+	var signals []string
+	if err := e.conn1.LastError(); err != nil {
+		signals = append(signals, errorSignal(err))
+	} else if sql.SQLType != types.SQLTypeDMLInsert {
+		res, err := e.conn1.Select("EXPLAIN FORMAT='brief' " + sql.SQLStmt)
+		if err != nil {
+			signals = append(signals, errorSignal(err))
+		} else if p, err := plan.ParseResult(res); err == nil {
+			signals = append(signals, p.Signals()...)
+		}
+	}
+	fresh := e.opt.Feedback.Observe(&feedback.Entry{
+		Kind:  sql.SQLType.String(),
+		SQL:   sql.SQLStmt,
+		Table: sql.SQLTable,
+		Trace: sql.Trace,
+	}, signals)
+	if fresh > 0 {
+		log.Infof("[feedback] %d new signals by %s", fresh, sql.SQLStmt)
+	}
+}
+
+// errorSignal returns the error code and the normalized message
+func errorSignal(err error) string {
+	mysqlErr, ok := errors.Cause(err).(*mysql.MySQLError)
+	if !ok {
+		return "error:unknown"
+	}
+	msg := errorQuotedRegex.ReplaceAllString(mysqlErr.Message, "?")
+	msg = errorNumberRegex.ReplaceAllString(msg, "N")
+	if len(msg) > maxErrorSignalLength {
+		msg = msg[:maxErrorSignalLength]
+	}
+	return fmt.Sprintf("error:%d:%s", mysqlErr.Number, strings.TrimSpace(msg))
+}
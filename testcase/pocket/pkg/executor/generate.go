@@ -50,11 +50,20 @@ func (e *Executor) reloadSchema() error {
 		indexes[col[1]] = index
 	}
 
+	partitions, err := e.conn1.FetchPartitions(e.dbname)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
 	e.ss = smith.New()
 	e.ss.LoadSchema(schema, indexes)
 	e.ss.SetDB(e.dbname)
+	e.ss.LoadPartitions(partitions)
 	e.ss.SetStable(e.opt.Stable)
 	e.ss.SetHint(e.opt.Hint)
+	e.ss.SetPartition(e.opt.Partition)
+	e.ss.SetSelectOptions(&e.opt.Select)
+	e.ss.SetFeedback(e.opt.Feedback)
 	e.BeginWithOnlineTables()
 	return nil
 }
@@ -98,8 +107,40 @@ func (e *Executor) GenerateDDLAlterTable(opt *generator.DDLOptions) (*types.SQL,
 	}, nil
 }
 
+// GenerateDDLOnline rand a DDL which runs concurrently with DMLs, the statements before the last one prepare for it,
+// the returned table is the one changed by the DDL
+func (e *Executor) GenerateDDLOnline(opt *generator.DDLOptions) ([]*types.SQL, string, error) {
+	if e.TiFlash && util.Rd(10) == 0 {
+		stmt, table, err := e.ss.TiFlashReplicaStmt()
+		if err != nil {
+			return nil, "", errors.Trace(err)
+		}
+		return []*types.SQL{{
+			SQLType:  types.SQLTypeDDLTiFlashReplica,
+			SQLTable: table,
+			SQLStmt:  stmt,
+		}}, table, nil
+	}
+	stmts, table, err := e.ss.OnlineDDLStmt(opt)
+	if err != nil {
+		return nil, "", errors.Trace(err)
+	}
+	var sqls []*types.SQL
+	for _, stmt := range stmts {
+		sqls = append(sqls, &types.SQL{
+			SQLType:  types.SQLTypeDDLAlterTable,
+			SQLTable: table,
+			SQLStmt:  stmt,
+		})
+	}
+	return sqls, table, nil
+}
+
 // GenerateDMLSelect rand select statement
 func (e *Executor) GenerateDMLSelect() (*types.SQL, error) {
+	if sql := e.mutateStmt(types.SQLTypeDMLSelect); sql != nil {
+		return sql, nil
+	}
 	stmt, table, err := e.ss.SelectStmt(4)
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -108,6 +149,7 @@ func (e *Executor) GenerateDMLSelect() (*types.SQL, error) {
 		SQLType:  types.SQLTypeDMLSelect,
 		SQLStmt:  stmt,
 		SQLTable: table,
+		Trace:    e.ss.Trace(),
 	}, nil
 }
 
@@ -121,11 +163,15 @@ func (e *Executor) GenerateDMLSelectForUpdate() (*types.SQL, error) {
 		SQLType:  types.SQLTypeDMLSelectForUpdate,
 		SQLStmt:  stmt,
 		SQLTable: table,
+		Trace:    e.ss.Trace(),
 	}, nil
 }
 
 // GenerateDMLUpdate rand update statement
 func (e *Executor) GenerateDMLUpdate() (*types.SQL, error) {
+	if sql := e.mutateStmt(types.SQLTypeDMLUpdate); sql != nil {
+		return sql, nil
+	}
 	stmt, table, err := e.ss.UpdateStmt()
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -138,11 +184,15 @@ func (e *Executor) GenerateDMLUpdate() (*types.SQL, error) {
 		SQLType:  types.SQLTypeDMLUpdate,
 		SQLStmt:  stmt,
 		SQLTable: table,
+		Trace:    e.ss.Trace(),
 	}, nil
 }
 
 // GenerateDMLDelete rand update statement
 func (e *Executor) GenerateDMLDelete() (*types.SQL, error) {
+	if sql := e.mutateStmt(types.SQLTypeDMLDelete); sql != nil {
+		return sql, nil
+	}
 	stmt, table, err := e.ss.DeleteStmt()
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -151,6 +201,7 @@ func (e *Executor) GenerateDMLDelete() (*types.SQL, error) {
 		SQLType:  types.SQLTypeDMLDelete,
 		SQLStmt:  stmt,
 		SQLTable: table,
+		Trace:    e.ss.Trace(),
 	}, nil
 }
 
@@ -164,6 +215,7 @@ func (e *Executor) GenerateDMLInsert() (*types.SQL, error) {
 		SQLType:  types.SQLTypeDMLInsert,
 		SQLStmt:  stmt,
 		SQLTable: table,
+		Trace:    e.ss.Trace(),
 	}, nil
 }
 
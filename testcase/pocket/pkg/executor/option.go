@@ -14,6 +14,17 @@
 
 package executor
 
+import (
+	"time"
+
+	"github.com/juju/errors"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/comparator"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/generator/generator"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/feedback"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/plan"
+)
+
 // Option struct
 type Option struct {
 	ID         int
@@ -27,10 +38,48 @@ type Option struct {
 	GeneralLog bool
 	Hint       bool
 	TiFlash    bool
+	// Partition enables generating range partitioned tables
+	Partition bool
+	// Oracle checks select statements by logic-bug oracles instead of executing them only
+	Oracle bool
+	// tolerances and session time zones for comparing results, see comparator.Option
+	FloatTolerance   float64
+	DecimalTolerance float64
+	TimeZone1        string
+	TimeZone2        string
+	// PlanReporter collects the plan differences of the select statements in abtest mode, nil disables the plan diff
+	PlanReporter *plan.Reporter
+	// PlanAnalyze runs EXPLAIN ANALYZE for the execution time instead of EXPLAIN FORMAT='brief'
+	PlanAnalyze bool
+	// Prepare also executes the generated DMLs as prepared statements with different parameters in abtest mode,
+	// with the prepared plan cache enabled in the first side and disabled in the second side
+	Prepare bool
+	// Select is the weights of the generated select statement shapes
+	Select generator.SelectOptions
+	// Feedback adapts the generation by the coverage signals of the executed statements, nil disables it
+	Feedback *feedback.Feedback
 }
 
 // Clone option
 func (o *Option) Clone() *Option {
 	o1 := *o
 	return &o1
+}
+
+// Comparator creates a result comparator from the option
+func (o *Option) Comparator() (*comparator.Comparator, error) {
+	loc1, err := time.LoadLocation(o.TimeZone1)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	loc2, err := time.LoadLocation(o.TimeZone2)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return comparator.New(comparator.Option{
+		FloatTolerance:   o.FloatTolerance,
+		DecimalTolerance: o.DecimalTolerance,
+		Location1:        loc1,
+		Location2:        loc2,
+	}), nil
 }
\ No newline at end of file
@@ -0,0 +1,187 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/connection"
+	smith "github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/util"
+)
+
+// oracleTestSelect checks the select statement by a random logic-bug oracle on a single database
+func (e *Executor) oracleTestSelect(sql string) error {
+	if !e.conn1.IfTxn() {
+		// all statements of an oracle must read the same snapshot
+		if err := e.conn1.Begin(); err != nil {
+			return errors.Trace(err)
+		}
+		defer func() {
+			_ = e.conn1.Rollback()
+		}()
+	}
+
+	var err error
+	switch util.Rd(3) {
+	case 0:
+		err = e.oracleTLP(sql)
+	case 1:
+		err = e.oracleNoREC(sql)
+	default:
+		err = e.oraclePQS()
+	}
+	if errors.Cause(err) == util.ErrExactlyNotSame {
+		log.Fatalf("logic bug found by oracle, %+v", err)
+	}
+	return err
+}
+
+// oracleTLP checks the union of the partitions is the same as the unfiltered statement
+func (e *Executor) oracleTLP(sql string) error {
+	base, partitions, err := smith.TLPStmts(sql)
+	if err != nil {
+		// e.g. aggregations, window functions, set operations and CTEs are not partitionable
+		_, err := e.oracleSelect(sql)
+		return errors.Trace(err)
+	}
+	expected, err := e.oracleSelect(base)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	var union [][]*connection.QueryItem
+	for _, partition := range partitions {
+		res, err := e.oracleSelect(partition)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		union = append(union, res...)
+	}
+	if err := e.cmp.Compare(expected, union, false); err != nil {
+		return util.WrapErrExactlyNotSame("TLP: %s, unfiltered: %s, partitions: %s",
+			err.Error(), base, strings.Join(partitions, "; "))
+	}
+	return nil
+}
+
+// oracleNoREC checks the optimized count is the same as the unoptimized one
+func (e *Executor) oracleNoREC(sql string) error {
+	optimized, unoptimized, err := smith.NoRECStmts(sql)
+	if err != nil {
+		_, err := e.oracleSelect(sql)
+		return errors.Trace(err)
+	}
+	res1, err := e.oracleSelect(optimized)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	res2, err := e.oracleSelect(unoptimized)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	count1, err := singleNumber(res1)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	count2, err := singleNumber(res2)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if count1 != count2 {
+		return util.WrapErrExactlyNotSame("NoREC: count not match optimized: %v, unoptimized: %v, statements: %s; %s",
+			count1, count2, optimized, unoptimized)
+	}
+	return nil
+}
+
+// oraclePQS checks the pivot row is contained by the statement synthesized from it
+func (e *Executor) oraclePQS() error {
+	tables, err := e.conn1.FetchTables(e.dbname)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+	table := tables[util.Rd(len(tables))]
+	res, err := e.oracleSelect(smith.PivotStmt(table))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	var pivot []smith.PivotValue
+	for _, item := range res[0] {
+		pivot = append(pivot, smith.PivotValue{
+			Column: item.ValType.Name(),
+			Type:   item.ValType.DatabaseTypeName(),
+			Null:   item.Null,
+			Value:  item.ValString,
+		})
+	}
+	stmt := smith.PQSStmt(table, pivot)
+	res, err = e.oracleSelect(stmt)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, row := range res {
+		if pivotRowMatch(row, pivot) {
+			return nil
+		}
+	}
+	return util.WrapErrExactlyNotSame("PQS: pivot row %s not found by %s", formatPivot(pivot), stmt)
+}
+
+func (e *Executor) oracleSelect(sql string) ([][]*connection.QueryItem, error) {
+	e.logStmtTodo(sql)
+	res, err := e.conn1.Select(sql)
+	e.logStmtResult(sql, err)
+	return res, errors.Trace(err)
+}
+
+func pivotRowMatch(row []*connection.QueryItem, pivot []smith.PivotValue) bool {
+	if len(row) != len(pivot) {
+		return false
+	}
+	for i, item := range row {
+		if item.Null != pivot[i].Null || item.ValString != pivot[i].Value {
+			return false
+		}
+	}
+	return true
+}
+
+func singleNumber(res [][]*connection.QueryItem) (float64, error) {
+	if len(res) != 1 || len(res[0]) != 1 || res[0][0].Null {
+		return 0, errors.Errorf("expect a single number, got %d rows", len(res))
+	}
+	return strconv.ParseFloat(res[0][0].ValString, 64)
+}
+
+func formatPivot(pivot []smith.PivotValue) string {
+	var values []string
+	for _, value := range pivot {
+		if value.Null {
+			values = append(values, value.Column+"=NULL")
+		} else {
+			values = append(values, value.Column+"="+strconv.Quote(value.Value))
+		}
+	}
+	return "(" + strings.Join(values, ", ") + ")"
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"sync"
+
+	"github.com/ngaut/log"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/connection"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/plan"
+)
+
+// abTestPlan explains the select statement in both sides and records the plan difference,
+// the errors are only logged since the plans do not affect the correctness
+func (e *Executor) abTestPlan(sql string) {
+	explain := "EXPLAIN FORMAT='brief' "
+	if e.opt.PlanAnalyze {
+		explain = "EXPLAIN ANALYZE "
+	}
+	var (
+		wg   sync.WaitGroup
+		res1 [][]*connection.QueryItem
+		res2 [][]*connection.QueryItem
+		err1 error
+		err2 error
+	)
+	wg.Add(2)
+	go func() {
+		res1, err1 = e.conn1.Select(explain + sql)
+		wg.Done()
+	}()
+	go func() {
+		res2, err2 = e.conn2.Select(explain + sql)
+		wg.Done()
+	}()
+	wg.Wait()
+	if err1 != nil || err2 != nil {
+		log.Errorf("explain %s error, A: %v, B: %v", sql, err1, err2)
+		return
+	}
+
+	plan1, err := plan.ParseResult(res1)
+	if err != nil {
+		log.Errorf("parse plan A of %s error %v", sql, err)
+		return
+	}
+	plan2, err := plan.ParseResult(res2)
+	if err != nil {
+		log.Errorf("parse plan B of %s error %v", sql, err)
+		return
+	}
+	if d := e.opt.PlanReporter.Record(sql, plan1, plan2); d != nil {
+		log.Infof("plan diff %s, slow %t: %s\nplan A:\n%splan B:\n%s", d.Kind, d.Slow, sql, plan1, plan2)
+	}
+}
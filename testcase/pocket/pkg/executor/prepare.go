@@ -0,0 +1,223 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/parser"
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/format"
+	tidbTypes "github.com/pingcap/tidb/types"
+	driver "github.com/pingcap/tidb/types/parser_driver"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/connection"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/mysql"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/util"
+)
+
+// preparedExecutions is how many times a prepared statement is executed with different parameters
+const preparedExecutions = 3
+
+// mismatchedParams are the parameters which may not match the types of the replaced literals
+var mismatchedParams = []interface{}{
+	nil,
+	int64(0),
+	int64(-1),
+	uint64(math.MaxUint64),
+	"",
+	"1abc",
+	"2020-02-29 12:34:56",
+	[]byte{0xff},
+}
+
+// literalRewriter replaces the literals which can be parameter markers in order
+type literalRewriter struct {
+	fn    func(i int, v *driver.ValueExpr) ast.ExprNode
+	count int
+}
+
+// Enter implements ast.Visitor interface
+func (r *literalRewriter) Enter(in ast.Node) (ast.Node, bool) {
+	switch n := in.(type) {
+	case *ast.ByItem:
+		// ORDER BY 1 is the position of the field, while ORDER BY ? is a constant
+		_, ok := n.Expr.(*driver.ValueExpr)
+		return in, ok
+	case *ast.FrameBound, *ast.Limit:
+		// only integer literals are allowed in the text protocol
+		return in, true
+	case *ast.AggregateFuncExpr:
+		// the separator of GROUP_CONCAT must be a literal
+		return in, strings.ToLower(n.F) == ast.AggFuncGroupConcat
+	}
+	return in, false
+}
+
+// Leave implements ast.Visitor interface
+func (r *literalRewriter) Leave(in ast.Node) (ast.Node, bool) {
+	if v, ok := in.(*driver.ValueExpr); ok {
+		node := r.fn(r.count, v)
+		r.count++
+		return node, true
+	}
+	return in, true
+}
+
+func rewriteLiterals(sql string, fn func(i int, v *driver.ValueExpr) ast.ExprNode) (string, error) {
+	p := parser.New()
+	p.EnableWindowFunc(true)
+	node, err := p.ParseOneStmt(sql, "", "")
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	node.Accept(&literalRewriter{fn: fn})
+	var sb strings.Builder
+	if err := node.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &sb)); err != nil {
+		return "", errors.Trace(err)
+	}
+	return sb.String(), nil
+}
+
+// parameterize replaces the literals of the statement with parameter markers,
+// and returns the statement to prepare and the replaced values as parameters
+func parameterize(sql string) (string, []interface{}, error) {
+	var params []interface{}
+	stmt, err := rewriteLiterals(sql, func(i int, v *driver.ValueExpr) ast.ExprNode {
+		params = append(params, paramOf(v))
+		return &driver.ParamMarkerExpr{Order: i}
+	})
+	return stmt, params, errors.Trace(err)
+}
+
+// literalize replaces the literals of the statement with the parameters,
+// it's the text protocol statement of executing the prepared statement with them
+func literalize(sql string, params []interface{}) (string, error) {
+	stmt, err := rewriteLiterals(sql, func(i int, v *driver.ValueExpr) ast.ExprNode {
+		return ast.NewValueExpr(params[i], "", "")
+	})
+	return stmt, errors.Trace(err)
+}
+
+// paramOf returns the parameter of the literal, which has the same meaning in the binary protocol
+func paramOf(v *driver.ValueExpr) interface{} {
+	switch v.Kind() {
+	case tidbTypes.KindNull:
+		return nil
+	case tidbTypes.KindInt64:
+		return v.GetInt64()
+	case tidbTypes.KindUint64:
+		return v.GetUint64()
+	case tidbTypes.KindFloat32, tidbTypes.KindFloat64:
+		return v.GetFloat64()
+	case tidbTypes.KindMysqlDecimal:
+		return v.GetMysqlDecimal().String()
+	case tidbTypes.KindBytes, tidbTypes.KindBinaryLiteral:
+		return v.GetBytes()
+	}
+	return v.GetString()
+}
+
+// preparedParams returns the parameter sets for executing the prepared statement,
+// some parameters are replaced with NULL or the values which may mismatch their types
+func preparedParams(params []interface{}, original bool) [][]interface{} {
+	var sets [][]interface{}
+	if original {
+		sets = append(sets, params)
+	}
+	for len(sets) < preparedExecutions {
+		set := make([]interface{}, len(params))
+		for i, param := range params {
+			if rand.Intn(2) == 0 {
+				set[i] = param
+			} else {
+				set[i] = mismatchedParams[rand.Intn(len(mismatchedParams))]
+			}
+		}
+		sets = append(sets, set)
+	}
+	return sets
+}
+
+// abTestPrepared executes the statement as a prepared statement in both sides with different parameters,
+// the prepared plan cache is enabled in the first side and disabled in the second side,
+// the results of select statements are also compared with the text protocol execution in the first side.
+func (e *Executor) abTestPrepared(sql string, isSelect bool) error {
+	stmt, params, err := parameterize(sql)
+	if err != nil || len(params) == 0 {
+		// not all the generated statements are supported by the parser
+		return nil
+	}
+	stmt1, err1 := e.conn1.PrepareStmt(stmt, true)
+	stmt2, err2 := e.conn2.PrepareStmt(stmt, false)
+	if stmt1 != nil {
+		defer func() {
+			_ = stmt1.Close()
+		}()
+	}
+	if stmt2 != nil {
+		defer func() {
+			_ = stmt2.Close()
+		}()
+	}
+	if errors.Cause(err1) == mysql.ErrVarNotApplied || errors.Cause(err2) == mysql.ErrVarNotApplied {
+		// the plan cache can't be switched in the session, the comparison makes no sense
+		log.Debugf("skip prepared statement %s, %v, %v", stmt, err1, err2)
+		return nil
+	}
+	if err := util.ErrorMustSame(err1, err2); err != nil || err1 != nil {
+		return err
+	}
+
+	for _, set := range preparedParams(params, isSelect) {
+		if !isSelect {
+			affectedRows1, err1 := stmt1.Exec(set...)
+			affectedRows2, err2 := stmt2.Exec(set...)
+			if err := util.ErrorMustSame(err1, err2); err != nil {
+				return errors.Annotatef(err, "execute %s with %v", stmt, set)
+			}
+			if err := util.AffectedRowsMustSame(affectedRows1, affectedRows2); err != nil {
+				return errors.Annotatef(err, "execute %s with %v", stmt, set)
+			}
+			continue
+		}
+
+		text, err := literalize(sql, set)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		res1, err1 := stmt1.Select(set...)
+		res2, err2 := stmt2.Select(set...)
+		if err := util.ErrorMustSame(err1, err2); err != nil {
+			return errors.Annotatef(err, "execute %s with %v", stmt, set)
+		}
+		res3, err3 := e.conn1.Select(text)
+		if err := util.ErrorMustSame(err1, err3); err != nil {
+			return errors.Annotatef(err, "execute %s with %v and text protocol %s", stmt, set, text)
+		}
+		if err1 != nil {
+			continue
+		}
+		for _, res := range [][][]*connection.QueryItem{res2, res3} {
+			if err := e.cmp.CompareQuery(text, res1, res); err != nil {
+				return util.WrapErrExactlyNotSame("execute %s with %v, text protocol %s: %s", stmt, set, text, err.Error())
+			}
+		}
+	}
+	return nil
+}
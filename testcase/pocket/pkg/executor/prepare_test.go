@@ -0,0 +1,37 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParameterize(t *testing.T) {
+	sql := "SELECT a, GROUP_CONCAT(b SEPARATOR ',') FROM t WHERE a > 1 AND b IN ('x', NULL) AND c = 1.50 GROUP BY a ORDER BY 1 LIMIT 10"
+	stmt, params, err := parameterize(sql)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT `a`,GROUP_CONCAT(`b` SEPARATOR ',') FROM `t` WHERE `a`>? AND `b` IN (?,?) AND `c`=? GROUP BY `a` ORDER BY 1 LIMIT 10", stmt)
+	assert.Equal(t, []interface{}{int64(1), "x", nil, "1.50"}, params)
+
+	text, err := literalize(sql, []interface{}{"1abc", int64(2), nil, nil})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT `a`,GROUP_CONCAT(`b` SEPARATOR ',') FROM `t` WHERE `a`>'1abc' AND `b` IN (2,NULL) AND `c`=NULL GROUP BY `a` ORDER BY 1 LIMIT 10", text)
+
+	sets := preparedParams(params, true)
+	assert.Len(t, sets, preparedExecutions)
+	assert.Equal(t, params, sets[0])
+	assert.Len(t, preparedParams(params, false), preparedExecutions)
+}
@@ -36,14 +36,25 @@ func (e *Executor) execSingleTestSQL(sql *types.SQL) error {
 
 	e.logStmtTodo(sql.SQLStmt)
 	switch sql.SQLType {
-	case types.SQLTypeDMLSelect, types.SQLTypeDMLSelectForUpdate:
+	case types.SQLTypeDMLSelect:
+		if e.opt.Oracle {
+			err = e.oracleTestSelect(sql.SQLStmt)
+		} else {
+			err = e.singleTestSelect(sql.SQLStmt)
+		}
+		e.observeFeedback(sql)
+	case types.SQLTypeDMLSelectForUpdate:
 		err = e.singleTestSelect(sql.SQLStmt)
+		e.observeFeedback(sql)
 	case types.SQLTypeDMLUpdate:
 		err = e.singleTestUpdate(sql.SQLStmt)
+		e.observeFeedback(sql)
 	case types.SQLTypeDMLInsert:
 		err = e.singleTestInsert(sql.SQLStmt)
+		e.observeFeedback(sql)
 	case types.SQLTypeDMLDelete:
 		err = e.singleTestDelete(sql.SQLStmt)
+		e.observeFeedback(sql)
 	case types.SQLTypeDDLCreateTable:
 		err = e.singleTestExecDDL(sql.SQLStmt)
 		if e.TiFlash {
@@ -53,6 +64,9 @@ func (e *Executor) execSingleTestSQL(sql *types.SQL) error {
 		}
 	case types.SQLTypeDDLAlterTable, types.SQLTypeDDLCreateIndex:
 		err = e.singleTestExecDDL(sql.SQLStmt)
+	case types.SQLTypeDDLTiFlashReplica:
+		// TiFlash is only on the first side
+		err = e.singleTestExecDDL(sql.SQLStmt)
 	case types.SQLTypeTxnBegin:
 		if err := e.reloadSchema(); err != nil {
 			log.Error(err)
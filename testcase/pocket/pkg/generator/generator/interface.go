@@ -1,5 +1,7 @@
 package generator
 
+import "github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/feedback"
+
 // Generator interface to unify the usage of sqlsmith and sqlspider
 type Generator interface {
 	// ReloadSchema function read raw scheme
@@ -11,6 +13,11 @@ type Generator interface {
 	// record[4] column type
 	// indexes map table name to index string slice
 	LoadSchema(records [][5]string, indexes map[string][]string)
+	// LoadPartitions read the range partitions of the loaded tables
+	// partitions map table name to its partitions in order
+	// partition[0] partition name
+	// partition[1] partition description, e.g. "100", "MAXVALUE"
+	LoadPartitions(partitions map[string][][2]string)
 	// SetDB set operation database
 	// the generated SQLs after this will be under this database
 	SetDB(db string)
@@ -19,6 +26,14 @@ type Generator interface {
 	SetStable(stable bool)
 	// SetHint can control if hints would be generated or not
 	SetHint(hint bool)
+	// SetPartition can control if range partitioned tables would be generated or not
+	SetPartition(partition bool)
+	// SetSelectOptions sets the weights of the select statement shapes
+	SetSelectOptions(opt *SelectOptions)
+	// SetFeedback adapts the random choices of the generation by the coverage feedback, nil disables it
+	SetFeedback(fb *feedback.Feedback)
+	// Trace returns the choices made for generating the last statement, it's empty without the feedback
+	Trace() feedback.Trace
 	// BeginWithOnlineTables to get online tables and begin transaction
 	// if DMLOptions.OnlineTable is set to true
 	// this function will return a table slice
@@ -42,6 +57,11 @@ type Generator interface {
 	AlterTableStmt(opt *DDLOptions) (string, error)
 	// CreateIndexStmt generate create index SQL, table slice for avoiding online DDL
 	CreateIndexStmt(opt *DDLOptions) (string, error)
+	// OnlineDDLStmt generate a DDL which runs concurrently with DMLs and the table it changes,
+	// the statements before the last one prepare for the DDL, e.g. create the table for exchanging partition
+	OnlineDDLStmt(opt *DDLOptions) ([]string, string, error)
+	// TiFlashReplicaStmt generate set TiFlash replica SQL
+	TiFlashReplicaStmt() (string, string, error)
 }
 
 // DMLOptions for DML generation
@@ -56,6 +76,27 @@ type DMLOptions struct {
 	OnlineTable bool
 }
 
+// SelectOptions for select statement generation,
+// each field is the weight of a shape, all zero means the plain select statements only
+type SelectOptions struct {
+	// Plain is a select from a table or a join of 2 tables
+	Plain int
+	// Aggregation has GROUP BY, HAVING and aggregate functions
+	Aggregation int
+	// Window has window functions in the fields
+	Window int
+	// Join is a join of more than 2 tables, including the outer joins
+	Join int
+	// SemiJoin has IN or EXISTS subqueries in the WHERE clause
+	SemiJoin int
+	// Derived selects from a derived table
+	Derived int
+	// SetOperation is UNION [ALL], EXCEPT or INTERSECT of select statements
+	SetOperation int
+	// CTE is a select statement with common table expressions, which may be recursive
+	CTE int
+}
+
 // DDLOptions for DDL generation
 type DDLOptions struct {
 	// if OnlineDDL is set to false
@@ -68,4 +109,7 @@ type DDLOptions struct {
 	// Tables contains all online tables which should not be modified with DDL
 	// pocket will collect them from other generator instances
 	Tables []string
+	// ShowCreateTable returns the CREATE TABLE statement of a table, the table which
+	// a partition is exchanged with is created by it without the PARTITION clause
+	ShowCreateTable func(table string) (string, error)
 }
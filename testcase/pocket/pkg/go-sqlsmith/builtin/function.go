@@ -26,6 +26,10 @@ func GenerateFuncCallExpr(table *types.Table, args int, stable bool) ast.ExprNod
 	if args == 0 && util.Rd(2) == 0 {
 		return ast.NewValueExpr(util.GenerateRandDataItem(), "", "")
 	}
+	// the functions with typed arguments are less likely to return errors
+	if args == 1 && util.Rd(2) == 0 {
+		return GenerateTypedFuncCallExpr(table)
+	}
 
 	funcCallExpr := ast.FuncCallExpr{}
 
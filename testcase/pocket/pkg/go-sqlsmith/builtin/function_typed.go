@@ -0,0 +1,120 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builtin
+
+import (
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/model"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/types"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/util"
+)
+
+// typedFunctionClass is a deterministic function of one argument whose return type is known,
+// they are used where the types matter, e.g. generated columns and expression indexes
+type typedFunctionClass struct {
+	name string
+	// argClass is the type class of the argument
+	argClass string
+	retType  string
+}
+
+var typedFunctions = []*typedFunctionClass{
+	{ast.Abs, util.TypeClassNumeric, "double"},
+	{ast.Ceil, util.TypeClassNumeric, "double"},
+	{ast.Floor, util.TypeClassNumeric, "double"},
+	{ast.Sign, util.TypeClassNumeric, "int"},
+	{ast.Upper, util.TypeClassString, "text"},
+	{ast.Lower, util.TypeClassString, "text"},
+	{ast.Reverse, util.TypeClassString, "text"},
+	{ast.RTrim, util.TypeClassString, "text"},
+	{ast.Length, util.TypeClassString, "bigint"},
+	{ast.CharLength, util.TypeClassString, "bigint"},
+	{ast.Year, util.TypeClassTime, "int"},
+	{ast.Month, util.TypeClassTime, "int"},
+	{ast.ToDays, util.TypeClassTime, "bigint"},
+	{ast.Date, util.TypeClassTime, "date"},
+	{ast.JSONType, util.TypeClassJSON, "text"},
+	{ast.JSONLength, util.TypeClassJSON, "bigint"},
+	{ast.JSONDepth, util.TypeClassJSON, "bigint"},
+}
+
+// typeOfClass is a type of each class for generating literal arguments
+var typeOfClass = map[string]string{
+	util.TypeClassNumeric: "int",
+	util.TypeClassString:  "varchar",
+	util.TypeClassTime:    "datetime",
+	util.TypeClassJSON:    "json",
+}
+
+func getTypedFuncs(class string) []*typedFunctionClass {
+	var fns []*typedFunctionClass
+	for _, fn := range typedFunctions {
+		if fn.argClass == class {
+			fns = append(fns, fn)
+		}
+	}
+	return fns
+}
+
+// GenerateColumnFuncCallExpr generate a function call on the given column and returns its type,
+// the column is not qualified so it can be used in generated columns and expression indexes
+func GenerateColumnFuncCallExpr(column *types.Column) (ast.ExprNode, string) {
+	fns := getTypedFuncs(util.TypeClass(column.DataType))
+	if len(fns) == 0 {
+		return nil, ""
+	}
+	fn := fns[util.Rd(len(fns))]
+	return &ast.FuncCallExpr{
+		FnName: model.NewCIStr(fn.name),
+		Args: []ast.ExprNode{
+			&ast.ColumnNameExpr{
+				Name: &ast.ColumnName{
+					Name: model.NewCIStr(column.Column),
+				},
+			},
+		},
+	}, fn.retType
+}
+
+// GenerateTypedFuncCallExpr generate a function call whose argument matches the function,
+// the argument is a column of the table if there is a compatible one, otherwise a literal
+func GenerateTypedFuncCallExpr(table *types.Table) ast.ExprNode {
+	fn := typedFunctions[util.Rd(len(typedFunctions))]
+	var arg ast.ExprNode
+	if table != nil {
+		var columns []*types.Column
+		for _, column := range table.Columns {
+			if !column.Func && util.TypeClass(column.DataType) == fn.argClass {
+				columns = append(columns, column)
+			}
+		}
+		if len(columns) != 0 {
+			column := columns[util.Rd(len(columns))]
+			arg = &ast.ColumnNameExpr{
+				Name: &ast.ColumnName{
+					Table: model.NewCIStr(column.Table),
+					Name:  model.NewCIStr(column.Column),
+				},
+			}
+		}
+	}
+	if arg == nil {
+		arg = ast.NewValueExpr(util.GenerateDataItem(typeOfClass[fn.argClass]), "", "")
+	}
+	return &ast.FuncCallExpr{
+		FnName: model.NewCIStr(fn.name),
+		Args:   []ast.ExprNode{arg},
+	}
+}
@@ -19,7 +19,6 @@ import (
 	"strings"
 
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/types"
-	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/util"
 )
 
 // BatchData generate testing data by schema in given batch
@@ -37,7 +36,7 @@ func (s *SQLSmith) BatchData(total, batchSize int) ([]string, error) {
 	for _, table := range database.Tables {
 		var columns []*types.Column
 		for _, column := range table.Columns {
-			if column.Column == "id" {
+			if column.Column == "id" || column.Generated {
 				continue
 			}
 			columns = append(columns, column)
@@ -48,7 +47,7 @@ func (s *SQLSmith) BatchData(total, batchSize int) ([]string, error) {
 		for i := 0; i < total; i++ {
 			var line []string
 			for _, column := range columns {
-				line = append(line, util.GenerateDataItemString(column.DataType))
+				line = append(line, column.RandValueString())
 			}
 			lines = append(lines, line)
 			count++
@@ -85,35 +84,3 @@ func mapFn(arr [][]string, fn func([]string) string) []string {
 	}
 	return res
 }
-
-func (s *SQLSmith) generateDataItem(columnType string) string {
-	switch columnType {
-	case "varchar":
-		return s.generateStringItem()
-	case "text":
-		return s.generateStringItem()
-	case "int":
-		return s.generateIntItem()
-	case "timestamp":
-		return s.generateDateItem()
-	case "float":
-		return s.generateFloatItem()
-	}
-	return ""
-}
-
-func (s *SQLSmith) generateStringItem() string {
-	return fmt.Sprintf("\"%s\"", s.rdString(s.rd(100)))
-}
-
-func (s *SQLSmith) generateIntItem() string {
-	return fmt.Sprintf("%d", s.rd(2147483647))
-}
-
-func (s *SQLSmith) generateFloatItem() string {
-	return fmt.Sprintf("%f", float64(s.rd(100000))*s.rdFloat64())
-}
-
-func (s *SQLSmith) generateDateItem() string {
-	return fmt.Sprintf("\"%s\"", s.rdDate().Format("2006-01-02 15:04:05"))
-}
@@ -17,6 +17,7 @@ import (
 	"github.com/pingcap/parser/ast"
 	"github.com/pingcap/parser/model"
 
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/types"
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/util"
 )
 
@@ -27,11 +28,13 @@ func (s *SQLSmith) createTableStmt() ast.Node {
 		Constraints: []*ast.Constraint{},
 		Options:     []*ast.TableOption{},
 	}
-	// TODO: config for enable partition
-	// partitionStmt is disabled
-	// createTableNode.Partition = s.partitionStmt()
+	if s.partition && util.Rd(2) == 0 {
+		createTableNode.Partition = s.partitionStmt()
+	}
 
-	return &createTableNode
+	return &types.CreateTableStmt{
+		CreateTableStmt: &createTableNode,
+	}
 }
 
 func (s *SQLSmith) alterTableStmt() ast.Node {
@@ -43,6 +46,55 @@ func (s *SQLSmith) alterTableStmt() ast.Node {
 	}
 }
 
+// onlineDDLStmt is an alter table statement with one of the specs which are expected to run online
+func (s *SQLSmith) onlineDDLStmt() *ast.AlterTableStmt {
+	var spec *ast.AlterTableSpec
+	switch util.Rd(10) {
+	case 0:
+		spec = s.alterTableSpecAddColumns()
+	case 1:
+		spec = s.alterTableSpecDropColumn()
+	case 2, 3:
+		spec = s.alterTableSpecModifyColumn()
+	case 4:
+		spec = s.alterTableSpecAddIndex()
+	case 5:
+		spec = s.alterTableSpecDropIndex()
+	case 6:
+		spec = s.alterTableSpecRenameTable()
+	case 7:
+		spec = &ast.AlterTableSpec{
+			Tp: ast.AlterTableTruncatePartition,
+		}
+	case 8:
+		spec = &ast.AlterTableSpec{
+			Tp: ast.AlterTableReorganizePartition,
+		}
+	default:
+		spec = &ast.AlterTableSpec{
+			Tp:             ast.AlterTableExchangePartition,
+			NewTable:       &ast.TableName{},
+			WithValidation: true,
+		}
+	}
+	return &ast.AlterTableStmt{
+		Table: &ast.TableName{},
+		Specs: []*ast.AlterTableSpec{spec},
+	}
+}
+
+func (s *SQLSmith) tiFlashReplicaStmt() *ast.AlterTableStmt {
+	return &ast.AlterTableStmt{
+		Table: &ast.TableName{},
+		Specs: []*ast.AlterTableSpec{
+			{
+				Tp:             ast.AlterTableSetTiFlashReplica,
+				TiFlashReplica: &ast.TiFlashReplicaSpec{},
+			},
+		},
+	}
+}
+
 func (s *SQLSmith) partitionStmt() *ast.PartitionOptions {
 	return &ast.PartitionOptions{
 		PartitionMethod: ast.PartitionMethod{
@@ -90,6 +142,32 @@ func (s *SQLSmith) alterTableSpecDropColumn() *ast.AlterTableSpec {
 	}
 }
 
+func (s *SQLSmith) alterTableSpecModifyColumn() *ast.AlterTableSpec {
+	return &ast.AlterTableSpec{
+		Tp:         ast.AlterTableModifyColumn,
+		NewColumns: []*ast.ColumnDef{{}},
+		Position: &ast.ColumnPosition{
+			Tp: ast.ColumnPositionNone,
+		},
+	}
+}
+
+func (s *SQLSmith) alterTableSpecAddIndex() *ast.AlterTableSpec {
+	return &ast.AlterTableSpec{
+		Tp: ast.AlterTableAddConstraint,
+		Constraint: &ast.Constraint{
+			Tp: ast.ConstraintIndex,
+		},
+	}
+}
+
+func (s *SQLSmith) alterTableSpecRenameTable() *ast.AlterTableSpec {
+	return &ast.AlterTableSpec{
+		Tp:       ast.AlterTableRenameTable,
+		NewTable: &ast.TableName{},
+	}
+}
+
 func (s *SQLSmith) createIndexStmt() *ast.CreateIndexStmt {
 	var indexType model.IndexType
 	switch util.Rd(2) {
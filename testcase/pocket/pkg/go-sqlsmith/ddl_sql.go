@@ -15,6 +15,12 @@
 package sqlsmith
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/parser/ast"
+
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/generator/generator"
 )
 
@@ -41,4 +47,57 @@ func (s *SQLSmith) CreateIndexStmt(opt *generator.DDLOptions) (string, error) {
 	tree := s.createIndexStmt()
 	stmt, _, err := s.Walk(tree)
 	return stmt, err
-}
\ No newline at end of file
+}
+
+// OnlineDDLStmt online DDL, and the statements creating the table it exchanges partition with
+func (s *SQLSmith) OnlineDDLStmt(opt *generator.DDLOptions) ([]string, string, error) {
+	s.setOnlineOtherTables(opt)
+	defer s.freeOnlineOtherTables()
+	tree := s.onlineDDLStmt()
+	stmt, table, err := s.Walk(tree)
+	if err != nil {
+		return nil, "", errors.Trace(err)
+	}
+	spec := tree.Specs[0]
+	if spec.Tp != ast.AlterTableExchangePartition {
+		return []string{stmt}, table, nil
+	}
+	// the table exchanged with is an empty copy of the partitioned table without partitions,
+	// ALTER TABLE ... REMOVE PARTITIONING is not supported by TiDB 4.0 and 5.x
+	if opt.ShowCreateTable == nil {
+		return nil, "", errors.New("exchange partition needs ShowCreateTable")
+	}
+	createStmt, err := opt.ShowCreateTable(table)
+	if err != nil {
+		return nil, "", errors.Trace(err)
+	}
+	createStmt, err = nonPartitionedCopy(createStmt, table, spec.NewTable.Name.O)
+	if err != nil {
+		return nil, "", errors.Trace(err)
+	}
+	return []string{createStmt, stmt}, table, nil
+}
+
+// nonPartitionedCopy rewrites the CREATE TABLE statement of SHOW CREATE TABLE to create the table
+// of the new name, whose PARTITION clause, eg. `PARTITION BY` or `/*!50100 PARTITION BY` of MySQL, is dropped.
+func nonPartitionedCopy(createStmt, table, newTable string) (string, error) {
+	prefix := fmt.Sprintf("CREATE TABLE `%s`", table)
+	if !strings.HasPrefix(createStmt, prefix) {
+		return "", errors.Errorf("unexpected create table statement %s", createStmt)
+	}
+	createStmt = fmt.Sprintf("CREATE TABLE `%s`", newTable) + strings.TrimPrefix(createStmt, prefix)
+	if i := strings.Index(createStmt, "PARTITION BY"); i >= 0 {
+		// the clause starts from a new line
+		if j := strings.LastIndex(createStmt[:i], "\n"); j >= 0 {
+			i = j
+		}
+		createStmt = strings.TrimRight(createStmt[:i], " ")
+	}
+	return createStmt, nil
+}
+
+// TiFlashReplicaStmt set TiFlash replica of a table
+func (s *SQLSmith) TiFlashReplicaStmt() (string, string, error) {
+	tree := s.tiFlashReplicaStmt()
+	return s.Walk(tree)
+}
@@ -14,8 +14,13 @@
 package sqlsmith
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/pingcap/parser"
+	"github.com/pingcap/parser/ast"
+
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/generator/generator"
 )
 
@@ -28,6 +33,52 @@ func TestSQLSmith_CreateTable(t *testing.T) {
 	t.Log(sql)
 }
 
+// TestSQLSmith_CreateTableTypes tests the generated column types and primary keys
+func TestSQLSmith_CreateTableTypes(t *testing.T) {
+	ss := New()
+	ss.LoadSchema([][5]string{}, make(map[string][]string))
+	ss.SetDB(dbname)
+
+	keywords := []string{"GENERATED ALWAYS", "DEFAULT", "CLUSTERED", "COLLATE", "UNSIGNED", "enum("}
+	found := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		sql, _, err := ss.CreateTableStmt()
+		if err != nil {
+			t.Fatalf("generate error %v", err)
+		}
+		for _, keyword := range keywords {
+			if strings.Contains(sql, keyword) {
+				found[keyword] = true
+			}
+		}
+		// the parser does not support the feature comments
+		for _, tp := range []string{"CLUSTERED", "NONCLUSTERED"} {
+			sql = strings.Replace(sql, " /*T![clustered_index] "+tp+" */", "", 1)
+		}
+		node, err := parser.New().ParseOneStmt(sql, "", "")
+		if err != nil {
+			t.Fatalf("parse %s error %v", sql, err)
+		}
+		for _, constraint := range node.(*ast.CreateTableStmt).Constraints {
+			if constraint.Tp != ast.ConstraintPrimaryKey {
+				continue
+			}
+			keys := make(map[string]bool)
+			for _, key := range constraint.Keys {
+				if keys[key.Column.Name.L] {
+					t.Fatalf("duplicate primary key column %s in %s", key.Column.Name, sql)
+				}
+				keys[key.Column.Name.L] = true
+			}
+		}
+	}
+	for _, keyword := range keywords {
+		if !found[keyword] {
+			t.Fatalf("%s is not generated", keyword)
+		}
+	}
+}
+
 // TestSQLSmith_AlterTable tests alter table statement
 func TestSQLSmith_AlterTable(t *testing.T) {
 	ss := New()
@@ -48,3 +99,81 @@ func TestSQLSmith_CreateIndex(t *testing.T) {
 	sql, _ := ss.CreateIndexStmt(&generator.DDLOptions{OnlineDDL: true})
 	t.Log(sql)
 }
+
+// TestSQLSmith_OnlineDDL tests the online DDL statements
+func TestSQLSmith_OnlineDDL(t *testing.T) {
+	ss := New()
+	indexes["users"] = []string{"idx1", "idx2"}
+	ss.LoadSchema(schema, indexes)
+	ss.SetDB(dbname)
+	ss.LoadPartitions(map[string][][2]string{
+		"users": {{"p0", "100"}, {"p1", "1000"}, {"pn", "MAXVALUE"}},
+	})
+
+	keywords := []string{"ADD COLUMN", "DROP COLUMN", "MODIFY COLUMN", "ADD INDEX", "DROP INDEX", "RENAME AS",
+		"TRUNCATE PARTITION", "REORGANIZE PARTITION", "EXCHANGE PARTITION"}
+	showCreateTable := func(table string) (string, error) {
+		return fmt.Sprintf("CREATE TABLE `%s` (\n  `id` int(11) NOT NULL\n) ENGINE=InnoDB\n"+
+			"PARTITION BY RANGE ( `id` ) (\n  PARTITION `p0` VALUES LESS THAN (100)\n)", table), nil
+	}
+	found := make(map[string]bool)
+	for i := 0; i < 500; i++ {
+		sqls, _, err := ss.OnlineDDLStmt(&generator.DDLOptions{OnlineDDL: true, ShowCreateTable: showCreateTable})
+		if err != nil {
+			continue
+		}
+		for _, sql := range sqls {
+			for _, keyword := range keywords {
+				if strings.Contains(sql, keyword) {
+					found[keyword] = true
+				}
+			}
+			if strings.HasPrefix(sql, "CREATE TABLE") && strings.Contains(sql, "PARTITION") {
+				t.Fatalf("the table to exchange partition with is partitioned %s", sql)
+			}
+			if _, err := parser.New().ParseOneStmt(sql, "", ""); err != nil {
+				t.Fatalf("parse %s error %v", sql, err)
+			}
+		}
+	}
+	for _, keyword := range keywords {
+		if !found[keyword] {
+			t.Fatalf("%s is not generated", keyword)
+		}
+	}
+}
+
+// TestNonPartitionedCopy tests dropping the PARTITION clause of TiDB and MySQL
+func TestNonPartitionedCopy(t *testing.T) {
+	cases := []struct {
+		createStmt string
+		expect     string
+	}{
+		{
+			createStmt: "CREATE TABLE `t` (\n  `id` int(11) NOT NULL\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4\n" +
+				"PARTITION BY RANGE ( `id` ) (\n  PARTITION `p0` VALUES LESS THAN (100)\n)",
+			expect: "CREATE TABLE `t_exchange` (\n  `id` int(11) NOT NULL\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+		},
+		{
+			createStmt: "CREATE TABLE `t` (\n  `id` int(11) NOT NULL\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4\n" +
+				"/*!50100 PARTITION BY RANGE (`id`)\n(PARTITION p0 VALUES LESS THAN (100) ENGINE = InnoDB) */",
+			expect: "CREATE TABLE `t_exchange` (\n  `id` int(11) NOT NULL\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+		},
+		{
+			createStmt: "CREATE TABLE `t` (\n  `id` int(11) NOT NULL\n) ENGINE=InnoDB",
+			expect:     "CREATE TABLE `t_exchange` (\n  `id` int(11) NOT NULL\n) ENGINE=InnoDB",
+		},
+	}
+	for _, c := range cases {
+		stmt, err := nonPartitionedCopy(c.createStmt, "t", "t_exchange")
+		if err != nil {
+			t.Fatalf("copy %s error %v", c.createStmt, err)
+		}
+		if stmt != c.expect {
+			t.Fatalf("expect %s, got %s", c.expect, stmt)
+		}
+	}
+	if _, err := nonPartitionedCopy("CREATE TABLE `t1` (`id` int)", "t", "t_exchange"); err == nil {
+		t.Fatalf("copy of another table should fail")
+	}
+}
@@ -33,13 +33,10 @@ func (s *SQLSmith) selectStmt(depth int) ast.Node {
 	}
 
 	if depth <= 1 {
-		complex := 0
-		if util.Rd(3) == 0 {
-			complex = 1
-		}
+		complex := s.choose("where-complex", 2, 1)
 		selectStmtNode.Where = s.binaryOperationExpr(0, complex)
 	} else {
-		selectStmtNode.Where = s.binaryOperationExpr(util.Rd(depth), 1)
+		selectStmtNode.Where = s.binaryOperationExpr(s.choose("where-depth", ones(depth)...), 1)
 	}
 
 	selectStmtNode.TableHints = s.tableHintsExpr()
@@ -64,7 +61,7 @@ func (s *SQLSmith) updateStmt() ast.Node {
 		},
 	}
 
-	whereRand := s.rd(10)
+	whereRand := s.choose("dml-where", 1, 1, 1, 1, 1, 1, 1, 1, 2)
 	if whereRand < 8 {
 		updateStmtNode.Where = s.binaryOperationExpr(whereRand, 0)
 	} else {
@@ -94,7 +91,7 @@ func (s *SQLSmith) deleteStmt() ast.Node {
 		TableRefs: s.tableRefsClause(1),
 	}
 
-	whereRand := s.rd(10)
+	whereRand := s.choose("dml-where", 1, 1, 1, 1, 1, 1, 1, 1, 2)
 	if whereRand < 8 {
 		deleteStmtNode.Where = s.binaryOperationExpr(whereRand, 0)
 	} else {
@@ -164,8 +161,7 @@ func (s *SQLSmith) tableRefsClause(depth int) *ast.TableRefsClause {
 func (s *SQLSmith) binaryOperationExpr(depth, complex int) ast.ExprNode {
 	node := ast.BinaryOperationExpr{}
 	if depth > 0 {
-		r := util.Rd(4)
-		switch r {
+		switch s.choose("logic-op", 1, 1, 2) {
 		case 0:
 			node.Op = opcode.LogicXor
 		case 1:
@@ -177,34 +173,16 @@ func (s *SQLSmith) binaryOperationExpr(depth, complex int) ast.ExprNode {
 		node.R = s.binaryOperationExpr(0, complex)
 	} else {
 		if complex > 0 {
-			switch util.Rd(4) {
+			switch s.choose("predicate", 1, 3) {
 			case 0:
 				return s.patternInExpr()
 			default:
-				switch util.Rd(4) {
-				case 0:
-					node.Op = opcode.GT
-				case 1:
-					node.Op = opcode.LT
-				case 2:
-					node.Op = opcode.NE
-				default:
-					node.Op = opcode.EQ
-				}
+				node.Op = s.compareOp()
 				node.L = s.exprNode(false)
 				node.R = s.exprNode(true)
 			}
 		} else {
-			switch util.Rd(4) {
-			case 0:
-				node.Op = opcode.GT
-			case 1:
-				node.Op = opcode.LT
-			case 2:
-				node.Op = opcode.NE
-			default:
-				node.Op = opcode.EQ
-			}
+			node.Op = s.compareOp()
 			node.L = &ast.ColumnNameExpr{}
 			node.R = ast.NewValueExpr(1, "", "")
 		}
@@ -236,7 +214,7 @@ func (s *SQLSmith) subqueryExpr() *ast.SubqueryExpr {
 }
 
 func (s *SQLSmith) exprNode(cons bool) ast.ExprNode {
-	switch util.Rd(6) {
+	switch s.choose("operand", 1, 1, 4) {
 	case 0:
 		return &ast.ColumnNameExpr{}
 	case 1:
@@ -26,30 +26,35 @@ import (
 
 // SelectStmt make random select statement SQL
 func (s *SQLSmith) SelectStmt(depth int) (string, string, error) {
-	tree := s.selectStmt(depth)
+	s.trace = nil
+	tree := s.randSelectStmt(depth)
 	return s.Walk(tree)
 }
 
 // SelectForUpdateStmt make random select statement SQL with for update lock
 func (s *SQLSmith) SelectForUpdateStmt(depth int) (string, string, error) {
+	s.trace = nil
 	tree := s.selectForUpdateStmt(depth)
 	return s.Walk(tree)
 }
 
 // UpdateStmt make random update statement SQL
 func (s *SQLSmith) UpdateStmt() (string, string, error) {
+	s.trace = nil
 	tree := s.updateStmt()
 	return s.Walk(tree)
 }
 
 // InsertStmt implement insert statement from AST
 func (s *SQLSmith) InsertStmt(fn bool) (string, string, error) {
+	s.trace = nil
 	tree := s.insertStmt()
 	return s.Walk(tree)
 }
 
 // DeleteStmt implement delete statement from AST
 func (s *SQLSmith) DeleteStmt() (string, string, error) {
+	s.trace = nil
 	tree := s.deleteStmt()
 	return s.Walk(tree)
 }
@@ -73,7 +78,7 @@ func (s *SQLSmith) InsertStmtStr(fn bool) (string, string, error) {
 	var columns []*types.Column
 	var columnNames []string
 	for _, column := range table.Columns {
-		if column.Column == "id" {
+		if column.Column == "id" || column.Generated {
 			continue
 		}
 		columns = append(columns, column)
@@ -90,7 +95,7 @@ func (s *SQLSmith) InsertStmtStr(fn bool) (string, string, error) {
 			}
 			vals = append(vals, builtinStr)
 		} else {
-			vals = append(vals, s.generateDataItem(column.DataType))
+			vals = append(vals, column.RandValueString())
 		}
 	}
 	sql := fmt.Sprintf("INSERT INTO %s(%s) VALUES(%s)",
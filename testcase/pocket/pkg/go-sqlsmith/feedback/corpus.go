@@ -0,0 +1,301 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feedback
+
+import (
+	"bytes"
+	"math/rand"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/parser"
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/format"
+	"github.com/pingcap/parser/opcode"
+	// register the value expressions of the parser
+	_ "github.com/pingcap/tidb/types/parser_driver"
+)
+
+// mutation is the Kind of the choices of the mutators
+const mutation = "mutation"
+
+// Entry is a generated statement
+type Entry struct {
+	// Kind is the type of the statement, only the statements of the same kind replace each other
+	Kind  string
+	SQL   string
+	Table string
+	Trace Trace
+	// score is the number of new signals the statement produced
+	score int
+}
+
+// mutator changes a random candidate node, it returns false if there is no candidate
+type mutator func(r *rand.Rand, node ast.StmtNode) bool
+
+var (
+	mutators = []mutator{
+		mutateCompareOp,
+		mutateLogicOp,
+		mutateLiteral,
+		mutateJoinType,
+		mutateNegation,
+		mutateDistinct,
+	}
+	compareOps = []opcode.Op{opcode.EQ, opcode.NE, opcode.LT, opcode.LE, opcode.GT, opcode.GE, opcode.NullEQ}
+	logicOps   = []opcode.Op{opcode.LogicAnd, opcode.LogicOr, opcode.LogicXor}
+	// boundaryValues may hit the edge cases of range building and type conversion
+	boundaryValues = []interface{}{nil, int64(0), int64(-1), int64(1), int64(9223372036854775807), uint64(18446744073709551615), "", "0", 0.5}
+)
+
+// addCorpus keeps the entry, a random entry with the lowest score is replaced if the corpus is full
+func (f *Feedback) addCorpus(entry *Entry) {
+	if len(f.corpus) < f.opt.CorpusSize {
+		f.corpus = append(f.corpus, entry)
+		return
+	}
+	victim := f.rand.Intn(len(f.corpus))
+	for i := 0; i < 4; i++ {
+		if j := f.rand.Intn(len(f.corpus)); f.corpus[j].score < f.corpus[victim].score {
+			victim = j
+		}
+	}
+	f.corpus[victim] = entry
+}
+
+// Mutate returns a mutation of an interesting statement of the kind by the MutateRatio,
+// the tables limit the statements to mutate if it's not empty.
+// The trace of the mutation is the one of the original statement and the mutator.
+func (f *Feedback) Mutate(kind string, tables []string) (*Entry, bool) {
+	f.Lock()
+	defer f.Unlock()
+	if len(f.corpus) == 0 || f.rand.Float64() >= f.opt.MutateRatio {
+		return nil, false
+	}
+	var (
+		candidates []*Entry
+		weights    []int
+	)
+	for _, entry := range f.corpus {
+		if entry.Kind != kind || (len(tables) > 0 && !contains(tables, entry.Table)) {
+			continue
+		}
+		candidates = append(candidates, entry)
+		weights = append(weights, entry.score)
+	}
+	if len(candidates) == 0 {
+		return nil, false
+	}
+	parent := candidates[pickWeighted(f.rand, weights)]
+	node, err := parse(parent.SQL)
+	if err != nil {
+		return nil, false
+	}
+
+	ones := make([]int, len(mutators))
+	for i := range ones {
+		ones[i] = 1
+	}
+	first := f.choose(mutation, ones)
+	for i := range mutators {
+		index := (first + i) % len(mutators)
+		if !mutators[index](f.rand, node) {
+			continue
+		}
+		sql, err := restore(node)
+		if err != nil || sql == parent.SQL {
+			return nil, false
+		}
+		f.mutated++
+		trace := make(Trace, 0, len(parent.Trace)+1)
+		trace = append(trace, parent.Trace...)
+		return &Entry{
+			Kind:  kind,
+			SQL:   sql,
+			Table: parent.Table,
+			Trace: append(trace, Choice{Kind: mutation, Index: index}),
+		}, true
+	}
+	return nil, false
+}
+
+func parse(sql string) (ast.StmtNode, error) {
+	p := parser.New()
+	p.EnableWindowFunc(true)
+	node, err := p.ParseOneStmt(sql, "", "")
+	return node, errors.Trace(err)
+}
+
+func restore(node ast.Node) (string, error) {
+	out := new(bytes.Buffer)
+	if err := node.Restore(format.NewRestoreCtx(format.RestoreStringDoubleQuotes, out)); err != nil {
+		return "", errors.Trace(err)
+	}
+	return out.String(), nil
+}
+
+// collector collects the nodes accepted by fn
+type collector struct {
+	fn    func(n ast.Node) bool
+	nodes []ast.Node
+}
+
+// Enter implements ast.Visitor interface
+func (c *collector) Enter(in ast.Node) (ast.Node, bool) {
+	if c.fn(in) {
+		c.nodes = append(c.nodes, in)
+	}
+	return in, false
+}
+
+// Leave implements ast.Visitor interface
+func (c *collector) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
+func pickNode(r *rand.Rand, node ast.StmtNode, fn func(n ast.Node) bool) ast.Node {
+	c := collector{fn: fn}
+	node.Accept(&c)
+	if len(c.nodes) == 0 {
+		return nil
+	}
+	return c.nodes[r.Intn(len(c.nodes))]
+}
+
+func mutateCompareOp(r *rand.Rand, node ast.StmtNode) bool {
+	n := pickNode(r, node, func(n ast.Node) bool {
+		b, ok := n.(*ast.BinaryOperationExpr)
+		return ok && containsOp(compareOps, b.Op)
+	})
+	if n == nil {
+		return false
+	}
+	n.(*ast.BinaryOperationExpr).Op = compareOps[r.Intn(len(compareOps))]
+	return true
+}
+
+func mutateLogicOp(r *rand.Rand, node ast.StmtNode) bool {
+	n := pickNode(r, node, func(n ast.Node) bool {
+		b, ok := n.(*ast.BinaryOperationExpr)
+		return ok && containsOp(logicOps, b.Op)
+	})
+	if n == nil {
+		return false
+	}
+	n.(*ast.BinaryOperationExpr).Op = logicOps[r.Intn(len(logicOps))]
+	return true
+}
+
+// mutateLiteral replaces the value of a literal, the literals in ORDER BY, GROUP BY and LIMIT are not changed
+// since they may be the positions of the fields
+func mutateLiteral(r *rand.Rand, node ast.StmtNode) bool {
+	n := pickNode(r, node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BinaryOperationExpr:
+			_, ok := n.R.(ast.ValueExpr)
+			return ok
+		case *ast.PatternInExpr:
+			return len(n.List) > 0
+		}
+		return false
+	})
+	if n == nil {
+		return false
+	}
+	value := ast.NewValueExpr(boundaryValues[r.Intn(len(boundaryValues))], "", "")
+	switch n := n.(type) {
+	case *ast.BinaryOperationExpr:
+		n.R = value
+	case *ast.PatternInExpr:
+		n.List[r.Intn(len(n.List))] = value
+	}
+	return true
+}
+
+// mutateJoinType changes the type of a join with ON condition, the outer joins without it are invalid
+func mutateJoinType(r *rand.Rand, node ast.StmtNode) bool {
+	n := pickNode(r, node, func(n ast.Node) bool {
+		j, ok := n.(*ast.Join)
+		return ok && j.Right != nil && j.On != nil && !j.NaturalJoin && !j.StraightJoin
+	})
+	if n == nil {
+		return false
+	}
+	tps := []ast.JoinType{ast.CrossJoin, ast.LeftJoin, ast.RightJoin}
+	n.(*ast.Join).Tp = tps[r.Intn(len(tps))]
+	return true
+}
+
+func mutateNegation(r *rand.Rand, node ast.StmtNode) bool {
+	n := pickNode(r, node, func(n ast.Node) bool {
+		s, ok := n.(*ast.SelectStmt)
+		return ok && s.Where != nil
+	})
+	if n == nil {
+		return false
+	}
+	s := n.(*ast.SelectStmt)
+	if u, ok := s.Where.(*ast.UnaryOperationExpr); ok && u.Op == opcode.Not {
+		s.Where = u.V
+	} else {
+		s.Where = &ast.UnaryOperationExpr{Op: opcode.Not, V: &ast.ParenthesesExpr{Expr: s.Where}}
+	}
+	return true
+}
+
+// mutateDistinct toggles DISTINCT of a select statement without locking
+func mutateDistinct(r *rand.Rand, node ast.StmtNode) bool {
+	n := pickNode(r, node, func(n ast.Node) bool {
+		s, ok := n.(*ast.SelectStmt)
+		return ok && s.LockTp == ast.SelectLockNone
+	})
+	if n == nil {
+		return false
+	}
+	s := n.(*ast.SelectStmt)
+	s.Distinct = !s.Distinct
+	return true
+}
+
+func containsOp(ops []opcode.Op, op opcode.Op) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(strs []string, s string) bool {
+	for _, str := range strs {
+		if str == s {
+			return true
+		}
+	}
+	return false
+}
+
+func pickWeighted(r *rand.Rand, weights []int) int {
+	all := 0
+	for _, weight := range weights {
+		all += weight
+	}
+	val := r.Intn(all)
+	for i, weight := range weights {
+		if val < weight {
+			return i
+		}
+		val -= weight
+	}
+	return len(weights) - 1
+}
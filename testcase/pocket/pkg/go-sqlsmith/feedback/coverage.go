@@ -0,0 +1,86 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feedback
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// Coverage polls the Go coverage profile of the target, e.g. TiDB built with coverage instrumentation,
+// and counts the newly covered code blocks
+type Coverage struct {
+	url     string
+	client  *http.Client
+	covered map[string]struct{}
+}
+
+// NewCoverage creates a Coverage polling the profile from the url
+func NewCoverage(url string) *Coverage {
+	return &Coverage{
+		url:     url,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		covered: make(map[string]struct{}),
+	}
+}
+
+// Poll fetches the profile and returns the number of the newly covered blocks
+func (c *Coverage) Poll() (int, error) {
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, errors.Errorf("fetch coverage profile from %s, status %s", c.url, resp.Status)
+	}
+	blocks, err := parseProfile(resp.Body)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	fresh := 0
+	for _, block := range blocks {
+		if _, ok := c.covered[block]; !ok {
+			c.covered[block] = struct{}{}
+			fresh++
+		}
+	}
+	return fresh, nil
+}
+
+// parseProfile returns the covered blocks of the profile in the format of `go test -coverprofile`,
+// each line except the mode line is "file:startLine.startCol,endLine.endCol statements count"
+func parseProfile(r io.Reader) ([]string, error) {
+	var blocks []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, errors.Errorf("invalid coverage profile line %s", line)
+		}
+		if fields[2] != "0" {
+			blocks = append(blocks, fields[0])
+		}
+	}
+	return blocks, errors.Trace(scanner.Err())
+}
@@ -0,0 +1,221 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feedback
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxPending is the max number of traces waiting for the next coverage poll
+const maxPending = 4096
+
+// Choice is a weighted random choice made during the generation, Index is the chosen one of Kind
+type Choice struct {
+	Kind  string
+	Index int
+}
+
+// Trace is the choices made for generating a statement
+type Trace []Choice
+
+// Option of the feedback loop
+type Option struct {
+	// Boost is how much the weight of a choice grows for each new signal its statement produced
+	Boost float64
+	// MaxBoost is the max multiplier of the weight of a choice
+	MaxBoost float64
+	// Decay pulls the multiplier of a choice back to 1 each time its statement produced no new signal
+	Decay float64
+	// CorpusSize is the max number of interesting statements kept for mutation
+	CorpusSize int
+	// MutateRatio is the probability of mutating an interesting statement instead of generating a new one
+	MutateRatio float64
+}
+
+// DefaultOption is the option used for the zero values
+var DefaultOption = Option{
+	Boost:       0.5,
+	MaxBoost:    16,
+	Decay:       0.99,
+	CorpusSize:  1000,
+	MutateRatio: 0.2,
+}
+
+// Feedback adapts the weights of the generator choices toward the ones which produced new signals,
+// e.g. physical operators and join types from EXPLAIN, error codes and covered code blocks of the target,
+// and keeps the interesting statements for mutation.
+// It's shared by the generators and safe for concurrent use.
+type Feedback struct {
+	sync.Mutex
+	opt     Option
+	rand    *rand.Rand
+	boosts  map[Choice]float64
+	signals map[string]int
+	corpus  []*Entry
+	pending []Trace
+	// statistics
+	observed    int
+	interesting int
+	mutated     int
+	coverage    int
+}
+
+// New creates a Feedback
+func New(opt Option) *Feedback {
+	if opt.Boost <= 0 {
+		opt.Boost = DefaultOption.Boost
+	}
+	if opt.MaxBoost < 1 {
+		opt.MaxBoost = DefaultOption.MaxBoost
+	}
+	if opt.Decay <= 0 || opt.Decay > 1 {
+		opt.Decay = DefaultOption.Decay
+	}
+	if opt.CorpusSize <= 0 {
+		opt.CorpusSize = DefaultOption.CorpusSize
+	}
+	return &Feedback{
+		opt:     opt,
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		boosts:  make(map[Choice]float64),
+		signals: make(map[string]int),
+	}
+}
+
+// Choose picks an index by the weights multiplied by the boosts of the choices,
+// zero weights are never picked, it returns -1 if all the weights are zero
+func (f *Feedback) Choose(kind string, weights []int) int {
+	f.Lock()
+	defer f.Unlock()
+	return f.choose(kind, weights)
+}
+
+func (f *Feedback) choose(kind string, weights []int) int {
+	var (
+		all       float64
+		effective = make([]float64, len(weights))
+	)
+	for i, weight := range weights {
+		if weight <= 0 {
+			continue
+		}
+		effective[i] = float64(weight) * f.boost(Choice{Kind: kind, Index: i})
+		all += effective[i]
+	}
+	if all == 0 {
+		return -1
+	}
+	val := f.rand.Float64() * all
+	last := -1
+	for i, weight := range effective {
+		if weight == 0 {
+			continue
+		}
+		if val < weight {
+			return i
+		}
+		val -= weight
+		last = i
+	}
+	// float rounding
+	return last
+}
+
+func (f *Feedback) boost(c Choice) float64 {
+	if b, ok := f.boosts[c]; ok {
+		return b
+	}
+	return 1
+}
+
+// Observe records the signals produced by the statement, boosts its choices if there are new signals,
+// and keeps it in the corpus. It returns the number of new signals.
+func (f *Feedback) Observe(entry *Entry, signals []string) int {
+	f.Lock()
+	defer f.Unlock()
+	f.observed++
+	fresh := 0
+	for _, signal := range signals {
+		if f.signals[signal] == 0 {
+			fresh++
+		}
+		f.signals[signal]++
+	}
+	f.reward(entry.Trace, fresh)
+	if len(f.pending) < maxPending {
+		f.pending = append(f.pending, entry.Trace)
+	}
+	if fresh > 0 {
+		f.interesting++
+		entry.score = fresh
+		f.addCorpus(entry)
+	}
+	return fresh
+}
+
+// ObserveCoverage rewards the statements observed since the last call with the newly covered code blocks
+func (f *Feedback) ObserveCoverage(covered int) {
+	f.Lock()
+	defer f.Unlock()
+	f.coverage += covered
+	if covered > 0 {
+		for _, trace := range f.pending {
+			f.reward(trace, 1)
+		}
+	}
+	f.pending = nil
+}
+
+// reward boosts the choices of the trace for the new signals, or decays them without new signals
+func (f *Feedback) reward(trace Trace, fresh int) {
+	for _, c := range trace {
+		b := f.boost(c)
+		if fresh > 0 {
+			b *= 1 + f.opt.Boost*float64(fresh)
+			if b > f.opt.MaxBoost {
+				b = f.opt.MaxBoost
+			}
+		} else {
+			b = 1 + (b-1)*f.opt.Decay
+		}
+		f.boosts[c] = b
+	}
+}
+
+// Report returns the statistics and the boosted choices
+func (f *Feedback) Report() string {
+	f.Lock()
+	defer f.Unlock()
+	var b strings.Builder
+	fmt.Fprintf(&b, "feedback: %d statements observed, %d interesting, %d mutated, %d signals, %d covered blocks, %d in corpus\n",
+		f.observed, f.interesting, f.mutated, len(f.signals), f.coverage, len(f.corpus))
+	var choices []Choice
+	for c, boost := range f.boosts {
+		if boost >= 1.5 {
+			choices = append(choices, c)
+		}
+	}
+	sort.Slice(choices, func(i, j int) bool {
+		return f.boosts[choices[i]] > f.boosts[choices[j]]
+	})
+	for _, c := range choices {
+		fmt.Fprintf(&b, "  %s[%d] x%.2f\n", c.Kind, c.Index, f.boosts[c])
+	}
+	return b.String()
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feedback
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestObserve(t *testing.T) {
+	f := New(Option{MutateRatio: 1})
+	trace := Trace{{Kind: "select", Index: 2}}
+	if n := f.Observe(&Entry{Kind: "select", SQL: "SELECT * FROM t WHERE a = 1", Table: "t", Trace: trace}, []string{"operator:HashJoin@root", "error:1105"}); n != 2 {
+		t.Fatalf("expect 2 new signals, got %d", n)
+	}
+	if n := f.Observe(&Entry{Kind: "select", SQL: "SELECT * FROM t", Trace: trace}, []string{"error:1105"}); n != 0 {
+		t.Fatalf("expect no new signal, got %d", n)
+	}
+	if b := f.boost(trace[0]); b <= 1 || b > DefaultOption.MaxBoost {
+		t.Fatalf("unexpected boost %f", b)
+	}
+	if len(f.corpus) != 1 {
+		t.Fatalf("expect 1 statement in corpus, got %d", len(f.corpus))
+	}
+
+	// the zero weights are never picked
+	for i := 0; i < 100; i++ {
+		if index := f.Choose("select", []int{0, 1, 1}); index == 0 {
+			t.Fatal("zero weight is picked")
+		}
+	}
+	if index := f.Choose("select", []int{0, 0}); index != -1 {
+		t.Fatalf("expect -1 for all zero weights, got %d", index)
+	}
+
+	entry, ok := f.Mutate("select", []string{"t"})
+	if !ok {
+		t.Fatal("expect a mutation")
+	}
+	if entry.SQL == "SELECT * FROM t WHERE a = 1" || entry.Trace[len(entry.Trace)-1].Kind != mutation {
+		t.Fatalf("unexpected mutation %s %v", entry.SQL, entry.Trace)
+	}
+	if _, ok := f.Mutate("select", []string{"s"}); ok {
+		t.Fatal("expect no mutation of other tables")
+	}
+}
+
+func TestMutators(t *testing.T) {
+	f := New(Option{})
+	for i, m := range mutators {
+		node, err := parse("SELECT DISTINCT t.a FROM t LEFT JOIN s ON t.a = s.a WHERE t.b > 1 AND t.c IN (1, 2)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !m(f.rand, node) {
+			t.Fatalf("mutator %d has no candidate", i)
+		}
+		if _, err := restore(node); err != nil {
+			t.Fatalf("mutator %d: %v", i, err)
+		}
+	}
+}
+
+func TestParseProfile(t *testing.T) {
+	profile := `mode: set
+github.com/pingcap/tidb/planner/core/rule_join_reorder.go:42.10,44.3 2 1
+github.com/pingcap/tidb/planner/core/rule_join_reorder.go:45.2,45.12 1 0
+`
+	blocks, err := parseProfile(strings.NewReader(profile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(blocks, []string{"github.com/pingcap/tidb/planner/core/rule_join_reorder.go:42.10,44.3"}) {
+		t.Fatalf("unexpected covered blocks %v", blocks)
+	}
+}
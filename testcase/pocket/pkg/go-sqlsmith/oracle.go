@@ -0,0 +1,225 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlsmith
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/parser"
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/opcode"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/util"
+)
+
+// The oracles here find logic bugs with a single database,
+// they rewrite a query into others whose results must be related to the original one.
+//
+// TLP, Ternary Logic Partitioning, partitions a query by its predicate p into
+// `WHERE p`, `WHERE NOT p` and `WHERE p IS NULL`, the union of them must be the unfiltered query.
+// NoREC, Non-optimizing Reference Engine Construction, counts the rows satisfy p by `WHERE p`,
+// which is optimized by the database, and by evaluating `(p) IS TRUE` on every row, which can hardly be optimized.
+// PQS, Pivoted Query Synthesis, picks a pivot row and generates a predicate which must be true for it,
+// the query filtered by the predicate must contain the pivot row.
+
+// PivotValue is a column value of the pivot row in PQS
+type PivotValue struct {
+	Column string
+	// Type is the database type name of the column, eg. INT, VARCHAR
+	Type  string
+	Null  bool
+	Value string
+}
+
+// TLPStmts partitions the select statement by its WHERE predicate,
+// returns the unfiltered statement and the 3 partitions of it.
+func TLPStmts(stmt string) (string, []string, error) {
+	node, predicate, err := parsePredicateSelect(stmt)
+	if err != nil {
+		return "", nil, errors.Trace(err)
+	}
+	node.Where = nil
+	base, err := util.BufferOut(node)
+	if err != nil {
+		return "", nil, errors.Trace(err)
+	}
+	var partitions []string
+	for _, where := range []ast.ExprNode{
+		&ast.ParenthesesExpr{Expr: predicate},
+		&ast.UnaryOperationExpr{Op: opcode.Not, V: &ast.ParenthesesExpr{Expr: predicate}},
+		&ast.IsNullExpr{Expr: &ast.ParenthesesExpr{Expr: predicate}},
+	} {
+		node.Where = where
+		partition, err := util.BufferOut(node)
+		if err != nil {
+			return "", nil, errors.Trace(err)
+		}
+		partitions = append(partitions, partition)
+	}
+	return base, partitions, nil
+}
+
+// NoRECStmts rewrites the select statement into a count of the rows filtered by its WHERE predicate
+// and a sum of the predicate evaluated on every row without filtering, both of them return a single number.
+func NoRECStmts(stmt string) (string, string, error) {
+	node, predicate, err := parsePredicateSelect(stmt)
+	if err != nil {
+		return "", "", errors.Trace(err)
+	}
+	node.Fields = &ast.FieldList{Fields: []*ast.SelectField{{
+		Expr: &ast.AggregateFuncExpr{
+			F:    ast.AggFuncCount,
+			Args: []ast.ExprNode{ast.NewValueExpr(1, "", "")},
+		},
+	}}}
+	optimized, err := util.BufferOut(node)
+	if err != nil {
+		return "", "", errors.Trace(err)
+	}
+
+	node.Where = nil
+	node.Fields = &ast.FieldList{Fields: []*ast.SelectField{{
+		Expr:   &ast.IsTruthExpr{Expr: &ast.ParenthesesExpr{Expr: predicate}, True: 1},
+		AsName: model.NewCIStr("c"),
+	}}}
+	unoptimized, err := util.BufferOut(node)
+	if err != nil {
+		return "", "", errors.Trace(err)
+	}
+	return optimized, fmt.Sprintf("SELECT IFNULL(SUM(t.c), 0) FROM (%s) AS t", unoptimized), nil
+}
+
+// PivotStmt picks a random row from the table
+func PivotStmt(table string) string {
+	return fmt.Sprintf("SELECT * FROM %s ORDER BY RAND() LIMIT 1", table)
+}
+
+// PQSStmt generates a select statement from the table whose result must contain the pivot row,
+// the columns are selected in the order of the pivot.
+func PQSStmt(table string, pivot []PivotValue) string {
+	var (
+		columns    []string
+		predicates []string
+	)
+	for _, value := range pivot {
+		columns = append(columns, fmt.Sprintf("`%s`", value.Column))
+		if util.Rd(2) == 0 {
+			predicates = append(predicates, pivotPredicate(value))
+		}
+	}
+	if len(predicates) == 0 {
+		predicates = append(predicates, pivotPredicate(pivot[util.Rd(len(pivot))]))
+	}
+	predicate := strings.Join(predicates, " AND ")
+	// a true predicate is still true when ORed with anything
+	if util.Rd(3) == 0 {
+		other := pivot[util.Rd(len(pivot))]
+		predicate = fmt.Sprintf("(%s) OR (%s)", predicate, pivotNotPredicate(other))
+	}
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(columns, ", "), table, predicate)
+}
+
+// parsePredicateSelect parses a select statement which has a predicate, and drops the clauses not affect the rows,
+// the statements with aggregation, window or limitation are not partitionable.
+func parsePredicateSelect(stmt string) (*ast.SelectStmt, ast.ExprNode, error) {
+	p := parser.New()
+	p.EnableWindowFunc(true)
+	node, err := p.ParseOneStmt(stmt, "", "")
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	sel, ok := node.(*ast.SelectStmt)
+	if !ok {
+		return nil, nil, errors.Errorf("not a select statement: %s", stmt)
+	}
+	if sel.Where == nil {
+		return nil, nil, errors.Errorf("no predicate in select statement: %s", stmt)
+	}
+	if sel.Distinct || sel.GroupBy != nil || sel.Having != nil || sel.Limit != nil {
+		return nil, nil, errors.Errorf("unsupported select statement: %s", stmt)
+	}
+	for _, field := range sel.Fields.Fields {
+		switch field.Expr.(type) {
+		case *ast.AggregateFuncExpr, *ast.WindowFuncExpr:
+			return nil, nil, errors.Errorf("unsupported select statement: %s", stmt)
+		}
+	}
+	sel.OrderBy = nil
+	sel.LockTp = ast.SelectLockNone
+	return sel, sel.Where, nil
+}
+
+// pivotPredicate generates a predicate which is true for the value
+func pivotPredicate(value PivotValue) string {
+	column := fmt.Sprintf("`%s`", value.Column)
+	if value.Null {
+		switch util.Rd(3) {
+		case 0:
+			return fmt.Sprintf("%s IS NULL", column)
+		case 1:
+			return fmt.Sprintf("%s <=> NULL", column)
+		default:
+			return fmt.Sprintf("NOT (%s IS NOT NULL)", column)
+		}
+	}
+	if !pivotComparable(value.Type) {
+		return fmt.Sprintf("%s IS NOT NULL", column)
+	}
+	literal := pivotLiteral(value)
+	switch util.Rd(6) {
+	case 0:
+		return fmt.Sprintf("%s = %s", column, literal)
+	case 1:
+		return fmt.Sprintf("%s <=> %s", column, literal)
+	case 2:
+		return fmt.Sprintf("%s >= %s", column, literal)
+	case 3:
+		return fmt.Sprintf("%s <= %s", column, literal)
+	case 4:
+		return fmt.Sprintf("%s IN (%s)", column, literal)
+	default:
+		return fmt.Sprintf("NOT (%s <> %s)", column, literal)
+	}
+}
+
+// pivotNotPredicate generates a predicate which is false or NULL for the value
+func pivotNotPredicate(value PivotValue) string {
+	column := fmt.Sprintf("`%s`", value.Column)
+	if value.Null || !pivotComparable(value.Type) {
+		return fmt.Sprintf("%s IS NULL AND %s IS NOT NULL", column, column)
+	}
+	return fmt.Sprintf("%s <> %s", column, pivotLiteral(value))
+}
+
+// pivotComparable reports whether the value of the type can be compared exactly by its literal,
+// float values may lose precision in their text format.
+func pivotComparable(tp string) bool {
+	switch strings.ToUpper(tp) {
+	case "FLOAT", "DOUBLE", "JSON", "BIT", "GEOMETRY":
+		return false
+	}
+	return true
+}
+
+func pivotLiteral(value PivotValue) string {
+	switch strings.TrimPrefix(strings.ToUpper(value.Type), "UNSIGNED ") {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "DECIMAL", "YEAR":
+		return value.Value
+	}
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return fmt.Sprintf("'%s'", r.Replace(value.Value))
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlsmith
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/parser"
+	"github.com/pingcap/parser/ast"
+)
+
+// TestTLPStmts tests the partitions of generated select statements
+func TestTLPStmts(t *testing.T) {
+	ss := new()
+	ss.LoadSchema(schema, indexes)
+	ss.SetDB(dbname)
+
+	for i := 0; i < 200; i++ {
+		sql, _, err := ss.SelectStmt(1 + rand.Intn(3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the generated statements may be invalid, which are not rewritten
+		if _, err := parser.New().ParseOneStmt(sql, "", ""); err != nil {
+			continue
+		}
+		base, partitions, err := TLPStmts(sql)
+		if err != nil {
+			t.Fatalf("partition %s error %v", sql, err)
+		}
+		node, err := parser.New().ParseOneStmt(base, "", "")
+		if err != nil {
+			t.Fatalf("parse %s error %v", base, err)
+		}
+		if sel := node.(*ast.SelectStmt); sel.Where != nil || sel.OrderBy != nil {
+			t.Fatalf("unfiltered statement %s should not contain WHERE or ORDER BY", base)
+		}
+		if len(partitions) != 3 {
+			t.Fatalf("expect 3 partitions, got %d", len(partitions))
+		}
+		for _, p := range partitions {
+			if _, err := parser.New().ParseOneStmt(p, "", ""); err != nil {
+				t.Fatalf("parse %s error %v", p, err)
+			}
+		}
+	}
+
+	if _, _, err := TLPStmts("SELECT id FROM t"); err == nil {
+		t.Fatal("statement without predicate should not be partitioned")
+	}
+	if _, _, err := TLPStmts("SELECT COUNT(*) FROM t WHERE id > 1"); err == nil {
+		t.Fatal("statement with aggregation should not be partitioned")
+	}
+}
+
+// TestNoRECStmts tests the rewrites of generated select statements
+func TestNoRECStmts(t *testing.T) {
+	ss := new()
+	ss.LoadSchema(schema, indexes)
+	ss.SetDB(dbname)
+
+	for i := 0; i < 200; i++ {
+		sql, _, err := ss.SelectStmt(1 + rand.Intn(3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the generated statements may be invalid, which are not rewritten
+		if _, err := parser.New().ParseOneStmt(sql, "", ""); err != nil {
+			continue
+		}
+		optimized, unoptimized, err := NoRECStmts(sql)
+		if err != nil {
+			t.Fatalf("rewrite %s error %v", sql, err)
+		}
+		for _, p := range []string{optimized, unoptimized} {
+			if _, err := parser.New().ParseOneStmt(p, "", ""); err != nil {
+				t.Fatalf("parse %s error %v", p, err)
+			}
+		}
+	}
+}
+
+// TestPQSStmt tests the statements contain the pivot row
+func TestPQSStmt(t *testing.T) {
+	pivot := []PivotValue{
+		{Column: "id", Type: "INT", Value: "1"},
+		{Column: "name", Type: "VARCHAR", Value: `it's \ok`},
+		{Column: "score", Type: "FLOAT", Value: "0.1"},
+		{Column: "deleted", Type: "DATETIME", Null: true},
+	}
+	for i := 0; i < 200; i++ {
+		sql := PQSStmt("t", pivot)
+		if !strings.HasPrefix(sql, "SELECT `id`, `name`, `score`, `deleted` FROM t WHERE ") {
+			t.Fatalf("unexpected statement %s", sql)
+		}
+		if strings.Contains(sql, "0.1") {
+			t.Fatalf("float should not be compared by its literal, %s", sql)
+		}
+		if _, err := parser.New().ParseOneStmt(sql, "", ""); err != nil {
+			t.Fatalf("parse %s error %v", sql, err)
+		}
+	}
+}
@@ -16,6 +16,8 @@ package sqlsmith
 import (
 	"fmt"
 	"time"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/feedback"
 )
 
 func (s *SQLSmith) rd(n int) int {
@@ -29,6 +31,36 @@ func (s *SQLSmith) rdRange(n, m int) int {
 	return n + s.Rand.Intn(m-n)
 }
 
+// choose picks an index by the weights, the choice is adapted and traced by the feedback if it's set
+func (s *SQLSmith) choose(kind string, weights ...int) int {
+	if s.feedback != nil {
+		index := s.feedback.Choose(kind, weights)
+		s.trace = append(s.trace, feedback.Choice{Kind: kind, Index: index})
+		return index
+	}
+	all := 0
+	for _, weight := range weights {
+		all += weight
+	}
+	val := s.rd(all)
+	for i, weight := range weights {
+		if val < weight {
+			return i
+		}
+		val -= weight
+	}
+	panic("unreachable")
+}
+
+// ones returns n equal weights
+func ones(n int) []int {
+	weights := make([]int, n)
+	for i := range weights {
+		weights[i] = 1
+	}
+	return weights
+}
+
 func (s *SQLSmith) rdFloat64() float64 {
 	return s.Rand.Float64()
 }
@@ -14,21 +14,38 @@
 package sqlsmith
 
 import (
-	"regexp"
+	"strconv"
 	"strings"
 
+	"github.com/pingcap/parser/ast"
+
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/generator/generator"
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/types"
 )
 
-const typeRegex = `\(\d+\)`
+// typeAliases maps the types shown by DESC to the types known by the generator
+var typeAliases = map[string]string{
+	"integer":    "int",
+	"bool":       "tinyint",
+	"boolean":    "tinyint",
+	"numeric":    "decimal",
+	"real":       "double",
+	"binary":     "char",
+	"varbinary":  "varchar",
+	"tinytext":   "text",
+	"mediumtext": "text",
+	"longtext":   "text",
+	"tinyblob":   "blob",
+	"mediumblob": "blob",
+	"longblob":   "blob",
+}
 
 // LoadSchema init schemas, tables and columns
 // record[0] dbname
 // record[1] table name
 // record[2] table type
 // record[3] column name
-// record[4] column type
+// record[4] column type, e.g. "decimal(10,2) unsigned not null", "enum('a','b')", "int(11) virtual generated"
 func (s *SQLSmith) LoadSchema(records [][5]string, indexes map[string][]string) {
 	// init databases
 	for _, record := range records {
@@ -57,15 +74,111 @@ func (s *SQLSmith) LoadSchema(records [][5]string, indexes map[string][]string)
 			}
 		}
 		if _, ok := s.Databases[dbname].Tables[tableName].Columns[columnName]; !ok {
-			s.Databases[dbname].Tables[tableName].Columns[columnName] = &types.Column{
-				DB:     dbname,
-				Table:  tableName,
-				Column: columnName,
-				// remove the data size in type definition
-				DataType: regexp.MustCompile(typeRegex).ReplaceAllString(strings.ToLower(columnType), ""),
+			column := parseColumnType(columnType)
+			column.DB = dbname
+			column.Table = tableName
+			column.Column = columnName
+			s.Databases[dbname].Tables[tableName].Columns[columnName] = column
+		}
+	}
+}
+
+// LoadPartitions init the range partitions of the loaded tables in current database
+// partition[0] partition name
+// partition[1] partition description
+func (s *SQLSmith) LoadPartitions(partitions map[string][][2]string) {
+	db := s.GetDB(s.currDB)
+	for tableName, records := range partitions {
+		table, ok := db.Tables[tableName]
+		if !ok {
+			continue
+		}
+		table.Partitions = nil
+		for _, record := range records {
+			table.Partitions = append(table.Partitions, &types.Partition{
+				Name:        record[0],
+				Description: record[1],
+			})
+		}
+	}
+}
+
+// parseColumnType parses the column type shown by DESC with the attributes
+func parseColumnType(columnType string) *types.Column {
+	column := types.Column{
+		DataLen: -1,
+		Decimal: -1,
+	}
+	var args, attrs string
+	if l, r := strings.Index(columnType, "("), strings.LastIndex(columnType, ")"); l > 0 && r > l {
+		column.DataType = strings.ToLower(columnType[:l])
+		args = columnType[l+1 : r]
+		attrs = strings.ToLower(columnType[r+1:])
+	} else {
+		fields := strings.SplitN(strings.ToLower(columnType), " ", 2)
+		column.DataType = fields[0]
+		if len(fields) > 1 {
+			attrs = fields[1]
+		}
+	}
+	if t, ok := typeAliases[column.DataType]; ok {
+		column.DataType = t
+	}
+
+	switch column.DataType {
+	case "enum", "set":
+		column.Elems = parseElems(args)
+	case "time", "datetime", "timestamp":
+		if n, err := strconv.Atoi(args); err == nil {
+			column.Decimal = n
+		}
+	default:
+		nums := strings.Split(args, ",")
+		if n, err := strconv.Atoi(strings.TrimSpace(nums[0])); err == nil {
+			column.DataLen = n
+		}
+		if len(nums) > 1 {
+			if n, err := strconv.Atoi(strings.TrimSpace(nums[1])); err == nil {
+				column.Decimal = n
+			}
+		}
+	}
+
+	column.Unsigned = strings.Contains(attrs, "unsigned")
+	if strings.Contains(attrs, "not null") {
+		column.AddOption(ast.ColumnOptionNotNull)
+	}
+	if strings.Contains(attrs, "generated") {
+		column.Generated = true
+		column.Stored = strings.Contains(attrs, "stored")
+	}
+	return &column
+}
+
+// parseElems parses the quoted members of enum and set, a quote in the member is doubled
+func parseElems(args string) []string {
+	var (
+		elems  []string
+		elem   strings.Builder
+		quoted bool
+	)
+	for i := 0; i < len(args); i++ {
+		c := args[i]
+		switch {
+		case c == '\'' && quoted && i+1 < len(args) && args[i+1] == '\'':
+			elem.WriteByte(c)
+			i++
+		case c == '\'':
+			if quoted {
+				elems = append(elems, elem.String())
+				elem.Reset()
 			}
+			quoted = !quoted
+		case quoted:
+			elem.WriteByte(c)
 		}
 	}
+	return elems
 }
 
 // BeginWithOnlineTables begins a transaction with some online tables
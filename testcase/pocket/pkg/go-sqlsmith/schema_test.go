@@ -99,3 +99,28 @@ func TestSQLSmith_LoadIndexes(t *testing.T) {
 	assert.Equal(t, len(ss.Databases[dbname].Tables), 6)
 	assert.Equal(t, len(ss.Databases[dbname].Tables["users"].Indexes), 2)
 }
+
+// TestSQLSmith_LoadColumnTypes tests parsing the column types with attributes
+func TestSQLSmith_LoadColumnTypes(t *testing.T) {
+	ss := new()
+	ss.LoadSchema([][5]string{
+		{"db", "t", "BASE TABLE", "d", "decimal(10,2) unsigned not null"},
+		{"db", "t", "BASE TABLE", "e", "enum('a','b''c')"},
+		{"db", "t", "BASE TABLE", "dt", "datetime(3)"},
+		{"db", "t", "BASE TABLE", "bl", "longblob"},
+		{"db", "t", "BASE TABLE", "g", "bigint(20) unsigned stored generated"},
+	}, map[string][]string{})
+	columns := ss.Databases["db"].Tables["t"].Columns
+
+	assert.Equal(t, columns["d"].DataType, "decimal")
+	assert.Equal(t, columns["d"].DataLen, 10)
+	assert.Equal(t, columns["d"].Decimal, 2)
+	assert.Equal(t, columns["d"].Unsigned, true)
+	assert.Equal(t, columns["d"].Nullable(), false)
+	assert.Equal(t, columns["e"].Elems, []string{"a", "b'c"})
+	assert.Equal(t, columns["dt"].Decimal, 3)
+	assert.Equal(t, columns["bl"].DataType, "blob")
+	assert.Equal(t, columns["g"].DataType, "bigint")
+	assert.Equal(t, columns["g"].Generated, true)
+	assert.Equal(t, columns["g"].Stored, true)
+}
@@ -0,0 +1,309 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlsmith
+
+import (
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/opcode"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/types"
+)
+
+var (
+	aggregateFuncs = []string{"count", "sum", "avg", "max", "min", "bit_and", "bit_or", "bit_xor", "group_concat"}
+	// unstableAggregateFuncs are TiDB only
+	unstableAggregateFuncs = []string{"approx_count_distinct"}
+	windowFuncs            = []string{
+		"row_number", "rank", "dense_rank", "percent_rank", "cume_dist", "ntile",
+		"lag", "lead", "first_value", "last_value", "nth_value",
+		"count", "sum", "avg", "max", "min",
+	}
+)
+
+// randSelectStmt makes the skeleton of a select statement, the shape is chosen by the weights
+func (s *SQLSmith) randSelectStmt(depth int) ast.Node {
+	opt := s.selectOpt
+	if opt == nil {
+		return s.selectStmt(depth)
+	}
+	weights := []int{opt.Plain, opt.Aggregation, opt.Window, opt.Join, opt.SemiJoin, opt.Derived, opt.SetOperation, opt.CTE}
+	shapes := []func(int) ast.Node{
+		s.selectStmt,
+		s.aggregationSelectStmt,
+		s.windowSelectStmt,
+		s.joinSelectStmt,
+		s.semiJoinSelectStmt,
+		s.derivedSelectStmt,
+		s.setOprStmt,
+		s.withStmt,
+	}
+	all := 0
+	for _, weight := range weights {
+		all += weight
+	}
+	if all == 0 {
+		return s.selectStmt(depth)
+	}
+	return shapes[s.choose("select", weights...)](depth)
+}
+
+// aggregationSelectStmt makes a select statement with GROUP BY and aggregate functions,
+// the group by items are filled by the walker.
+func (s *SQLSmith) aggregationSelectStmt(depth int) ast.Node {
+	node := s.selectStmt(depth).(*ast.SelectStmt)
+	node.GroupBy = &ast.GroupByClause{}
+	count := 1 + s.rd(3)
+	for i := 0; i < count; i++ {
+		node.Fields.Fields = append(node.Fields.Fields, &ast.SelectField{Expr: s.aggregateFuncExpr()})
+	}
+	if s.rd(2) == 0 {
+		node.Having = &ast.HavingClause{
+			Expr: &ast.BinaryOperationExpr{
+				Op: s.compareOp(),
+				L:  s.aggregateFuncExpr(),
+				R:  ast.NewValueExpr(1, "", ""),
+			},
+		}
+	}
+	return node
+}
+
+func (s *SQLSmith) aggregateFuncExpr() ast.ExprNode {
+	funcs := aggregateFuncs
+	if !s.stable {
+		funcs = append(append([]string{}, aggregateFuncs...), unstableAggregateFuncs...)
+	}
+	f := funcs[s.rd(len(funcs))]
+	switch f {
+	case "group_concat":
+		return &types.GroupConcatExpr{
+			AggregateFuncExpr: ast.AggregateFuncExpr{
+				F:        f,
+				Args:     []ast.ExprNode{&ast.ColumnNameExpr{}, ast.NewValueExpr(",", "", "")},
+				Distinct: s.rd(3) == 0,
+			},
+			OrderBy: &ast.OrderByClause{},
+		}
+	case "count", "sum", "avg", "max", "min":
+		return &ast.AggregateFuncExpr{
+			F:        f,
+			Args:     []ast.ExprNode{&ast.ColumnNameExpr{}},
+			Distinct: s.rd(3) == 0,
+		}
+	}
+	return &ast.AggregateFuncExpr{
+		F:    f,
+		Args: []ast.ExprNode{&ast.ColumnNameExpr{}},
+	}
+}
+
+// windowSelectStmt makes a select statement with window functions,
+// the arguments, partitions and orders of the windows are filled by the walker.
+func (s *SQLSmith) windowSelectStmt(depth int) ast.Node {
+	node := s.selectStmt(depth).(*ast.SelectStmt)
+	count := 1 + s.rd(2)
+	for i := 0; i < count; i++ {
+		f := windowFuncs[s.rd(len(windowFuncs))]
+		expr := &ast.WindowFuncExpr{
+			F:    f,
+			Spec: ast.WindowSpec{OrderBy: &ast.OrderByClause{}},
+		}
+		if s.rd(3) > 0 {
+			expr.Spec.PartitionBy = &ast.PartitionByClause{}
+		}
+		switch f {
+		case "row_number", "rank", "dense_rank", "percent_rank", "cume_dist":
+		case "ntile":
+			expr.Args = []ast.ExprNode{ast.NewValueExpr(int64(1+s.rd(4)), "", "")}
+		case "lag", "lead", "nth_value":
+			expr.Args = []ast.ExprNode{&ast.ColumnNameExpr{}, ast.NewValueExpr(int64(1+s.rd(3)), "", "")}
+		default:
+			expr.Args = []ast.ExprNode{&ast.ColumnNameExpr{}}
+		}
+		node.Fields.Fields = append(node.Fields.Fields, &ast.SelectField{Expr: expr})
+	}
+	return node
+}
+
+// joinSelectStmt makes a select statement from a join of more than 2 tables
+func (s *SQLSmith) joinSelectStmt(depth int) ast.Node {
+	node := s.selectStmt(1).(*ast.SelectStmt)
+	var join ast.ResultSetNode = s.joinLeaf(depth)
+	count := 3 + s.rd(2)
+	for i := 1; i < count; i++ {
+		j := &ast.Join{
+			Left:  join,
+			Right: s.joinLeaf(depth),
+		}
+		switch s.choose("join-type", 1, 1, 2) {
+		case 0:
+			j.Tp = ast.LeftJoin
+		case 1:
+			j.Tp = ast.RightJoin
+		default:
+			j.Tp = ast.CrossJoin
+		}
+		if j.Tp != ast.CrossJoin || s.rd(4) > 0 {
+			j.On = &ast.OnCondition{
+				Expr: &ast.BinaryOperationExpr{
+					Op: opcode.EQ,
+					L:  &ast.ColumnNameExpr{},
+					R:  &ast.ColumnNameExpr{},
+				},
+			}
+		}
+		join = j
+	}
+	node.From = &ast.TableRefsClause{TableRefs: join.(*ast.Join)}
+	return node
+}
+
+// joinLeaf makes an aliased table or a derived table, the alias is given by the walker
+func (s *SQLSmith) joinLeaf(depth int) ast.ResultSetNode {
+	if depth > 1 && s.choose("join-leaf", 3, 1) == 1 {
+		return &ast.TableSource{Source: s.selectStmt(1)}
+	}
+	return &ast.TableSource{Source: &ast.TableName{}}
+}
+
+// semiJoinSelectStmt makes a select statement filtered by IN or EXISTS subqueries
+func (s *SQLSmith) semiJoinSelectStmt(depth int) ast.Node {
+	node := s.selectStmt(depth).(*ast.SelectStmt)
+	var subquery ast.ExprNode
+	if s.choose("semi-join", 1, 1) == 0 {
+		subquery = s.existsSubqueryExpr()
+	} else {
+		subquery = s.patternInExpr()
+	}
+	node.Where = &ast.BinaryOperationExpr{
+		Op: opcode.LogicAnd,
+		L:  node.Where,
+		R:  subquery,
+	}
+	return node
+}
+
+// existsSubqueryExpr makes [NOT] EXISTS subquery, which is correlated to the outer query by the walker
+func (s *SQLSmith) existsSubqueryExpr() *ast.ExistsSubqueryExpr {
+	sub := s.selectStmt(1).(*ast.SelectStmt)
+	sub.From = &ast.TableRefsClause{
+		TableRefs: &ast.Join{
+			Left: &ast.TableSource{Source: &ast.TableName{}},
+		},
+	}
+	sub.OrderBy = nil
+	sub.Where = &ast.BinaryOperationExpr{
+		Op: opcode.LogicAnd,
+		L: &ast.BinaryOperationExpr{
+			Op: opcode.EQ,
+			L:  &ast.ColumnNameExpr{},
+			R:  &ast.ColumnNameExpr{},
+		},
+		R: sub.Where,
+	}
+	return &ast.ExistsSubqueryExpr{
+		Sel: &ast.SubqueryExpr{
+			Query:  sub,
+			Exists: true,
+		},
+		Not: s.rd(2) == 0,
+	}
+}
+
+// derivedSelectStmt makes a select statement from a derived table
+func (s *SQLSmith) derivedSelectStmt(depth int) ast.Node {
+	node := s.selectStmt(1).(*ast.SelectStmt)
+	var inner ast.Node
+	switch s.choose("derived", 1, 1, 1) {
+	case 0:
+		inner = s.aggregationSelectStmt(depth - 1)
+	case 1:
+		inner = s.windowSelectStmt(depth - 1)
+	default:
+		inner = s.selectStmt(depth - 1)
+	}
+	node.From = &ast.TableRefsClause{
+		TableRefs: &ast.Join{
+			Left: &ast.TableSource{Source: inner.(*ast.SelectStmt)},
+		},
+	}
+	return node
+}
+
+// setOprStmt makes set operations of select statements, which select the same columns from the same table
+func (s *SQLSmith) setOprStmt(depth int) ast.Node {
+	node := &types.SetOprStmt{}
+	count := 2 + s.rd(2)
+	// the operations are the same or UNION [ALL], so there is no ambiguity of the precedence
+	tp := types.SetOprType(s.choose("set-operation", 1, 1, 1, 1))
+	for i := 0; i < count; i++ {
+		sel := s.selectStmt(1).(*ast.SelectStmt)
+		sel.OrderBy = nil
+		node.Selects = append(node.Selects, sel)
+		if i == 0 {
+			continue
+		}
+		switch tp {
+		case types.Union, types.UnionAll:
+			node.Oprs = append(node.Oprs, types.SetOprType(s.rd(2)))
+		default:
+			node.Oprs = append(node.Oprs, tp)
+		}
+	}
+	return node
+}
+
+// withStmt makes a select statement with common table expressions
+func (s *SQLSmith) withStmt(depth int) ast.Node {
+	node := &types.WithStmt{
+		Recursive: s.rd(3) == 0,
+	}
+	count := 1 + s.rd(2)
+	for i := 0; i < count; i++ {
+		cte := &types.CTE{}
+		switch {
+		case i == 0 && node.Recursive:
+			sel := s.selectStmt(1).(*ast.SelectStmt)
+			sel.OrderBy = nil
+			cte.Recursive = true
+			cte.Query = sel
+		case s.rd(4) == 0:
+			cte.Query = s.setOprStmt(1)
+		case s.rd(3) == 0:
+			cte.Query = s.aggregationSelectStmt(1)
+		default:
+			cte.Query = s.selectStmt(1)
+		}
+		node.CTEs = append(node.CTEs, cte)
+	}
+	if s.rd(3) == 0 {
+		node.Query = s.aggregationSelectStmt(depth)
+	} else {
+		node.Query = s.selectStmt(depth)
+	}
+	return node
+}
+
+func (s *SQLSmith) compareOp() opcode.Op {
+	switch s.choose("compare-op", 1, 1, 1, 1) {
+	case 0:
+		return opcode.GT
+	case 1:
+		return opcode.LT
+	case 2:
+		return opcode.NE
+	default:
+		return opcode.EQ
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlsmith
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/parser"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/generator/generator"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/feedback"
+)
+
+// TestSQLSmith_SelectShapes tests each shape of select statements
+func TestSQLSmith_SelectShapes(t *testing.T) {
+	cases := []struct {
+		name     string
+		opt      generator.SelectOptions
+		keywords []string
+		// the parser does not support them yet
+		noParse []string
+	}{
+		{"aggregation", generator.SelectOptions{Aggregation: 1}, []string{" AS a0"}, []string{"GROUP_CONCAT"}},
+		{"window", generator.SelectOptions{Window: 1}, []string{" OVER (", " AS w0"}, nil},
+		{"join", generator.SelectOptions{Join: 1}, []string{" JOIN ", " AS c0"}, nil},
+		{"semi join", generator.SelectOptions{SemiJoin: 1}, []string{" IN ("}, nil},
+		{"derived", generator.SelectOptions{Derived: 1}, []string{"FROM (SELECT"}, []string{"GROUP_CONCAT"}},
+		{"set operation", generator.SelectOptions{SetOperation: 1}, []string{" UNION ", " EXCEPT ", " INTERSECT "}, []string{" EXCEPT ", " INTERSECT "}},
+		{"cte", generator.SelectOptions{CTE: 1}, []string{"WITH "}, []string{"WITH "}},
+	}
+
+	for _, c := range cases {
+		ss := new()
+		ss.LoadSchema(schema, indexes)
+		ss.SetDB(dbname)
+		opt := c.opt
+		ss.SetSelectOptions(&opt)
+
+		found := make(map[string]bool)
+		for i := 0; i < 200; i++ {
+			sql, _, err := ss.SelectStmt(1 + rand.Intn(3))
+			if err != nil {
+				t.Fatalf("%s: generate error %v", c.name, err)
+			}
+			for _, keyword := range c.keywords {
+				if strings.Contains(sql, keyword) {
+					found[keyword] = true
+				}
+			}
+			parse := true
+			for _, keyword := range c.noParse {
+				if strings.Contains(sql, keyword) {
+					parse = false
+				}
+			}
+			if !parse {
+				continue
+			}
+			p := parser.New()
+			p.EnableWindowFunc(true)
+			if _, err := p.ParseOneStmt(sql, "", ""); err != nil {
+				t.Fatalf("%s: parse %s error %v", c.name, sql, err)
+			}
+		}
+		if len(found) == 0 {
+			t.Fatalf("%s: none of %v is generated", c.name, c.keywords)
+		}
+	}
+}
+
+// TestSQLSmith_Feedback tests the choices are traced with the feedback and the zero weights are respected
+func TestSQLSmith_Feedback(t *testing.T) {
+	ss := new()
+	ss.LoadSchema(schema, indexes)
+	ss.SetDB(dbname)
+	ss.SetSelectOptions(&generator.SelectOptions{Join: 1, SemiJoin: 1})
+	ss.SetFeedback(feedback.New(feedback.Option{}))
+
+	for i := 0; i < 50; i++ {
+		if _, _, err := ss.SelectStmt(3); err != nil {
+			t.Fatal(err)
+		}
+		trace := ss.Trace()
+		if len(trace) == 0 || trace[0].Kind != "select" {
+			t.Fatalf("unexpected trace %v", trace)
+		}
+		if trace[0].Index != 3 && trace[0].Index != 4 {
+			t.Fatalf("zero weight shape %d is chosen", trace[0].Index)
+		}
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/pingcap/parser/ast"
 
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/generator/generator"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/feedback"
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/stateflow"
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/types"
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/util"
@@ -40,6 +41,10 @@ type SQLSmith struct {
 	debug         bool
 	stable        bool
 	hint          bool
+	partition     bool
+	selectOpt     *generator.SelectOptions
+	feedback      *feedback.Feedback
+	trace         feedback.Trace
 }
 
 // New create SQLSmith instance
@@ -99,6 +104,26 @@ func (s *SQLSmith) SetHint(hint bool) {
 	s.hint = hint
 }
 
+// SetPartition ...
+func (s *SQLSmith) SetPartition(partition bool) {
+	s.partition = partition
+}
+
+// SetSelectOptions sets the weights of the select statement shapes
+func (s *SQLSmith) SetSelectOptions(opt *generator.SelectOptions) {
+	s.selectOpt = opt
+}
+
+// SetFeedback sets the coverage feedback which adapts the random choices
+func (s *SQLSmith) SetFeedback(fb *feedback.Feedback) {
+	s.feedback = fb
+}
+
+// Trace returns the choices made for generating the last statement
+func (s *SQLSmith) Trace() feedback.Trace {
+	return s.trace
+}
+
 // Walk will walk the tree and fillin tables and columns data
 func (s *SQLSmith) Walk(tree ast.Node) (string, string, error) {
 	node, table, err := stateflow.New(s.GetDB(s.currDB), s.stable).WalkTree(tree)
@@ -41,15 +41,8 @@ func (s *StateFlow) randTableFromTable(table *types.Table, newName bool, fn bool
 		newTable.Table = table.Table
 	}
 	for _, column := range columns {
-		newColumn := &types.Column{
-			DB:           column.DB,
-			Table:        column.Table,
-			OriginTable:  column.OriginTable,
-			Column:       column.Column,
-			OriginColumn: column.OriginColumn,
-			DataType:     column.DataType,
-			Func:         column.Func,
-		}
+		newColumn := column.Clone()
+		newColumn.NewFunc = false
 		if newName {
 			newColumn.Table = newTableName
 		} else {
@@ -75,6 +68,10 @@ func (s *StateFlow) randTableFromTable(table *types.Table, newName bool, fn bool
 }
 
 func (s *StateFlow) randTable(newName bool, fn bool, online bool) *types.Table {
+	// prefer the common table expressions defined in WITH
+	if len(s.ctes) > 0 && util.Rd(3) > 0 {
+		return s.ctes[util.Rd(len(s.ctes))].Clone()
+	}
 	tables := []*types.Table{}
 	for _, table := range s.db.Tables {
 		// get online tables
@@ -109,6 +106,20 @@ func (s *StateFlow) randOfflineTable(newName bool, fn bool) *types.Table {
 	return tables[util.Rd(len(tables))].Clone()
 }
 
+func (s *StateFlow) randPartitionedTable() *types.Table {
+	tables := []*types.Table{}
+	for _, table := range s.db.Tables {
+		if len(table.Partitions) > 0 {
+			tables = append(tables, table)
+		}
+	}
+
+	if len(tables) == 0 {
+		return nil
+	}
+	return tables[util.Rd(len(tables))].Clone()
+}
+
 func (s *StateFlow) randOriginTable() *types.Table {
 	tables := s.db.Tables
 	index := 0
@@ -133,60 +144,48 @@ func (s *StateFlow) randColumns(table *types.Table) []*types.Column {
 	return columns
 }
 
-func (s *StateFlow) mergeTable(table1 *types.Table, table2 *types.Table) (*types.Table, [2]*types.Column) {
+// mergeTables makes the result table of joined tables, its columns are c0, c1, ...
+func (s *StateFlow) mergeTables(tables ...*types.Table) *types.Table {
 	subTableName := s.getSubTableName()
 	table := types.Table{
-		DB:      table1.DB,
+		DB:      tables[0].DB,
 		Table:   subTableName,
 		Type:    "SUB TABLE",
 		Columns: make(map[string]*types.Column),
 	}
 
-	var onColumns [2]*types.Column
 	index := 0
-
-	for _, column := range table1.Columns {
-		newColumn := types.Column{
-			DB:           column.DB,
-			Table:        subTableName,
-			OriginTable:  column.Table,
-			DataType:     column.DataType,
-			Column:       fmt.Sprintf("c%d", index),
-			OriginColumn: column.Column,
-		}
-		if column.NewFunc {
-			newColumn.Func = column.Func
+	for _, t := range tables {
+		for _, column := range t.Columns {
+			newColumn := column.Clone()
+			newColumn.Table = subTableName
+			newColumn.OriginTable = column.Table
+			newColumn.Column = fmt.Sprintf("c%d", index)
+			newColumn.OriginColumn = column.Column
+			newColumn.Func = column.NewFunc && column.Func
+			newColumn.NewFunc = false
+			table.Columns[newColumn.Column] = newColumn
+			index++
 		}
-		table.Columns[fmt.Sprintf("c%d", index)] = &newColumn
-		index++
-	}
-	for _, column := range table2.Columns {
-		newColumn := types.Column{
-			DB:           column.DB,
-			Table:        subTableName,
-			OriginTable:  column.Table,
-			DataType:     column.DataType,
-			Column:       fmt.Sprintf("c%d", index),
-			OriginColumn: column.Column,
-		}
-		if column.NewFunc {
-			newColumn.Func = column.Func
-		}
-		table.Columns[fmt.Sprintf("c%d", index)] = &newColumn
-		index++
 	}
+	return &table
+}
 
-	for _, column1 := range table1.Columns {
-		for _, column2 := range table2.Columns {
-			if column1.DataType == column2.DataType {
-				onColumns[0] = column1
-				onColumns[1] = column2
-				return &table, onColumns
-			}
+// scopeTable makes a table of all the columns of joined tables,
+// which are referred by the clauses except the field list
+func (s *StateFlow) scopeTable(tables ...*types.Table) *types.Table {
+	table := types.Table{
+		DB:      tables[0].DB,
+		Type:    "SUB TABLE",
+		Columns: make(map[string]*types.Column),
+	}
+	for _, t := range tables {
+		for _, column := range t.Columns {
+			table.Columns[column.Table+"."+column.Column] = column
 		}
 	}
-
-	return &table, onColumns
+	table.AddToInnerTables(tables...)
+	return &table
 }
 
 func (s *StateFlow) renameTable(table *types.Table) *types.Table {
@@ -221,6 +220,7 @@ func (s *StateFlow) randNewTable() *types.Table {
 		Column:   "uuid",
 		DataType: "varchar",
 		DataLen:  253,
+		Collate:  "utf8mb4_bin",
 	}
 	columnCount := util.RdRange(4, 20)
 	for i := 0; i < columnCount; i++ {
@@ -246,10 +246,19 @@ func (s *StateFlow) randNewColumn() *types.Column {
 			}
 		}
 	}
-	return &types.Column{
+	column := &types.Column{
 		Column:   columnName,
 		DataType: columnType,
 		DataLen:  columnLen,
+		Decimal:  util.RdDecimal(columnType, columnLen),
+		Unsigned: util.RdUnsigned(columnType),
 		Options:  columnOptions,
 	}
+	if columnType == "enum" || columnType == "set" {
+		column.Elems = util.RdElems()
+	}
+	if util.IsStringType(columnType) {
+		column.Collate = util.RdCollation()
+	}
+	return column
 }
\ No newline at end of file
@@ -27,6 +27,8 @@ type StateFlow struct {
 	rand       *rand.Rand
 	tableIndex int
 	stable     bool
+	// ctes are the tables defined in WITH, only available when walking a WithStmt
+	ctes []*types.Table
 }
 
 // New Create StateFlow
@@ -17,7 +17,6 @@ package stateflow
 import (
 	"github.com/pingcap/parser/ast"
 	"github.com/pingcap/parser/model"
-	tidbTypes "github.com/pingcap/tidb/types"
 	driver "github.com/pingcap/tidb/types/parser_driver"
 
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/builtin"
@@ -54,41 +53,56 @@ func (s *StateFlow) walkTree(node ast.Node) (*types.Table, error) {
 		table = s.walkDeleteStmt(node)
 	// DDL
 	case *ast.CreateTableStmt:
+		table = s.walkCreateTableStmt(&types.CreateTableStmt{CreateTableStmt: node})
+	case *types.CreateTableStmt:
 		table = s.walkCreateTableStmt(node)
 	case *ast.AlterTableStmt:
-		table = s.walkAlterTableStmt(node)
+		table, err = s.walkAlterTableStmt(node)
 	case *ast.CreateIndexStmt:
 		table, err = s.walkCreateIndexStmt(node)
+	// nodes not supported by the parser
+	case *types.SetOprStmt:
+		table = s.walkSetOprStmt(node)
+	case *types.WithStmt:
+		table = s.walkWithStmt(node)
 	}
 	return table, err
 }
 
 func (s *StateFlow) walkSelectStmt(node *ast.SelectStmt) *types.Table {
-	var table *types.Table
+	// aggregate and window functions are placeholders made by the generator,
+	// other fields are filled by the walker
+	funcFields := s.funcFields(node.Fields.Fields)
+	node.Fields.Fields = nil
+
+	var (
+		table *types.Table
+		scope *types.Table
+		join  bool
+	)
 	if node.From.TableRefs.Right == nil && node.From.TableRefs.Left != nil {
 		table = s.walkResultSetNode(node.From.TableRefs.Left)
-		s.walkSelectStmtColumns(node, table, false)
 		table.AddToInnerTables(table)
+		scope = table
 	} else if node.From.TableRefs.Right != nil && node.From.TableRefs.Left != nil {
-		lTable := s.walkResultSetNode(node.From.TableRefs.Left)
-		rTable := s.walkResultSetNode(node.From.TableRefs.Right)
-
-		mergeTable, _ := s.mergeTable(lTable, rTable)
-		if node.From.TableRefs.On != nil {
-			s.walkOnStmt(node.From.TableRefs.On, lTable, rTable)
-		}
-		table = mergeTable
-
-		s.walkSelectStmtColumns(node, table, true)
-		// No Alias Name Of Tables
-		table.AddToInnerTables(lTable.InnerTableList...)
-		table.AddToInnerTables(rTable.InnerTableList...)
-		table.AddToInnerTables(table, lTable, rTable)
+		leaves := s.walkJoin(node.From.TableRefs)
+		// the columns of the merged table are only referred by their alias names,
+		// the others clauses refer to the columns of the joined tables
+		table = s.mergeTables(leaves...)
+		table.AddToInnerTables(leaves...)
+		scope = s.scopeTable(leaves...)
+		join = true
 	}
-	s.walkOrderByClause(node.OrderBy, table)
 	// s.walkWhereClause(node.Where, table)
-	s.walkExprNode(node.Where, table, nil)
-	_, node.TableHints = s.walkHintList(len(node.TableHints), table)
+	s.walkExprNode(node.Where, scope, nil)
+	_, node.TableHints = s.walkHintList(len(node.TableHints), scope)
+	if node.GroupBy != nil {
+		table = s.walkAggregation(node, funcFields, scope, table)
+	} else {
+		s.walkSelectStmtColumns(node, table, join)
+		s.walkWindowFields(node, funcFields, scope, table)
+	}
+	s.walkOrderByClause(node.OrderBy, table)
 	return table
 }
 
@@ -121,29 +135,47 @@ func (s *StateFlow) walkDeleteStmt(node *ast.DeleteStmt) *types.Table {
 	return table
 }
 
+// walkJoin walks the join tree and returns the joined tables from left to right
+func (s *StateFlow) walkJoin(node *ast.Join) []*types.Table {
+	var tables []*types.Table
+	if left, ok := node.Left.(*ast.Join); ok {
+		tables = s.walkJoin(left)
+	} else {
+		tables = append(tables, s.walkResultSetNode(node.Left))
+	}
+	if node.Right == nil {
+		return tables
+	}
+	right := s.walkResultSetNode(node.Right)
+	if node.On != nil {
+		s.walkOnStmt(node.On, s.scopeTable(tables...), right)
+	}
+	return append(tables, right)
+}
+
+// walkOnStmt makes an equal condition of the columns with the same data type if there are
 func (s *StateFlow) walkOnStmt(node *ast.OnCondition, table1, table2 *types.Table) {
-	switch node := node.Expr.(type) {
-	case *ast.BinaryOperationExpr:
-		s.walkExprNode(node.R, table2, s.walkExprNode(node.L, table1, nil))
+	expr, ok := node.Expr.(*ast.BinaryOperationExpr)
+	if !ok {
+		return
 	}
-	// if node.From.TableRefs.On != nil {
-	// if onColumns[0] == nil {
-	// 	// node.From.TableRefs.On = ast.
-	// }
-	// if onColumns[1] == nil {
-	// 	// TODO add some builtin function to on clause
-	// 	// node.From.TableRefs.On = nil
-	// } else {
-	// 	switch node := node.Expr.(type) {
-	// 	case *ast.BinaryOperationExpr:
-	// 		s.walkExprNode(node.L, onColumns[0])
-	// 		s.walkExprNode(node.R, onColumns[1])
-	// 	}
-	// }
-	// 		if node, ok := node.From.TableRefs.On.Expr.(*ast.BinaryOperationExpr); ok {
-	// 		}
-	// 	}
-	// }
+	column := s.walkExprNode(expr.L, table1, nil)
+	r, ok := expr.R.(*ast.ColumnNameExpr)
+	if !ok || column == nil {
+		s.walkExprNode(expr.R, table2, column)
+		return
+	}
+	var candidates []*types.Column
+	for _, c := range table2.GetColumns() {
+		if c.DataType == column.DataType {
+			candidates = append(candidates, c)
+		}
+	}
+	if len(candidates) == 0 {
+		s.walkColumnNameExpr(r, table2)
+		return
+	}
+	r.Name = columnName(candidates[util.Rd(len(candidates))])
 }
 
 func (s *StateFlow) walkResultSetNode(node ast.ResultSetNode) *types.Table {
@@ -151,12 +183,20 @@ func (s *StateFlow) walkResultSetNode(node ast.ResultSetNode) *types.Table {
 	case *ast.TableName:
 		return s.walkTableName(node, true, false)
 	case *ast.TableSource:
-		n := node
-		if node, ok := node.Source.(*ast.SelectStmt); ok {
-			table := s.renameTable(s.walkSelectStmt(node))
-			if table.OriginTable != "" {
-				n.AsName = model.NewCIStr(table.Table)
+		switch source := node.Source.(type) {
+		case *ast.SelectStmt:
+			table := s.renameTable(s.walkSelectStmt(source))
+			// the columns of a derived table are referred by their names in it
+			for _, column := range table.Columns {
+				column.OriginTable = ""
+				column.OriginColumn = ""
 			}
+			table.InnerTableList = nil
+			node.AsName = model.NewCIStr(table.Table)
+			return table
+		case *ast.TableName:
+			table := s.renameTable(s.walkTableName(source, true, false))
+			node.AsName = model.NewCIStr(table.Table)
 			return table
 		}
 	}
@@ -218,6 +258,8 @@ func (s *StateFlow) walkExprNode(node ast.ExprNode, table *types.Table, column *
 		s.walkPatternInExpr(n, table)
 	case *ast.SubqueryExpr:
 		return s.walkSubqueryExpr(n).RandColumn()
+	case *ast.ExistsSubqueryExpr:
+		s.walkExistsSubqueryExpr(n, table)
 	}
 	return nil
 }
@@ -240,28 +282,16 @@ func (s *StateFlow) walkHintList(hintLength int, table *types.Table) (*types.Tab
 
 func (s *StateFlow) walkColumnNameExpr(node *ast.ColumnNameExpr, table *types.Table) *types.Column {
 	column := table.RandColumn()
-	node.Name = &ast.ColumnName{
-		Table: model.NewCIStr(column.Table),
-		Name:  model.NewCIStr(column.Column),
-	}
+	node.Name = columnName(column)
 	return column
 }
 
 func (s *StateFlow) walkValueExpr(node *driver.ValueExpr, table *types.Table, column *types.Column) *types.Table {
 	if column != nil {
-		switch column.DataType {
-		case "varchar", "text":
-			node.SetString(util.GenerateStringItem(), "")
+		node.SetValue(column.RandValue())
+		if util.IsStringType(column.DataType) {
 			node.TexprNode.Type.Charset = "utf8mb4"
 			node.TexprNode.Type.Collate = "utf8mb4_bin"
-		case "int":
-			node.SetInt64(int64(util.GenerateIntItem()))
-		case "float":
-			node.SetFloat64(util.GenerateFloatItem())
-		case "timestamp":
-			node.SetMysqlTime(tidbTypes.NewTime(tidbTypes.FromGoTime(util.GenerateTimestampItem()), 0, 0))
-		case "datetime":
-			node.SetMysqlTime(tidbTypes.NewTime(tidbTypes.FromGoTime(util.GenerateTimestampItem()), 0, 0))
 		}
 	}
 	return table
@@ -272,7 +302,7 @@ func (s *StateFlow) walkAssignmentList(list *[]*ast.Assignment, table *types.Tab
 	for _, column := range columns {
 		// TODO: specify primary key in type Table
 		// to avoid this hard coding
-		if column.Column == "id" || column.Column == "uuid" {
+		if column.Column == "id" || column.Column == "uuid" || column.Generated {
 			continue
 		}
 		assignment := ast.Assignment{
@@ -280,7 +310,7 @@ func (s *StateFlow) walkAssignmentList(list *[]*ast.Assignment, table *types.Tab
 				Table: model.NewCIStr(column.Table),
 				Name:  model.NewCIStr(column.Column),
 			},
-			Expr: ast.NewValueExpr(util.GenerateDataItem(column.DataType), "", ""),
+			Expr: ast.NewValueExpr(column.RandValue(), "", ""),
 		}
 		*list = append(*list, &assignment)
 	}
@@ -293,7 +323,8 @@ func (s *StateFlow) walkBinaryOperationExpr(node *ast.BinaryOperationExpr, table
 func (s *StateFlow) walkColumns(columns *[]*ast.ColumnName, table *types.Table) []*types.Column {
 	var cols []*types.Column
 	for _, column := range table.Columns {
-		if column.Column == "id" {
+		// generated columns can not be assigned
+		if column.Column == "id" || column.Generated {
 			continue
 		}
 		*columns = append(*columns, &ast.ColumnName{
@@ -321,7 +352,7 @@ func (s *StateFlow) makeList(columns []*types.Column) []ast.ExprNode {
 		if column.Column == "uuid" {
 			list = append(list, ast.NewValueExpr(util.GetUUID(), "", ""))
 		} else {
-			list = append(list, ast.NewValueExpr(util.GenerateDataItem(column.DataType), "", ""))
+			list = append(list, ast.NewValueExpr(column.RandValue(), "", ""))
 		}
 	}
 	return list
@@ -331,7 +362,12 @@ func (s *StateFlow) walkOrderByClause(node *ast.OrderByClause, table *types.Tabl
 	if node == nil {
 		return
 	}
+	node.Items = nil
 	orderBys := s.randColumns(table)
+	// an empty ORDER BY is invalid
+	if len(orderBys) == 0 {
+		orderBys = append(orderBys, table.RandColumn())
+	}
 	for _, column := range orderBys {
 		item := ast.ByItem{
 			Expr: &ast.ColumnNameExpr{
@@ -363,37 +399,39 @@ func (s *StateFlow) walkPatternInExpr(node *ast.PatternInExpr, table *types.Tabl
 		node.Not = false
 	}
 
-	var subTable *types.Table
+	var (
+		subTable *types.Table
+		columns  = table.GetColumns()
+		count    int
+	)
 
 	switch node := node.Sel.(type) {
 	case *ast.SubqueryExpr:
 		subTable = s.walkSubqueryExpr(node)
-		for len(subTable.Columns) == 0 || len(subTable.Columns) > len(table.Columns) {
+		for i := 0; len(subTable.Columns) == 0 || len(subTable.Columns) > len(columns); i++ {
+			// derived tables and CTEs may have fewer columns than any table,
+			// select part of the columns instead of retrying forever
+			if i >= 10 && len(subTable.Columns) > 0 {
+				sel := node.Query.(*ast.SelectStmt)
+				sel.Fields.Fields = sel.Fields.Fields[:len(columns)]
+				break
+			}
 			subTable = s.walkSubqueryExpr(node)
 		}
+		count = len(node.Query.(*ast.SelectStmt).Fields.Fields)
 	default:
 		panic("unhandled switch")
 	}
 
-	var (
-		subColumns = subTable.GetColumns()
-		columns    = table.GetColumns()
-	)
-	if len(columns) == 1 {
+	if count == 1 {
 		node.Expr = &ast.ColumnNameExpr{
-			Name: &ast.ColumnName{
-				// Schema: model.NewCIStr(""),
-				// Table: model.NewCIStr(""),
-				Name: model.NewCIStr(table.RandColumn().Column),
-			},
+			Name: columnName(table.RandColumn()),
 		}
 	} else {
 		rowExpr := ast.RowExpr{}
-		for index := range subColumns {
+		for index := 0; index < count; index++ {
 			rowExpr.Values = append(rowExpr.Values, &ast.ColumnNameExpr{
-				Name: &ast.ColumnName{
-					Name: model.NewCIStr(columns[index].Column),
-				},
+				Name: columnName(columns[index]),
 			})
 		}
 		node.Expr = &rowExpr
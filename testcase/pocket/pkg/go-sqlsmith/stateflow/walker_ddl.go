@@ -16,14 +16,20 @@ package stateflow
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/juju/errors"
+	"github.com/pingcap/parser"
 	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/charset"
 	"github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
 	parserTypes "github.com/pingcap/parser/types"
 	tidbTypes "github.com/pingcap/tidb/types"
 	driver "github.com/pingcap/tidb/types/parser_driver"
 
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/builtin"
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/types"
 	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/util"
 )
@@ -38,52 +44,125 @@ var (
 	timestampPartition = []string{"1980-01-01 00:00:00", "1990-01-01 00:00:00", "2000-01-01 00:00:00", "2010-01-01 00:00:00", "2020-01-01 00:00:00"}
 )
 
-func (s *StateFlow) walkCreateTableStmt(node *ast.CreateTableStmt) *types.Table {
+func (s *StateFlow) walkCreateTableStmt(node *types.CreateTableStmt) *types.Table {
 	table := s.randNewTable()
 	for _, column := range table.Columns {
-		node.Cols = append(node.Cols, &ast.ColumnDef{
-			Name: &ast.ColumnName{
-				Name: model.NewCIStr(column.Column),
-			},
-			Tp:      s.makeFieldType(column.DataType, column.DataLen),
-			Options: s.makeColumnOptions(column, column.Options),
-		})
-		if column.HasOption(ast.ColumnOptionAutoIncrement) {
-			s.makeConstraintPrimaryKey(node, column)
-		}
+		node.Cols = append(node.Cols, s.makeColumnDef(column))
+	}
+	// the primary key is (id) or (id, uuid)
+	s.makeConstraintPrimaryKey(node.CreateTableStmt, table.Columns["id"])
+	if util.Rd(3) == 0 {
+		s.makeConstraintPrimaryKey(node.CreateTableStmt, table.Columns["uuid"])
 	}
+	node.PrimaryKeyType = types.PrimaryKeyType(util.Rd(3))
+	s.walkGeneratedColumns(node.CreateTableStmt, table)
 	node.Table.Name = model.NewCIStr(table.Table)
-	s.walkTableOption(node)
-	if column := table.RandColumn(); node.Partition != nil && column != nil {
+	s.walkTableOption(node.CreateTableStmt)
+	if column := s.randPartitionColumn(table); node.Partition != nil && column != nil {
 		s.walkPartition(node.Partition, column)
 		if column.Column != "id" {
-			s.makeConstraintPrimaryKey(node, column)
+			s.makeConstraintPrimaryKey(node.CreateTableStmt, column)
 		}
+	} else {
+		node.Partition = nil
 	}
 	return table
 }
 
-func (s *StateFlow) walkAlterTableStmt(node *ast.AlterTableStmt) *types.Table {
-	table := s.randTable(false, false, false)
+// walkGeneratedColumns adds virtual or stored generated columns which are functions of the other columns
+func (s *StateFlow) walkGeneratedColumns(node *ast.CreateTableStmt, table *types.Table) {
+	columns := table.GetColumns()
+	count := util.Rd(3)
+	for i := 0; i < count; i++ {
+		expr, retType := builtin.GenerateColumnFuncCallExpr(columns[util.Rd(len(columns))])
+		if expr == nil {
+			continue
+		}
+		column := &types.Column{
+			DB:        table.DB,
+			Table:     table.Table,
+			Column:    util.RdStringChar(util.RdRange(5, 10)),
+			DataType:  retType,
+			DataLen:   -1,
+			Decimal:   -1,
+			Generated: true,
+			Stored:    util.RdBool(),
+		}
+		if util.IsStringType(retType) {
+			column.Collate = util.RdCollation()
+		}
+		if _, ok := table.Columns[column.Column]; ok {
+			continue
+		}
+		table.Columns[column.Column] = column
+		def := s.makeColumnDef(column)
+		def.Options = append(def.Options, &ast.ColumnOption{
+			Tp:     ast.ColumnOptionGenerated,
+			Expr:   expr,
+			Stored: column.Stored,
+		})
+		node.Cols = append(node.Cols, def)
+	}
+}
+
+// randPartitionColumn picks a column whose type is supported by walkPartition
+func (s *StateFlow) randPartitionColumn(table *types.Table) *types.Column {
+	var columns []*types.Column
+	for _, column := range table.Columns {
+		switch column.DataType {
+		case "int", "datetime", "timestamp":
+			if !column.Generated {
+				columns = append(columns, column)
+			}
+		}
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	return columns[util.Rd(len(columns))]
+}
+
+func (s *StateFlow) walkAlterTableStmt(node *ast.AlterTableStmt) (*types.Table, error) {
+	var table *types.Table
+	switch node.Specs[0].Tp {
+	case ast.AlterTableTruncatePartition, ast.AlterTableReorganizePartition, ast.AlterTableExchangePartition:
+		table = s.randPartitionedTable()
+	default:
+		table = s.randTable(false, false, false)
+	}
+	if table == nil {
+		return nil, errors.New("no table available")
+	}
 	node.Table.Name = model.NewCIStr(table.Table)
 	// we support only one spec now
 	// unless TiDB will print error
 	// ERROR 8200 (HY000): Unsupported multi schema change
-	switch node.Specs[0].Tp {
+	spec := node.Specs[0]
+	switch spec.Tp {
 	case ast.AlterTableAddColumns:
-		{
-			s.alterTableSpecAddColumns(node.Specs[0], table)
-		}
+		s.alterTableSpecAddColumns(spec, table)
 	case ast.AlterTableDropColumn:
-		{
-			s.alterTableSpecDropColumn(node.Specs[0], table)
-		}
+		s.alterTableSpecDropColumn(spec, table)
 	case ast.AlterTableDropIndex:
-		{
-			s.alterTableSpecDropIndex(node.Specs[0], table)
-		}
+		return table, s.alterTableSpecDropIndex(spec, table)
+	case ast.AlterTableModifyColumn:
+		return table, s.alterTableSpecModifyColumn(spec, table)
+	case ast.AlterTableAddConstraint:
+		return table, s.alterTableSpecAddIndex(spec, table)
+	case ast.AlterTableRenameTable:
+		spec.NewTable.Name = model.NewCIStr(util.RdStringChar(util.RdRange(5, 10)))
+	case ast.AlterTableTruncatePartition:
+		spec.PartitionNames = []model.CIStr{model.NewCIStr(table.Partitions[util.Rd(len(table.Partitions))].Name)}
+	case ast.AlterTableReorganizePartition:
+		return table, s.alterTableSpecReorganizePartition(spec, table)
+	case ast.AlterTableExchangePartition:
+		// the table to exchange with is created by the generator before this statement
+		spec.PartitionNames = []model.CIStr{model.NewCIStr(table.Partitions[util.Rd(len(table.Partitions))].Name)}
+		spec.NewTable.Name = model.NewCIStr(util.RdStringChar(util.RdRange(5, 10)))
+	case ast.AlterTableSetTiFlashReplica:
+		spec.TiFlashReplica.Count = uint64(util.Rd(2))
 	}
-	return table
+	return table, nil
 }
 
 func (s *StateFlow) walkCreateIndexStmt(node *ast.CreateIndexStmt) (*types.Table, error) {
@@ -93,20 +172,90 @@ func (s *StateFlow) walkCreateIndexStmt(node *ast.CreateIndexStmt) (*types.Table
 	}
 	node.Table.Name = model.NewCIStr(table.Table)
 	node.IndexName = util.RdStringChar(5)
-	for _, column := range table.Columns {
-		node.IndexPartSpecifications = append(node.IndexPartSpecifications,
-			&ast.IndexPartSpecification{
-				Column: &ast.ColumnName{
-					Name: model.NewCIStr(column.Column),
-				},
-			})
+	node.IndexPartSpecifications = s.randIndexParts(table)
+	if len(node.IndexPartSpecifications) == 0 {
+		return nil, errors.New("no column can be indexed")
 	}
 	return table, nil
 }
 
-func (s *StateFlow) makeFieldType(t string, l int) *parserTypes.FieldType {
-	fieldType := parserTypes.NewFieldType(util.Type2Tp(t))
-	fieldType.Flen = l
+// randIndexParts rand 1 to 3 columns of the table, and an expression sometimes
+func (s *StateFlow) randIndexParts(table *types.Table) []*ast.IndexPartSpecification {
+	var (
+		parts   []*ast.IndexPartSpecification
+		columns = table.GetColumns()
+		count   = util.RdRange(1, 4)
+	)
+	for _, i := range s.rand.Perm(len(columns)) {
+		if len(parts) >= count {
+			break
+		}
+		if part := s.makeIndexPart(columns[i]); part != nil {
+			parts = append(parts, part)
+		}
+	}
+	// the expression index
+	if util.Rd(4) == 0 {
+		expr, retType := builtin.GenerateColumnFuncCallExpr(columns[util.Rd(len(columns))])
+		// functional index parts can not return BLOB or TEXT
+		if expr != nil && retType != "text" {
+			parts = append(parts, &ast.IndexPartSpecification{
+				Expr: expr,
+			})
+		}
+	}
+	return parts
+}
+
+// makeIndexPart makes the index part of the column, the long strings are indexed by prefix
+func (s *StateFlow) makeIndexPart(column *types.Column) *ast.IndexPartSpecification {
+	part := ast.IndexPartSpecification{
+		Column: &ast.ColumnName{
+			Name: model.NewCIStr(column.Column),
+		},
+	}
+	switch column.DataType {
+	case "json":
+		return nil
+	case "text", "blob":
+		part.Length = util.RdRange(1, 64)
+	case "varchar", "char":
+		if column.DataLen > 64 {
+			part.Length = util.RdRange(1, 64)
+		}
+	}
+	return &part
+}
+
+func (s *StateFlow) makeColumnDef(column *types.Column) *ast.ColumnDef {
+	return &ast.ColumnDef{
+		Name: &ast.ColumnName{
+			Name: model.NewCIStr(column.Column),
+		},
+		Tp:      s.makeFieldType(column),
+		Options: s.makeColumnOptions(column, column.Options),
+	}
+}
+
+func (s *StateFlow) makeFieldType(column *types.Column) *parserTypes.FieldType {
+	fieldType := parserTypes.NewFieldType(util.Type2Tp(column.DataType))
+	fieldType.Flen = column.DataLen
+	switch column.DataType {
+	case "decimal", "time", "datetime", "timestamp":
+		fieldType.Decimal = column.Decimal
+	case "enum", "set":
+		fieldType.Elems = column.Elems
+	case "blob":
+		fieldType.Charset = charset.CharsetBin
+		fieldType.Collate = charset.CollationBin
+	}
+	if column.Unsigned {
+		fieldType.Flag |= mysql.UnsignedFlag
+	}
+	if column.Collate != "" {
+		fieldType.Charset = charset.CharsetUTF8MB4
+		fieldType.Collate = column.Collate
+	}
 	return fieldType
 }
 
@@ -134,6 +283,12 @@ func (s *StateFlow) makeColumnOption(column *types.Column, option ast.ColumnOpti
 func (s *StateFlow) makeConstraintPrimaryKey(node *ast.CreateTableStmt, column *types.Column) {
 	for _, constraint := range node.Constraints {
 		if constraint.Tp == ast.ConstraintPrimaryKey {
+			// eg. uuid may be chosen as the partition column after it's added
+			for _, key := range constraint.Keys {
+				if key.Column.Name.L == strings.ToLower(column.Column) {
+					return
+				}
+			}
 			constraint.Keys = append(constraint.Keys, &ast.IndexPartSpecification{
 				Column: &ast.ColumnName{
 					Name: model.NewCIStr(column.Column),
@@ -279,13 +434,13 @@ func (s *StateFlow) walkPartitionDefinitionsDatetime(definitions *[]*ast.Partiti
 func (s *StateFlow) walkPartitionDefinitionsTimestamp(definitions *[]*ast.PartitionDefinition) {
 	for i := 0; i < len(timestampPartition); i += util.RdRange(1, 3) {
 		val := driver.ValueExpr{}
-		val.SetMysqlTime(tidbTypes.NewTime(tidbTypes.FromGoTime(util.GenerateTimestampItem()), 0, 0))
+		val.SetMysqlTime(tidbTypes.NewTime(tidbTypes.FromGoTime(util.TimeMustParse(timeParseFormat, timestampPartition[i])), 0, 0))
 		*definitions = append(*definitions, &ast.PartitionDefinition{
 			Name: model.NewCIStr(fmt.Sprintf("p%d", i)),
 			Clause: &ast.PartitionDefinitionClauseLessThan{
 				Exprs: []ast.ExprNode{
 					&ast.FuncCallExpr{
-						FnName: model.NewCIStr("TO_DAYS"),
+						FnName: model.NewCIStr("UNIX_TIMESTAMP"),
 						Args:   []ast.ExprNode{&val},
 					},
 				},
@@ -300,7 +455,7 @@ func (s *StateFlow) alterTableSpecAddColumns(node *ast.AlterTableSpec, table *ty
 		Name: &ast.ColumnName{
 			Name: model.NewCIStr(column.Column),
 		},
-		Tp:      s.makeFieldType(column.DataType, column.DataLen),
+		Tp:      s.makeFieldType(column),
 		Options: s.makeColumnOptions(column, column.Options),
 	}
 }
@@ -312,9 +467,134 @@ func (s *StateFlow) alterTableSpecDropColumn(node *ast.AlterTableSpec, table *ty
 	}
 }
 
-func (s *StateFlow) alterTableSpecDropIndex(node *ast.AlterTableSpec, table *types.Table) {
-	// when index is a empty string
-	// there will be a SQL error will
-	// not it doesn't matter
+func (s *StateFlow) alterTableSpecDropIndex(node *ast.AlterTableSpec, table *types.Table) error {
 	node.Name = table.RandIndex()
-}
\ No newline at end of file
+	if node.Name == "" {
+		return errors.New("no index can be dropped")
+	}
+	return nil
+}
+// modifiableTypes are the types a column can be modified to in each type class
+var modifiableTypes = map[string][]string{
+	util.TypeClassNumeric: {"tinyint", "smallint", "mediumint", "int", "bigint", "decimal", "float", "double"},
+	util.TypeClassString:  {"char", "varchar", "text"},
+	util.TypeClassTime:    {"date", "datetime"},
+}
+
+func (s *StateFlow) alterTableSpecModifyColumn(node *ast.AlterTableSpec, table *types.Table) error {
+	var columns []*types.Column
+	for _, column := range table.GetColumns() {
+		// the primary key and generated columns are left unchanged
+		if column.Column != "id" && column.Column != "uuid" && !column.Generated {
+			columns = append(columns, column)
+		}
+	}
+	if len(columns) == 0 {
+		return errors.New("no column can be modified")
+	}
+	node.NewColumns[0] = s.makeColumnDef(s.modifyColumnType(columns[util.Rd(len(columns))]))
+	return nil
+}
+
+// modifyColumnType changes the column to a random type of the same type class,
+// the new type may be narrower than the old one, so the change can be lossy
+func (s *StateFlow) modifyColumnType(column *types.Column) *types.Column {
+	newColumn := column.Clone()
+	if tps, ok := modifiableTypes[util.TypeClass(column.DataType)]; ok {
+		newColumn.DataType = tps[util.Rd(len(tps))]
+		newColumn.Elems = nil
+	} else if len(column.Elems) > 1 {
+		// drop the last members of enum and set
+		newColumn.Elems = column.Elems[:util.RdRange(1, len(column.Elems))]
+	}
+	newColumn.DataLen = util.RdDataLen(newColumn.DataType)
+	switch newColumn.DataType {
+	case "char", "varchar":
+		if util.RdBool() {
+			newColumn.DataLen = util.RdRange(1, 32)
+		}
+	}
+	newColumn.Decimal = util.RdDecimal(newColumn.DataType, newColumn.DataLen)
+	newColumn.Unsigned = util.RdUnsigned(newColumn.DataType)
+	if !util.IsStringType(newColumn.DataType) {
+		newColumn.Collate = ""
+	}
+	return newColumn
+}
+
+func (s *StateFlow) alterTableSpecAddIndex(node *ast.AlterTableSpec, table *types.Table) error {
+	node.Constraint.Name = util.RdStringChar(5)
+	node.Constraint.Keys = s.randIndexParts(table)
+	if len(node.Constraint.Keys) == 0 {
+		return errors.New("no column can be indexed")
+	}
+	return nil
+}
+
+// alterTableSpecReorganizePartition splits a partition into two, or merges two adjacent partitions into one
+func (s *StateFlow) alterTableSpecReorganizePartition(node *ast.AlterTableSpec, table *types.Table) error {
+	partitions := table.Partitions
+	if util.RdBool() {
+		// only the partitions of integer ranges can be split
+		for _, i := range s.rand.Perm(len(partitions)) {
+			if i == 0 {
+				continue
+			}
+			lower, err1 := strconv.ParseInt(partitions[i-1].Description, 10, 64)
+			upper, err2 := strconv.ParseInt(partitions[i].Description, 10, 64)
+			if err1 != nil || err2 != nil || upper-lower < 2 {
+				continue
+			}
+			mid := driver.ValueExpr{}
+			mid.SetInt64(lower + s.rand.Int63n(upper-lower-1) + 1)
+			top := driver.ValueExpr{}
+			top.SetInt64(upper)
+			node.PartitionNames = []model.CIStr{model.NewCIStr(partitions[i].Name)}
+			node.PartDefinitions = []*ast.PartitionDefinition{
+				{
+					Name: model.NewCIStr("p" + util.RdStringChar(5)),
+					Clause: &ast.PartitionDefinitionClauseLessThan{
+						Exprs: []ast.ExprNode{&mid},
+					},
+				},
+				{
+					Name: model.NewCIStr(partitions[i].Name),
+					Clause: &ast.PartitionDefinitionClauseLessThan{
+						Exprs: []ast.ExprNode{&top},
+					},
+				},
+			}
+			return nil
+		}
+	}
+	if len(partitions) < 2 {
+		return errors.New("no partitions can be merged")
+	}
+	i := util.Rd(len(partitions) - 1)
+	expr, err := partitionDescriptionExpr(partitions[i+1].Description)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	node.PartitionNames = []model.CIStr{model.NewCIStr(partitions[i].Name), model.NewCIStr(partitions[i+1].Name)}
+	node.PartDefinitions = []*ast.PartitionDefinition{
+		{
+			Name: model.NewCIStr(partitions[i+1].Name),
+			Clause: &ast.PartitionDefinitionClauseLessThan{
+				Exprs: []ast.ExprNode{expr},
+			},
+		},
+	}
+	return nil
+}
+
+// partitionDescriptionExpr parses the partition description into the expression of VALUES LESS THAN
+func partitionDescriptionExpr(description string) (ast.ExprNode, error) {
+	if strings.EqualFold(description, "MAXVALUE") {
+		return &ast.MaxValueExpr{}, nil
+	}
+	stmt, err := parser.New().ParseOneStmt("SELECT "+description, "", "")
+	if err != nil {
+		return nil, errors.Annotatef(err, "invalid partition description %s", description)
+	}
+	return stmt.(*ast.SelectStmt).Fields.Fields[0].Expr, nil
+}
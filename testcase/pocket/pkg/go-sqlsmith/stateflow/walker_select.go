@@ -0,0 +1,371 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stateflow
+
+import (
+	"fmt"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/opcode"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/types"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/util"
+)
+
+func columnName(column *types.Column) *ast.ColumnName {
+	return &ast.ColumnName{
+		Table: model.NewCIStr(column.Table),
+		Name:  model.NewCIStr(column.Column),
+	}
+}
+
+func fieldName(field *ast.SelectField) string {
+	if field.AsName.O != "" {
+		return field.AsName.O
+	}
+	if expr, ok := field.Expr.(*ast.ColumnNameExpr); ok {
+		return expr.Name.Name.O
+	}
+	return ""
+}
+
+// funcFields picks the placeholders of aggregate and window functions
+func (s *StateFlow) funcFields(fields []*ast.SelectField) []*ast.SelectField {
+	var funcs []*ast.SelectField
+	for _, field := range fields {
+		switch field.Expr.(type) {
+		case *ast.AggregateFuncExpr, *types.GroupConcatExpr, *ast.WindowFuncExpr:
+			funcs = append(funcs, field)
+		}
+	}
+	return funcs
+}
+
+// walkAggregation fills the group by items and aggregate functions,
+// the result table is made of the group by columns g0, g1, ... and the aggregations a0, a1, ...
+func (s *StateFlow) walkAggregation(node *ast.SelectStmt, fields []*ast.SelectField, scope, table *types.Table) *types.Table {
+	result := &types.Table{
+		DB:             table.DB,
+		Table:          table.Table,
+		Type:           table.Type,
+		Columns:        make(map[string]*types.Column),
+		InnerTableList: table.InnerTableList,
+	}
+	addColumn := func(name, dataType string) {
+		result.Columns[name] = &types.Column{
+			DB:       result.DB,
+			Table:    result.Table,
+			Column:   name,
+			DataType: dataType,
+		}
+	}
+
+	node.GroupBy.Items = nil
+	for i, column := range s.randGroupColumns(scope) {
+		name := fmt.Sprintf("g%d", i)
+		node.Fields.Fields = append(node.Fields.Fields, &ast.SelectField{
+			Expr:   &ast.ColumnNameExpr{Name: columnName(column)},
+			AsName: model.NewCIStr(name),
+		})
+		node.GroupBy.Items = append(node.GroupBy.Items, &ast.ByItem{
+			Expr: &ast.ColumnNameExpr{Name: columnName(column)},
+		})
+		addColumn(name, column.DataType)
+	}
+	if len(node.GroupBy.Items) == 0 {
+		node.GroupBy = nil
+	}
+	for i, field := range fields {
+		name := fmt.Sprintf("a%d", i)
+		field.AsName = model.NewCIStr(name)
+		node.Fields.Fields = append(node.Fields.Fields, field)
+		addColumn(name, s.walkAggregateFuncExpr(field.Expr, scope))
+	}
+
+	if node.Having != nil {
+		if expr, ok := node.Having.Expr.(*ast.BinaryOperationExpr); ok {
+			dataType := s.walkAggregateFuncExpr(expr.L, scope)
+			s.walkExprNode(expr.R, scope, &types.Column{DataType: dataType})
+		}
+	}
+	return result
+}
+
+// randGroupColumns picks at most 3 columns, grouping by too many columns makes the aggregations trivial
+func (s *StateFlow) randGroupColumns(table *types.Table) []*types.Column {
+	columns := table.GetColumns()
+	count := util.Rd(4)
+	if count > len(columns) {
+		count = len(columns)
+	}
+	var groups []*types.Column
+	for _, i := range s.rand.Perm(len(columns))[:count] {
+		groups = append(groups, columns[i])
+	}
+	return groups
+}
+
+// walkAggregateFuncExpr fills the arguments and returns the data type of the aggregation
+func (s *StateFlow) walkAggregateFuncExpr(node ast.ExprNode, scope *types.Table) string {
+	switch node := node.(type) {
+	case *types.GroupConcatExpr:
+		s.walkExprNode(node.Args[0], scope, nil)
+		// order by the argument itself, so the result is deterministic
+		node.OrderBy.Items = []*ast.ByItem{{Expr: node.Args[0]}}
+		return "text"
+	case *ast.AggregateFuncExpr:
+		column := s.walkExprNode(node.Args[0], scope, nil)
+		switch node.F {
+		case "count", "bit_and", "bit_or", "bit_xor", "approx_count_distinct":
+			return "int"
+		case "sum", "avg":
+			return "float"
+		}
+		if column != nil {
+			return column.DataType
+		}
+	}
+	return "int"
+}
+
+// walkWindowFields fills the window functions and adds their results w0, w1, ... to the table
+func (s *StateFlow) walkWindowFields(node *ast.SelectStmt, fields []*ast.SelectField, scope, table *types.Table) {
+	var columns []*types.Column
+	for _, field := range fields {
+		expr, ok := field.Expr.(*ast.WindowFuncExpr)
+		if !ok {
+			continue
+		}
+		name := fmt.Sprintf("w%d", len(columns))
+		field.AsName = model.NewCIStr(name)
+		node.Fields.Fields = append(node.Fields.Fields, field)
+		columns = append(columns, &types.Column{
+			DB:       table.DB,
+			Table:    table.Table,
+			Column:   name,
+			DataType: s.walkWindowFuncExpr(expr, scope),
+		})
+	}
+	// the scope may be the table itself, so add the results after all the windows are walked
+	for _, column := range columns {
+		table.Columns[column.Column] = column
+	}
+}
+
+// walkWindowFuncExpr fills the window, which is ordered by all the columns to make the result deterministic
+func (s *StateFlow) walkWindowFuncExpr(node *ast.WindowFuncExpr, scope *types.Table) string {
+	var column *types.Column
+	if len(node.Args) > 0 {
+		column = s.walkExprNode(node.Args[0], scope, nil)
+	}
+	if node.Spec.PartitionBy != nil {
+		node.Spec.PartitionBy.Items = nil
+		for _, c := range s.randColumns(scope) {
+			node.Spec.PartitionBy.Items = append(node.Spec.PartitionBy.Items, &ast.ByItem{
+				Expr: &ast.ColumnNameExpr{Name: columnName(c)},
+			})
+		}
+		if len(node.Spec.PartitionBy.Items) == 0 {
+			node.Spec.PartitionBy = nil
+		}
+	}
+	node.Spec.OrderBy.Items = nil
+	for _, c := range scope.GetColumns() {
+		node.Spec.OrderBy.Items = append(node.Spec.OrderBy.Items, &ast.ByItem{
+			Expr: &ast.ColumnNameExpr{Name: columnName(c)},
+		})
+	}
+
+	switch node.F {
+	case "row_number", "rank", "dense_rank", "ntile", "count":
+		return "int"
+	case "percent_rank", "cume_dist", "sum", "avg":
+		return "float"
+	}
+	if column != nil {
+		return column.DataType
+	}
+	return "int"
+}
+
+// walkExistsSubqueryExpr correlates the subquery to the outer table by the first equal condition
+func (s *StateFlow) walkExistsSubqueryExpr(node *ast.ExistsSubqueryExpr, table *types.Table) {
+	sub, ok := node.Sel.(*ast.SubqueryExpr)
+	if !ok {
+		return
+	}
+	sel, ok := sub.Query.(*ast.SelectStmt)
+	if !ok {
+		return
+	}
+	inner := s.walkSelectStmt(sel)
+
+	where, ok := sel.Where.(*ast.BinaryOperationExpr)
+	if !ok || where.Op != opcode.LogicAnd {
+		return
+	}
+	eq, ok := where.L.(*ast.BinaryOperationExpr)
+	if !ok || eq.Op != opcode.EQ {
+		return
+	}
+	l, lok := eq.L.(*ast.ColumnNameExpr)
+	r, rok := eq.R.(*ast.ColumnNameExpr)
+	if !lok || !rok || table == nil {
+		return
+	}
+	column, ok := inner.Columns[l.Name.Name.O]
+	if !ok {
+		return
+	}
+	var candidates []*types.Column
+	for _, c := range table.GetColumns() {
+		if c.DataType == column.DataType {
+			candidates = append(candidates, c)
+		}
+	}
+	if len(candidates) == 0 {
+		r.Name = columnName(table.RandColumn())
+		return
+	}
+	r.Name = columnName(candidates[util.Rd(len(candidates))])
+}
+
+// walkSetOprStmt makes all the selects choose the same columns from the same table,
+// so they are union compatible
+func (s *StateFlow) walkSetOprStmt(node *types.SetOprStmt) *types.Table {
+	first := node.Selects[0]
+	table := s.walkSelectStmt(first)
+	for _, sel := range node.Selects[1:] {
+		sel.From = first.From
+		sel.Fields = first.Fields
+		s.walkExprNode(sel.Where, table, nil)
+		_, sel.TableHints = s.walkHintList(len(sel.TableHints), table)
+	}
+	return table
+}
+
+// walkWithStmt names the common table expressions cte0, cte1, ... with columns c0, c1, ...,
+// they can be referred by the later ones and the query
+func (s *StateFlow) walkWithStmt(node *types.WithStmt) *types.Table {
+	defer func() {
+		s.ctes = nil
+	}()
+	for i, cte := range node.CTEs {
+		cte.Name = fmt.Sprintf("cte%d", i)
+		var (
+			table  *types.Table
+			fields []*ast.SelectField
+		)
+		switch query := cte.Query.(type) {
+		case *ast.SelectStmt:
+			table = s.walkSelectStmt(query)
+			fields = query.Fields.Fields
+		case *types.SetOprStmt:
+			table = s.walkSetOprStmt(query)
+			fields = query.Selects[0].Fields.Fields
+		default:
+			s.shouldNotWalkHere(query)
+			continue
+		}
+
+		var dataTypes []string
+		if cte.Recursive {
+			// the counter of recursion
+			dataTypes = append(dataTypes, "int")
+		}
+		for _, field := range fields {
+			dataType := "int"
+			if column, ok := table.Columns[fieldName(field)]; ok {
+				dataType = column.DataType
+			}
+			dataTypes = append(dataTypes, dataType)
+		}
+
+		result := &types.Table{
+			DB:      table.DB,
+			Table:   cte.Name,
+			Type:    "CTE",
+			Columns: make(map[string]*types.Column),
+		}
+		cte.Columns = nil
+		for j, dataType := range dataTypes {
+			name := fmt.Sprintf("c%d", j)
+			cte.Columns = append(cte.Columns, name)
+			result.Columns[name] = &types.Column{
+				DB:       result.DB,
+				Table:    result.Table,
+				Column:   name,
+				DataType: dataType,
+			}
+		}
+		if cte.Recursive {
+			s.walkRecursiveCTE(cte)
+		}
+		s.ctes = append(s.ctes, result)
+	}
+
+	switch query := node.Query.(type) {
+	case *ast.SelectStmt:
+		return s.walkSelectStmt(query)
+	case *types.SetOprStmt:
+		return s.walkSetOprStmt(query)
+	}
+	s.shouldNotWalkHere(node.Query)
+	return nil
+}
+
+// walkRecursiveCTE makes the query of a recursive CTE from its seed part,
+// the recursive part increases the counter c0 until it reaches a small limit
+func (s *StateFlow) walkRecursiveCTE(cte *types.CTE) {
+	seed, ok := cte.Query.(*ast.SelectStmt)
+	if !ok {
+		return
+	}
+	seed.Fields.Fields = append([]*ast.SelectField{{Expr: ast.NewValueExpr(1, "", "")}}, seed.Fields.Fields...)
+
+	counter := &ast.ColumnNameExpr{Name: &ast.ColumnName{Name: model.NewCIStr(cte.Columns[0])}}
+	fields := []*ast.SelectField{{
+		Expr: &ast.BinaryOperationExpr{
+			Op: opcode.Plus,
+			L:  counter,
+			R:  ast.NewValueExpr(1, "", ""),
+		},
+	}}
+	for _, column := range cte.Columns[1:] {
+		fields = append(fields, &ast.SelectField{
+			Expr: &ast.ColumnNameExpr{Name: &ast.ColumnName{Name: model.NewCIStr(column)}},
+		})
+	}
+	recursive := &ast.SelectStmt{
+		SelectStmtOpts: &ast.SelectStmtOpts{
+			SQLCache: true,
+		},
+		Fields: &ast.FieldList{Fields: fields},
+		From: &ast.TableRefsClause{
+			TableRefs: &ast.Join{
+				Left: &ast.TableName{Name: model.NewCIStr(cte.Name)},
+			},
+		},
+		Where: &ast.BinaryOperationExpr{
+			Op: opcode.LT,
+			L:  counter,
+			R:  ast.NewValueExpr(util.RdRange(2, 5), "", ""),
+		},
+	}
+	cte.Query = &types.SetOprStmt{
+		Selects: []*ast.SelectStmt{seed, recursive},
+		Oprs:    []types.SetOprType{types.UnionAll},
+	}
+}
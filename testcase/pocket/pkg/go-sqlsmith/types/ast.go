@@ -0,0 +1,284 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/format"
+)
+
+// The AST nodes below are not supported by the parser yet,
+// they are generated and walked like the parser nodes, and restored to SQL in the same way.
+
+// SetOprType is the type of set operation
+type SetOprType int
+
+// set operation types
+const (
+	Union SetOprType = iota
+	UnionAll
+	Except
+	Intersect
+)
+
+func (t SetOprType) String() string {
+	switch t {
+	case Union:
+		return "UNION"
+	case UnionAll:
+		return "UNION ALL"
+	case Except:
+		return "EXCEPT"
+	case Intersect:
+		return "INTERSECT"
+	}
+	return ""
+}
+
+type textNode struct {
+	text string
+}
+
+// SetText implements Node interface
+func (n *textNode) SetText(text string) {
+	n.text = text
+}
+
+// Text implements Node interface
+func (n *textNode) Text() string {
+	return n.text
+}
+
+// SetOprStmt is the set operations of select statements,
+// Oprs[i] is the operation between Selects[i] and Selects[i+1].
+type SetOprStmt struct {
+	textNode
+	Selects []*ast.SelectStmt
+	Oprs    []SetOprType
+}
+
+// Restore implements Node interface
+func (n *SetOprStmt) Restore(ctx *format.RestoreCtx) error {
+	for i, sel := range n.Selects {
+		if i > 0 {
+			ctx.WritePlain(" ")
+			ctx.WriteKeyWord(n.Oprs[i-1].String())
+			ctx.WritePlain(" ")
+		}
+		if err := sel.Restore(ctx); err != nil {
+			return errors.Annotatef(err, "An error occurred while restore SetOprStmt.Selects[%d]", i)
+		}
+	}
+	return nil
+}
+
+// Accept implements Node Accept interface
+func (n *SetOprStmt) Accept(v ast.Visitor) (ast.Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*SetOprStmt)
+	for i, sel := range n.Selects {
+		node, ok := sel.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Selects[i] = node.(*ast.SelectStmt)
+	}
+	return v.Leave(n)
+}
+
+// CTE is a common table expression
+type CTE struct {
+	Name    string
+	Columns []string
+	// Recursive CTE is made of its query, which is the seed part, by the walker
+	Recursive bool
+	// Query is either *ast.SelectStmt or *SetOprStmt
+	Query ast.Node
+}
+
+// WithStmt is a select statement with common table expressions
+type WithStmt struct {
+	textNode
+	Recursive bool
+	CTEs      []*CTE
+	// Query is either *ast.SelectStmt or *SetOprStmt
+	Query ast.Node
+}
+
+// Restore implements Node interface
+func (n *WithStmt) Restore(ctx *format.RestoreCtx) error {
+	ctx.WriteKeyWord("WITH ")
+	if n.Recursive {
+		ctx.WriteKeyWord("RECURSIVE ")
+	}
+	for i, cte := range n.CTEs {
+		if i > 0 {
+			ctx.WritePlain(", ")
+		}
+		ctx.WriteName(cte.Name)
+		if len(cte.Columns) > 0 {
+			ctx.WritePlain(" (")
+			for j, column := range cte.Columns {
+				if j > 0 {
+					ctx.WritePlain(", ")
+				}
+				ctx.WriteName(column)
+			}
+			ctx.WritePlain(")")
+		}
+		ctx.WriteKeyWord(" AS ")
+		ctx.WritePlain("(")
+		if err := cte.Query.Restore(ctx); err != nil {
+			return errors.Annotatef(err, "An error occurred while restore WithStmt.CTEs[%d]", i)
+		}
+		ctx.WritePlain(")")
+	}
+	ctx.WritePlain(" ")
+	return errors.Annotate(n.Query.Restore(ctx), "An error occurred while restore WithStmt.Query")
+}
+
+// Accept implements Node Accept interface
+func (n *WithStmt) Accept(v ast.Visitor) (ast.Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*WithStmt)
+	for _, cte := range n.CTEs {
+		node, ok := cte.Query.Accept(v)
+		if !ok {
+			return n, false
+		}
+		cte.Query = node
+	}
+	node, ok := n.Query.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Query = node
+	return v.Leave(n)
+}
+
+// GroupConcatExpr is GROUP_CONCAT with ORDER BY, which makes the result deterministic
+type GroupConcatExpr struct {
+	ast.AggregateFuncExpr
+	OrderBy *ast.OrderByClause
+}
+
+// Restore implements Node interface, the last argument is the separator
+func (n *GroupConcatExpr) Restore(ctx *format.RestoreCtx) error {
+	ctx.WriteKeyWord(n.F)
+	ctx.WritePlain("(")
+	if n.Distinct {
+		ctx.WriteKeyWord("DISTINCT ")
+	}
+	for i := 0; i < len(n.Args)-1; i++ {
+		if i != 0 {
+			ctx.WritePlain(", ")
+		}
+		if err := n.Args[i].Restore(ctx); err != nil {
+			return errors.Annotatef(err, "An error occurred while restore GroupConcatExpr.Args[%d]", i)
+		}
+	}
+	if n.OrderBy != nil {
+		ctx.WritePlain(" ")
+		if err := n.OrderBy.Restore(ctx); err != nil {
+			return errors.Annotate(err, "An error occurred while restore GroupConcatExpr.OrderBy")
+		}
+	}
+	ctx.WriteKeyWord(" SEPARATOR ")
+	if err := n.Args[len(n.Args)-1].Restore(ctx); err != nil {
+		return errors.Annotate(err, "An error occurred while restore GroupConcatExpr.Args SEPARATOR")
+	}
+	ctx.WritePlain(")")
+	return nil
+}
+
+// Accept implements Node Accept interface
+func (n *GroupConcatExpr) Accept(v ast.Visitor) (ast.Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*GroupConcatExpr)
+	for i, arg := range n.Args {
+		node, ok := arg.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Args[i] = node.(ast.ExprNode)
+	}
+	return v.Leave(n)
+}
+
+// PrimaryKeyType tells if the primary key is the clustered index
+type PrimaryKeyType int
+
+// primary key types
+const (
+	PrimaryKeyDefault PrimaryKeyType = iota
+	PrimaryKeyClustered
+	PrimaryKeyNonClustered
+)
+
+func (t PrimaryKeyType) String() string {
+	switch t {
+	case PrimaryKeyClustered:
+		return "CLUSTERED"
+	case PrimaryKeyNonClustered:
+		return "NONCLUSTERED"
+	}
+	return ""
+}
+
+// CreateTableStmt is the create table statement with [NON]CLUSTERED primary key,
+// the keyword is written in a TiDB feature comment so that MySQL ignores it
+type CreateTableStmt struct {
+	*ast.CreateTableStmt
+	PrimaryKeyType PrimaryKeyType
+}
+
+// Restore implements Node interface
+func (n *CreateTableStmt) Restore(ctx *format.RestoreCtx) error {
+	var out bytes.Buffer
+	if err := n.CreateTableStmt.Restore(format.NewRestoreCtx(ctx.Flags, &out)); err != nil {
+		return errors.Trace(err)
+	}
+	sql := out.String()
+	for _, constraint := range n.Constraints {
+		if constraint.Tp != ast.ConstraintPrimaryKey || n.PrimaryKeyType == PrimaryKeyDefault {
+			continue
+		}
+		var pk bytes.Buffer
+		if err := constraint.Restore(format.NewRestoreCtx(ctx.Flags, &pk)); err != nil {
+			return errors.Annotate(err, "An error occurred while restore CreateTableStmt.Constraints")
+		}
+		sql = strings.Replace(sql, pk.String(), pk.String()+" /*T![clustered_index] "+n.PrimaryKeyType.String()+" */", 1)
+	}
+	ctx.WritePlain(sql)
+	return nil
+}
+
+// Accept implements Node Accept interface
+func (n *CreateTableStmt) Accept(v ast.Visitor) (ast.Node, bool) {
+	newNode, _ := v.Enter(n)
+	return v.Leave(newNode)
+}
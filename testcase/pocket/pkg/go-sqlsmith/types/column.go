@@ -13,7 +13,11 @@
 
 package types
 
-import "github.com/pingcap/parser/ast"
+import (
+	"github.com/pingcap/parser/ast"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/util"
+)
 
 // Column defines database column
 type Column struct {
@@ -24,9 +28,18 @@ type Column struct {
 	OriginColumn string
 	DataType     string
 	DataLen      int
-	Func         bool
-	NewFunc      bool
-	Options      []ast.ColumnOptionType
+	// Decimal is the scale of decimal or the fsp of time types
+	Decimal  int
+	Unsigned bool
+	// Elems are the members of enum and set
+	Elems   []string
+	Collate string
+	// Generated is true for virtual and stored generated columns
+	Generated bool
+	Stored    bool
+	Func      bool
+	NewFunc   bool
+	Options   []ast.ColumnOptionType
 }
 
 // Clone makes a replica of column
@@ -39,6 +52,12 @@ func (c *Column) Clone() *Column {
 		OriginColumn: c.OriginColumn,
 		DataType:     c.DataType,
 		DataLen:      c.DataLen,
+		Decimal:      c.Decimal,
+		Unsigned:     c.Unsigned,
+		Elems:        c.Elems,
+		Collate:      c.Collate,
+		Generated:    c.Generated,
+		Stored:       c.Stored,
 		Func:         c.Func,
 		NewFunc:      c.NewFunc,
 		Options:      c.Options,
@@ -64,3 +83,31 @@ func (c *Column) HasOption(opt ast.ColumnOptionType) bool {
 	}
 	return false
 }
+
+// Nullable returns if the column accepts NULL
+func (c *Column) Nullable() bool {
+	return !c.HasOption(ast.ColumnOptionNotNull) &&
+		!c.HasOption(ast.ColumnOptionPrimaryKey) &&
+		!c.HasOption(ast.ColumnOptionAutoIncrement)
+}
+
+// DataOption returns the option for generating data of the column
+func (c *Column) DataOption() util.DataOption {
+	return util.DataOption{
+		Len:      c.DataLen,
+		Decimal:  c.Decimal,
+		Unsigned: c.Unsigned,
+		Nullable: c.Nullable(),
+		Elems:    c.Elems,
+	}
+}
+
+// RandValue rand a value which can be stored in the column
+func (c *Column) RandValue() interface{} {
+	return util.GenerateDataItemWithOption(c.DataType, c.DataOption())
+}
+
+// RandValueString rand a value which can be stored in the column as a SQL literal
+func (c *Column) RandValueString() string {
+	return util.FormatDataItem(c.RandValue())
+}
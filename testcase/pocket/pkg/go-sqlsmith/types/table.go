@@ -35,6 +35,15 @@ type Table struct {
 	// which means this table is being manipulated in other txns and should not be a DDL table
 	OnlineOther    bool
 	InnerTableList []*Table
+	// Partitions are the range partitions in order, empty if the table is not partitioned
+	Partitions []*Partition
+}
+
+// Partition defines a range partition of table
+type Partition struct {
+	Name string
+	// Description is the VALUES LESS THAN expression, e.g. "100", "MAXVALUE"
+	Description string
 }
 
 type byColumn []*Column
@@ -74,6 +83,7 @@ func (t *Table) Clone() *Table {
 		Online:         t.Online,
 		OnlineOther:    t.OnlineOther,
 		InnerTableList: t.InnerTableList,
+		Partitions:     t.Partitions,
 	}
 	for k, column := range t.Columns {
 		newTable.Columns[k] = column.Clone()
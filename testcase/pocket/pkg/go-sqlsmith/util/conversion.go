@@ -16,20 +16,82 @@ package util
 
 import "github.com/pingcap/parser/mysql"
 
+// type classes of the data types, which tell the builtin functions they are compatible with
+const (
+	TypeClassNumeric = "numeric"
+	TypeClassString  = "string"
+	TypeClassTime    = "time"
+	TypeClassJSON    = "json"
+)
+
 // Type2Tp conver type string to tp byte
-// TODO: complete conversion map
 func Type2Tp(t string) byte {
 	switch t {
+	case "tinyint":
+		return mysql.TypeTiny
+	case "smallint":
+		return mysql.TypeShort
+	case "mediumint":
+		return mysql.TypeInt24
 	case "int":
 		return mysql.TypeLong
+	case "bigint":
+		return mysql.TypeLonglong
+	case "decimal":
+		return mysql.TypeNewDecimal
+	case "float":
+		return mysql.TypeFloat
+	case "double":
+		return mysql.TypeDouble
+	case "bit":
+		return mysql.TypeBit
+	case "char":
+		return mysql.TypeString
 	case "varchar":
 		return mysql.TypeVarchar
+	case "text", "blob":
+		return mysql.TypeBlob
+	case "enum":
+		return mysql.TypeEnum
+	case "set":
+		return mysql.TypeSet
+	case "json":
+		return mysql.TypeJSON
+	case "date":
+		return mysql.TypeDate
+	case "time":
+		return mysql.TypeDuration
+	case "year":
+		return mysql.TypeYear
 	case "timestamp":
 		return mysql.TypeTimestamp
 	case "datetime":
 		return mysql.TypeDatetime
-	case "text":
-		return mysql.TypeBlob
 	}
 	return mysql.TypeNull
-}
\ No newline at end of file
+}
+
+// TypeClass returns the class of the given type,
+// the types whose builtin functions results depend on the session, e.g. TIME and TIMESTAMP, have no class
+func TypeClass(t string) string {
+	switch t {
+	case "tinyint", "smallint", "mediumint", "int", "bigint", "decimal", "float", "double":
+		return TypeClassNumeric
+	case "char", "varchar", "text", "enum", "set":
+		return TypeClassString
+	case "date", "datetime":
+		return TypeClassTime
+	case "json":
+		return TypeClassJSON
+	}
+	return ""
+}
+
+// IsStringType returns if the type has charset and collation
+func IsStringType(t string) bool {
+	switch t {
+	case "char", "varchar", "text", "enum", "set":
+		return true
+	}
+	return false
+}
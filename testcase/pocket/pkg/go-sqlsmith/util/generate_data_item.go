@@ -15,12 +15,16 @@
 package util
 
 import (
-	"fmt"
+	"math"
+	"math/rand"
 	"strings"
 	"time"
 
+	"github.com/pingcap/parser/ast"
 	"github.com/pingcap/parser/mysql"
 	tidbTypes "github.com/pingcap/tidb/types"
+	// register the value expression of the parser
+	_ "github.com/pingcap/tidb/types/parser_driver"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -29,6 +33,31 @@ func GetUUID() string {
 	return strings.ToUpper(uuid.NewV4().String())
 }
 
+// DataOption describes the column which a data item is generated for
+type DataOption struct {
+	// Len is the length of strings, the precision of decimals or the width of bits
+	Len int
+	// Decimal is the scale of decimals or the fsp of time types
+	Decimal  int
+	Unsigned bool
+	Nullable bool
+	// Elems are the members of enum and set
+	Elems []string
+}
+
+var (
+	// intTypeRanges are the max values of the signed integer types
+	intTypeRanges = map[string]int64{
+		"tinyint":   math.MaxInt8,
+		"smallint":  math.MaxInt16,
+		"mediumint": 1<<23 - 1,
+		"int":       math.MaxInt32,
+		"bigint":    math.MaxInt64,
+	}
+	multiByteStrings = []string{"中文", "ß", "😀", "ǅ", "ｆｕｌｌ"}
+	jsonItems        = []string{`{"a": 1, "b": [1, 2]}`, `[1, "x", null]`, `"str"`, `1.5`, `true`, `{"a": {"b": {"c": []}}}`}
+)
+
 // GenerateRandDataItem rand data item with rand type
 func GenerateRandDataItem() interface{} {
 	switch Rd(6) {
@@ -48,42 +77,180 @@ func GenerateRandDataItem() interface{} {
 	panic("unhandled switch")
 }
 
-// GenerateDataItemString rand data with given type
+// GenerateDataItemString rand data with given type, the result is a SQL literal
 func GenerateDataItemString(columnType string) string {
-	d := GenerateDataItem(columnType)
-	switch c := d.(type) {
-	case string:
-		return c
-	case int:
-		return fmt.Sprintf("\"%d\"", c)
-	case time.Time:
-		return c.Format("2006-01-02 15:04:05")
-	case tidbTypes.Time:
-		return c.String()
-	case float64:
-		return fmt.Sprintf("%f", c)
-	}
-	return "not implement data transfer"
+	return FormatDataItem(GenerateDataItem(columnType))
+}
+
+// FormatDataItem formats a generated data item as a SQL literal
+func FormatDataItem(d interface{}) string {
+	node := ast.NewValueExpr(d, "", "")
+	out, err := BufferOut(node)
+	if err != nil {
+		return "NULL"
+	}
+	return out
 }
 
 // GenerateDataItem rand data interface with given type
 func GenerateDataItem(columnType string) interface{} {
+	return generateDataItem(columnType, DataOption{})
+}
+
+// GenerateDataItemWithOption rand data interface with given type and column,
+// the edge values such as NULL, extremes, empty and multi-byte strings are generated in a low probability
+func GenerateDataItemWithOption(columnType string, opt DataOption) interface{} {
+	if Rd(8) == 0 {
+		if opt.Nullable && Rd(3) == 0 {
+			return nil
+		}
+		if d := generateEdgeDataItem(columnType, opt); d != nil {
+			return d
+		}
+	}
+	return generateDataItem(columnType, opt)
+}
+
+func generateDataItem(columnType string, opt DataOption) interface{} {
 	var res interface{}
 	switch columnType {
-	case "varchar":
+	case "varchar", "char":
 		res = GenerateStringItem()
-	case "text":
+		if opt.Len > 0 && len(res.(string)) > opt.Len {
+			res = res.(string)[:opt.Len]
+		}
+	case "text", "blob":
 		res = GenerateStringItem()
 	case "int":
-		res = GenerateIntItem()
-	case "timestamp", "datetime":
-		res = GenerateTiDBDateItem()
-	case "float":
+		if opt.Unsigned {
+			res = uint64(GenerateIntItem())
+		} else {
+			res = GenerateIntItem()
+		}
+	case "tinyint", "smallint", "mediumint", "bigint":
+		max := intTypeRanges[columnType]
+		if opt.Unsigned {
+			res = uint64(rand.Int63n(max))*2 + uint64(Rd(2))
+		} else if Rd(4) == 0 {
+			res = -rand.Int63n(max)
+		} else {
+			res = rand.Int63n(max)
+		}
+	case "decimal":
+		res = GenerateDecimalItem(opt.Len, opt.Decimal, opt.Unsigned)
+	case "float", "double":
 		res = GenerateFloatItem()
+		if !opt.Unsigned && Rd(4) == 0 {
+			res = -res.(float64)
+		}
+	case "bit":
+		res = rand.Uint64() & bitMask(opt.Len)
+	case "enum":
+		if len(opt.Elems) == 0 {
+			return nil
+		}
+		res = opt.Elems[Rd(len(opt.Elems))]
+	case "set":
+		var members []string
+		for _, e := range opt.Elems {
+			if RdBool() {
+				members = append(members, e)
+			}
+		}
+		res = strings.Join(members, ",")
+	case "json":
+		res = jsonItems[Rd(len(jsonItems))]
+	case "date":
+		res = tidbTypes.NewTime(tidbTypes.FromGoTime(GenerateDateItem()), mysql.TypeDate, 0)
+	case "time":
+		d := time.Duration(rand.Int63n(int64(24*time.Hour))) / time.Microsecond * time.Microsecond
+		res = tidbTypes.Duration{Duration: d, Fsp: int8(opt.Decimal)}
+	case "year":
+		res = int64(RdRange(1901, 2156))
+	case "datetime":
+		res = GenerateTiDBDateItem(mysql.TypeDatetime, opt.Decimal)
+	case "timestamp":
+		res = GenerateTiDBDateItem(mysql.TypeTimestamp, opt.Decimal)
 	}
 	return res
 }
 
+// generateEdgeDataItem returns the extremes and the special values of the given type
+func generateEdgeDataItem(columnType string, opt DataOption) interface{} {
+	var items []interface{}
+	switch columnType {
+	case "varchar", "char", "text", "blob":
+		items = []interface{}{"", " ", "a ", strings.Repeat("z", MinInt(MaxInt(opt.Len, 1), 100))}
+		for _, str := range multiByteStrings {
+			if opt.Len <= 0 || len([]rune(str)) <= opt.Len {
+				items = append(items, str)
+			}
+		}
+	case "int", "tinyint", "smallint", "mediumint", "bigint":
+		max := intTypeRanges[columnType]
+		if opt.Unsigned {
+			items = []interface{}{uint64(0), uint64(max)*2 + 1}
+		} else {
+			items = []interface{}{int64(0), int64(-1), max, -max - 1}
+		}
+	case "decimal":
+		items = []interface{}{tidbTypes.NewDecFromInt(0), maxDecimal(opt.Len, opt.Decimal, false)}
+		if !opt.Unsigned {
+			items = append(items, maxDecimal(opt.Len, opt.Decimal, true))
+		}
+	case "float":
+		items = []interface{}{float64(0), 1e38, 1e-38}
+		if !opt.Unsigned {
+			items = append(items, -1e38)
+		}
+	case "double":
+		items = []interface{}{float64(0), 1e308, 1e-307}
+		if !opt.Unsigned {
+			items = append(items, -1e308)
+		}
+	case "bit":
+		items = []interface{}{uint64(0), bitMask(opt.Len)}
+	case "enum":
+		if len(opt.Elems) != 0 {
+			items = []interface{}{opt.Elems[0], opt.Elems[len(opt.Elems)-1]}
+		}
+	case "set":
+		items = []interface{}{"", strings.Join(opt.Elems, ",")}
+	case "json":
+		items = []interface{}{"null", "{}", "[]", `"中文"`, "-0", "18446744073709551615"}
+	case "date":
+		items = []interface{}{
+			tidbTypes.NewTime(tidbTypes.FromDate(1000, 1, 1, 0, 0, 0, 0), mysql.TypeDate, 0),
+			tidbTypes.NewTime(tidbTypes.FromDate(9999, 12, 31, 0, 0, 0, 0), mysql.TypeDate, 0),
+		}
+	case "time":
+		max := 838*time.Hour + 59*time.Minute + 59*time.Second
+		fsp := int8(opt.Decimal)
+		items = []interface{}{
+			tidbTypes.Duration{Duration: 0, Fsp: fsp},
+			tidbTypes.Duration{Duration: max, Fsp: fsp},
+			tidbTypes.Duration{Duration: -max, Fsp: fsp},
+		}
+	case "year":
+		items = []interface{}{int64(1901), int64(2155)}
+	case "datetime":
+		items = []interface{}{
+			tidbTypes.NewTime(tidbTypes.FromDate(1000, 1, 1, 0, 0, 0, 0), mysql.TypeDatetime, int8(opt.Decimal)),
+			tidbTypes.NewTime(tidbTypes.FromDate(9999, 12, 31, 23, 59, 59, 0), mysql.TypeDatetime, int8(opt.Decimal)),
+		}
+	case "timestamp":
+		// keep a day away from the bounds, which depend on the time zone
+		items = []interface{}{
+			tidbTypes.NewTime(tidbTypes.FromDate(1970, 1, 2, 0, 0, 0, 0), mysql.TypeTimestamp, int8(opt.Decimal)),
+			tidbTypes.NewTime(tidbTypes.FromDate(2038, 1, 18, 0, 0, 0, 0), mysql.TypeTimestamp, int8(opt.Decimal)),
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return items[Rd(len(items))]
+}
+
 // GenerateStringItem generate string item
 func GenerateStringItem() string {
 	return strings.ToUpper(RdStringChar(Rd(100)))
@@ -94,6 +261,70 @@ func GenerateIntItem() int {
 	return Rd(2147483647)
 }
 
+// GenerateDecimalItem generate decimal item with the given precision and scale
+func GenerateDecimalItem(precision, scale int, unsigned bool) *tidbTypes.MyDecimal {
+	if precision <= 0 {
+		precision = 10
+	}
+	if scale < 0 || scale > precision {
+		scale = 0
+	}
+	var b strings.Builder
+	if !unsigned && Rd(4) == 0 {
+		b.WriteString("-")
+	}
+	b.WriteString("0")
+	for i := Rd(precision - scale + 1); i > 0; i-- {
+		b.WriteByte(byte('0' + Rd(10)))
+	}
+	if scale > 0 {
+		b.WriteString(".")
+		for i := 0; i < scale; i++ {
+			b.WriteByte(byte('0' + Rd(10)))
+		}
+	}
+	d := new(tidbTypes.MyDecimal)
+	if err := d.FromString([]byte(b.String())); err != nil {
+		return tidbTypes.NewDecFromInt(0)
+	}
+	return d
+}
+
+// maxDecimal returns the max or min value of the given precision and scale
+func maxDecimal(precision, scale int, negative bool) *tidbTypes.MyDecimal {
+	if precision <= 0 {
+		precision = 10
+	}
+	if scale < 0 || scale > precision {
+		scale = 0
+	}
+	str := strings.Repeat("9", precision-scale)
+	if str == "" {
+		str = "0"
+	}
+	if scale > 0 {
+		str += "." + strings.Repeat("9", scale)
+	}
+	if negative {
+		str = "-" + str
+	}
+	d := new(tidbTypes.MyDecimal)
+	if err := d.FromString([]byte(str)); err != nil {
+		return tidbTypes.NewDecFromInt(0)
+	}
+	return d
+}
+
+func bitMask(width int) uint64 {
+	if width <= 0 {
+		width = 1
+	}
+	if width >= 64 {
+		return math.MaxUint64
+	}
+	return 1<<uint(width) - 1
+}
+
 // GenerateFloatItem generate float item
 func GenerateFloatItem() float64 {
 	return float64(Rd(100000)) * RdFloat64()
@@ -117,13 +348,18 @@ func GenerateTimestampItem() time.Time {
 	return t
 }
 
-// GenerateTiDBDateItem generate date item
-func GenerateTiDBDateItem() tidbTypes.Time {
-	// return tidbTypes.Time{
-	// 	Time: tidbTypes.FromGoTime(GenerateDateItem()),
-	// 	Type: mysql.TypeDatetime,
-	// }
-	return tidbTypes.NewTime(tidbTypes.FromGoTime(GenerateDateItem()), mysql.TypeDatetime, 0)
+// GenerateTiDBDateItem generate datetime or timestamp item with the given fsp
+func GenerateTiDBDateItem(tp byte, fsp int) tidbTypes.Time {
+	var t time.Time
+	if tp == mysql.TypeTimestamp {
+		t = GenerateTimestampItem()
+	} else {
+		t = GenerateDateItem()
+	}
+	if fsp > 0 {
+		t = t.Add(time.Duration(rand.Int63n(int64(time.Second))) / time.Microsecond * time.Microsecond)
+	}
+	return tidbTypes.NewTime(tidbTypes.FromGoTime(t), tp, int8(fsp))
 }
 
 func ifDaylightTime(t time.Time) bool {
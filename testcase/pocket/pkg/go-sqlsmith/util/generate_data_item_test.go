@@ -14,6 +14,7 @@
 package util
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -29,3 +30,25 @@ func TestSQLSmith_TestIfDaylightTime(t *testing.T) {
 	assert.Equal(t, ifDaylightTime(TimeMustParse(layout, "1985-08-05 11:45:14")), false)
 	assert.Equal(t, ifDaylightTime(TimeMustParse(layout, "1992-06-05 11:45:14")), false)
 }
+
+func TestSQLSmith_GenerateDataItemWithOption(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		d := GenerateDataItemWithOption("enum", DataOption{Elems: []string{"a", "b"}})
+		assert.Contains(t, []interface{}{"a", "b"}, d)
+
+		d = GenerateDataItemWithOption("bit", DataOption{Len: 3})
+		assert.True(t, d.(uint64) < 8)
+
+		d = GenerateDataItemWithOption("tinyint", DataOption{Unsigned: true})
+		assert.True(t, d.(uint64) <= 255)
+
+		d = GenerateDataItemWithOption("decimal", DataOption{Len: 5, Decimal: 2})
+		assert.True(t, len(strings.TrimLeft(FormatDataItem(d), "-")) <= 6)
+
+		d = GenerateDataItemWithOption("varchar", DataOption{Len: 4})
+		assert.True(t, len([]rune(d.(string))) <= 4)
+
+		assert.NotEqual(t, FormatDataItem(GenerateDataItemWithOption("datetime", DataOption{Decimal: 3})), "NULL")
+		assert.NotEqual(t, FormatDataItem(GenerateDataItemWithOption("time", DataOption{})), "NULL")
+	}
+}
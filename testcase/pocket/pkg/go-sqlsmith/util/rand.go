@@ -89,19 +89,21 @@ func RdStringChar(length int) string {
 	return res
 }
 
+var (
+	// columnTypes are the data types of the generated columns,
+	// int and varchar are more likely to be chosen
+	columnTypes = []string{
+		"int", "int", "tinyint", "smallint", "mediumint", "bigint",
+		"decimal", "float", "double", "bit",
+		"varchar", "varchar", "char", "text", "blob", "enum", "set", "json",
+		"date", "time", "year", "datetime", "timestamp",
+	}
+	collations = []string{"utf8mb4_bin", "utf8mb4_general_ci", "utf8mb4_unicode_ci"}
+)
+
 // RdType rand data type
 func RdType() string {
-	switch Rd(6) {
-	case 0:
-		return "varchar"
-	case 1:
-		return "text"
-	case 2:
-		return "timestamp"
-	case 3:
-		return "datetime"
-	}
-	return "int"
+	return columnTypes[Rd(len(columnTypes))]
 }
 
 // RdDataLen rand data with given type
@@ -111,18 +113,60 @@ func RdDataLen(t string) int {
 		return RdRange(8, 20)
 	case "varchar":
 		return RdRange(255, 2047)
-	case "float":
-		return RdRange(16, 64)
-	case "timestamp":
-		return -1
-	case "datetime":
-		return -1
-	case "text":
+	case "char":
+		return RdRange(1, 256)
+	case "decimal":
+		return RdRange(1, 66)
+	case "bit":
+		return RdRange(1, 65)
+	case "tinyint", "smallint", "mediumint", "bigint", "float", "double",
+		"timestamp", "datetime", "date", "time", "year",
+		"text", "blob", "enum", "set", "json":
 		return -1
 	}
 	return 10
 }
 
+// RdDecimal rand the scale of decimal or the fsp of time types with given type and length
+func RdDecimal(t string, l int) int {
+	switch t {
+	case "decimal":
+		return Rd(MinInt(l, 30) + 1)
+	case "time", "datetime", "timestamp":
+		if Rd(2) == 0 {
+			return 0
+		}
+		return Rd(7)
+	}
+	return -1
+}
+
+// RdUnsigned rand if the numeric type is unsigned
+func RdUnsigned(t string) bool {
+	switch t {
+	case "int", "tinyint", "smallint", "mediumint", "bigint", "decimal":
+		return Rd(4) == 0
+	}
+	return false
+}
+
+// RdElems rand the members of enum and set
+func RdElems() []string {
+	var (
+		elems []string
+		exist = make(map[string]bool)
+	)
+	count := RdRange(1, 8)
+	for i := 0; i < count; i++ {
+		elem := RdStringChar(RdRange(1, 6))
+		if !exist[elem] {
+			exist[elem] = true
+			elems = append(elems, elem)
+		}
+	}
+	return elems
+}
+
 // RdColumnOptions for rand column option with given type
 func RdColumnOptions(t string) (options []ast.ColumnOptionType) {
 	if Rd(3) == 0 {
@@ -131,7 +175,9 @@ func RdColumnOptions(t string) (options []ast.ColumnOptionType) {
 		options = append(options, ast.ColumnOptionNull)
 	}
 	switch t {
-	case "varchar", "timestamp", "datetime", "int":
+	// BLOB, TEXT and JSON columns can not have a default value
+	case "text", "blob", "json":
+	default:
 		if Rd(2) == 0 {
 			options = append(options, ast.ColumnOptionDefaultValue)
 		}
@@ -143,10 +189,15 @@ func RdColumnOptions(t string) (options []ast.ColumnOptionType) {
 func RdCharset() string {
 	switch Rd(4) {
 	default:
-		return "utf8"
+		return "utf8mb4"
 	}
 }
 
+// RdCollation rand collation of utf8mb4
+func RdCollation() string {
+	return collations[Rd(len(collations))]
+}
+
 // RdBool ...
 func RdBool() bool {
 	return Rd(2) == 0
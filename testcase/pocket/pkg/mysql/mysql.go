@@ -16,6 +16,8 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -152,6 +154,115 @@ func (conn *DBConnect) Rollback() error {
 	return conn.txn.Rollback()
 }
 
+// ErrVarNotApplied means the session variable set for a prepared statement doesn't take effect,
+// e.g. the variable is unknown to the server
+var ErrVarNotApplied = errors.New("session variable not applied")
+
+// session is the transaction or the dedicated connection a statement is prepared in
+type session interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// Stmt is a server-side prepared statement, executed by the binary protocol
+type Stmt struct {
+	*sql.Stmt
+	sess session
+	// conn is the dedicated connection of the statement prepared out of transaction
+	conn *sql.Conn
+	// vars are the session variables to be reset when the statement is closed
+	vars []string
+}
+
+// Close the statement, reset the session variables and release its connection
+func (s *Stmt) Close() error {
+	var err error
+	if s.Stmt != nil {
+		err = s.Stmt.Close()
+	}
+	if err1 := resetVars(s.sess, s.vars); err == nil {
+		err = err1
+	}
+	if s.conn != nil {
+		if err1 := s.conn.Close(); err == nil {
+			err = err1
+		}
+	}
+	return err
+}
+
+// Prepare creates a prepared statement in the transaction if there is one, otherwise in a dedicated connection,
+// the session variables are set in the same session before preparing and reset to default when the statement is closed,
+// so that they are not left in the pooled connection. ErrVarNotApplied is returned if any of them doesn't take effect.
+func (conn *DBConnect) Prepare(query string, vars map[string]string) (*Stmt, error) {
+	conn.Lock()
+	defer conn.Unlock()
+	ctx := context.Background()
+	stmt := &Stmt{}
+	if conn.txn != nil {
+		stmt.sess = conn.txn
+	} else {
+		c, err := conn.db.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		stmt.sess, stmt.conn = c, c
+	}
+	for name, value := range vars {
+		stmt.vars = append(stmt.vars, name)
+		if err := setVar(ctx, stmt.sess, name, value); err != nil {
+			_ = stmt.Close()
+			return nil, err
+		}
+	}
+	var err error
+	if stmt.Stmt, err = stmt.sess.PrepareContext(ctx, query); err != nil {
+		_ = stmt.Close()
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// setVar sets the session variable and reads it back to make sure it takes effect
+func setVar(ctx context.Context, sess session, name, value string) error {
+	if _, err := sess.ExecContext(ctx, fmt.Sprintf("SET @@session.%s = %s", name, value)); err != nil {
+		if isMySQLError(err, 1193) {
+			return errors.Annotatef(ErrVarNotApplied, "unknown variable %s", name)
+		}
+		return err
+	}
+	var actual sql.NullString
+	if err := sess.QueryRowContext(ctx, fmt.Sprintf("SELECT @@session.%s", name)).Scan(&actual); err != nil {
+		return err
+	}
+	if normalizeVar(actual.String) != normalizeVar(value) {
+		return errors.Annotatef(ErrVarNotApplied, "%s is %s after set to %s", name, actual.String, value)
+	}
+	return nil
+}
+
+// resetVars resets the session variables to their global values
+func resetVars(sess session, vars []string) error {
+	for _, name := range vars {
+		_, err := sess.ExecContext(context.Background(), fmt.Sprintf("SET @@session.%s = DEFAULT", name))
+		if err != nil && !isMySQLError(err, 1193) {
+			return err
+		}
+	}
+	return nil
+}
+
+func normalizeVar(value string) string {
+	switch strings.ToUpper(value) {
+	case "ON", "TRUE":
+		return "1"
+	case "OFF", "FALSE":
+		return "0"
+	}
+	return strings.ToUpper(value)
+}
+
 // CloseDB turn off db connection
 func (conn *DBConnect) CloseDB() error {
 	return conn.db.Close()
@@ -213,6 +324,18 @@ func IsErrDupEntry(err error) bool {
 	return isMySQLError(err, 1062)
 }
 
+// IsErrUnknownTable returns true if error code = 1109,
+// e.g. query information_schema.ddl_jobs of MySQL
+func IsErrUnknownTable(err error) bool {
+	return isMySQLError(err, 1109)
+}
+
+// IsErrDataInconsistent returns true if ADMIN CHECK TABLE finds the indexes and rows are inconsistent,
+// error code = 8003 (admin check table), 8133 (data inconsistent) or 8134 (data inconsistent mismatch count)
+func IsErrDataInconsistent(err error) bool {
+	return isMySQLError(err, 8003) || isMySQLError(err, 8133) || isMySQLError(err, 8134)
+}
+
 func isMySQLError(err error, code uint16) bool {
 	err = originError(err)
 	e, ok := err.(*mysql.MySQLError)
@@ -0,0 +1,130 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeKind is the class of the difference between 2 plans
+type ChangeKind int
+
+// change kinds, a plan change is classified to the first matched kind in this order
+const (
+	ChangeNone ChangeKind = iota
+	// ChangePushdown means the operators are pushed down to different stores, e.g. TiKV and TiFlash
+	ChangePushdown
+	// ChangeAccessPath means the tables are read by different readers, e.g. table scan and index lookup
+	ChangeAccessPath
+	// ChangeIndexChoice means the same access path reads different indexes
+	ChangeIndexChoice
+	// ChangeJoinType means the join algorithms are different, e.g. hash join and index join
+	ChangeJoinType
+	// ChangeJoinOrder means the tables are joined in different orders
+	ChangeJoinOrder
+	// ChangeOther is any other difference of the plan trees
+	ChangeOther
+)
+
+// ChangeKinds are all the kinds of plan changes
+var ChangeKinds = []ChangeKind{ChangePushdown, ChangeAccessPath, ChangeIndexChoice, ChangeJoinType, ChangeJoinOrder, ChangeOther}
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeNone:
+		return "none"
+	case ChangePushdown:
+		return "pushdown"
+	case ChangeAccessPath:
+		return "access-path"
+	case ChangeIndexChoice:
+		return "index-choice"
+	case ChangeJoinType:
+		return "join-type"
+	case ChangeJoinOrder:
+		return "join-order"
+	}
+	return "other"
+}
+
+// Classify tells the kind of change from plan a to plan b
+func Classify(a, b *Plan) ChangeKind {
+	if a.String() == b.String() {
+		return ChangeNone
+	}
+
+	stores := func(p *Plan) []string {
+		return p.collect(func(op *Operator) (string, bool) {
+			return op.Name + "@" + store(op.Task), true
+		})
+	}
+	if !reflect.DeepEqual(stores(a), stores(b)) && reflect.DeepEqual(names(a, nil), names(b, nil)) {
+		return ChangePushdown
+	}
+	if !reflect.DeepEqual(usedStores(a), usedStores(b)) {
+		return ChangePushdown
+	}
+
+	if !reflect.DeepEqual(names(a, isAccessOperator), names(b, isAccessOperator)) {
+		return ChangeAccessPath
+	}
+	accessObjects := func(p *Plan) []string {
+		return p.collect(func(op *Operator) (string, bool) {
+			return op.AccessObject, op.AccessObject != ""
+		})
+	}
+	if !reflect.DeepEqual(accessObjects(a), accessObjects(b)) {
+		return ChangeIndexChoice
+	}
+
+	isJoin := func(name string) bool {
+		return strings.HasSuffix(name, "Join") || strings.HasSuffix(name, "Apply")
+	}
+	if !reflect.DeepEqual(names(a, isJoin), names(b, isJoin)) {
+		return ChangeJoinType
+	}
+	tablesA, tablesB := a.tables(), b.tables()
+	if !reflect.DeepEqual(tablesA, tablesB) {
+		sortedA := append([]string{}, tablesA...)
+		sortedB := append([]string{}, tablesB...)
+		sort.Strings(sortedA)
+		sort.Strings(sortedB)
+		if reflect.DeepEqual(sortedA, sortedB) {
+			return ChangeJoinOrder
+		}
+	}
+	return ChangeOther
+}
+
+// names returns the sorted operator names accepted by fn, nil fn accepts all operators
+func names(p *Plan, fn func(name string) bool) []string {
+	return p.collect(func(op *Operator) (string, bool) {
+		return op.Name, fn == nil || fn(op.Name)
+	})
+}
+
+func usedStores(p *Plan) []string {
+	used := make(map[string]struct{})
+	p.walk(func(op *Operator, _ int) {
+		used[store(op.Task)] = struct{}{}
+	})
+	var stores []string
+	for s := range used {
+		stores = append(stores, s)
+	}
+	sort.Strings(stores)
+	return stores
+}
@@ -0,0 +1,253 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/juju/errors"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/connection"
+)
+
+var (
+	operatorIDRegex   = regexp.MustCompile(`_\d+(\(\w+\))?$`)
+	accessObjectRegex = regexp.MustCompile(`(table|partition|index):[^ ,]+(\([^)]*\))?`)
+	execTimeRegex     = regexp.MustCompile(`time:\s*([0-9.]+[a-zµμ]+)`)
+	joinTypeRegex     = regexp.MustCompile(`^(?:CARTESIAN )?((?:anti )?(?:left outer semi|semi|inner|left outer|right outer) join)`)
+)
+
+// Operator is a node of the normalized plan tree, the IDs, estimations and timings are stripped
+type Operator struct {
+	// Name is the operator without ID, e.g. HashJoin, IndexLookUp, TableFullScan
+	Name string
+	// Task is where the operator runs, e.g. root, cop[tikv], mpp[tiflash]
+	Task string
+	// AccessObject is the table, partition and index the operator reads
+	AccessObject string
+	// JoinType is the join type in operator info of the join operators, e.g. inner join, anti semi join
+	JoinType string
+	Children []*Operator
+}
+
+// Plan is the normalized plan of a query
+type Plan struct {
+	Root *Operator
+	// ExecTime is the execution time of the root operator, only exists in EXPLAIN ANALYZE
+	ExecTime    time.Duration
+	HasExecTime bool
+}
+
+// ParseResult parses the result of EXPLAIN [ANALYZE] returned by connection
+func ParseResult(res [][]*connection.QueryItem) (*Plan, error) {
+	if len(res) == 0 {
+		return nil, errors.New("empty plan")
+	}
+	columns := make([]string, len(res[0]))
+	for i, item := range res[0] {
+		if item.ValType != nil {
+			columns[i] = item.ValType.Name()
+		}
+	}
+	rows := make([][]string, len(res))
+	for i, row := range res {
+		rows[i] = make([]string, len(row))
+		for j, item := range row {
+			rows[i][j] = item.ValString
+		}
+	}
+	return Parse(columns, rows)
+}
+
+// Parse parses the EXPLAIN [ANALYZE] result by the column names,
+// the tree structure comes from the indentation of the id column.
+func Parse(columns []string, rows [][]string) (*Plan, error) {
+	var (
+		idCol, taskCol, accessCol, infoCol, execCol = -1, -1, -1, -1, -1
+	)
+	for i, column := range columns {
+		switch strings.ToLower(column) {
+		case "id":
+			idCol = i
+		case "task":
+			taskCol = i
+		case "access object":
+			accessCol = i
+		case "operator info":
+			infoCol = i
+		case "execution info":
+			execCol = i
+		}
+	}
+	if idCol == -1 {
+		return nil, errors.Errorf("no id column in plan columns %v", columns)
+	}
+
+	var (
+		p      Plan
+		stack  []*Operator
+		depths []int
+	)
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return nil, errors.Errorf("plan row %d has %d columns, expect %d", i, len(row), len(columns))
+		}
+		id := row[idCol]
+		name := strings.TrimLeft(id, " │├└─")
+		depth := utf8.RuneCountInString(id[:len(id)-len(name)])
+		op := &Operator{Name: operatorIDRegex.ReplaceAllString(name, "$1")}
+		if taskCol != -1 {
+			op.Task = row[taskCol]
+		}
+		if accessCol != -1 {
+			op.AccessObject = row[accessCol]
+		} else if infoCol != -1 {
+			// the access object is in operator info before 4.0
+			op.AccessObject = strings.Join(accessObjectRegex.FindAllString(row[infoCol], -1), ", ")
+		}
+		if infoCol != -1 {
+			if m := joinTypeRegex.FindStringSubmatch(row[infoCol]); m != nil {
+				op.JoinType = m[1]
+			}
+		}
+
+		for len(depths) > 0 && depths[len(depths)-1] >= depth {
+			stack, depths = stack[:len(stack)-1], depths[:len(depths)-1]
+		}
+		if len(stack) == 0 {
+			if p.Root != nil {
+				return nil, errors.Errorf("more than one root in plan, row %d", i)
+			}
+			p.Root = op
+			if execCol != -1 {
+				if m := execTimeRegex.FindStringSubmatch(row[execCol]); m != nil {
+					d, err := time.ParseDuration(m[1])
+					if err != nil {
+						return nil, errors.Trace(err)
+					}
+					p.ExecTime, p.HasExecTime = d, true
+				}
+			}
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, op)
+		}
+		stack, depths = append(stack, op), append(depths, depth)
+	}
+	return &p, nil
+}
+
+// String returns the normalized plan tree, equal plans have the same string
+func (p *Plan) String() string {
+	var b strings.Builder
+	p.walk(func(op *Operator, depth int) {
+		b.WriteString(strings.Repeat("  ", depth))
+		b.WriteString(op.Name)
+		if op.Task != "" {
+			b.WriteString(" " + op.Task)
+		}
+		if op.AccessObject != "" {
+			b.WriteString(" " + op.AccessObject)
+		}
+		b.WriteString("\n")
+	})
+	return b.String()
+}
+
+// Signals returns the operators with their stores, the join types and the parent-child operator pairs of the plan,
+// they are the cheap coverage signals of the optimizer paths
+func (p *Plan) Signals() []string {
+	var signals []string
+	var visit func(op *Operator)
+	visit = func(op *Operator) {
+		signals = append(signals, "operator:"+op.Name+"@"+store(op.Task))
+		if op.JoinType != "" {
+			signals = append(signals, "join:"+op.Name+":"+op.JoinType)
+		}
+		for _, child := range op.Children {
+			signals = append(signals, "edge:"+op.Name+">"+child.Name)
+			visit(child)
+		}
+	}
+	if p.Root != nil {
+		visit(p.Root)
+	}
+	return signals
+}
+
+// walk visits the operators in pre-order
+func (p *Plan) walk(fn func(op *Operator, depth int)) {
+	var visit func(op *Operator, depth int)
+	visit = func(op *Operator, depth int) {
+		fn(op, depth)
+		for _, child := range op.Children {
+			visit(child, depth+1)
+		}
+	}
+	if p.Root != nil {
+		visit(p.Root, 0)
+	}
+}
+
+// collect returns the sorted keys of the operators accepted by fn
+func (p *Plan) collect(fn func(op *Operator) (string, bool)) []string {
+	var keys []string
+	p.walk(func(op *Operator, _ int) {
+		if key, ok := fn(op); ok {
+			keys = append(keys, key)
+		}
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+// tables returns the tables in the order they are read
+func (p *Plan) tables() []string {
+	var tables []string
+	p.walk(func(op *Operator, _ int) {
+		if table := accessTable(op.AccessObject); table != "" {
+			tables = append(tables, table)
+		}
+	})
+	return tables
+}
+
+func accessTable(accessObject string) string {
+	for _, part := range strings.Split(accessObject, ",") {
+		part = strings.TrimSpace(part)
+		if strings.HasPrefix(part, "table:") {
+			return strings.TrimPrefix(part, "table:")
+		}
+	}
+	return ""
+}
+
+func store(task string) string {
+	switch {
+	case strings.Contains(task, "tiflash"):
+		return "tiflash"
+	case strings.Contains(task, "tikv"), strings.HasPrefix(task, "cop"):
+		return "tikv"
+	}
+	return "root"
+}
+
+func isAccessOperator(name string) bool {
+	return strings.HasSuffix(name, "Reader") || strings.HasSuffix(name, "Scan") ||
+		strings.HasSuffix(name, "PointGet") || name == "IndexLookUp" || name == "IndexMerge"
+}
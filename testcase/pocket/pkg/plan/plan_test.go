@@ -0,0 +1,158 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var briefColumns = []string{"id", "estRows", "task", "access object", "operator info"}
+
+func mustParse(t *testing.T, columns []string, rows [][]string) *Plan {
+	p, err := Parse(columns, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestParse(t *testing.T) {
+	p := mustParse(t, []string{"id", "estRows", "actRows", "task", "access object", "execution info", "operator info", "memory", "disk"}, [][]string{
+		{"HashJoin_8", "12.49", "3", "root", "", "time:1.5ms, loops:2", "inner join, equal:[eq(t.a, s.a)]", "1 KB", "0 Bytes"},
+		{"├─TableReader_15(Build)", "9.99", "3", "root", "", "time:500µs, loops:2", "data:Selection_14", "N/A", "N/A"},
+		{"│ └─Selection_14", "9.99", "3", "cop[tikv]", "", "time:0s, loops:1", "not(isnull(s.a))", "N/A", "N/A"},
+		{"│   └─TableFullScan_13", "10000.00", "3", "cop[tikv]", "table:s", "time:0s, loops:1", "keep order:false", "N/A", "N/A"},
+		{"└─IndexLookUp_12(Probe)", "10.00", "3", "root", "", "time:1ms, loops:2", "", "N/A", "N/A"},
+		{"  ├─IndexRangeScan_10(Build)", "10.00", "3", "cop[tikv]", "table:t, index:a(a)", "time:0s, loops:1", "range:[1,1]", "N/A", "N/A"},
+		{"  └─TableRowIDScan_11(Probe)", "10.00", "3", "cop[tikv]", "table:t", "time:0s, loops:1", "keep order:false", "N/A", "N/A"},
+	})
+	expect := `HashJoin root
+  TableReader(Build) root
+    Selection cop[tikv]
+      TableFullScan cop[tikv] table:s
+  IndexLookUp(Probe) root
+    IndexRangeScan(Build) cop[tikv] table:t, index:a(a)
+    TableRowIDScan(Probe) cop[tikv] table:t
+`
+	if p.String() != expect {
+		t.Fatalf("unexpected plan\n%s", p)
+	}
+	if !p.HasExecTime || p.ExecTime != 1500*time.Microsecond {
+		t.Fatalf("unexpected execution time %s", p.ExecTime)
+	}
+	if p.Root.JoinType != "inner join" {
+		t.Fatalf("unexpected join type %s", p.Root.JoinType)
+	}
+	signals := strings.Join(p.Signals(), " ")
+	for _, signal := range []string{"operator:TableFullScan@tikv", "join:HashJoin:inner join", "edge:IndexLookUp(Probe)>IndexRangeScan(Build)"} {
+		if !strings.Contains(signals, signal) {
+			t.Fatalf("signal %s not found in %s", signal, signals)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	base := [][]string{
+		{"HashJoin_8", "12.49", "root", "", ""},
+		{"├─TableReader_15(Build)", "9.99", "root", "", ""},
+		{"│ └─TableFullScan_13", "10000.00", "cop[tikv]", "table:s", ""},
+		{"└─TableReader_12(Probe)", "10.00", "root", "", ""},
+		{"  └─TableFullScan_11", "10000.00", "cop[tikv]", "table:t", ""},
+	}
+	cases := []struct {
+		rows [][]string
+		kind ChangeKind
+	}{
+		{base, ChangeNone},
+		{[][]string{
+			{"HashJoin_9", "1.00", "root", "", ""},
+			{"├─TableReader_16(Build)", "1.00", "root", "", ""},
+			{"│ └─TableFullScan_14", "1.00", "cop[tikv]", "table:s", ""},
+			{"└─TableReader_17(Probe)", "1.00", "root", "", ""},
+			{"  └─TableFullScan_15", "1.00", "cop[tikv]", "table:t", ""},
+		}, ChangeNone},
+		{[][]string{
+			{"HashJoin_8", "12.49", "root", "", ""},
+			{"├─TableReader_15(Build)", "9.99", "root", "", ""},
+			{"│ └─TableFullScan_13", "10000.00", "cop[tiflash]", "table:s", ""},
+			{"└─TableReader_12(Probe)", "10.00", "root", "", ""},
+			{"  └─TableFullScan_11", "10000.00", "cop[tikv]", "table:t", ""},
+		}, ChangePushdown},
+		{[][]string{
+			{"HashJoin_8", "12.49", "root", "", ""},
+			{"├─TableReader_15(Build)", "9.99", "root", "", ""},
+			{"│ └─TableFullScan_13", "10000.00", "cop[tikv]", "table:s", ""},
+			{"└─IndexReader_12(Probe)", "10.00", "root", "", ""},
+			{"  └─IndexFullScan_11", "10000.00", "cop[tikv]", "table:t, index:a(a)", ""},
+		}, ChangeAccessPath},
+		{[][]string{
+			{"MergeJoin_8", "12.49", "root", "", ""},
+			{"├─TableReader_15(Build)", "9.99", "root", "", ""},
+			{"│ └─TableFullScan_13", "10000.00", "cop[tikv]", "table:s", ""},
+			{"└─TableReader_12(Probe)", "10.00", "root", "", ""},
+			{"  └─TableFullScan_11", "10000.00", "cop[tikv]", "table:t", ""},
+		}, ChangeJoinType},
+		{[][]string{
+			{"HashJoin_8", "12.49", "root", "", ""},
+			{"├─TableReader_15(Build)", "9.99", "root", "", ""},
+			{"│ └─TableFullScan_13", "10000.00", "cop[tikv]", "table:t", ""},
+			{"└─TableReader_12(Probe)", "10.00", "root", "", ""},
+			{"  └─TableFullScan_11", "10000.00", "cop[tikv]", "table:s", ""},
+		}, ChangeJoinOrder},
+	}
+	a := mustParse(t, briefColumns, base)
+	for i, c := range cases {
+		if kind := Classify(a, mustParse(t, briefColumns, c.rows)); kind != c.kind {
+			t.Errorf("case %d: expect %s, got %s", i, c.kind, kind)
+		}
+	}
+
+	index := func(name string) [][]string {
+		return [][]string{
+			{"IndexReader_6", "10.00", "root", "", ""},
+			{"└─IndexRangeScan_5", "10.00", "cop[tikv]", "table:t, index:" + name, ""},
+		}
+	}
+	if kind := Classify(mustParse(t, briefColumns, index("a(a)")), mustParse(t, briefColumns, index("b(b)"))); kind != ChangeIndexChoice {
+		t.Errorf("expect %s, got %s", ChangeIndexChoice, kind)
+	}
+}
+
+func TestReporter(t *testing.T) {
+	r := NewReporter(Option{SlowRatio: 2, SlowThreshold: 10 * time.Millisecond})
+	columns := []string{"id", "task", "access object", "execution info"}
+	plan := func(scan, index, execTime string) *Plan {
+		return mustParse(t, columns, [][]string{
+			{"IndexReader_6", "root", "", "time:" + execTime + ", loops:1"},
+			{"└─" + scan + "_5", "cop[tikv]", "table:t, index:" + index, "time:0s"},
+		})
+	}
+	if d := r.Record("SELECT a FROM t", plan("IndexRangeScan", "a(a)", "5ms"), plan("IndexRangeScan", "a(a)", "12ms")); d != nil {
+		t.Fatalf("unexpected diff %+v", d)
+	}
+	if d := r.Record("SELECT a FROM t WHERE a > 1", plan("IndexRangeScan", "a(a)", "5ms"), plan("IndexRangeScan", "a(a)", "50ms")); d == nil || !d.Slow || d.Kind != ChangeNone {
+		t.Fatalf("expect slow diff, got %+v", d)
+	}
+	if d := r.Record("SELECT b FROM t", plan("IndexRangeScan", "a(a)", "1ms"), plan("IndexFullScan", "a(a)", "1ms")); d == nil || d.Slow || d.Kind != ChangeAccessPath {
+		t.Fatalf("expect access path diff, got %+v", d)
+	}
+	report := r.Report()
+	for _, s := range []string{"3 queries compared", "## access-path (1)", "## slow (1)", "SELECT a FROM t WHERE a > 1;"} {
+		if !strings.Contains(report, s) {
+			t.Errorf("%q not in report\n%s", s, report)
+		}
+	}
+}
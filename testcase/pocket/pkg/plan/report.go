@@ -0,0 +1,119 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// Option of the reporter
+type Option struct {
+	// B is significantly slower if its execution time is more than SlowRatio times of A's,
+	// and the difference is at least SlowThreshold
+	SlowRatio     float64
+	SlowThreshold time.Duration
+}
+
+// Diff is a query whose plans are different or whose B side is significantly slower
+type Diff struct {
+	SQL   string
+	Kind  ChangeKind
+	Slow  bool
+	PlanA *Plan
+	PlanB *Plan
+}
+
+// Reporter collects the plan diffs of the executors and writes the report grouped by the change kinds
+type Reporter struct {
+	sync.Mutex
+	opt     Option
+	total   int
+	diffs   map[ChangeKind][]*Diff
+	slowest []*Diff
+}
+
+// NewReporter creates a Reporter
+func NewReporter(opt Option) *Reporter {
+	return &Reporter{
+		opt:   opt,
+		diffs: make(map[ChangeKind][]*Diff),
+	}
+}
+
+// Record compares the plans of A and B, returns the diff if it should be reported, otherwise nil
+func (r *Reporter) Record(sql string, a, b *Plan) *Diff {
+	d := Diff{
+		SQL:   sql,
+		Kind:  Classify(a, b),
+		Slow:  r.slower(a, b),
+		PlanA: a,
+		PlanB: b,
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.total++
+	if d.Kind == ChangeNone && !d.Slow {
+		return nil
+	}
+	if d.Kind != ChangeNone {
+		r.diffs[d.Kind] = append(r.diffs[d.Kind], &d)
+	}
+	if d.Slow {
+		r.slowest = append(r.slowest, &d)
+	}
+	return &d
+}
+
+func (r *Reporter) slower(a, b *Plan) bool {
+	if !a.HasExecTime || !b.HasExecTime || r.opt.SlowRatio <= 0 {
+		return false
+	}
+	return float64(b.ExecTime) > float64(a.ExecTime)*r.opt.SlowRatio &&
+		b.ExecTime-a.ExecTime >= r.opt.SlowThreshold
+}
+
+// Report returns the report grouped by the change kinds, the significantly slower queries are in their own group
+func (r *Reporter) Report() string {
+	r.Lock()
+	defer r.Unlock()
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Plan Diff Report\n\n%d queries compared\n", r.total)
+	for _, kind := range ChangeKinds {
+		writeGroup(&b, kind.String(), r.diffs[kind])
+	}
+	writeGroup(&b, "slow", r.slowest)
+	return b.String()
+}
+
+// Flush writes the report to the file
+func (r *Reporter) Flush(path string) error {
+	return errors.Trace(ioutil.WriteFile(path, []byte(r.Report()), 0644))
+}
+
+func writeGroup(b *strings.Builder, name string, diffs []*Diff) {
+	fmt.Fprintf(b, "\n## %s (%d)\n", name, len(diffs))
+	for _, d := range diffs {
+		fmt.Fprintf(b, "\n%s;\n", d.SQL)
+		if d.PlanA.HasExecTime && d.PlanB.HasExecTime {
+			fmt.Fprintf(b, "-- time A: %s, B: %s\n", d.PlanA.ExecTime, d.PlanB.ExecTime)
+		}
+		fmt.Fprintf(b, "-- plan A:\n%s-- plan B:\n%s", d.PlanA, d.PlanB)
+	}
+}
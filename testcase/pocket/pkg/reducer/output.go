@@ -0,0 +1,62 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reducer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// WriteSessions writes a session-N.sql script for each session in dir,
+// each statement is headed by its order in all sessions, which is the order to replay them.
+func WriteSessions(dir string, stmts []*Statement, failure *Failure) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Trace(err)
+	}
+	scripts := make(map[int]*strings.Builder)
+	for _, session := range sessions(stmts) {
+		sb := new(strings.Builder)
+		if failure != nil {
+			kind := "error"
+			if failure.Mismatch {
+				kind = "mismatch"
+			}
+			fmt.Fprintf(sb, "-- %s: %s\n", kind, oneLine(failure.Message))
+		}
+		scripts[session] = sb
+	}
+	for i, stmt := range stmts {
+		sql := strings.TrimRight(strings.TrimSpace(stmt.SQL), ";")
+		fmt.Fprintf(scripts[stmt.Session], "-- #%d\n%s;\n", i, sql)
+	}
+
+	var files []string
+	for _, session := range sessions(stmts) {
+		file := path.Join(dir, fmt.Sprintf("session-%d.sql", session))
+		if err := ioutil.WriteFile(file, []byte(scripts[session].String()), 0644); err != nil {
+			return nil, errors.Trace(err)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
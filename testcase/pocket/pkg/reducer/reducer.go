@@ -0,0 +1,301 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reducer
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/types"
+)
+
+var (
+	digitsPattern = regexp.MustCompile(`[0-9]+`)
+	// ErrNotReproduced is returned if the original statements do not fail
+	ErrNotReproduced = errors.New("the failure is not reproduced")
+)
+
+// Statement is a statement executed by a session
+type Statement struct {
+	Session int
+	Type    types.SQLType
+	SQL     string
+}
+
+// Failure is the first failure found by replaying the statements
+type Failure struct {
+	// Index is the index of the failed statement
+	Index int
+	// Mismatch means the results of the databases are not the same,
+	// otherwise the statement returns an unexpected error
+	Mismatch bool
+	Message  string
+}
+
+// Same reports whether the failures are caused by the same bug,
+// which is judged by the kind and the message without numbers and values.
+func (f *Failure) Same(other *Failure) bool {
+	if f == nil || other == nil {
+		return f == other
+	}
+	return f.Mismatch == other.Mismatch && f.signature() == other.signature()
+}
+
+func (f *Failure) signature() string {
+	msg := f.Message
+	if f.Mismatch {
+		// the values of a mismatch are after the colon
+		if i := strings.Index(msg, ":"); i >= 0 {
+			msg = msg[:i]
+		}
+	}
+	return digitsPattern.ReplaceAllString(msg, "N")
+}
+
+// Runner replays the statements in order on fresh databases
+type Runner interface {
+	// Run returns the first failure, or nil if the statements pass,
+	// the error means the statements are not replayed.
+	Run(ctx context.Context, stmts []*Statement) (*Failure, error)
+}
+
+// Reducer reduces the statements to a minimal case which still reproduces the same failure
+type Reducer struct {
+	runner Runner
+	// MaxRuns is the max replay times, 0 means no limitation
+	MaxRuns int
+	runs    int
+	target  *Failure
+}
+
+// New creates a Reducer
+func New(runner Runner) *Reducer {
+	return &Reducer{runner: runner}
+}
+
+// Reduce replays the statements and reduces them until no pass makes progress
+func (r *Reducer) Reduce(ctx context.Context, stmts []*Statement) ([]*Statement, *Failure, error) {
+	failure, err := r.runner.Run(ctx, stmts)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	if failure == nil {
+		return nil, nil, ErrNotReproduced
+	}
+	r.runs = 1
+	r.target = failure
+	stmts = stmts[:failure.Index+1]
+	log.Infof("reproduced %s by %d statements", failure.Message, len(stmts))
+
+	passes := []struct {
+		name string
+		fn   func(context.Context, []*Statement) ([]*Statement, bool, error)
+	}{
+		{"remove sessions", r.removeSessions},
+		{"remove statements", r.removeStatements},
+		{"drop tables", r.dropTables},
+		{"simplify statements", r.simplifyStatements},
+		{"drop columns", r.dropColumns},
+	}
+	for progress := true; progress; {
+		progress = false
+		for _, pass := range passes {
+			reduced, ok, err := pass.fn(ctx, stmts)
+			if err != nil {
+				return stmts, r.target, errors.Trace(err)
+			}
+			if ok {
+				log.Infof("%s: %d statements left", pass.name, len(reduced))
+				stmts = reduced
+				progress = true
+			}
+		}
+	}
+	return stmts, r.target, nil
+}
+
+// try reports whether the candidate still reproduces the target failure,
+// and truncates the statements after the failed one.
+func (r *Reducer) try(ctx context.Context, candidate []*Statement) ([]*Statement, bool, error) {
+	if len(candidate) == 0 {
+		return nil, false, nil
+	}
+	if r.MaxRuns > 0 && r.runs >= r.MaxRuns {
+		return nil, false, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, false, errors.Trace(err)
+	}
+	r.runs++
+	failure, err := r.runner.Run(ctx, candidate)
+	if err != nil {
+		return nil, false, errors.Trace(err)
+	}
+	if !r.target.Same(failure) {
+		return nil, false, nil
+	}
+	r.target = failure
+	return candidate[:failure.Index+1], true, nil
+}
+
+func (r *Reducer) removeSessions(ctx context.Context, stmts []*Statement) ([]*Statement, bool, error) {
+	progress := false
+	for _, session := range sessions(stmts) {
+		candidate := filter(stmts, func(stmt *Statement) bool {
+			return stmt.Session != session
+		})
+		reduced, ok, err := r.try(ctx, candidate)
+		if err != nil {
+			return nil, false, errors.Trace(err)
+		}
+		if ok {
+			stmts, progress = reduced, true
+		}
+	}
+	return stmts, progress, nil
+}
+
+// removeStatements removes chunks of statements, from the half of them to single ones
+func (r *Reducer) removeStatements(ctx context.Context, stmts []*Statement) ([]*Statement, bool, error) {
+	progress := false
+	for chunk := len(stmts) / 2; chunk > 0; chunk /= 2 {
+		// the failed statement is the last one, which is never removed
+		for start := 0; start < len(stmts)-1; {
+			end := start + chunk
+			if end > len(stmts)-1 {
+				end = len(stmts) - 1
+			}
+			candidate := append(append([]*Statement{}, stmts[:start]...), stmts[end:]...)
+			reduced, ok, err := r.try(ctx, candidate)
+			if err != nil {
+				return nil, false, errors.Trace(err)
+			}
+			if ok {
+				stmts, progress = reduced, true
+				continue
+			}
+			start = end
+		}
+	}
+	return stmts, progress, nil
+}
+
+// dropTables removes all the statements referencing a table
+func (r *Reducer) dropTables(ctx context.Context, stmts []*Statement) ([]*Statement, bool, error) {
+	progress := false
+	for _, table := range createdTables(stmts) {
+		candidate := filter(stmts, func(stmt *Statement) bool {
+			return !referTable(stmt.SQL, table)
+		})
+		if len(candidate) == len(stmts) {
+			continue
+		}
+		reduced, ok, err := r.try(ctx, candidate)
+		if err != nil {
+			return nil, false, errors.Trace(err)
+		}
+		if ok {
+			stmts, progress = reduced, true
+		}
+	}
+	return stmts, progress, nil
+}
+
+// simplifyStatements replaces each statement by its simplified variants
+func (r *Reducer) simplifyStatements(ctx context.Context, stmts []*Statement) ([]*Statement, bool, error) {
+	progress := false
+	for i := 0; i < len(stmts); i++ {
+		variants := simplify(stmts[i].SQL)
+		for k := 0; k < len(variants); k++ {
+			reduced, ok, err := r.try(ctx, replace(stmts, i, variants[k]))
+			if err != nil {
+				return nil, false, errors.Trace(err)
+			}
+			if !ok {
+				continue
+			}
+			stmts, progress = reduced, true
+			if i >= len(stmts) {
+				break
+			}
+			// go on with the variants of the simplified statement
+			variants, k = simplify(stmts[i].SQL), -1
+		}
+	}
+	return stmts, progress, nil
+}
+
+// dropColumns removes a column from the table and all the statements referencing it
+func (r *Reducer) dropColumns(ctx context.Context, stmts []*Statement) ([]*Statement, bool, error) {
+	progress := false
+	for _, table := range createdTables(stmts) {
+		for _, column := range tableColumns(stmts, table) {
+			candidate := make([]*Statement, 0, len(stmts))
+			changed := false
+			for _, stmt := range stmts {
+				sql, ok := dropColumn(stmt.SQL, table, column)
+				if ok {
+					changed = true
+					stmt = &Statement{Session: stmt.Session, Type: stmt.Type, SQL: sql}
+				}
+				candidate = append(candidate, stmt)
+			}
+			if !changed {
+				continue
+			}
+			reduced, ok, err := r.try(ctx, candidate)
+			if err != nil {
+				return nil, false, errors.Trace(err)
+			}
+			if ok {
+				stmts, progress = reduced, true
+			}
+		}
+	}
+	return stmts, progress, nil
+}
+
+func sessions(stmts []*Statement) []int {
+	var ids []int
+	set := make(map[int]struct{})
+	for _, stmt := range stmts {
+		if _, ok := set[stmt.Session]; !ok {
+			set[stmt.Session] = struct{}{}
+			ids = append(ids, stmt.Session)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func filter(stmts []*Statement, keep func(*Statement) bool) []*Statement {
+	var kept []*Statement
+	for _, stmt := range stmts {
+		if keep(stmt) {
+			kept = append(kept, stmt)
+		}
+	}
+	return kept
+}
+
+func replace(stmts []*Statement, i int, sql string) []*Statement {
+	replaced := append([]*Statement{}, stmts...)
+	replaced[i] = &Statement{Session: stmts[i].Session, Type: stmts[i].Type, SQL: sql}
+	return replaced
+}
@@ -0,0 +1,172 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reducer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/types"
+)
+
+// fakeRunner fails at the first statement which satisfies the bug,
+// if all the required statements are executed before it.
+type fakeRunner struct {
+	required []string
+	bug      func(sql string) bool
+}
+
+func (r *fakeRunner) Run(_ context.Context, stmts []*Statement) (*Failure, error) {
+	executed := make(map[string]bool)
+	for i, stmt := range stmts {
+		if r.bug(stmt.SQL) {
+			for _, required := range r.required {
+				if !executed[required] {
+					return nil, nil
+				}
+			}
+			return &Failure{Index: i, Mismatch: true, Message: "row number not match res1: 1, res2: 0"}, nil
+		}
+		for _, required := range r.required {
+			if strings.Contains(compact(stmt.SQL), required) {
+				executed[required] = true
+			}
+		}
+	}
+	return nil, nil
+}
+
+// compact removes the spaces and quotes which are different between the original and restored statements
+func compact(sql string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "`", "").Replace(sql))
+}
+
+func TestReduce(t *testing.T) {
+	stmts := []*Statement{
+		{Session: 1, Type: types.SQLTypeDDLCreateTable, SQL: "CREATE TABLE t (id INT, name VARCHAR(10), score DOUBLE, PRIMARY KEY (id), KEY idx_name (name))"},
+		{Session: 1, Type: types.SQLTypeDDLCreateTable, SQL: "CREATE TABLE s (id INT)"},
+		{Session: 2, Type: types.SQLTypeDMLInsert, SQL: "INSERT INTO s (id) VALUES (1)"},
+		{Session: 1, Type: types.SQLTypeDMLInsert, SQL: "INSERT INTO t (id, name, score) VALUES (1, 'a', 0.1), (2, 'b', 0.2), (3, 'c', 0.3)"},
+		{Session: 2, Type: types.SQLTypeDMLUpdate, SQL: "UPDATE s SET id = 2 WHERE id = 1"},
+		{Session: 1, Type: types.SQLTypeDMLSelect, SQL: "SELECT id, name FROM t WHERE id IN (1, 2, 3) AND name > 'a' ORDER BY id"},
+		{Session: 1, Type: types.SQLTypeDMLSelect, SQL: "SELECT id, name, score FROM t WHERE (id > 1 OR name IS NULL) AND score < 1 ORDER BY id"},
+		{Session: 2, Type: types.SQLTypeDMLSelect, SQL: "SELECT id FROM s"},
+	}
+	runner := &fakeRunner{
+		required: []string{"createtablet(", "(2,'b',0.2)"},
+		bug: func(sql string) bool {
+			sql = compact(sql)
+			return strings.HasPrefix(sql, "select") && strings.Contains(sql, "score<1")
+		},
+	}
+
+	reduced, failure, err := New(runner).Reduce(context.Background(), stmts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failure == nil || !failure.Mismatch {
+		t.Fatalf("unexpected failure %+v", failure)
+	}
+	if len(reduced) >= len(stmts) {
+		t.Fatalf("statements are not reduced, %d left", len(reduced))
+	}
+	for _, stmt := range reduced {
+		if stmt.Session != 1 {
+			t.Fatalf("session 2 should be removed, got %s", stmt.SQL)
+		}
+		if referTable(stmt.SQL, "s") {
+			t.Fatalf("table s should be dropped, got %s", stmt.SQL)
+		}
+	}
+	last := reduced[len(reduced)-1].SQL
+	if strings.Contains(last, "ORDER BY") || strings.Contains(last, "OR ") {
+		t.Fatalf("the failed statement should be simplified, got %s", last)
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	variants := simplify("SELECT a, b FROM t WHERE a IN (1, 2) AND b > 1 ORDER BY a")
+	expected := []string{
+		"SELECT `a`,`b` FROM `t` WHERE `a` IN (1,2) AND `b`>1",
+		"SELECT `a`,`b` FROM `t` ORDER BY `a`",
+		"SELECT `b` FROM `t` WHERE `a` IN (1,2) AND `b`>1 ORDER BY `a`",
+		"SELECT `a`,`b` FROM `t` WHERE `a` IN (1,2) ORDER BY `a`",
+		"SELECT `a`,`b` FROM `t` WHERE `a` IN (2) AND `b`>1 ORDER BY `a`",
+		"SELECT `a`,`b` FROM `t` WHERE 1 AND `b`>1 ORDER BY `a`",
+	}
+	for _, e := range expected {
+		found := false
+		for _, v := range variants {
+			if v == e {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("variant %s not found in %v", e, variants)
+		}
+	}
+	if simplify("not a statement") != nil {
+		t.Error("invalid statement should not be simplified")
+	}
+}
+
+func TestDropColumn(t *testing.T) {
+	cases := []struct {
+		sql      string
+		expected string
+	}{
+		{"CREATE TABLE t (id INT, name VARCHAR(10), KEY idx (name))", "CREATE TABLE `t` (`id` INT)"},
+		{"INSERT INTO t (id, name) VALUES (1, 'a'), (2, 'b')", "INSERT INTO `t` (`id`) VALUES (1),(2)"},
+		{"UPDATE t SET id = 1, name = 'a'", "UPDATE `t` SET `id`=1"},
+		{"SELECT id, name FROM t ORDER BY name", "SELECT `id` FROM `t`"},
+		{"SELECT name FROM s", ""},
+	}
+	for _, c := range cases {
+		sql, ok := dropColumn(c.sql, "t", "name")
+		if ok != (c.expected != "") || sql != c.expected {
+			t.Errorf("drop column of %s, got %s, expect %s", c.sql, sql, c.expected)
+		}
+	}
+}
+
+func TestWriteSessions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reducer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	stmts := []*Statement{
+		{Session: 2, SQL: "BEGIN"},
+		{Session: 1, SQL: "SELECT 1;"},
+		{Session: 2, SQL: "COMMIT"},
+	}
+	files, err := WriteSessions(dir, stmts, &Failure{Message: "Error 1105:\nunknown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expect 2 files, got %v", files)
+	}
+	content, err := ioutil.ReadFile(files[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "-- error: Error 1105: unknown\n-- #0\nBEGIN;\n-- #2\nCOMMIT;\n" {
+		t.Fatalf("unexpected script %q", content)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reducer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/juju/errors"
+
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/comparator"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/connection"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/types"
+	"github.com/pingcap/tipocket/testcase/pocket/pkg/util"
+)
+
+// FromLogs converts the executed SQLs of the executors to statements, each executor is a session
+func FromLogs(logs []*types.Log) []*Statement {
+	var stmts []*Statement
+	for _, l := range logs {
+		s := l.GetSQL()
+		if s.SQLStmt == "" {
+			continue
+		}
+		stmts = append(stmts, &Statement{Session: l.GetNode(), Type: s.SQLType, SQL: s.SQLStmt})
+	}
+	return stmts
+}
+
+// DBRunner replays the statements on recreated databases,
+// the results are compared if there are 2 DSNs.
+type DBRunner struct {
+	dsns []string
+	cmp  *comparator.Comparator
+	// errPattern matches the errors which are the failures
+	errPattern *regexp.Regexp
+}
+
+// NewDBRunner creates a DBRunner, the databases in the DSNs are dropped before each replay
+func NewDBRunner(dsns []string, cmp *comparator.Comparator, errPattern *regexp.Regexp) (*DBRunner, error) {
+	if len(dsns) != 1 && len(dsns) != 2 {
+		return nil, errors.Errorf("expect 1 or 2 DSNs, got %d", len(dsns))
+	}
+	if len(dsns) == 1 && errPattern == nil {
+		return nil, errors.New("error pattern is required by a single DSN")
+	}
+	for _, dsn := range dsns {
+		cfg, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if cfg.DBName == "" {
+			return nil, errors.Errorf("no database in DSN %s", dsn)
+		}
+	}
+	if cmp == nil {
+		cmp = comparator.New(comparator.Option{})
+	}
+	return &DBRunner{dsns: dsns, cmp: cmp, errPattern: errPattern}, nil
+}
+
+// Run implements Runner
+func (r *DBRunner) Run(ctx context.Context, stmts []*Statement) (*Failure, error) {
+	for _, dsn := range r.dsns {
+		if err := recreateDatabase(ctx, dsn); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	sessions := make(map[int][]*connection.Connection)
+	defer func() {
+		for _, conns := range sessions {
+			for _, conn := range conns {
+				_ = conn.CloseDB()
+			}
+		}
+	}()
+
+	for i, stmt := range stmts {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.Trace(err)
+		}
+		conns, ok := sessions[stmt.Session]
+		if !ok {
+			for side, dsn := range r.dsns {
+				conn, err := connection.New(dsn, &connection.Option{
+					Name: fmt.Sprintf("reducer-%d-%d", stmt.Session, side),
+					Mute: true,
+				})
+				if err != nil {
+					return nil, errors.Trace(err)
+				}
+				conns = append(conns, conn)
+			}
+			sessions[stmt.Session] = conns
+		}
+
+		var (
+			results  = make([][][]*connection.QueryItem, len(conns))
+			affected = make([]int64, len(conns))
+			errs     = make([]error, len(conns))
+		)
+		for side, conn := range conns {
+			results[side], affected[side], errs[side] = execute(conn, stmt)
+		}
+		if len(conns) == 2 {
+			if err := util.ErrorMustSame(errs[0], errs[1]); err != nil {
+				return &Failure{Index: i, Mismatch: true, Message: err.Error()}, nil
+			}
+			if errs[0] == nil {
+				var err error
+				switch stmt.Type {
+				case types.SQLTypeDMLSelect, types.SQLTypeDMLSelectForUpdate:
+					err = r.cmp.WithCollations(conns[0].FetchCollation).CompareQuery(stmt.SQL, results[0], results[1])
+				case types.SQLTypeDMLUpdate, types.SQLTypeDMLInsert, types.SQLTypeDMLDelete:
+					err = util.AffectedRowsMustSame(affected[0], affected[1])
+				}
+				if err != nil {
+					return &Failure{Index: i, Mismatch: true, Message: err.Error()}, nil
+				}
+			}
+		}
+		for _, err := range errs {
+			if err != nil && r.errPattern != nil && r.errPattern.MatchString(err.Error()) {
+				return &Failure{Index: i, Message: err.Error()}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func execute(conn *connection.Connection, stmt *Statement) ([][]*connection.QueryItem, int64, error) {
+	switch stmt.Type {
+	case types.SQLTypeDMLSelect, types.SQLTypeDMLSelectForUpdate:
+		res, err := conn.Select(stmt.SQL)
+		return res, 0, err
+	case types.SQLTypeDMLUpdate, types.SQLTypeDMLInsert, types.SQLTypeDMLDelete:
+		affected, err := conn.Update(stmt.SQL)
+		return nil, affected, err
+	case types.SQLTypeTxnBegin:
+		return nil, 0, conn.Begin()
+	case types.SQLTypeTxnCommit:
+		return nil, 0, conn.Commit()
+	case types.SQLTypeTxnRollback:
+		return nil, 0, conn.Rollback()
+	default:
+		return nil, 0, conn.ExecDDL(stmt.SQL)
+	}
+}
+
+// recreateDatabase drops and creates the database of the DSN
+func recreateDatabase(ctx context.Context, dsn string) error {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	dbname := cfg.DBName
+	cfg.DBName = ""
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", dbname)); err != nil {
+		return errors.Trace(err)
+	}
+	_, err = db.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE `%s`", dbname))
+	return errors.Trace(err)
+}
@@ -0,0 +1,440 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reducer
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pingcap/parser"
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/format"
+	"github.com/pingcap/parser/opcode"
+	// register the value expression driver for parsing
+	_ "github.com/pingcap/tidb/types/parser_driver"
+)
+
+func parse(sql string) (ast.StmtNode, bool) {
+	p := parser.New()
+	p.EnableWindowFunc(true)
+	node, err := p.ParseOneStmt(sql, "", "")
+	return node, err == nil
+}
+
+func restore(node ast.Node) (string, bool) {
+	var sb strings.Builder
+	if err := node.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &sb)); err != nil {
+		return "", false
+	}
+	return sb.String(), true
+}
+
+// simplify returns the simplified variants of the statement,
+// each of them removes or replaces a single part of it.
+func simplify(sql string) []string {
+	node, ok := parse(sql)
+	if !ok {
+		return nil
+	}
+	var variants []string
+	for k := range collectEdits(node) {
+		// the edits change the tree, so each variant is made from a new one
+		node, _ := parse(sql)
+		collectEdits(node)[k]()
+		if variant, ok := restore(node); ok && variant != sql {
+			variants = append(variants, variant)
+		}
+	}
+	return variants
+}
+
+// collectEdits collects the edits of the statement in a deterministic order
+func collectEdits(node ast.StmtNode) []func() {
+	c := &editCollector{root: node}
+	c.clauses(node)
+	node.Accept(c)
+	return c.edits
+}
+
+type editCollector struct {
+	root  ast.StmtNode
+	edits []func()
+}
+
+func (c *editCollector) add(edit func()) {
+	c.edits = append(c.edits, edit)
+}
+
+// clauses removes the optional clauses and the items of the lists in the statement
+func (c *editCollector) clauses(node ast.StmtNode) {
+	switch stmt := node.(type) {
+	case *ast.SelectStmt:
+		if stmt.TableHints != nil {
+			c.add(func() { stmt.TableHints = nil })
+		}
+		if stmt.OrderBy != nil {
+			c.add(func() { stmt.OrderBy = nil })
+		}
+		if stmt.Limit != nil {
+			c.add(func() { stmt.Limit = nil })
+		}
+		if stmt.Where != nil {
+			c.add(func() { stmt.Where = nil })
+		}
+		if stmt.Having != nil {
+			c.add(func() { stmt.Having = nil })
+		}
+		if stmt.GroupBy != nil && stmt.Having == nil {
+			c.add(func() { stmt.GroupBy = nil })
+		}
+		if stmt.Distinct {
+			c.add(func() { stmt.Distinct = false })
+		}
+		if stmt.Fields != nil && len(stmt.Fields.Fields) > 1 {
+			for i := range stmt.Fields.Fields {
+				i := i
+				c.add(func() { stmt.Fields.Fields = removeField(stmt.Fields.Fields, i) })
+			}
+		}
+	case *ast.InsertStmt:
+		if len(stmt.Lists) > 1 {
+			for i := range stmt.Lists {
+				i := i
+				c.add(func() { stmt.Lists = append(stmt.Lists[:i:i], stmt.Lists[i+1:]...) })
+			}
+		}
+		if len(stmt.OnDuplicate) > 0 {
+			c.add(func() { stmt.OnDuplicate = nil })
+		}
+	case *ast.UpdateStmt:
+		if stmt.TableHints != nil {
+			c.add(func() { stmt.TableHints = nil })
+		}
+		if stmt.Where != nil {
+			c.add(func() { stmt.Where = nil })
+		}
+		if stmt.Order != nil {
+			c.add(func() { stmt.Order = nil })
+		}
+		if stmt.Limit != nil {
+			c.add(func() { stmt.Limit = nil })
+		}
+		if len(stmt.List) > 1 {
+			for i := range stmt.List {
+				i := i
+				c.add(func() { stmt.List = append(stmt.List[:i:i], stmt.List[i+1:]...) })
+			}
+		}
+	case *ast.DeleteStmt:
+		if stmt.TableHints != nil {
+			c.add(func() { stmt.TableHints = nil })
+		}
+		if stmt.Where != nil {
+			c.add(func() { stmt.Where = nil })
+		}
+		if stmt.Order != nil {
+			c.add(func() { stmt.Order = nil })
+		}
+		if stmt.Limit != nil {
+			c.add(func() { stmt.Limit = nil })
+		}
+	case *ast.CreateTableStmt:
+		if stmt.Partition != nil {
+			c.add(func() { stmt.Partition = nil })
+		}
+		if len(stmt.Options) > 0 {
+			c.add(func() { stmt.Options = nil })
+		}
+		for i := range stmt.Constraints {
+			i := i
+			c.add(func() { stmt.Constraints = append(stmt.Constraints[:i:i], stmt.Constraints[i+1:]...) })
+		}
+		for _, col := range stmt.Cols {
+			for i := range col.Options {
+				col, i := col, i
+				c.add(func() { col.Options = append(col.Options[:i:i], col.Options[i+1:]...) })
+			}
+		}
+	}
+}
+
+// Enter collects the replacements of the expressions
+func (c *editCollector) Enter(n ast.Node) (ast.Node, bool) {
+	switch expr := n.(type) {
+	case *ast.BinaryOperationExpr:
+		switch expr.Op {
+		case opcode.LogicAnd, opcode.LogicOr, opcode.LogicXor:
+			c.replace(expr, expr.L)
+			c.replace(expr, expr.R)
+		case opcode.EQ, opcode.NE, opcode.LT, opcode.LE, opcode.GT, opcode.GE, opcode.NullEQ:
+			c.replace(expr, trueExpr())
+		}
+	case *ast.ParenthesesExpr:
+		c.replace(expr, expr.Expr)
+	case *ast.UnaryOperationExpr:
+		c.replace(expr, expr.V)
+	case *ast.PatternInExpr:
+		c.replace(expr, trueExpr())
+		if len(expr.List) > 1 {
+			for i := range expr.List {
+				i := i
+				c.add(func() { expr.List = append(expr.List[:i:i], expr.List[i+1:]...) })
+			}
+		}
+	case *ast.PatternLikeExpr, *ast.BetweenExpr, *ast.IsNullExpr, *ast.IsTruthExpr:
+		c.replace(expr.(ast.ExprNode), trueExpr())
+	case *ast.FuncCallExpr:
+		for _, arg := range expr.Args {
+			c.replace(expr, arg)
+		}
+	case *ast.CaseExpr:
+		for _, when := range expr.WhenClauses {
+			c.replace(expr, when.Result)
+		}
+		if expr.ElseClause != nil {
+			c.replace(expr, expr.ElseClause)
+		}
+	}
+	return n, false
+}
+
+// Leave implements ast.Visitor
+func (c *editCollector) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
+
+func (c *editCollector) replace(from, to ast.ExprNode) {
+	root := c.root
+	c.add(func() { root.Accept(&replacer{from: from, to: to}) })
+}
+
+// replacer replaces a node of the tree by another
+type replacer struct {
+	from ast.Node
+	to   ast.Node
+}
+
+func (r *replacer) Enter(n ast.Node) (ast.Node, bool) {
+	return n, n == r.from
+}
+
+func (r *replacer) Leave(n ast.Node) (ast.Node, bool) {
+	if n == r.from {
+		return r.to, true
+	}
+	return n, true
+}
+
+func trueExpr() ast.ExprNode {
+	return ast.NewValueExpr(1, "", "")
+}
+
+func removeField(fields []*ast.SelectField, i int) []*ast.SelectField {
+	return append(fields[:i:i], fields[i+1:]...)
+}
+
+// createdTables returns the tables created by the statements
+func createdTables(stmts []*Statement) []string {
+	var tables []string
+	set := make(map[string]struct{})
+	for _, stmt := range stmts {
+		node, ok := parse(stmt.SQL)
+		if !ok {
+			continue
+		}
+		if create, ok := node.(*ast.CreateTableStmt); ok {
+			name := create.Table.Name.L
+			if _, ok := set[name]; !ok {
+				set[name] = struct{}{}
+				tables = append(tables, name)
+			}
+		}
+	}
+	return tables
+}
+
+// tableColumns returns the columns of the table, which is empty if there is only one
+func tableColumns(stmts []*Statement, table string) []string {
+	for _, stmt := range stmts {
+		node, ok := parse(stmt.SQL)
+		if !ok {
+			continue
+		}
+		create, ok := node.(*ast.CreateTableStmt)
+		if !ok || create.Table.Name.L != table || len(create.Cols) < 2 {
+			continue
+		}
+		var columns []string
+		for _, col := range create.Cols {
+			columns = append(columns, col.Name.Name.L)
+		}
+		return columns
+	}
+	return nil
+}
+
+type tableFinder struct {
+	table string
+	found bool
+}
+
+func (f *tableFinder) Enter(n ast.Node) (ast.Node, bool) {
+	if name, ok := n.(*ast.TableName); ok && name.Name.L == f.table {
+		f.found = true
+	}
+	return n, f.found
+}
+
+func (f *tableFinder) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
+
+// referTable reports whether the statement references the table,
+// the statement which can not be parsed is matched by the table name.
+func referTable(sql, table string) bool {
+	node, ok := parse(sql)
+	if !ok {
+		return regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(table) + `\b`).MatchString(sql)
+	}
+	f := &tableFinder{table: table}
+	node.Accept(f)
+	return f.found
+}
+
+// dropColumn removes the column from the statement if it is about the table
+func dropColumn(sql, table, column string) (string, bool) {
+	node, ok := parse(sql)
+	if !ok {
+		return "", false
+	}
+	changed := false
+	switch stmt := node.(type) {
+	case *ast.CreateTableStmt:
+		if stmt.Table.Name.L != table || len(stmt.Cols) < 2 {
+			return "", false
+		}
+		for i, col := range stmt.Cols {
+			if col.Name.Name.L == column {
+				stmt.Cols = append(stmt.Cols[:i:i], stmt.Cols[i+1:]...)
+				changed = true
+				break
+			}
+		}
+		var constraints []*ast.Constraint
+		for _, constraint := range stmt.Constraints {
+			if !constraintOn(constraint, column) {
+				constraints = append(constraints, constraint)
+			}
+		}
+		stmt.Constraints = constraints
+	case *ast.InsertStmt:
+		if singleTable(stmt.Table) != table {
+			return "", false
+		}
+		for i, col := range stmt.Columns {
+			if col.Name.L != column {
+				continue
+			}
+			stmt.Columns = append(stmt.Columns[:i:i], stmt.Columns[i+1:]...)
+			for j, row := range stmt.Lists {
+				if i < len(row) {
+					stmt.Lists[j] = append(row[:i:i], row[i+1:]...)
+				}
+			}
+			changed = true
+			break
+		}
+		var setChanged, dupChanged bool
+		stmt.Setlist, setChanged = removeAssignments(stmt.Setlist, column)
+		stmt.OnDuplicate, dupChanged = removeAssignments(stmt.OnDuplicate, column)
+		changed = changed || setChanged || dupChanged
+	case *ast.UpdateStmt:
+		if singleTable(stmt.TableRefs) != table {
+			return "", false
+		}
+		stmt.List, changed = removeAssignments(stmt.List, column)
+	case *ast.SelectStmt:
+		if singleTable(stmt.From) != table || stmt.Fields == nil {
+			return "", false
+		}
+		var fields []*ast.SelectField
+		for _, field := range stmt.Fields.Fields {
+			if name, ok := field.Expr.(*ast.ColumnNameExpr); ok && name.Name.Name.L == column {
+				continue
+			}
+			fields = append(fields, field)
+		}
+		if len(fields) > 0 && len(fields) < len(stmt.Fields.Fields) {
+			stmt.Fields.Fields = fields
+			changed = true
+		}
+		if stmt.OrderBy != nil {
+			var items []*ast.ByItem
+			for _, item := range stmt.OrderBy.Items {
+				if name, ok := item.Expr.(*ast.ColumnNameExpr); ok && name.Name.Name.L == column {
+					changed = true
+					continue
+				}
+				items = append(items, item)
+			}
+			stmt.OrderBy.Items = items
+			if len(items) == 0 {
+				stmt.OrderBy = nil
+			}
+		}
+	}
+	if !changed {
+		return "", false
+	}
+	return restore(node)
+}
+
+func constraintOn(constraint *ast.Constraint, column string) bool {
+	for _, key := range constraint.Keys {
+		if key.Column != nil && key.Column.Name.L == column {
+			return true
+		}
+	}
+	return false
+}
+
+// removeAssignments removes the assignments of the column unless all of them are removed
+func removeAssignments(list []*ast.Assignment, column string) ([]*ast.Assignment, bool) {
+	var kept []*ast.Assignment
+	for _, assignment := range list {
+		if assignment.Column.Name.L != column {
+			kept = append(kept, assignment)
+		}
+	}
+	if len(kept) == 0 || len(kept) == len(list) {
+		return list, false
+	}
+	return kept, true
+}
+
+// singleTable returns the table name if the clause is a single table
+func singleTable(refs *ast.TableRefsClause) string {
+	if refs == nil || refs.TableRefs == nil || refs.TableRefs.Right != nil {
+		return ""
+	}
+	source, ok := refs.TableRefs.Left.(*ast.TableSource)
+	if !ok {
+		return ""
+	}
+	name, ok := source.Source.(*ast.TableName)
+	if !ok {
+		return ""
+	}
+	return name.Name.L
+}
@@ -13,6 +13,8 @@
 
 package types
 
+import "github.com/pingcap/tipocket/testcase/pocket/pkg/go-sqlsmith/feedback"
+
 // SQLType enums for SQL types
 type SQLType int
 
@@ -36,6 +38,7 @@ const (
 	SQLTypeSleep
 	SQLTypeCreateDatabase
 	SQLTypeDropDatabase
+	SQLTypeDDLTiFlashReplica
 )
 
 // SQL struct
@@ -46,6 +49,8 @@ type SQL struct {
 	// ExecTime is for avoid lock watched interference before time out
 	// useful for sleep statement
 	ExecTime int
+	// Trace is the generator choices of the statement, for the coverage feedback
+	Trace feedback.Trace
 }
 
 func (t SQLType) String() string {
@@ -84,6 +89,8 @@ func (t SQLType) String() string {
 		return "SQLTypeCreateDatabase"
 	case SQLTypeDropDatabase:
 		return "SQLTypeDropDatabase"
+	case SQLTypeDDLTiFlashReplica:
+		return "SQLTypeDDLTiFlashReplica"
 	default:
 		return "SQLTypeUnknown"
 	}
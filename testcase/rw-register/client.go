@@ -349,6 +349,10 @@ func mustAtoi(s string) int {
 func convertOperationsToHistory(events []core.Operation) ellecore.History {
 	var history ellecore.History
 	for _, event := range events {
+		// skip nemesis and health gate events
+		if event.Action != core.InvokeOperation && event.Action != core.ReturnOperation {
+			continue
+		}
 		var op ellecore.Op
 		switch e := event.Data.(type) {
 		case ellecore.Op:
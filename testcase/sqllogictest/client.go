@@ -39,6 +39,8 @@ type Config struct {
 	ReplicaRead         string
 	DbName              string
 	TiFlashDataReplicas int
+	// JUnitFile is the path of the JUnit XML report, no report if it's empty
+	JUnitFile string
 }
 
 type sqllogicClient struct {
@@ -107,19 +109,7 @@ func (c *sqllogicClient) TearDown(ctx context.Context, nodes []cluster.ClientNod
 // Start starts test
 func (c *sqllogicClient) Start(ctx context.Context, _ interface{}, clientNodes []cluster.ClientNode) error {
 	startTime := time.Now()
-	var fileNames []string
-	filepath.Walk(c.TestDir, func(testPath string, info os.FileInfo, err error) error {
-		if info == nil || info.IsDir() {
-			return nil
-		}
-
-		if !strings.HasSuffix(testPath, ".test") {
-			return nil
-		}
-
-		fileNames = append(fileNames, testPath)
-		return nil
-	})
+	fileNames := listTestFiles(c.TestDir)
 
 	if c.TaskCount > len(fileNames) {
 		c.TaskCount = len(fileNames)
@@ -141,8 +131,33 @@ func (c *sqllogicClient) Start(ctx context.Context, _ interface{}, clientNodes [
 
 	go doWait(ctx, doneChan, resultChan, c.TaskCount)
 
-	if errCnt, errMsg := doResult(ctx, resultChan, startTime); errCnt > 0 {
-		log.Fatalf("test failed, error count:%d, error message%s", errCnt, errMsg)
+	sum := doResult(ctx, resultChan, startTime)
+	sum.print()
+	if c.JUnitFile != "" {
+		if err := sum.writeJUnit(c.JUnitFile); err != nil {
+			log.Errorf("write junit report to %s err %v", c.JUnitFile, err)
+		}
+	}
+	if failed := sum.failedFiles(); len(failed) > 0 {
+		log.Fatalf("test failed, %d of %d files failed: %s", len(failed), len(sum.files), strings.Join(failed, ", "))
 	}
 	return nil
+}
+
+// listTestFiles returns the .test files in the dir
+func listTestFiles(dir string) []string {
+	var fileNames []string
+	filepath.Walk(dir, func(testPath string, info os.FileInfo, err error) error {
+		if info == nil || info.IsDir() {
+			return nil
+		}
+
+		if !strings.HasSuffix(testPath, ".test") {
+			return nil
+		}
+
+		fileNames = append(fileNames, testPath)
+		return nil
+	})
+	return fileNames
 }
\ No newline at end of file
@@ -19,6 +19,7 @@ import (
 
 	// use mysql
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/ngaut/log"
 
 	test_infra "github.com/pingcap/tipocket/pkg/test-infra"
 
@@ -37,11 +38,27 @@ var (
 	replicaRead         = flag.String("tidb-replica-read", "leader", "tidb_replica_read mode, support values: leader / follower / leader-and-follower, default value: leader.")
 	dbname              = flag.String("dbname", "test", "name of database to test")
 	tiflashDataReplicas = flag.Int("tiflash-data-replicas", 0, "the number of the tiflash data replica")
+	junitFile           = flag.String("junit", "", "the path of the JUnit XML report, no report if it's empty")
+	record              = flag.Bool("record", false, "rewrite the expected results of the test files in -d with the results of -record-dsn instead of testing")
+	recordDSN           = flag.String("record-dsn", "root:@tcp(127.0.0.1:3306)/", "the reference database of the record mode")
+	hashThreshold       = flag.Int("hash-threshold", 0, "record the results with more values than it as their md5 hash unless the file has a hash-threshold, 0 means never")
 )
 
 func main() {
 	flag.Parse()
 
+	if *record {
+		err := sqllogictest.Record(context.Background(), &sqllogictest.RecordConfig{
+			DSN:           *recordDSN,
+			TestDir:       *testDir,
+			HashThreshold: *hashThreshold,
+		})
+		if err != nil {
+			log.Fatalf("record failed: %v", err)
+		}
+		return
+	}
+
 	cfg := control.Config{
 		Mode:        control.ModeStandard,
 		ClientCount: 1,
@@ -60,6 +77,7 @@ func main() {
 				ReplicaRead:         *replicaRead,
 				DbName:              *dbname,
 				TiFlashDataReplicas: *tiflashDataReplicas,
+				JUnitFile:           *junitFile,
 			},
 		},
 		NemesisGens: util.ParseNemesisGenerators(fixture.Context.Nemesis),
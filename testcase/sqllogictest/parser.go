@@ -0,0 +1,250 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqllogictest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// engineName is the database name used by skipif and onlyif, TiDB runs the cases for mysql
+const engineName = "mysql"
+
+type recordType int
+
+const (
+	// commentRecord is a block of comments, it's only kept for rewriting the file
+	commentRecord recordType = iota
+	statementRecord
+	queryRecord
+	haltRecord
+	hashThresholdRecord
+)
+
+type condition struct {
+	// skip is true for skipif and false for onlyif
+	skip   bool
+	engine string
+}
+
+// record is a record of a test file, the records are separated by blank lines
+type record struct {
+	tp  recordType
+	pos string
+	// prefix are the comments and the conditions before the record
+	prefix     []string
+	conditions []condition
+	// lines are the record itself as in the file, sqlLines are the SQL lines in it
+	lines    []string
+	sqlLines []string
+
+	stmt      statement
+	query     query
+	threshold int
+}
+
+// skipped returns whether the record is skipped by its conditions
+func (r *record) skipped() bool {
+	for _, c := range r.conditions {
+		if c.skip == (c.engine == engineName) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseFile(path string) ([]*record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseRecords(path, file)
+}
+
+// parseRecords parses the records of the sqllogictest dialect
+func parseRecords(path string, reader io.Reader) ([]*record, error) {
+	var (
+		records []*record
+		r       = &record{}
+		s       = newLineScanner(reader)
+	)
+	// readBlock reads the lines until a blank line or stop returns true, the stopping line is not included
+	readBlock := func(stop func(line string) bool) (lines []string, stopped bool) {
+		for s.Scan() {
+			line := s.Text()
+			if strings.TrimSpace(line) == "" {
+				return lines, false
+			}
+			if stop != nil && stop(line) {
+				return lines, true
+			}
+			lines = append(lines, line)
+		}
+		return lines, false
+	}
+
+	for s.Scan() {
+		line := s.Text()
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			// the comments and conditions which aren't followed by a record
+			if len(r.prefix) > 0 {
+				records = append(records, &record{tp: commentRecord, prefix: r.prefix})
+				r = &record{}
+			}
+			continue
+		}
+		cmd := fields[0]
+		if strings.HasPrefix(cmd, "#") {
+			r.prefix = append(r.prefix, line)
+			continue
+		}
+		r.pos = fmt.Sprintf("%s:%d", path, s.line)
+		if cmd == "skipif" || cmd == "onlyif" {
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("%s: invalid %s: %s", r.pos, cmd, line)
+			}
+			r.prefix = append(r.prefix, line)
+			r.conditions = append(r.conditions, condition{skip: cmd == "skipif", engine: fields[1]})
+			continue
+		}
+		r.lines = []string{line}
+		switch cmd {
+		case "statement":
+			// format is: statement ok | statement error
+			if len(fields) != 2 || (fields[1] != "ok" && fields[1] != "error") {
+				return nil, fmt.Errorf("%s: invalid test statement: %s", r.pos, line)
+			}
+			r.tp = statementRecord
+			r.sqlLines, _ = readBlock(nil)
+			r.lines = append(r.lines, r.sqlLines...)
+			r.stmt = statement{
+				pos:       r.pos,
+				sql:       strings.TrimSpace(strings.Join(r.sqlLines, "\n")),
+				expectErr: fields[1] == "error",
+			}
+		case "query":
+			q, err := parseQueryHeader(r.pos, fields)
+			if err != nil {
+				return nil, err
+			}
+			var hasResults bool
+			r.tp = queryRecord
+			r.sqlLines, hasResults = readBlock(func(line string) bool { return line == "----" })
+			r.lines = append(r.lines, r.sqlLines...)
+			q.sql = strings.TrimSpace(strings.Join(r.sqlLines, "\n"))
+			if hasResults {
+				results, _ := readBlock(nil)
+				r.lines = append(append(r.lines, "----"), results...)
+				if err := q.parseResults(results); err != nil {
+					return nil, err
+				}
+			}
+			r.query = q
+		case "halt":
+			r.tp = haltRecord
+		case "hash-threshold":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("%s: invalid hash-threshold: %s", r.pos, line)
+			}
+			threshold, err := strconv.Atoi(fields[1])
+			if err != nil || threshold < 0 {
+				return nil, fmt.Errorf("%s: invalid hash-threshold: %s", r.pos, line)
+			}
+			r.tp = hashThresholdRecord
+			r.threshold = threshold
+		default:
+			return nil, fmt.Errorf("%s: unknown record: %s", r.pos, line)
+		}
+		records = append(records, r)
+		r = &record{}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(r.prefix) > 0 {
+		records = append(records, &record{tp: commentRecord, prefix: r.prefix})
+	}
+	return records, nil
+}
+
+// parseQueryHeader parses the header of a query, the format is query <type-string> <sort-mode> <label>
+func parseQueryHeader(pos string, fields []string) (query, error) {
+	q := query{pos: pos, sortMode: "nosort"}
+	if len(fields) < 2 {
+		return q, fmt.Errorf("%s: invalid test query: %s", pos, strings.Join(fields, " "))
+	}
+	q.colTypes = fields[1]
+	for _, v := range q.colTypes {
+		if v != intType && v != floatType && v != stringType {
+			return q, fmt.Errorf("%s: invalid type string in query: %s, must be 'I', 'R', or 'T'", pos, q.colTypes)
+		}
+	}
+	if len(fields) >= 3 {
+		switch fields[2] {
+		case "nosort", "rowsort", "valuesort":
+			q.sortMode = fields[2]
+		default:
+			return q, fmt.Errorf("%s: invalid sort mode in query: %s", pos, fields[2])
+		}
+	}
+	if len(fields) == 4 {
+		q.label = fields[3]
+	}
+	return q, nil
+}
+
+// parseResults parses the expected results, which have two formats
+// 1 a hash result like "15 values hashing to f7f59b0d893d8b24a77e45c84e33a4dc"
+// 2 a two-dimension result set for individual value
+func (q *query) parseResults(lines []string) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	if m := resultHashRE.FindStringSubmatch(lines[0]); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return fmt.Errorf("%s: invalid result value in query: %s", q.pos, lines[0])
+		}
+		q.expectedValues, q.expectedHash = n, m[2]
+		return nil
+	}
+	for _, line := range lines {
+		q.expectedResults = append(q.expectedResults, strings.Fields(line)...)
+	}
+	q.expectedValues = len(q.expectedResults)
+	return nil
+}
+
+// writeRecords writes the records in the sqllogictest dialect, the records are separated by a blank line
+func writeRecords(w io.Writer, records []*record) error {
+	bw := bufio.NewWriter(w)
+	for i, r := range records {
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+		for _, line := range r.prefix {
+			bw.WriteString(line + "\n")
+		}
+		for _, line := range r.lines {
+			bw.WriteString(line + "\n")
+		}
+	}
+	return bw.Flush()
+}
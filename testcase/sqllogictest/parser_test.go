@@ -0,0 +1,160 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqllogictest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+const testFile = `hash-threshold 8
+
+# create the table
+statement ok
+CREATE TABLE t1(a INTEGER, b VARCHAR(10))
+
+statement error
+INSERT INTO t2 VALUES(1)
+
+skipif mysql
+query I nosort
+SELECT a FROM t1 LIMIT 1
+----
+1
+
+onlyif mysql
+query IT rowsort label-1
+SELECT a, b
+FROM t1
+----
+1
+x
+2
+(empty)
+
+query I valuesort
+SELECT a FROM t1
+----
+15 values hashing to f7f59b0d893d8b24a77e45c84e33a4dc
+
+query I nosort
+SELECT a FROM t1 WHERE a < 0
+----
+
+# the end
+onlyif sqlite
+halt
+`
+
+func TestParseRecords(t *testing.T) {
+	records, err := parseRecords("t.test", strings.NewReader(testFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := []recordType{hashThresholdRecord, statementRecord, statementRecord, queryRecord, queryRecord, queryRecord, queryRecord, haltRecord}
+	if len(records) != len(types) {
+		t.Fatalf("expect %d records, got %d", len(types), len(records))
+	}
+	for i, r := range records {
+		if r.tp != types[i] {
+			t.Fatalf("record %d: expect type %d, got %d", i, types[i], r.tp)
+		}
+	}
+	if records[0].threshold != 8 {
+		t.Fatalf("expect hash-threshold 8, got %d", records[0].threshold)
+	}
+	if !records[2].stmt.expectErr || records[2].stmt.pos != "t.test:7" {
+		t.Fatalf("unexpected statement %+v", records[2].stmt)
+	}
+	if !records[3].skipped() || records[4].skipped() || !records[7].skipped() {
+		t.Fatal("the records aren't skipped by the conditions")
+	}
+	q := records[4].query
+	if q.sql != "SELECT a, b\nFROM t1" || q.sortMode != "rowsort" || q.label != "label-1" || q.expectedValues != 4 {
+		t.Fatalf("unexpected query %+v", q)
+	}
+	if q := records[5].query; q.expectedHash != "f7f59b0d893d8b24a77e45c84e33a4dc" || q.expectedValues != 15 {
+		t.Fatalf("unexpected query %+v", q)
+	}
+
+	// the records are written back as they are
+	var buf bytes.Buffer
+	if err := writeRecords(&buf, records); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != testFile {
+		t.Fatalf("unexpected rewritten file:\n%s", buf.String())
+	}
+
+	for _, invalid := range []string{"statement maybe\nSELECT 1\n", "query X\nSELECT 1\n", "hash-threshold x\n", "select 1\n"} {
+		if _, err := parseRecords("t.test", strings.NewReader(invalid)); err == nil {
+			t.Fatalf("expect error for %q", invalid)
+		}
+	}
+}
+
+func TestCheckResults(t *testing.T) {
+	tester := &tester{labelHashes: make(map[string]string)}
+	results := []string{"1", "x", "2", "(empty)"}
+	hashed := query{expectedValues: 4, expectedHash: hashResults(results), label: "l"}
+	if err := tester.checkResults(hashed, results); err != nil {
+		t.Fatal(err)
+	}
+	if err := tester.checkResults(query{expectedResults: results, label: "l"}, results); err != nil {
+		t.Fatal(err)
+	}
+	if err := tester.checkResults(query{expectedResults: results, label: "l"}, results[:2]); err == nil {
+		t.Fatal("expect error for the different results")
+	}
+	// the same label must have the same results
+	if err := tester.checkResults(query{expectedResults: results[:2], label: "l"}, results[:2]); err == nil {
+		t.Fatal("expect error for the different results of the same label")
+	}
+
+	if lines := formatResults(results, 3); len(lines) != 1 || lines[0] != "4 values hashing to "+hashResults(results) {
+		t.Fatalf("unexpected hashed results %v", lines)
+	}
+	if lines := formatResults(results, 4); len(lines) != 4 {
+		t.Fatalf("unexpected results %v", lines)
+	}
+}
+
+func TestJUnit(t *testing.T) {
+	sum := newSummary()
+	sum.file("a.test").passed = 3
+	b := sum.file("b.test")
+	b.passed, b.failures = 1, []string{"b.test:3: expected 1 results, but found 2"}
+	sum.file("c.test").fatal = "c.test:1: expected success"
+
+	if failed := sum.failedFiles(); strings.Join(failed, ",") != "b.test,c.test" {
+		t.Fatalf("unexpected failed files %v", failed)
+	}
+	data, err := xml.Marshal(sum.junit())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatal(err)
+	}
+	suite := suites.Suites[0]
+	if suite.Tests != 3 || suite.Failures != 1 || suite.Errors != 1 {
+		t.Fatalf("unexpected suite %+v", suite)
+	}
+	if suite.Cases[0].Failure != nil || suite.Cases[1].Failure == nil || suite.Cases[2].Error == nil {
+		t.Fatalf("unexpected cases %+v", suite.Cases)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqllogictest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+)
+
+const recordDBName = "sqllogic_record"
+
+// RecordConfig is for the record mode, which regenerates the expected results of the test files
+type RecordConfig struct {
+	// DSN is the reference database, like root:@tcp(127.0.0.1:3306)/
+	DSN     string
+	TestDir string
+	// HashThreshold is the default hash-threshold of the files, the results which have more values
+	// are recorded as their md5 hash, 0 means never
+	HashThreshold int
+}
+
+// Record runs the test files against the reference database and rewrites them with its results
+func Record(ctx context.Context, cfg *RecordConfig) error {
+	dsn, err := mysql.ParseDSN(cfg.DSN)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	admin, err := sql.Open("mysql", dsn.FormatDSN())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer admin.Close()
+
+	dsn.DBName = recordDBName
+	if dsn.Params == nil {
+		dsn.Params = make(map[string]string)
+	}
+	dsn.Params["sql_mode"] = "'STRICT_TRANS_TABLES,NO_ENGINE_SUBSTITUTION'"
+
+	fileNames := listTestFiles(cfg.TestDir)
+	var failed int
+	for _, path := range fileNames {
+		for _, stmt := range []string{"DROP DATABASE IF EXISTS " + recordDBName, "CREATE DATABASE " + recordDBName} {
+			if _, err := admin.ExecContext(ctx, stmt); err != nil {
+				return errors.Errorf("executing %s err %v", stmt, err)
+			}
+		}
+		db, err := sql.Open("mysql", dsn.FormatDSN())
+		if err != nil {
+			return errors.Trace(err)
+		}
+		// all the records run in one session like the test
+		db.SetMaxOpenConns(1)
+		n, err := recordFile(ctx, db, path, cfg.HashThreshold)
+		db.Close()
+		if err != nil {
+			return errors.Annotatef(err, "record %s", path)
+		}
+		if n > 0 {
+			failed++
+		}
+		log.Infof("[sqllogic] recorded %s, %d queries failed on the reference database", path, n)
+	}
+	log.Infof("[sqllogic] recorded %d files, %d of them have failed queries", len(fileNames), failed)
+	return nil
+}
+
+// recordFile rewrites the expected results of the file, the failed queries are kept as they are
+func recordFile(ctx context.Context, db *sql.DB, path string, hashThreshold int) (failed int, err error) {
+	records, err := parseFile(path)
+	if err != nil {
+		return 0, err
+	}
+	t := &tester{
+		labelHashes: make(map[string]string),
+		db:          db,
+	}
+	for _, r := range records {
+		if r.tp == commentRecord || r.skipped() {
+			continue
+		}
+		if r.tp == haltRecord {
+			// the rest of the records are kept as they are
+			break
+		}
+		switch r.tp {
+		case hashThresholdRecord:
+			hashThreshold = r.threshold
+		case statementRecord:
+			_, err := db.ExecContext(ctx, r.stmt.sql)
+			if expectErr := err != nil; expectErr != r.stmt.expectErr {
+				log.Infof("[sqllogic] %s: the reference database returns error %v", r.pos, err)
+				r.stmt.expectErr = expectErr
+			}
+			r.lines = append([]string{statementHeader(r.stmt.expectErr)}, r.sqlLines...)
+		case queryRecord:
+			results, err := t.queryResults(ctx, r.query, 1)
+			if err != nil {
+				log.Warnf("[sqllogic] %v", err)
+				failed++
+				continue
+			}
+			r.lines = append(append([]string{r.lines[0]}, r.sqlLines...), "----")
+			r.lines = append(r.lines, formatResults(results, hashThreshold)...)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return failed, err
+	}
+	var buf bytes.Buffer
+	if err := writeRecords(&buf, records); err != nil {
+		return failed, err
+	}
+	return failed, os.WriteFile(path, buf.Bytes(), info.Mode())
+}
+
+func statementHeader(expectErr bool) string {
+	if expectErr {
+		return "statement error"
+	}
+	return "statement ok"
+}
+
+// formatResults formats the values one per line, or the hash of them if there are more values than the threshold
+func formatResults(results []string, hashThreshold int) []string {
+	if hashThreshold > 0 && len(results) > hashThreshold {
+		return []string{fmt.Sprintf("%d values hashing to %s", len(results), hashResults(results))}
+	}
+	return results
+}
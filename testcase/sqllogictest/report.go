@@ -0,0 +1,153 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqllogictest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ngaut/log"
+)
+
+// fileSummary is the result of a test file
+type fileSummary struct {
+	name     string
+	passed   int
+	failures []string
+	// fatal is the error which aborted the file
+	fatal   string
+	elapsed time.Duration
+}
+
+func (f *fileSummary) failed() bool {
+	return len(f.failures) > 0 || f.fatal != ""
+}
+
+type summary struct {
+	files   map[string]*fileSummary
+	elapsed time.Duration
+}
+
+func newSummary() *summary {
+	return &summary{files: make(map[string]*fileSummary)}
+}
+
+func (s *summary) file(name string) *fileSummary {
+	f, ok := s.files[name]
+	if !ok {
+		f = &fileSummary{name: name}
+		s.files[name] = f
+	}
+	return f
+}
+
+func (s *summary) sortedFiles() []*fileSummary {
+	files := make([]*fileSummary, 0, len(s.files))
+	for _, f := range s.files {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	return files
+}
+
+func (s *summary) failedFiles() []string {
+	var names []string
+	for _, f := range s.sortedFiles() {
+		if f.failed() {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+// print logs the pass/fail summary of each file
+func (s *summary) print() {
+	for _, f := range s.sortedFiles() {
+		status := "PASS"
+		if f.failed() {
+			status = "FAIL"
+		}
+		msg := fmt.Sprintf("[sqllogic] %s %s: %d passed, %d failed, cost %s", status, f.name, f.passed, len(f.failures), f.elapsed)
+		if f.fatal != "" {
+			msg += ", aborted by " + f.fatal
+		}
+		log.Info(msg)
+	}
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Content string `xml:",chardata"`
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// junit converts the summary to JUnit XML, each file is a test case
+func (s *summary) junit() junitTestSuites {
+	suite := junitTestSuite{Name: "sqllogictest", Time: junitTime(s.elapsed)}
+	for _, f := range s.sortedFiles() {
+		c := junitTestCase{Name: f.name, Classname: "sqllogictest", Time: junitTime(f.elapsed)}
+		if len(f.failures) > 0 {
+			suite.Failures++
+			c.Failure = &junitMessage{
+				Message: fmt.Sprintf("%d of %d records failed", len(f.failures), len(f.failures)+f.passed),
+				Content: strings.Join(f.failures, "\n"),
+			}
+		}
+		if f.fatal != "" {
+			suite.Errors++
+			c.Error = &junitMessage{Message: "test file aborted", Content: f.fatal}
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	suite.Tests = len(suite.Cases)
+	return junitTestSuites{Suites: []junitTestSuite{suite}}
+}
+
+// writeJUnit writes the summary as a JUnit XML report
+func (s *summary) writeJUnit(path string) error {
+	data, err := xml.MarshalIndent(s.junit(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), data...), 0644)
+}
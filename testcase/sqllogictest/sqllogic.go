@@ -16,14 +16,12 @@ package sqllogictest
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"crypto/md5"
 	"database/sql"
 	"fmt"
 	"io"
 	"math/rand"
-	"os"
 	"regexp"
 	"runtime/debug"
 	"sort"
@@ -58,11 +56,16 @@ const (
 	infoType msgType = iota
 	errorType
 	fatalType
+	// finishType means the file is finished
+	finishType
 )
 
 type result struct {
+	file string
 	data string
 	tp   msgType
+	// elapsed is the duration of the file, it's only set by finishType
+	elapsed time.Duration
 }
 
 type tester struct {
@@ -198,127 +201,44 @@ func (t *tester) prepare(ctx context.Context, runid int, task string) {
 }
 
 func (t *tester) run(ctx context.Context, path string, resultChan chan *result, runid int, tiFlashDataReplicas int, skipError bool) {
-	var err error
+	startTime := time.Now()
+	defer func() {
+		resultChan <- &result{file: path, tp: finishType, elapsed: time.Since(startTime)}
+	}()
 
-	file, err := os.Open(path)
+	records, err := parseFile(path)
 	if err != nil {
-		sendFatalResult(resultChan, err.Error())
+		sendFatalResult(resultChan, path, err.Error())
 		return
 	}
 
 	t.prepare(ctx, runid, path)
 
-	s := newLineScanner(file)
-
-LOOP:
-	for s.Scan() {
+	for _, r := range records {
 		select {
 		case <-ctx.Done():
 			return
 		default:
 		}
-		fields := strings.Fields(s.Text())
-		if len(fields) == 0 {
+		if r.tp == commentRecord || r.skipped() {
 			continue
 		}
-		cmd := fields[0]
-		if strings.HasPrefix(cmd, "#") {
-			// Skip comment lines.
-			continue
-		}
-		switch cmd {
-		case "statement":
-			stmt := statement{pos: fmt.Sprintf("%s:%d", path, s.line)}
-
-			// format is: statement ok | statement error
-			if len(fields) != 2 {
-				data := fmt.Sprintf("%s: invalid test statement: %s", stmt.pos, s.Text())
-				sendFatalResult(resultChan, data)
-				return
-			}
+		switch r.tp {
+		case haltRecord:
+			// ignore the rest of the cases.
+			return
 
-			stmt.expectErr = fields[1] == "error"
+		case hashThresholdRecord:
+			// the threshold only matters when recording the results
+			continue
 
-			var buf bytes.Buffer
-			for s.Scan() {
-				line := s.Text()
-				if line == "" {
-					break
-				}
-				fmt.Fprintln(&buf, line)
-			}
-			stmt.sql = strings.TrimSpace(buf.String())
-			if err := t.execStatement(ctx, stmt, fmt.Sprintf("sqllogic_test_%d", runid), tiFlashDataReplicas); err != nil {
-				sendFatalResult(resultChan, err.Error())
+		case statementRecord:
+			if err := t.execStatement(ctx, r.stmt, fmt.Sprintf("sqllogic_test_%d", runid), tiFlashDataReplicas); err != nil {
+				sendFatalResult(resultChan, path, err.Error())
 				return
 			}
 
-		case "query":
-			q := query{pos: fmt.Sprintf("%s:%d", path, s.line), sortMode: "nosort"}
-
-			// format is query <type-string> <sort-mode> <label>
-			if len(fields) < 2 {
-				log.Fatalf("[sqllogic] %s: invalid test statement: %s", q.pos, s.Text())
-			} else {
-				q.colTypes = fields[1]
-				for _, v := range q.colTypes {
-					if v != intType && v != floatType && v != stringType {
-						data := fmt.Sprintf("%s: invalid type string in query: %s, must be 'I', 'R', or 'T'", q.pos, s.Text())
-						sendFatalResult(resultChan, data)
-						return
-					}
-				}
-
-				if len(fields) >= 3 {
-					switch fields[2] {
-					case "nosort", "rowsort", "valuesort":
-						q.sortMode = fields[2]
-					default:
-						data := fmt.Sprintf("%s: invalid sort mode in query: %s", q.pos, s.Text())
-						sendFatalResult(resultChan, data)
-						return
-					}
-				}
-				if len(fields) == 4 {
-					q.label = fields[3]
-				}
-			}
-			var buf bytes.Buffer
-			for s.Scan() {
-				line := s.Text()
-				if line == "----" {
-					break
-				}
-				fmt.Fprintln(&buf, line)
-			}
-			q.sql = strings.TrimSpace(buf.String())
-
-			// query has two result format
-			// 1 a hash result like "15 values hashing to f7f59b0d893d8b24a77e45c84e33a4dc"
-			// 2 a two-dimension result set for individual value
-			if s.Scan() {
-				if m := resultHashRE.FindStringSubmatch(s.Text()); m != nil {
-					q.expectedValues, err = strconv.Atoi(m[1])
-					if err != nil {
-						data := fmt.Sprintf("%s: invalid result value in query: %s", q.pos, s.Text())
-						sendFatalResult(resultChan, data)
-						return
-					}
-					q.expectedHash = m[2]
-				} else {
-					for {
-						results := strings.Fields(s.Text())
-						if len(results) == 0 {
-							break
-						}
-						q.expectedResults = append(q.expectedResults, results...)
-						if !s.Scan() {
-							break
-						}
-					}
-					q.expectedValues = len(q.expectedResults)
-				}
-			}
+		case queryRecord:
 			if tiFlashDataReplicas > 0 {
 				if _, err := t.db.Exec("set @@session.tidb_isolation_read_engines='tiflash'"); err != nil {
 					log.Warnf("[sqllogic] meet error %s", err)
@@ -326,13 +246,12 @@ LOOP:
 				}
 			}
 
-			if err := t.execQuery(ctx, q); err != nil {
-				if skipError {
-					sendErrorResult(resultChan, err.Error())
-				} else {
-					sendFatalResult(resultChan, err.Error())
+			if err := t.execQuery(ctx, r.query); err != nil {
+				if !skipError {
+					sendFatalResult(resultChan, path, err.Error())
 					return
 				}
+				sendErrorResult(resultChan, path, err.Error())
 			}
 
 			// restore `tidb_isolation_read_engines`
@@ -342,58 +261,22 @@ LOOP:
 					return
 				}
 			}
-		case "halt":
-			// for debug only, ignore the rest of the cases.
-			break LOOP
-
-		case "hash-threshold":
-			// we just run the origin test, no need to re-generate.
-			// so no need to handle this
-
-		case "onlyif", "skipif":
-			// we only care mysql now
-			if len(fields) < 2 {
-				data := fmt.Sprintf("invalid %s: %s", cmd, s.Text())
-				sendFatalResult(resultChan, data)
-				return
-			}
-
-			needSkip := false
-			name := fields[1]
-			if (cmd == "onlyif" && name != "mysql") ||
-				(cmd == "skipif" && name == "mysql") {
-				needSkip = true
-			}
-			if needSkip {
-				// skip this case
-				for s.Scan() {
-					line := s.Text()
-					if line == "" {
-						break
-					}
-				}
-			}
 		}
-		sendInfoResult(resultChan, "")
-	}
-
-	if err := s.Err(); err != nil {
-		sendFatalResult(resultChan, err.Error())
-		return
+		sendInfoResult(resultChan, path, "")
 	}
 }
 
-func sendInfoResult(resultChan chan *result, data string) {
-	msg := &result{data, infoType}
+func sendInfoResult(resultChan chan *result, file, data string) {
+	msg := &result{file: file, data: data, tp: infoType}
 	resultChan <- msg
 }
 
-func sendErrorResult(resultChan chan *result, data string) {
-	msg := &result{data, errorType}
+func sendErrorResult(resultChan chan *result, file, data string) {
+	msg := &result{file: file, data: data, tp: errorType}
 	resultChan <- msg
 }
-func sendFatalResult(resultChan chan *result, data string) {
-	msg := &result{data, fatalType}
+func sendFatalResult(resultChan chan *result, file, data string) {
+	msg := &result{file: file, data: data, tp: fatalType}
 	resultChan <- msg
 }
 
@@ -484,24 +367,36 @@ func (t *tester) execQuery(ctx context.Context, q query) error {
 		}
 	}()
 
+	results, err := t.queryResults(ctx, q, dbTryNumber)
+	if err != nil {
+		return err
+	}
+	return t.checkResults(q, results)
+}
+
+// queryResults runs the query and returns the values sorted by the sort mode
+func (t *tester) queryResults(ctx context.Context, q query, tryNumber int) ([]string, error) {
 	var rows *sql.Rows
 	var err error
-	for i := 0; i < dbTryNumber; i++ {
+	for i := 0; i < tryNumber; i++ {
 		rows, err = t.db.QueryContext(ctx, q.sql)
-		if err == nil {
+		if err == nil || i == tryNumber-1 {
 			break
 		}
 		time.Sleep(3 * time.Second)
 	}
 	if err != nil {
-		return fmt.Errorf("%s: query err %v - sql[ %s ]", q.pos, err, q.sql)
+		return nil, fmt.Errorf("%s: query err %v - sql[ %s ]", q.pos, err, q.sql)
 	}
 
 	defer rows.Close()
 
 	cols, err := rows.Columns()
 	if err != nil {
-		return fmt.Errorf("%s: get result columns err %v - sql[ %s ]", q.pos, err, q.sql)
+		return nil, fmt.Errorf("%s: get result columns err %v - sql[ %s ]", q.pos, err, q.sql)
+	}
+	if len(cols) != len(q.colTypes) {
+		return nil, fmt.Errorf("%s: expected %d columns, but found %d - sql[ %s ]", q.pos, len(q.colTypes), len(cols), q.sql)
 	}
 	vals := make([]interface{}, len(cols))
 	for i := range vals {
@@ -512,7 +407,7 @@ func (t *tester) execQuery(ctx context.Context, q query) error {
 
 	for rows.Next() {
 		if err := rows.Scan(vals...); err != nil {
-			return fmt.Errorf("%s: scan rows err %v - sql[ %s ]", q.pos, err, q.sql)
+			return nil, fmt.Errorf("%s: scan rows err %v - sql[ %s ]", q.pos, err, q.sql)
 		}
 		for _, v := range vals {
 			vv := string(v.(*value).Value)
@@ -520,7 +415,7 @@ func (t *tester) execQuery(ctx context.Context, q query) error {
 		}
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("%s: scan rows err %v  - sql[ %s ]", q.pos, err, q.sql)
+		return nil, fmt.Errorf("%s: scan rows err %v  - sql[ %s ]", q.pos, err, q.sql)
 	}
 
 	switch q.sortMode {
@@ -539,24 +434,18 @@ func (t *tester) execQuery(ctx context.Context, q query) error {
 	case "valuesort":
 		sort.Strings(results)
 	}
+	return results, nil
+}
 
-	h := md5.New()
-
-	for _, vv := range results {
-		_, _ = io.WriteString(h, vv)
-		_, _ = io.WriteString(h, "\n")
-	}
-
-	hash := fmt.Sprintf("%x", h.Sum(nil))
+// checkResults checks the results with the expected ones and the results of the same label
+func (t *tester) checkResults(q query, results []string) error {
+	hash := hashResults(results)
 
 	if q.expectedHash != "" {
 		n := len(results)
 		if q.expectedValues != n {
 			return fmt.Errorf("%s: expected %d results, but found %d - sql[ %s ]", q.pos, q.expectedValues, n, q.sql)
 		}
-		// Hash the values using MD5. This hashing precisely matches the hashing in
-		// sqllogictest.c.
-
 		if q.expectedHash != hash {
 			return fmt.Errorf("%s: expected %s, but found %s - sql[ %s ]", q.pos, q.expectedHash, hash, q.sql)
 		}
@@ -568,7 +457,7 @@ func (t *tester) execQuery(ctx context.Context, q query) error {
 		}
 	}
 
-	// TODO, if we have a label, we will check hash with other tests for same label
+	// if we have a label, we will check hash with other tests for same label
 	if q.label != "" {
 		if lastHash, ok := t.labelHashes[q.label]; ok {
 			if hash != lastHash {
@@ -581,6 +470,17 @@ func (t *tester) execQuery(ctx context.Context, q query) error {
 	return nil
 }
 
+// hashResults hashes the values using MD5. This hashing precisely matches the hashing in
+// sqllogictest.c.
+func hashResults(results []string) string {
+	h := md5.New()
+	for _, vv := range results {
+		_, _ = io.WriteString(h, vv)
+		_, _ = io.WriteString(h, "\n")
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
 type rowSlice [][]string
 
 func (r rowSlice) Len() int {
@@ -632,33 +532,40 @@ func doWait(ctx context.Context, doneChan chan struct{}, resultChan chan *result
 
 }
 
-// Return error count
-func doResult(ctx context.Context, resultChan chan *result, startTime time.Time) (int64, string) {
+// doResult collects the results of the files
+func doResult(ctx context.Context, resultChan chan *result, startTime time.Time) *summary {
 	var totalCount, errCount int64
-	var errMsg string
+	sum := newSummary()
 	ticker := time.NewTicker(3 * time.Second)
 	for {
 		select {
 		case <-ctx.Done():
-			return errCount, errMsg
+			return sum
 		case <-ticker.C:
 			printResultInfo("run", totalCount, errCount, startTime)
 		case msg, ok := <-resultChan:
 			if !ok {
 				log.Infof("[sqllogic] sqllogictest finished!")
 				printResultInfo("final", totalCount, errCount, startTime)
-				return errCount, errMsg
+				sum.elapsed = time.Since(startTime)
+				return sum
 			}
 
+			file := sum.file(msg.file)
 			switch msg.tp {
 			case infoType:
 				totalCount++
+				file.passed++
 			case errorType:
 				errCount++
 				log.Errorf("[sqllogic] %v", msg.data)
-				errMsg += msg.data
+				file.failures = append(file.failures, msg.data)
 			case fatalType:
-				log.Fatalf("[sqllogic] %v", msg.data)
+				errCount++
+				log.Errorf("[sqllogic] %s aborted: %v", msg.file, msg.data)
+				file.fatal = msg.data
+			case finishType:
+				file.elapsed = msg.elapsed
 			}
 		}
 	}
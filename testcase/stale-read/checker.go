@@ -0,0 +1,193 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package staleread
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ngaut/log"
+
+	"github.com/pingcap/tipocket/pkg/core"
+	"github.com/pingcap/tipocket/pkg/history"
+)
+
+// maxReportedViolations limits the violations in the check error
+const maxReportedViolations = 10
+
+type parser struct{}
+
+// Parser parses a history of stale read test
+func Parser() history.RecordParser {
+	return parser{}
+}
+
+// OnRequest implements history.RecordParser
+func (parser) OnRequest(data json.RawMessage) (interface{}, error) {
+	r := request{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+// OnResponse implements history.RecordParser
+func (parser) OnResponse(data json.RawMessage) (interface{}, error) {
+	r := response{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+// OnNoopResponse implements history.RecordParser
+func (parser) OnNoopResponse() interface{} {
+	return response{Unknown: true}
+}
+
+// OnState implements history.RecordParser
+func (parser) OnState(state json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+// version is a committed value of a row
+type version struct {
+	commitTS uint64
+	value    int64
+}
+
+type write struct {
+	request
+	response
+}
+
+type read struct {
+	request
+	response
+}
+
+type checker struct{}
+
+// Checker reconstructs the state at the read_ts of every stale read from the committed writes,
+// and checks the read returns exactly that state
+func Checker() core.Checker {
+	return checker{}
+}
+
+// Name implements core.Checker
+func (checker) Name() string {
+	return "stale_read_checker"
+}
+
+// Check implements core.Checker
+func (checker) Check(_ core.Model, ops []core.Operation) (bool, error) {
+	writes, reads, err := parseOps(ops)
+	if err != nil {
+		return false, err
+	}
+	// the committed versions of each row ordered by commit_ts, all the rows are 0 initially
+	versions := make(map[int][]version)
+	for _, w := range writes {
+		if !w.Ok {
+			continue
+		}
+		for _, id := range w.IDs {
+			versions[id] = append(versions[id], version{commitTS: w.CommitTS, value: w.Value})
+		}
+	}
+	for _, vs := range versions {
+		sort.Slice(vs, func(i, j int) bool { return vs[i].commitTS < vs[j].commitTS })
+	}
+
+	var violations []string
+	stats := make(map[string]int)
+	for _, r := range reads {
+		stats[fmt.Sprintf("%s/%s", r.Mode, r.ReplicaRead)]++
+		for _, id := range r.IDs {
+			expected := stateAt(versions[id], r.ReadTS)
+			observed, ok := r.Values[id]
+			if !ok {
+				violations = append(violations, fmt.Sprintf("%s at read_ts %d: row %d is missing, expect %d", r.request, r.ReadTS, id, expected))
+				continue
+			}
+			if observed == expected {
+				continue
+			}
+			w, ok := writes[observed]
+			switch {
+			case ok && !w.Ok:
+				// the write may be committed or not, its commit_ts is unknown
+				continue
+			case ok:
+				violations = append(violations, fmt.Sprintf("%s at read_ts %d: row %d is %d written at commit_ts %d, expect %d",
+					r.request, r.ReadTS, id, observed, w.CommitTS, expected))
+			default:
+				violations = append(violations, fmt.Sprintf("%s at read_ts %d: row %d is %d which is never written, expect %d",
+					r.request, r.ReadTS, id, observed, expected))
+			}
+		}
+	}
+	log.Infof("[stale read] checked %d reads against %d writes, reads by mode/replica read: %v", len(reads), len(writes), stats)
+	if len(violations) == 0 {
+		return true, nil
+	}
+	for _, v := range violations {
+		log.Errorf("[stale read] %s", v)
+	}
+	if len(violations) > maxReportedViolations {
+		violations = violations[:maxReportedViolations]
+	}
+	return false, fmt.Errorf("stale reads match no committed prefix:\n%s", strings.Join(violations, "\n"))
+}
+
+// stateAt returns the value of the latest version committed at or before the ts
+func stateAt(vs []version, ts uint64) int64 {
+	i := sort.Search(len(vs), func(i int) bool { return vs[i].commitTS > ts })
+	if i == 0 {
+		return 0
+	}
+	return vs[i-1].value
+}
+
+// parseOps pairs the requests and responses of each process, it returns the writes by value and the ok reads,
+// the failed writes are kept since the unknown ones may be observed
+func parseOps(ops []core.Operation) (map[int64]*write, []read, error) {
+	writes := make(map[int64]*write)
+	var reads []read
+	pending := make(map[int64]request)
+	for _, op := range ops {
+		switch op.Action {
+		case core.InvokeOperation:
+			req := op.Data.(request)
+			pending[op.Proc] = req
+			if req.Kind == opWrite {
+				if _, ok := writes[req.Value]; ok {
+					return nil, nil, fmt.Errorf("value %d is written twice", req.Value)
+				}
+				writes[req.Value] = &write{request: req}
+			}
+		case core.ReturnOperation:
+			req, ok := pending[op.Proc]
+			if !ok {
+				return nil, nil, fmt.Errorf("no request for the response of process %d", op.Proc)
+			}
+			delete(pending, op.Proc)
+			res := op.Data.(response)
+			if req.Kind == opWrite {
+				writes[req.Value].response = res
+			} else if res.Ok {
+				reads = append(reads, read{request: req, response: res})
+			}
+		}
+	}
+	return writes, reads, nil
+}
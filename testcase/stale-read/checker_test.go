@@ -0,0 +1,58 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package staleread
+
+import (
+	"testing"
+
+	"github.com/pingcap/tipocket/pkg/core"
+)
+
+func TestChecker(t *testing.T) {
+	history := func(readTS uint64, values map[int]int64) []core.Operation {
+		return []core.Operation{
+			{Action: core.InvokeOperation, Proc: 1, Data: request{Kind: opWrite, IDs: []int{1, 2}, Value: 1}},
+			{Action: core.ReturnOperation, Proc: 1, Data: response{Ok: true, CommitTS: 10}},
+			{Action: core.InvokeOperation, Proc: 1, Data: request{Kind: opWrite, IDs: []int{2}, Value: 2}},
+			{Action: core.ReturnOperation, Proc: 1, Data: response{Ok: true, CommitTS: 20}},
+			{Action: core.InvokeOperation, Proc: 2, Data: request{Kind: opWrite, IDs: []int{1}, Value: 3}},
+			{Action: core.ReturnOperation, Proc: 2, Data: response{Unknown: true}},
+			{Action: core.InvokeOperation, Proc: 3, Data: request{Kind: opRead, IDs: []int{1, 2}, Mode: ReadSnapshot}},
+			{Action: core.ReturnOperation, Proc: 3, Data: response{Ok: true, ReadTS: readTS, Values: values}},
+		}
+	}
+	for _, c := range []struct {
+		readTS uint64
+		values map[int]int64
+		ok     bool
+	}{
+		{5, map[int]int64{1: 0, 2: 0}, true},
+		{10, map[int]int64{1: 1, 2: 1}, true},
+		{15, map[int]int64{1: 1, 2: 1}, true},
+		{25, map[int]int64{1: 1, 2: 2}, true},
+		// the unknown write may be committed
+		{25, map[int]int64{1: 3, 2: 2}, true},
+		// the write committed after the read_ts is observed
+		{15, map[int]int64{1: 1, 2: 2}, false},
+		// the write committed before the read_ts is missed
+		{25, map[int]int64{1: 1, 2: 1}, false},
+		{5, map[int]int64{1: 0}, false},
+		{5, map[int]int64{1: 0, 2: 4}, false},
+	} {
+		ok, err := Checker().Check(nil, history(c.readTS, c.values))
+		if ok != c.ok {
+			t.Fatalf("read %v at %d, expect %t, got %t, %v", c.values, c.readTS, c.ok, ok, err)
+		}
+	}
+}
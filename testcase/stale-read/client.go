@@ -1,4 +1,3 @@
-
 // Copyright 2021 PingCAP, Inc.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
@@ -17,12 +16,11 @@ package staleread
 import (
 	"context"
 	"database/sql"
-	"errors"
+	"encoding/json"
 	"fmt"
 	"math/rand"
-	"strconv"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ngaut/log"
@@ -37,318 +35,365 @@ var (
 	Table = "stale_read"
 )
 
+const (
+	opWrite = "write"
+	opRead  = "read"
+
+	// ReadAsOfTimestamp reads in a transaction started with READ ONLY AS OF TIMESTAMP
+	ReadAsOfTimestamp = "as-of-timestamp"
+	// ReadStaleness reads by an auto-commit statement with tidb_read_staleness
+	ReadStaleness = "read-staleness"
+	// ReadSnapshot reads with tidb_snapshot
+	ReadSnapshot = "snapshot"
+
+	// maxRowsPerRequest limits the rows written or read by a request
+	maxRowsPerRequest = 10
+	// staleMargin keeps the stale reads after the data is loaded, it tolerates the clock drift between the client and PD
+	staleMargin = 3 * time.Second
+)
+
+// ReadModes are all the ways to do stale reads
+var ReadModes = []string{ReadAsOfTimestamp, ReadStaleness, ReadSnapshot}
+
 // ClientCreator creates stale client
 type ClientCreator struct {
 	Cfg *Config
+
+	nextValue int64
+	loadedAt  atomic.Value
 }
 
 // Config for stale read test
 type Config struct {
-	DBName          string
-	TotalRows       int
-	Concurrency     int
+	DBName    string
+	TotalRows int
+	// ReadRatio is the probability of a request being a read
+	ReadRatio    float64
+	MaxStaleness int
+	// ReadModes are the ways of the stale reads, chosen randomly for each read
+	ReadModes []string
+	// ReplicaReads are the values of tidb_replica_read, chosen randomly for each read
+	ReplicaReads []string
+	// RequestInterval is the pause of a client before each request
 	RequestInterval time.Duration
-	MaxStaleness    int
+}
+
+type request struct {
+	Kind string `json:"kind"`
+	IDs  []int  `json:"ids"`
+	// Value is the value to write, every write has a unique value so that the checker knows where a value comes from
+	Value int64 `json:"value,omitempty"`
+	// Mode, Staleness and ReplicaRead describe a read
+	Mode        string `json:"mode,omitempty"`
+	Staleness   int    `json:"staleness,omitempty"`
+	ReplicaRead string `json:"replica_read,omitempty"`
+}
+
+func (r request) String() string {
+	if r.Kind == opWrite {
+		return fmt.Sprintf("write %d to %v", r.Value, r.IDs)
+	}
+	return fmt.Sprintf("read %v by %s %ds ago, replica read %s", r.IDs, r.Mode, r.Staleness, r.ReplicaRead)
+}
+
+type response struct {
+	Ok      bool   `json:"ok"`
+	Unknown bool   `json:"unknown"`
+	Error   string `json:"error,omitempty"`
+	// CommitTS is the commit_ts of a write
+	CommitTS uint64 `json:"commit_ts,omitempty"`
+	// ReadTS is the resolved read_ts of a read
+	ReadTS uint64 `json:"read_ts,omitempty"`
+	// Values are the values read by id
+	Values map[int]int64 `json:"values,omitempty"`
+}
+
+// IsUnknown implements core.UnknownResponse
+func (r response) IsUnknown() bool {
+	return r.Unknown
+}
+
+func (r response) String() string {
+	if !r.Ok {
+		return fmt.Sprintf("ok: %t, unknown: %t, error: %s", r.Ok, r.Unknown, r.Error)
+	}
+	if r.Values == nil {
+		return fmt.Sprintf("ok: true, commit_ts: %d", r.CommitTS)
+	}
+	return fmt.Sprintf("ok: true, read_ts: %d, values: %v", r.ReadTS, r.Values)
 }
 
 type staleRead struct {
 	*Config
+	creator *ClientCreator
+	r       *rand.Rand
 	db      *sql.DB
-	counter int64
-}
-
-func init() {
-	rand.Seed(time.Now().UnixNano())
+	conn    *sql.Conn
 }
 
 // Create creates StaleReadClient
 func (s *ClientCreator) Create(node cluster.ClientNode) core.Client {
 	return &staleRead{
 		Config:  s.Cfg,
-		counter: 0,
+		creator: s,
+		r:       rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 }
 
 // SetUp
 func (s *staleRead) SetUp(ctx context.Context, nodes []cluster.Node, clientNodes []cluster.ClientNode, idx int) error {
-	if idx != 0 {
-		return nil
-	}
-
-	log.Infof("[stale read] start to init...")
-
 	var err error
 	node := clientNodes[idx]
-	s.db, err = util.OpenDB(fmt.Sprintf("root@tcp(%s:%d)/%s", node.IP, node.Port, s.DBName), s.Concurrency)
+	s.db, err = util.OpenDB(fmt.Sprintf("root@tcp(%s:%d)/%s", node.IP, node.Port, s.DBName), 1)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	// the session variables of the reads must stay in one connection, which is never reconnected silently
+	if s.conn, err = s.db.Conn(ctx); err != nil {
+		return err
+	}
+	if idx != 0 {
+		return nil
 	}
 
-	// Load data
+	log.Infof("[stale read] start to init...")
 	s.prepareData()
-
+	s.creator.loadedAt.Store(time.Now())
 	return nil
 }
 
-// Start
-func (s *staleRead) Start(ctx context.Context, cfg interface{}, clientNodes []cluster.ClientNode) error {
-	log.Info("[stale read] start to test...")
-	var wg sync.WaitGroup
-	// Write data
-	for i := 0; i < s.Concurrency; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			s.backgroundUpdate(ctx)
-		}()
-	}
-	// Wait `MaxStaleness` elapsed so all read can reture data
-	time.Sleep(time.Duration(s.MaxStaleness) * time.Second)
-	// Read data
-	for i := 0; i < s.Concurrency; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			s.mustEqualRead(ctx)
-		}()
-	}
-	wg.Wait()
-	return nil
+// TearDown
+func (s *staleRead) TearDown(ctx context.Context, nodes []cluster.ClientNode, idx int) error {
+	s.conn.Close()
+	return s.db.Close()
 }
 
-func (s *staleRead) backgroundUpdate(ctx context.Context) {
-	rnd := rand.New(rand.NewSource(time.Now().Unix()))
-	conn, err := s.db.Conn(ctx)
-	if err != nil {
-		log.Fatalf("Failed to establish connection for background updating, error: %v", err)
+// NextRequest implements the core.OnScheduleClientExtensions interface.
+func (s *staleRead) NextRequest() interface{} {
+	if s.RequestInterval > 0 {
+		time.Sleep(s.RequestInterval)
 	}
-	for {
-		ids := make([]int, rand.Intn(99)+1)
-		for i := range ids {
-			ids[i] = rand.Intn(s.TotalRows) + 1
-		}
-		idsStr := strings.Trim(strings.Join(strings.Fields(fmt.Sprint(ids)), ", "), "[]")
-		pad := make([]byte, 255)
-		util.RandString(pad, rnd)
-		stmt := fmt.Sprintf(`UPDATE %s.%s SET pad="%s" WHERE id in (%s)`, s.DBName, Table, pad, idsStr)
-		if _, err := conn.ExecContext(ctx, stmt); err != nil {
-			conn.Close()
-			log.Warnf("[stale read] Failed to execute sql [%s], error: %v", stmt, err)
-			// Backoff then establish a new connection and retry
-			if stopped := sleepOrStop(ctx, s.RequestInterval); stopped {
-				return
-			}
-			conn, err = s.db.Conn(ctx)
-			if err != nil {
-				log.Fatalf("Failed to establish connection for background updating, error: %v", err)
-			}
-			continue
-		}
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			time.Sleep(s.RequestInterval)
-		}
+	ids := make([]int, s.r.Intn(maxRowsPerRequest)+1)
+	for i := range ids {
+		ids[i] = s.r.Intn(s.TotalRows) + 1
+	}
+	if s.r.Float64() >= s.ReadRatio {
+		return request{Kind: opWrite, IDs: ids, Value: atomic.AddInt64(&s.creator.nextValue, 1)}
+	}
+	req := request{
+		Kind:      opRead,
+		IDs:       ids,
+		Mode:      s.ReadModes[s.r.Intn(len(s.ReadModes))],
+		Staleness: s.r.Intn(s.MaxStaleness) + 1,
+	}
+	if len(s.ReplicaReads) > 0 {
+		req.ReplicaRead = s.ReplicaReads[s.r.Intn(len(s.ReplicaReads))]
+	}
+	// never read before the data is loaded
+	limit := 0
+	if loadedAt, ok := s.creator.loadedAt.Load().(time.Time); ok {
+		limit = int((time.Since(loadedAt) - staleMargin) / time.Second)
+	}
+	if req.Staleness > limit {
+		req.Staleness = limit
 	}
+	if req.Staleness < 1 {
+		req.Staleness = 1
+	}
+	return req
 }
 
-func (s *staleRead) mustEqualRead(ctx context.Context) {
-	staleReadConn, err := s.db.Conn(ctx)
-	if err != nil {
-		log.Fatalf("Failed to establish connection for stale read, error: %v", err)
+// Invoke implements the core.OnScheduleClientExtensions interface.
+func (s *staleRead) Invoke(ctx context.Context, node cluster.ClientNode, r interface{}) core.UnknownResponse {
+	req := r.(request)
+	var res response
+	if req.Kind == opWrite {
+		res = s.write(ctx, req)
+	} else {
+		res = s.read(ctx, req)
 	}
-	strongReadConn, err := s.db.Conn(ctx)
-	if err != nil {
-		log.Fatalf("Failed to establish connection for strong read, error: %v", err)
-	}
-	count := 0
-	for {
-		// prepare id
-		ids := make([]int, rand.Intn(99)+1)
-		for i := range ids {
-			ids[i] = rand.Intn(s.TotalRows) + 1
-		}
-		// 1s-100s ago
-		ago := rand.Intn(s.MaxStaleness) + 1
-		timeAgo := time.Now().Add(time.Duration(-ago) * time.Second)
-
-		stale, ts, err := s.staleRead(ctx, staleReadConn, timeAgo, ids)
-		if err != nil {
-			staleReadConn.Close()
-			log.Warnf("stale read return error: %v", err)
-			// Backoff then establish a new connection and retry
-			if stopped := sleepOrStop(ctx, s.RequestInterval); stopped {
-				return
-			}
-			staleReadConn, err = s.db.Conn(ctx)
-			if err != nil {
-				log.Fatalf("Failed to establish connection for stale read, error: %v", err)
-			}
-			continue
-		}
-
-		strong, err := s.strongRead(ctx, strongReadConn, ts, ids)
-		if err != nil {
-			strongReadConn.Close()
-			log.Warnf("strong read return error: %v", err)
-			// Backoff then establish a new connection and retry
-			if stopped := sleepOrStop(ctx, s.RequestInterval); stopped {
-				return
-			}
-			strongReadConn, err = s.db.Conn(ctx)
-			if err != nil {
-				log.Fatalf("Failed to establish connection for strong read, error: %v", err)
-			}
-			continue
-		}
+	if !res.Ok && isBadConn(res.Error) {
+		s.reconnect(ctx)
+	}
+	return res
+}
 
-		// The result should be the same
-		if len(stale) != len(strong) || len(strong) == 0 {
-			log.Fatalf("got different number of resluts at %d ago, ids: %d, stale: %d, strong: %d, stale res: %v, strong res: %v", ago, len(ids), len(stale), len(strong), stale, strong)
-		}
-		for id, pad := range strong {
-			if stalePad, ok := stale[id]; !ok || stalePad != pad {
-				log.Fatalf("got different resluts for id %d at %ds ago, tso: %v, stale read: %s, strong read: %s", id, ago, ts, stale[id], pad)
-			}
-		}
-		if count%10000 == 0 {
-			sql := fmt.Sprintf("select * from %s.%s where id in (%s)", s.DBName, Table, strings.Trim(strings.Join(strings.Fields(fmt.Sprint(ids)), ", "), "[]"))
-			log.Infof("Stale read %d seconds ago equal to strong read, sql: %s", ago, sql)
-		}
+// DumpState implements the core.OnScheduleClientExtensions interface.
+func (s *staleRead) DumpState(ctx context.Context) (interface{}, error) {
+	return nil, nil
+}
 
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			time.Sleep(s.RequestInterval)
-			count++
-		}
+func (s *staleRead) write(ctx context.Context, req request) response {
+	txn, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return response{Error: err.Error()}
+	}
+	stmt := fmt.Sprintf("UPDATE %s.%s SET val = ? WHERE id IN (%s)", s.DBName, Table, joinIDs(req.IDs))
+	if _, err := txn.ExecContext(ctx, stmt, req.Value); err != nil {
+		_ = txn.Rollback()
+		return response{Error: err.Error()}
+	}
+	if err := txn.Commit(); err != nil {
+		return response{Unknown: true, Error: err.Error()}
+	}
+	var info string
+	if err := s.conn.QueryRowContext(ctx, "SELECT @@tidb_last_txn_info").Scan(&info); err != nil {
+		return response{Unknown: true, Error: err.Error()}
 	}
+	startTS, commitTS, err := parseTxnInfo(info)
+	if err != nil || startTS == 0 || commitTS == 0 {
+		return response{Unknown: true, Error: fmt.Sprintf("unexpected last txn info %s, err: %v", info, err)}
+	}
+	return response{Ok: true, CommitTS: commitTS}
 }
 
-func sleepOrStop(ctx context.Context, interval time.Duration) bool {
-	select {
-	case <-ctx.Done():
-		return true
+func (s *staleRead) read(ctx context.Context, req request) response {
+	if req.ReplicaRead != "" {
+		if _, err := s.conn.ExecContext(ctx, fmt.Sprintf("SET @@tidb_replica_read = '%s'", req.ReplicaRead)); err != nil {
+			return response{Error: err.Error()}
+		}
+	}
+	var (
+		values map[int]int64
+		readTS uint64
+		err    error
+	)
+	query := fmt.Sprintf("SELECT id, val FROM %s.%s WHERE id IN (%s)", s.DBName, Table, joinIDs(req.IDs))
+	switch req.Mode {
+	case ReadAsOfTimestamp:
+		values, readTS, err = s.readAsOfTimestamp(ctx, query, req.Staleness)
+	case ReadStaleness:
+		values, readTS, err = s.readStaleness(ctx, query, req.Staleness)
+	case ReadSnapshot:
+		values, readTS, err = s.readSnapshot(ctx, query, req.Staleness)
 	default:
-		time.Sleep(interval)
-		return false
+		err = fmt.Errorf("unknown read mode %s", req.Mode)
 	}
+	if err != nil {
+		return response{Error: err.Error()}
+	}
+	if readTS == 0 {
+		return response{Error: "the read_ts is not resolved"}
+	}
+	return response{Ok: true, ReadTS: readTS, Values: values}
 }
 
-func (s *staleRead) staleRead(ctx context.Context, conn *sql.Conn, t time.Time, ids []int) (map[int]string, uint64, error) {
-	if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET TRANSACTION READ ONLY AS OF TIMESTAMP '%s'", t.String())); err != nil {
-		log.Warnf("failed to set stale read-only transaction, time: %s, err: %v", t.String(), err)
+func (s *staleRead) readAsOfTimestamp(ctx context.Context, query string, staleness int) (map[int]int64, uint64, error) {
+	stmt := fmt.Sprintf("START TRANSACTION READ ONLY AS OF TIMESTAMP NOW() - INTERVAL %d SECOND", staleness)
+	if _, err := s.conn.ExecContext(ctx, stmt); err != nil {
 		return nil, 0, err
 	}
+	defer s.conn.ExecContext(ctx, "ROLLBACK")
 
-	txn, err := conn.BeginTx(ctx, &sql.TxOptions{})
-	if err != nil {
-		log.Warnf("failed to start a read-only transaction, err: %v", err)
+	var readTS uint64
+	if err := s.conn.QueryRowContext(ctx, "SELECT @@tidb_current_ts").Scan(&readTS); err != nil {
 		return nil, 0, err
 	}
-	defer txn.Rollback()
+	values, err := queryValues(ctx, s.conn, query)
+	return values, readTS, err
+}
 
-	var tso uint64
-	if err = txn.QueryRow("select @@tidb_current_ts").Scan(&tso); err != nil {
-		log.Warnf("failed to get tidb_current_ts, err: %v", err)
+func (s *staleRead) readStaleness(ctx context.Context, query string, staleness int) (map[int]int64, uint64, error) {
+	if _, err := s.conn.ExecContext(ctx, fmt.Sprintf("SET @@tidb_read_staleness = '-%d'", staleness)); err != nil {
 		return nil, 0, err
 	}
+	defer s.conn.ExecContext(ctx, "SET @@tidb_read_staleness = ''")
 
-	idsStr := strings.Trim(strings.Join(strings.Fields(fmt.Sprint(ids)), ", "), "[]")
-	rows, err := txn.QueryContext(ctx, fmt.Sprintf("select * from %s.%s where id in (%s)", s.DBName, Table, idsStr))
+	values, err := queryValues(ctx, s.conn, query)
 	if err != nil {
-		return nil, tso, err
+		return nil, 0, err
 	}
-	defer rows.Close()
-	results := make(map[int]string, len(ids))
-	for rows.Next() {
-		var id int
-		var pad string
-		if err = rows.Scan(&id, &pad); err != nil {
-			return nil, tso, err
-		}
-		results[id] = pad
+	// the read_ts is resolved by TiDB, the auto-commit read is the last transaction
+	var info string
+	if err := s.conn.QueryRowContext(ctx, "SELECT @@tidb_last_txn_info").Scan(&info); err != nil {
+		return nil, 0, err
 	}
+	readTS, _, err := parseTxnInfo(info)
+	return values, readTS, err
+}
 
-	if err := txn.Commit(); err != nil {
-		log.Warnf("failed to commit a read-only transaction, err: %v", err)
-		return nil, tso, err
+func (s *staleRead) readSnapshot(ctx context.Context, query string, staleness int) (map[int]int64, uint64, error) {
+	readTS := composeTS(time.Now().Add(-time.Duration(staleness) * time.Second))
+	if _, err := s.conn.ExecContext(ctx, fmt.Sprintf("SET @@tidb_snapshot = '%d'", readTS)); err != nil {
+		return nil, 0, err
 	}
-	return results, tso, nil
+	defer s.conn.ExecContext(ctx, "SET @@tidb_snapshot = ''")
+
+	values, err := queryValues(ctx, s.conn, query)
+	return values, readTS, err
 }
 
-func (s *staleRead) strongRead(ctx context.Context, conn *sql.Conn, tso uint64, ids []int) (map[int]string, error) {
-	if tso == 0 {
-		return nil, errors.New("tso can't be zero")
-	}
-	if _, err := conn.ExecContext(ctx, fmt.Sprintf("set @@tidb_snapshot = '%d'", tso)); err != nil {
-		log.Warnf("failed to set tidb_snapshot, tso: %d, err: %v", tso, err)
-		return nil, err
+func (s *staleRead) reconnect(ctx context.Context) {
+	s.conn.Close()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		log.Fatalf("Failed to establish connection, error: %v", err)
 	}
+	s.conn = conn
+}
 
-	idsStr := strings.Trim(strings.Join(strings.Fields(fmt.Sprint(ids)), ", "), "[]")
-	rows, err := conn.QueryContext(ctx, fmt.Sprintf("select * from %s.%s where id in (%s)", s.DBName, Table, idsStr))
+func queryValues(ctx context.Context, conn *sql.Conn, query string) (map[int]int64, error) {
+	rows, err := conn.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	results := make(map[int]string, len(ids))
+	values := make(map[int]int64)
 	for rows.Next() {
-		var id int
-		var pad string
-		if err = rows.Scan(&id, &pad); err != nil {
+		var (
+			id  int
+			val int64
+		)
+		if err = rows.Scan(&id, &val); err != nil {
 			return nil, err
 		}
-		results[id] = pad
+		values[id] = val
+	}
+	return values, rows.Err()
+}
+
+// parseTxnInfo parses @@tidb_last_txn_info
+func parseTxnInfo(info string) (startTS uint64, commitTS uint64, err error) {
+	var txnInfo struct {
+		StartTS  uint64 `json:"start_ts"`
+		CommitTS uint64 `json:"commit_ts"`
+	}
+	if err = json.Unmarshal([]byte(info), &txnInfo); err != nil {
+		return 0, 0, err
 	}
-	return results, nil
+	return txnInfo.StartTS, txnInfo.CommitTS, nil
+}
+
+// composeTS composes a TSO of the physical time without the logical part
+func composeTS(t time.Time) uint64 {
+	return uint64(t.UnixNano()/int64(time.Millisecond)) << 18
+}
+
+func joinIDs(ids []int) string {
+	return strings.Trim(strings.Join(strings.Fields(fmt.Sprint(ids)), ", "), "[]")
+}
+
+func isBadConn(err string) bool {
+	return strings.Contains(err, "bad connection") || strings.Contains(err, "invalid connection") ||
+		strings.Contains(err, "connection is already closed")
 }
 
 func (s *staleRead) prepareData() {
 	log.Info("[stale read] prepare data")
 
-	// Create table
-	util.MustExec(s.db, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.%s (id int(10) PRIMARY KEY, pad varchar(255))", s.DBName, Table))
-
-	// Load data
-	var wg sync.WaitGroup
-	insertSQL := fmt.Sprintf("INSERT IGNORE INTO %s.%s VALUES", s.DBName, Table)
-	for i := 0; i < s.Concurrency; i++ {
-		wg.Add(1)
-		go func(insertSQL string, item int) {
-			defer wg.Done()
-			chunk := s.TotalRows / s.Concurrency
-			start := item * chunk
-			stmt := insertSQL
-			unexec := false
-			rnd := rand.New(rand.NewSource(time.Now().Unix()))
-			for i := 0; i < chunk; i++ {
-				pad := make([]byte, 255)
-				util.RandString(pad, rnd)
-				stmt += fmt.Sprintf(`(%v, "%s")`, strconv.Itoa(start+i+1), pad)
-				if i > 0 && i%100 == 0 {
-					util.MustExec(s.db, stmt)
-					stmt = insertSQL
-					unexec = false
-				} else if i+1 < chunk {
-					stmt += ","
-					unexec = true
-				}
-			}
-			if unexec {
-				util.MustExec(s.db, stmt)
-			}
-		}(insertSQL, i)
-	}
-	wg.Wait()
+	util.MustExec(s.db, fmt.Sprintf("DROP TABLE IF EXISTS %s.%s", s.DBName, Table))
+	util.MustExec(s.db, fmt.Sprintf("CREATE TABLE %s.%s (id int(10) PRIMARY KEY, val bigint NOT NULL)", s.DBName, Table))
+
+	// all the rows are 0 initially
+	insertSQL := fmt.Sprintf("INSERT INTO %s.%s VALUES ", s.DBName, Table)
+	var values []string
+	for i := 1; i <= s.TotalRows; i++ {
+		values = append(values, fmt.Sprintf("(%d, 0)", i))
+		if len(values) == 100 || i == s.TotalRows {
+			util.MustExec(s.db, insertSQL+strings.Join(values, ","))
+			values = values[:0]
+		}
+	}
 
 	log.Info("[stale read] prepare data finish")
-	return
 }
-
-// TearDown
-func (s *staleRead) TearDown(ctx context.Context, nodes []cluster.ClientNode, idx int) error {
-	return nil
-}
\ No newline at end of file
@@ -1,4 +1,3 @@
-
 // Copyright 2021 PingCAP, Inc.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
@@ -17,50 +16,86 @@ package main
 import (
 	"context"
 	"flag"
-	"time"
+	"strings"
+
+	"github.com/ngaut/log"
 
 	"github.com/pingcap/tipocket/cmd/util"
 	"github.com/pingcap/tipocket/pkg/cluster"
 	"github.com/pingcap/tipocket/pkg/control"
 	"github.com/pingcap/tipocket/pkg/test-infra/fixture"
+	"github.com/pingcap/tipocket/pkg/verify"
 	staleread "github.com/pingcap/tipocket/testcase/stale-read"
 
 	test_infra "github.com/pingcap/tipocket/pkg/test-infra"
 )
 
 var (
-	dbname          = flag.String("dbname", "test", "name of database to test")
-	concurrency     = flag.Int("concurrency", 200, "concurrency worker count")
-	totalRows       = flag.Int("rows", 4000000, "total rows of data")
-	requestInterval = flag.Duration("request-interval", 25*time.Millisecond, "request freq")
-	maxStaleness    = flag.Int("staleness", 100, "the max staleness in second")
+	dbname       = flag.String("dbname", "test", "name of database to test")
+	totalRows    = flag.Int("rows", 1000, "total rows of data")
+	readRatio    = flag.Float64("read-ratio", 0.5, "the probability of a request being a read")
+	maxStaleness = flag.Int("staleness", 100, "the max staleness in second")
+	readModes    = flag.String("read-modes", strings.Join(staleread.ReadModes, ","),
+		"the ways of stale reads, separated by comma, any of 'as-of-timestamp', 'read-staleness' and 'snapshot'")
+	replicaReads = flag.String("replica-reads", "leader,follower,leader-and-follower",
+		"the values of tidb_replica_read for stale reads, separated by comma, e.g. 'learner' if there are learners")
+	concurrency     = flag.Int("concurrency", 0, "deprecated, use -client instead")
+	requestInterval = flag.Duration("request-interval", 0, "deprecated, the pause of a client before each request")
 )
 
 func main() {
 	flag.Parse()
-
-	cfg := control.Config{
-		Mode:        control.ModeStandard,
-		ClientCount: 1,
-		RunTime:     fixture.Context.RunTime,
-		RunRound:    1,
+	if *concurrency > 0 {
+		log.Warnf("-concurrency is deprecated, use -client instead")
+		fixture.Context.ClientCount = *concurrency
+	}
+	if *requestInterval > 0 {
+		log.Warnf("-request-interval is deprecated, the requests are limited by -request-count and -run-time")
+	}
+	modes := splitList(*readModes)
+	if len(modes) == 0 {
+		modes = staleread.ReadModes
 	}
 
 	suit := util.Suit{
-		Config:   &cfg,
+		Config: &control.Config{
+			Mode:         control.ModeOnSchedule,
+			ClientCount:  fixture.Context.ClientCount,
+			RequestCount: fixture.Context.RequestCount,
+			RunRound:     fixture.Context.RunRound,
+			RunTime:      fixture.Context.RunTime,
+			History:      fixture.Context.HistoryFile,
+		},
 		Provider: cluster.NewDefaultClusterProvider(),
 		ClientCreator: &staleread.ClientCreator{
 			Cfg: &staleread.Config{
 				DBName:          *dbname,
-				Concurrency:     *concurrency,
 				TotalRows:       *totalRows,
-				RequestInterval: *requestInterval,
+				ReadRatio:       *readRatio,
 				MaxStaleness:    *maxStaleness,
+				ReadModes:       modes,
+				ReplicaReads:    splitList(*replicaReads),
+				RequestInterval: *requestInterval,
 			},
 		},
-		NemesisGens: util.ParseNemesisGenerators(fixture.Context.Nemesis),
+		NemesisGens:      util.ParseNemesisGenerators(fixture.Context.Nemesis),
+		ClientRequestGen: util.OnClientLoop,
+		VerifySuit: verify.Suit{
+			Checker: staleread.Checker(),
+			Parser:  staleread.Parser(),
+		},
 		ClusterDefs: test_infra.NewDefaultCluster(fixture.Context.Namespace, fixture.Context.Namespace,
 			fixture.Context.TiDBClusterConfig),
 	}
 	suit.Run(context.Background())
-}
\ No newline at end of file
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,231 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikvclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ngaut/log"
+	tikverr "github.com/tikv/client-go/v2/error"
+	"github.com/tikv/client-go/v2/txnkv"
+	"github.com/tikv/client-go/v2/txnkv/transaction"
+
+	"github.com/pingcap/tipocket/pkg/cluster"
+	"github.com/pingcap/tipocket/pkg/core"
+	ellecore "github.com/pingcap/tipocket/pkg/elle/core"
+	elleappend "github.com/pingcap/tipocket/pkg/elle/list_append"
+	elletxn "github.com/pingcap/tipocket/pkg/elle/txn"
+	"github.com/pingcap/tipocket/pkg/history"
+)
+
+// appendKeyPrefix is the prefix of the list keys, a list is stored as comma separated integers
+const appendKeyPrefix = "tikv_client_append_"
+
+type txnAppendClient struct {
+	cli         *txnkv.Client
+	nextRequest func() ellecore.Op
+}
+
+// SetUp implements the core.Client interface.
+func (c *txnAppendClient) SetUp(ctx context.Context, _ []cluster.Node, clientNodes []cluster.ClientNode, idx int) error {
+	var err error
+	if c.cli, err = txnkv.NewClient(pdAddrs(clientNodes)); err != nil {
+		return fmt.Errorf("create txnkv client failed: %v", err)
+	}
+	return nil
+}
+
+// TearDown implements the core.Client interface.
+func (c *txnAppendClient) TearDown(ctx context.Context, _ []cluster.ClientNode, _ int) error {
+	return c.cli.Close()
+}
+
+// Invoke implements the core.Client interface.
+func (c *txnAppendClient) Invoke(ctx context.Context, _ cluster.ClientNode, r interface{}) core.UnknownResponse {
+	request := r.(ellecore.Op)
+	fail := func(err error) elleappend.Response {
+		return elleappend.Response{
+			Result: ellecore.Op{
+				Time:  time.Now(),
+				Type:  ellecore.OpTypeFail,
+				Value: request.Value,
+				Error: err.Error(),
+			},
+		}
+	}
+	txn, err := c.cli.Begin()
+	if err != nil {
+		return fail(err)
+	}
+	var mops []ellecore.Mop
+	for _, mop := range *request.Value {
+		k := mop.GetKey()
+		list, err := getList(ctx, txn, k)
+		if err != nil {
+			_ = txn.Rollback()
+			return fail(err)
+		}
+		switch mop.GetMopType() {
+		case ellecore.MopTypeAppend:
+			v := mop.GetValue().(int)
+			if err := txn.Set(appendKey(k), []byte(strings.Join(append(list, strconv.Itoa(v)), ","))); err != nil {
+				_ = txn.Rollback()
+				return fail(err)
+			}
+			mops = append(mops, ellecore.Append(k, v))
+		case ellecore.MopTypeRead:
+			var v []int
+			for _, n := range list {
+				v = append(v, mustAtoi(n))
+			}
+			mops = append(mops, ellecore.Read(k, v))
+		}
+	}
+
+	if err := txn.Commit(ctx); err != nil {
+		tp := ellecore.OpTypeFail
+		if errors.Is(err, tikverr.ErrResultUndetermined) {
+			tp = ellecore.OpTypeUnknown
+		}
+		return elleappend.Response{
+			Result: ellecore.Op{
+				Time:  time.Now(),
+				Type:  tp,
+				Value: &mops,
+				Error: err.Error(),
+			},
+		}
+	}
+
+	return elleappend.Response{
+		Result: ellecore.Op{
+			Time:  time.Now(),
+			Type:  ellecore.OpTypeOk,
+			Value: &mops,
+		},
+	}
+}
+
+// NextRequest implements the core.Client interface.
+func (c *txnAppendClient) NextRequest() interface{} {
+	return c.nextRequest()
+}
+
+// DumpState implements the core.Client interface, it clears the lists of the previous round.
+func (c *txnAppendClient) DumpState(ctx context.Context) (interface{}, error) {
+	txn, err := c.cli.Begin()
+	if err != nil {
+		return nil, err
+	}
+	it, err := txn.Iter([]byte(appendKeyPrefix), prefixEnd(appendKeyPrefix))
+	if err != nil {
+		_ = txn.Rollback()
+		return nil, err
+	}
+	var keys [][]byte
+	for it.Valid() {
+		keys = append(keys, append([]byte(nil), it.Key()...))
+		if err := it.Next(); err != nil {
+			it.Close()
+			_ = txn.Rollback()
+			return nil, err
+		}
+	}
+	it.Close()
+	for _, k := range keys {
+		if err := txn.Delete(k); err != nil {
+			_ = txn.Rollback()
+			return nil, err
+		}
+	}
+	log.Infof("delete %d lists of the previous round", len(keys))
+	return nil, txn.Commit(ctx)
+}
+
+func appendKey(k string) []byte {
+	return []byte(appendKeyPrefix + k)
+}
+
+func prefixEnd(prefix string) []byte {
+	end := []byte(prefix)
+	end[len(end)-1]++
+	return end
+}
+
+func getList(ctx context.Context, txn *transaction.KVTxn, k string) ([]string, error) {
+	val, err := txn.Get(ctx, appendKey(k))
+	if tikverr.IsErrNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(val) == 0 {
+		return nil, nil
+	}
+	return strings.Split(string(val), ","), nil
+}
+
+// txnAppendClientCreator creates list append clients
+type txnAppendClientCreator struct {
+	it *elletxn.MopIterator
+	mu sync.Mutex
+}
+
+// NewTxnAppendClientCreator creates a creator of clients which append to and read lists by TxnKV
+func NewTxnAppendClientCreator() core.ClientCreator {
+	return &txnAppendClientCreator{
+		it: elletxn.WrTxnWithDefaultOpts(),
+	}
+}
+
+// Create creates a client.
+func (a *txnAppendClientCreator) Create(_ cluster.ClientNode) core.Client {
+	return &txnAppendClient{
+		nextRequest: func() ellecore.Op {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			value := a.it.Next()
+			return ellecore.Op{
+				Type:  ellecore.OpTypeInvoke,
+				Time:  time.Now(),
+				Value: &value,
+			}
+		},
+	}
+}
+
+// AppendParser parses a history of the list append workload
+func AppendParser() history.RecordParser {
+	return elleappend.Parser{}
+}
+
+// AppendChecker checks the list append history by elle
+func AppendChecker() core.Checker {
+	return elleappend.Checker{}
+}
+
+func mustAtoi(s string) int {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		log.Fatalf("parse int failed: %s", s)
+	}
+	return i
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikvclient
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/pingcap/tipocket/pkg/core"
+	ellecore "github.com/pingcap/tipocket/pkg/elle/core"
+	elleappend "github.com/pingcap/tipocket/pkg/elle/list_append"
+	"github.com/pingcap/tipocket/pkg/history"
+)
+
+func appendInvoke(mops ...ellecore.Mop) ellecore.Op {
+	return ellecore.Op{Type: ellecore.OpTypeInvoke, Value: &mops}
+}
+
+func appendReturn(tp ellecore.OpType, mops ...ellecore.Mop) elleappend.Response {
+	return elleappend.Response{Result: ellecore.Op{Type: tp, Value: &mops}}
+}
+
+func checkAppendHistory(t *testing.T, actions []action) bool {
+	tmpDir, err := ioutil.TempDir("", "append")
+	if err != nil {
+		t.Fatalf("create temp dir failed %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	ops, _, err := history.ReadHistory(recordHistory(t, tmpDir, nil, actions), AppendParser())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := elleappend.ConvertOperationsToHistory(append(ops, core.Operation{Action: core.InvokeNemesis}))
+	if len(h) != len(actions) {
+		t.Fatalf("expect %d ops, got %d", len(actions), len(h))
+	}
+	for i, op := range h {
+		if op.Process.MustGet() != int(actions[i].proc) {
+			t.Fatalf("expect process %d of op %d, got %s", actions[i].proc, i, op)
+		}
+		for _, mop := range *op.Value {
+			if mop.IsAppend() {
+				if _, ok := mop.GetValue().(int); !ok {
+					t.Fatalf("append of op %s isn't converted to int", op)
+				}
+			}
+			if mop.IsRead() && mop.GetValue() != nil {
+				if _, ok := mop.GetValue().([]int); !ok {
+					t.Fatalf("read of op %s isn't converted to []int", op)
+				}
+			}
+		}
+	}
+
+	var ok bool
+	inDir(t, tmpDir, func() {
+		ok, err = AppendChecker().Check(nil, ops)
+	})
+	if ok != (err == nil) {
+		t.Fatalf("the checker returns %t with error %v", ok, err)
+	}
+	return ok
+}
+
+func TestTxnAppendHistory(t *testing.T) {
+	ok, fail := ellecore.OpTypeOk, ellecore.OpTypeFail
+	for _, cs := range []struct {
+		name    string
+		actions []action
+		ok      bool
+	}{
+		{"serial", []action{
+			{1, appendInvoke(ellecore.Append("x", 1), ellecore.Read("y", nil))},
+			{1, appendReturn(ok, ellecore.Append("x", 1), ellecore.Read("y", nil))},
+			{2, appendInvoke(ellecore.Append("y", 1), ellecore.Read("x", nil))},
+			{2, appendReturn(ok, ellecore.Append("y", 1), ellecore.Read("x", []int{1}))},
+			{1, appendInvoke(ellecore.Append("x", 2))},
+			{1, appendReturn(fail, ellecore.Append("x", 2))},
+			{3, appendInvoke(ellecore.Read("x", nil), ellecore.Read("y", nil))},
+			{3, appendReturn(ok, ellecore.Read("x", []int{1}), ellecore.Read("y", []int{1}))},
+		}, true},
+		{"read skew", []action{
+			{1, appendInvoke(ellecore.Append("x", 1), ellecore.Append("y", 1))},
+			{1, appendReturn(ok, ellecore.Append("x", 1), ellecore.Append("y", 1))},
+			{2, appendInvoke(ellecore.Read("x", nil), ellecore.Read("y", nil))},
+			{2, appendReturn(ok, ellecore.Read("x", []int{1}), ellecore.Read("y", nil))},
+		}, false},
+	} {
+		if ok := checkAppendHistory(t, cs.actions); ok != cs.ok {
+			t.Errorf("%s: expect %t, got %t", cs.name, cs.ok, ok)
+		}
+	}
+}
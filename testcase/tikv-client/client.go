@@ -0,0 +1,26 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tikvclient contains workloads which talk to TiKV by client-go directly,
+// so TiKV can be checked in isolation from the SQL layer of TiDB.
+package tikvclient
+
+import (
+	"fmt"
+
+	"github.com/pingcap/tipocket/pkg/cluster"
+)
+
+func pdAddrs(clientNodes []cluster.ClientNode) []string {
+	return []string{fmt.Sprintf("%s-pd.%s.svc:2379", clientNodes[0].ClusterName, clientNodes[0].Namespace)}
+}
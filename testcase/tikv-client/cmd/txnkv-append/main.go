@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/pingcap/tipocket/cmd/util"
+	"github.com/pingcap/tipocket/pkg/cluster"
+	"github.com/pingcap/tipocket/pkg/control"
+	test_infra "github.com/pingcap/tipocket/pkg/test-infra"
+	"github.com/pingcap/tipocket/pkg/test-infra/fixture"
+	"github.com/pingcap/tipocket/pkg/verify"
+	tikvclient "github.com/pingcap/tipocket/testcase/tikv-client"
+)
+
+func main() {
+	flag.Parse()
+
+	suit := util.Suit{
+		Config: &control.Config{
+			Mode:         control.ModeOnSchedule,
+			ClientCount:  fixture.Context.ClientCount,
+			RequestCount: fixture.Context.RequestCount,
+			RunRound:     fixture.Context.RunRound,
+			RunTime:      fixture.Context.RunTime,
+			History:      fixture.Context.HistoryFile,
+		},
+		Provider:         cluster.NewDefaultClusterProvider(),
+		ClientCreator:    tikvclient.NewTxnAppendClientCreator(),
+		NemesisGens:      util.ParseNemesisGenerators(fixture.Context.Nemesis),
+		ClientRequestGen: util.OnClientLoop,
+		VerifySuit: verify.Suit{
+			Checker: tikvclient.AppendChecker(),
+			Parser:  tikvclient.AppendParser(),
+		},
+		ClusterDefs: test_infra.NewDefaultCluster(fixture.Context.Namespace, fixture.Context.ClusterName,
+			fixture.Context.TiDBClusterConfig),
+	}
+	suit.Run(context.Background())
+}
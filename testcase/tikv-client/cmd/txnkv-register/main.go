@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/pingcap/tipocket/cmd/util"
+	"github.com/pingcap/tipocket/pkg/check/porcupine"
+	"github.com/pingcap/tipocket/pkg/cluster"
+	"github.com/pingcap/tipocket/pkg/control"
+	"github.com/pingcap/tipocket/pkg/model"
+	test_infra "github.com/pingcap/tipocket/pkg/test-infra"
+	"github.com/pingcap/tipocket/pkg/test-infra/fixture"
+	"github.com/pingcap/tipocket/pkg/verify"
+	tikvclient "github.com/pingcap/tipocket/testcase/tikv-client"
+)
+
+func main() {
+	flag.Parse()
+
+	suit := util.Suit{
+		Config: &control.Config{
+			Mode:         control.ModeOnSchedule,
+			ClientCount:  fixture.Context.ClientCount,
+			RequestCount: fixture.Context.RequestCount,
+			RunRound:     fixture.Context.RunRound,
+			RunTime:      fixture.Context.RunTime,
+			History:      fixture.Context.HistoryFile,
+		},
+		Provider:         cluster.NewDefaultClusterProvider(),
+		ClientCreator:    tikvclient.TxnRegisterClientCreator{},
+		NemesisGens:      util.ParseNemesisGenerators(fixture.Context.Nemesis),
+		ClientRequestGen: util.OnClientLoop,
+		VerifySuit: verify.Suit{
+			Model:   model.CasRegisterModel(),
+			Checker: porcupine.Checker{},
+			Parser:  model.CasRegisterParser(),
+		},
+		ClusterDefs: test_infra.NewDefaultCluster(fixture.Context.Namespace, fixture.Context.ClusterName,
+			fixture.Context.TiDBClusterConfig),
+	}
+	suit.Run(context.Background())
+}
@@ -0,0 +1,117 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikvclient
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/ngaut/log"
+	"github.com/tikv/client-go/v2/config"
+	"github.com/tikv/client-go/v2/rawkv"
+
+	"github.com/pingcap/tipocket/pkg/cluster"
+	"github.com/pingcap/tipocket/pkg/core"
+	"github.com/pingcap/tipocket/pkg/model"
+)
+
+var rawRegisterKey = []byte("tikv_client_raw_register")
+
+type rawCASClient struct {
+	r   *rand.Rand
+	cli *rawkv.Client
+}
+
+// SetUp implements the core.Client interface.
+func (c *rawCASClient) SetUp(ctx context.Context, _ []cluster.Node, clientNodes []cluster.ClientNode, idx int) error {
+	c.r = rand.New(rand.NewSource(time.Now().UnixNano()))
+	cli, err := rawkv.NewClient(ctx, pdAddrs(clientNodes), config.Security{})
+	if err != nil {
+		return fmt.Errorf("create rawkv client failed: %v", err)
+	}
+	// all the writes of the key must be atomic, otherwise CompareAndSwap isn't linearizable with them
+	c.cli = cli.SetAtomicForCAS(true)
+	if idx != 0 {
+		return nil
+	}
+
+	log.Infof("initialize the rawkv register to 0")
+	return c.cli.Put(ctx, rawRegisterKey, []byte("0"))
+}
+
+// TearDown implements the core.Client interface.
+func (c *rawCASClient) TearDown(ctx context.Context, _ []cluster.ClientNode, _ int) error {
+	return c.cli.Close()
+}
+
+// Invoke implements the core.Client interface.
+func (c *rawCASClient) Invoke(ctx context.Context, _ cluster.ClientNode, r interface{}) core.UnknownResponse {
+	req := r.(model.CasRegisterRequest)
+	switch req.Op {
+	case model.CasRegisterRead:
+		val, err := c.cli.Get(ctx, rawRegisterKey)
+		if err != nil {
+			return model.CasRegisterResponse{Unknown: true}
+		}
+		if val == nil {
+			return model.CasRegisterResponse{}
+		}
+		v, err := strconv.Atoi(string(val))
+		if err != nil {
+			log.Fatalf("invalid value %q of the rawkv register", val)
+		}
+		return model.CasRegisterResponse{Exists: true, Value: v}
+	case model.CasRegisterWrite:
+		if err := c.cli.Put(ctx, rawRegisterKey, []byte(strconv.Itoa(req.Arg1))); err != nil {
+			return model.CasRegisterResponse{Unknown: true}
+		}
+		return model.CasRegisterResponse{Ok: true}
+	case model.CasRegisterCAS:
+		_, ok, err := c.cli.CompareAndSwap(ctx, rawRegisterKey, []byte(strconv.Itoa(req.Arg1)), []byte(strconv.Itoa(req.Arg2)))
+		if err != nil {
+			return model.CasRegisterResponse{Unknown: true}
+		}
+		return model.CasRegisterResponse{Ok: ok}
+	default:
+		panic("unreachable")
+	}
+}
+
+// NextRequest implements the core.Client interface.
+func (c *rawCASClient) NextRequest() interface{} {
+	return nextRegisterRequest(c.r)
+}
+
+// DumpState implements the core.Client interface.
+func (c *rawCASClient) DumpState(ctx context.Context) (interface{}, error) {
+	val, err := c.cli.Get(ctx, rawRegisterKey)
+	if err != nil {
+		return nil, err
+	}
+	if val == nil {
+		return -1, nil
+	}
+	return strconv.Atoi(string(val))
+}
+
+// RawCASClientCreator creates clients which operate a CAS register by RawKV in atomic mode
+type RawCASClientCreator struct{}
+
+// Create creates a client.
+func (RawCASClientCreator) Create(_ cluster.ClientNode) core.Client {
+	return &rawCASClient{}
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikvclient
+
+import (
+	"testing"
+
+	"github.com/pingcap/tipocket/pkg/model"
+)
+
+func TestRawCASHistory(t *testing.T) {
+	read := model.CasRegisterRequest{Op: model.CasRegisterRead}
+	write := func(v int) model.CasRegisterRequest {
+		return model.CasRegisterRequest{Op: model.CasRegisterWrite, Arg1: v}
+	}
+	cas := func(from, to int) model.CasRegisterRequest {
+		return model.CasRegisterRequest{Op: model.CasRegisterCAS, Arg1: from, Arg2: to}
+	}
+	for _, cs := range []struct {
+		name    string
+		state   int
+		actions []action
+		ok      bool
+	}{
+		// DumpState returns -1 if the register is deleted
+		{"missing key", -1, []action{
+			{1, read}, {1, model.CasRegisterResponse{}},
+			{2, cas(0, 1)}, {2, model.CasRegisterResponse{Ok: false}},
+			{1, write(0)}, {1, model.CasRegisterResponse{Ok: true}},
+			{2, cas(0, 1)}, {2, model.CasRegisterResponse{Ok: true}},
+			{3, read}, {3, model.CasRegisterResponse{Exists: true, Value: 1}},
+		}, true},
+		{"unknown cas", 0, []action{
+			{1, cas(0, 1)}, {1, model.CasRegisterResponse{Unknown: true}},
+			{2, read}, {2, model.CasRegisterResponse{Exists: true, Value: 1}},
+		}, true},
+		{"concurrent cas", 0, []action{
+			{1, cas(0, 1)},
+			{2, cas(0, 2)},
+			{1, model.CasRegisterResponse{Ok: true}},
+			{2, model.CasRegisterResponse{Ok: false}},
+			{3, read}, {3, model.CasRegisterResponse{Exists: true, Value: 1}},
+		}, true},
+		{"cas not atomic with write", 0, []action{
+			{1, write(2)}, {1, model.CasRegisterResponse{Ok: true}},
+			{2, cas(0, 1)}, {2, model.CasRegisterResponse{Ok: true}},
+		}, false},
+		{"phantom key", -1, []action{
+			{1, read}, {1, model.CasRegisterResponse{Exists: true, Value: 0}},
+		}, false},
+	} {
+		if ok := checkRegisterHistory(t, cs.state, cs.actions); ok != cs.ok {
+			t.Errorf("%s: expect %t, got %t", cs.name, cs.ok, ok)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikvclient
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/ngaut/log"
+	tikverr "github.com/tikv/client-go/v2/error"
+	"github.com/tikv/client-go/v2/txnkv"
+	"github.com/tikv/client-go/v2/txnkv/transaction"
+
+	"github.com/pingcap/tipocket/pkg/cluster"
+	"github.com/pingcap/tipocket/pkg/core"
+	"github.com/pingcap/tipocket/pkg/model"
+)
+
+// registerValueRange is the range of the register values, it's small so that CAS can succeed
+const registerValueRange = 5
+
+var txnRegisterKey = []byte("tikv_client_txn_register")
+
+type txnRegisterClient struct {
+	r   *rand.Rand
+	cli *txnkv.Client
+}
+
+// SetUp implements the core.Client interface.
+func (c *txnRegisterClient) SetUp(ctx context.Context, _ []cluster.Node, clientNodes []cluster.ClientNode, idx int) error {
+	c.r = rand.New(rand.NewSource(time.Now().UnixNano()))
+	var err error
+	if c.cli, err = txnkv.NewClient(pdAddrs(clientNodes)); err != nil {
+		return fmt.Errorf("create txnkv client failed: %v", err)
+	}
+	if idx != 0 {
+		return nil
+	}
+
+	log.Infof("initialize the txnkv register to 0")
+	txn, err := c.cli.Begin()
+	if err != nil {
+		return err
+	}
+	if err := txn.Set(txnRegisterKey, []byte("0")); err != nil {
+		_ = txn.Rollback()
+		return err
+	}
+	return txn.Commit(ctx)
+}
+
+// TearDown implements the core.Client interface.
+func (c *txnRegisterClient) TearDown(ctx context.Context, _ []cluster.ClientNode, _ int) error {
+	return c.cli.Close()
+}
+
+// Invoke implements the core.Client interface.
+// A CAS reads and writes the register in one optimistic transaction, so the commit fails with
+// a write conflict if the register is changed after the read.
+func (c *txnRegisterClient) Invoke(ctx context.Context, _ cluster.ClientNode, r interface{}) core.UnknownResponse {
+	req := r.(model.CasRegisterRequest)
+	txn, err := c.cli.Begin()
+	if err != nil {
+		return model.CasRegisterResponse{Unknown: true}
+	}
+	switch req.Op {
+	case model.CasRegisterRead:
+		value, exists, err := getRegister(ctx, txn)
+		_ = txn.Rollback()
+		if err != nil {
+			return model.CasRegisterResponse{Unknown: true}
+		}
+		return model.CasRegisterResponse{Exists: exists, Value: value}
+	case model.CasRegisterWrite:
+		if err := txn.Set(txnRegisterKey, []byte(strconv.Itoa(req.Arg1))); err != nil {
+			_ = txn.Rollback()
+			return model.CasRegisterResponse{Unknown: true}
+		}
+	case model.CasRegisterCAS:
+		value, exists, err := getRegister(ctx, txn)
+		if err != nil {
+			_ = txn.Rollback()
+			return model.CasRegisterResponse{Unknown: true}
+		}
+		if !exists || value != req.Arg1 {
+			_ = txn.Rollback()
+			return model.CasRegisterResponse{Ok: false}
+		}
+		if err := txn.Set(txnRegisterKey, []byte(strconv.Itoa(req.Arg2))); err != nil {
+			_ = txn.Rollback()
+			return model.CasRegisterResponse{Unknown: true}
+		}
+	default:
+		panic("unreachable")
+	}
+	// an aborted transaction has no effect, but the model can't tell it from a failed CAS
+	if err := txn.Commit(ctx); err != nil {
+		return model.CasRegisterResponse{Unknown: true}
+	}
+	return model.CasRegisterResponse{Ok: true}
+}
+
+// NextRequest implements the core.Client interface.
+func (c *txnRegisterClient) NextRequest() interface{} {
+	return nextRegisterRequest(c.r)
+}
+
+// DumpState implements the core.Client interface.
+func (c *txnRegisterClient) DumpState(ctx context.Context) (interface{}, error) {
+	txn, err := c.cli.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+	value, exists, err := getRegister(ctx, txn)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return -1, nil
+	}
+	return value, nil
+}
+
+func getRegister(ctx context.Context, txn *transaction.KVTxn) (int, bool, error) {
+	val, err := txn.Get(ctx, txnRegisterKey)
+	if tikverr.IsErrNotFound(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	v, err := strconv.Atoi(string(val))
+	if err != nil {
+		return 0, false, err
+	}
+	return v, true, nil
+}
+
+func nextRegisterRequest(r *rand.Rand) model.CasRegisterRequest {
+	switch r.Intn(3) {
+	case 0:
+		return model.CasRegisterRequest{Op: model.CasRegisterRead}
+	case 1:
+		return model.CasRegisterRequest{Op: model.CasRegisterWrite, Arg1: r.Intn(registerValueRange)}
+	default:
+		return model.CasRegisterRequest{Op: model.CasRegisterCAS, Arg1: r.Intn(registerValueRange), Arg2: r.Intn(registerValueRange)}
+	}
+}
+
+// TxnRegisterClientCreator creates clients which operate a CAS register by TxnKV
+type TxnRegisterClientCreator struct{}
+
+// Create creates a client.
+func (TxnRegisterClientCreator) Create(_ cluster.ClientNode) core.Client {
+	return &txnRegisterClient{}
+}
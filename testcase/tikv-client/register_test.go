@@ -0,0 +1,165 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikvclient
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/pingcap/tipocket/pkg/check/porcupine"
+	"github.com/pingcap/tipocket/pkg/history"
+	"github.com/pingcap/tipocket/pkg/model"
+)
+
+type action struct {
+	proc int64
+	op   interface{}
+}
+
+// recordHistory records the actions as a history file, the requests are the odd actions of a process
+func recordHistory(t *testing.T, dir string, state interface{}, actions []action) string {
+	name := path.Join(dir, "history.log")
+	r, err := history.NewRecorder(name)
+	if err != nil {
+		t.Fatalf("create recorder failed %v", err)
+	}
+	defer r.Close()
+	invoked := make(map[int64]bool)
+	for _, a := range actions {
+		if invoked[a.proc] {
+			err = r.RecordResponse(a.proc, a.op)
+		} else {
+			err = r.RecordRequest(a.proc, a.op)
+		}
+		if err != nil {
+			t.Fatalf("record %v failed %v", a.op, err)
+		}
+		invoked[a.proc] = !invoked[a.proc]
+	}
+	if state != nil {
+		if err = r.RecordState(state); err != nil {
+			t.Fatalf("record state failed %v", err)
+		}
+	}
+	return name
+}
+
+// inDir runs f in the dir, where the checkers dump their reports
+func inDir(t *testing.T, dir string, f func()) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	f()
+}
+
+// checkRegisterHistory verifies the history the same way as the register workloads
+func checkRegisterHistory(t *testing.T, state int, actions []action) bool {
+	tmpDir, err := ioutil.TempDir("", "register")
+	if err != nil {
+		t.Fatalf("create temp dir failed %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	ops, s, err := history.ReadHistory(recordHistory(t, tmpDir, state, actions), model.CasRegisterParser())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != len(actions) {
+		t.Fatalf("expect %d operations, got %d", len(actions), len(ops))
+	}
+	if ops, err = history.CompleteOperations(ops, model.CasRegisterParser()); err != nil {
+		t.Fatal(err)
+	}
+	m := model.CasRegisterModel()
+	m.Prepare(s)
+	var ok bool
+	inDir(t, tmpDir, func() {
+		ok, err = porcupine.Checker{}.Check(m, ops)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ok
+}
+
+func TestNextRegisterRequest(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	ops := make(map[model.CasOp]bool)
+	for i := 0; i < 100; i++ {
+		req := nextRegisterRequest(r)
+		ops[req.Op] = true
+		if req.Arg1 < 0 || req.Arg1 >= registerValueRange || req.Arg2 < 0 || req.Arg2 >= registerValueRange {
+			t.Fatalf("request %+v out of range", req)
+		}
+		if req.Op == model.CasRegisterRead && (req.Arg1 != 0 || req.Arg2 != 0) {
+			t.Fatalf("read %+v has arguments", req)
+		}
+	}
+	if len(ops) != 3 {
+		t.Fatalf("expect read, write and cas requests, got %v", ops)
+	}
+}
+
+func TestTxnRegisterHistory(t *testing.T) {
+	read := model.CasRegisterRequest{Op: model.CasRegisterRead}
+	write := func(v int) model.CasRegisterRequest {
+		return model.CasRegisterRequest{Op: model.CasRegisterWrite, Arg1: v}
+	}
+	cas := func(from, to int) model.CasRegisterRequest {
+		return model.CasRegisterRequest{Op: model.CasRegisterCAS, Arg1: from, Arg2: to}
+	}
+	for _, cs := range []struct {
+		name    string
+		actions []action
+		ok      bool
+	}{
+		{"sequential", []action{
+			{1, read}, {1, model.CasRegisterResponse{Exists: true, Value: 0}},
+			{2, write(3)}, {2, model.CasRegisterResponse{Ok: true}},
+			{1, cas(3, 4)}, {1, model.CasRegisterResponse{Ok: true}},
+			{2, cas(3, 1)}, {2, model.CasRegisterResponse{Ok: false}},
+			{3, read}, {3, model.CasRegisterResponse{Exists: true, Value: 4}},
+		}, true},
+		{"concurrent", []action{
+			{1, write(2)},
+			{2, read},
+			{2, model.CasRegisterResponse{Exists: true, Value: 2}},
+			{1, model.CasRegisterResponse{Ok: true}},
+		}, true},
+		{"aborted commit", []action{
+			{1, cas(0, 1)}, {1, model.CasRegisterResponse{Unknown: true}},
+			{2, read}, {2, model.CasRegisterResponse{Exists: true, Value: 0}},
+		}, true},
+		{"stale read", []action{
+			{1, write(3)}, {1, model.CasRegisterResponse{Ok: true}},
+			{2, read}, {2, model.CasRegisterResponse{Exists: true, Value: 0}},
+		}, false},
+		{"lost cas", []action{
+			{1, cas(0, 1)}, {1, model.CasRegisterResponse{Ok: true}},
+			{2, cas(0, 2)}, {2, model.CasRegisterResponse{Ok: true}},
+		}, false},
+	} {
+		if ok := checkRegisterHistory(t, 0, cs.actions); ok != cs.ok {
+			t.Errorf("%s: expect %t, got %t", cs.name, cs.ok, ok)
+		}
+	}
+}
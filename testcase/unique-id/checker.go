@@ -0,0 +1,211 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testcase
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ngaut/log"
+
+	"github.com/pingcap/tipocket/pkg/core"
+)
+
+// maxReportedViolations limits the violations in the check error
+const maxReportedViolations = 10
+
+// allocation is an ok insert with its real time interval
+type allocation struct {
+	kind    string
+	id      int64
+	session string
+	start   time.Time
+	end     time.Time
+}
+
+func (a *allocation) String() string {
+	return fmt.Sprintf("%s id %d by %s in [%s, %s]", a.kind, a.id, a.session,
+		a.start.Format(time.RFC3339Nano), a.end.Format(time.RFC3339Nano))
+}
+
+// event is a nemesis or a pod restart, which may make TiDB or PD lose the allocated ids
+type event struct {
+	name string
+	time time.Time
+}
+
+type checker struct{}
+
+// Checker checks the ids are globally unique and never reused, monotonic in each session except AUTO_RANDOM,
+// and monotonic in real time for AUTO_ID_CACHE 1
+func Checker() core.Checker {
+	return checker{}
+}
+
+// Name implements core.Checker
+func (checker) Name() string {
+	return "unique_id_checker"
+}
+
+// Check implements core.Checker
+func (checker) Check(_ core.Model, ops []core.Operation) (bool, error) {
+	allocs, events, violations, err := parseOps(ops)
+	if err != nil {
+		return false, err
+	}
+	byKind := make(map[string][]*allocation)
+	for _, a := range allocs {
+		byKind[a.kind] = append(byKind[a.kind], a)
+	}
+	for _, kind := range Kinds {
+		as := byKind[kind]
+		violations = append(violations, checkUnique(as, events)...)
+		if kind != AutoRandom {
+			violations = append(violations, checkSessionMonotonic(as)...)
+		}
+		if kind == AutoIDCache1 {
+			violations = append(violations, checkRealTimeMonotonic(as)...)
+		}
+		log.Infof("[unique id] checked %d ids of %s", len(as), kind)
+	}
+	if len(violations) == 0 {
+		return true, nil
+	}
+	for _, v := range violations {
+		log.Errorf("[unique id] %s", v)
+	}
+	if len(violations) > maxReportedViolations {
+		violations = violations[:maxReportedViolations]
+	}
+	return false, fmt.Errorf("id allocation violations:\n%s", strings.Join(violations, "\n"))
+}
+
+// checkUnique checks no id is allocated twice, the nemesis between them are reported
+func checkUnique(allocs []*allocation, events []event) []string {
+	var violations []string
+	seen := make(map[int64]*allocation, len(allocs))
+	for _, a := range allocs {
+		prev, ok := seen[a.id]
+		if !ok {
+			seen[a.id] = a
+			continue
+		}
+		first, second := prev, a
+		if second.start.Before(first.start) {
+			first, second = second, first
+		}
+		violations = append(violations, fmt.Sprintf("%s is reused by %s, events between: %s",
+			first, second, eventsBetween(events, first.start, second.end)))
+	}
+	return violations
+}
+
+// checkSessionMonotonic checks the ids are increasing in each session
+func checkSessionMonotonic(allocs []*allocation) []string {
+	var violations []string
+	sessions := make(map[string][]*allocation)
+	for _, a := range allocs {
+		sessions[a.session] = append(sessions[a.session], a)
+	}
+	for _, as := range sessions {
+		sort.Slice(as, func(i, j int) bool { return as[i].start.Before(as[j].start) })
+		for i := 1; i < len(as); i++ {
+			if as[i].id <= as[i-1].id {
+				violations = append(violations, fmt.Sprintf("%s is not greater than %s in the same session", as[i], as[i-1]))
+			}
+		}
+	}
+	return violations
+}
+
+// checkRealTimeMonotonic checks an id is greater than all the ids allocated before its insert began
+func checkRealTimeMonotonic(allocs []*allocation) []string {
+	var violations []string
+	byStart := append([]*allocation(nil), allocs...)
+	sort.Slice(byStart, func(i, j int) bool { return byStart[i].start.Before(byStart[j].start) })
+	byEnd := append([]*allocation(nil), allocs...)
+	sort.Slice(byEnd, func(i, j int) bool { return byEnd[i].end.Before(byEnd[j].end) })
+
+	var (
+		max *allocation
+		i   int
+	)
+	for _, a := range byStart {
+		for ; i < len(byEnd) && byEnd[i].end.Before(a.start); i++ {
+			if max == nil || byEnd[i].id > max.id {
+				max = byEnd[i]
+			}
+		}
+		if max != nil && a.id <= max.id {
+			violations = append(violations, fmt.Sprintf("%s is not greater than %s which was allocated before", a, max))
+		}
+	}
+	return violations
+}
+
+func eventsBetween(events []event, start, end time.Time) string {
+	var names []string
+	for _, e := range events {
+		if !e.time.Before(start) && !e.time.After(end) {
+			names = append(names, fmt.Sprintf("%s at %s", e.name, e.time.Format(time.RFC3339Nano)))
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// parseOps pairs the requests and responses of each process, it returns the ok allocations,
+// the nemesis events and the inserts failed by duplicate entries
+func parseOps(ops []core.Operation) ([]*allocation, []event, []string, error) {
+	var (
+		allocs     []*allocation
+		events     []event
+		violations []string
+	)
+	pending := make(map[int64]core.Operation)
+	for _, op := range ops {
+		switch op.Action {
+		case core.InvokeOperation:
+			pending[op.Proc] = op
+		case core.ReturnOperation:
+			invoke, ok := pending[op.Proc]
+			if !ok {
+				return nil, nil, nil, fmt.Errorf("no request for the response of process %d", op.Proc)
+			}
+			delete(pending, op.Proc)
+			req := invoke.Data.(request)
+			res := op.Data.(response)
+			if res.Duplicate {
+				violations = append(violations, fmt.Sprintf("%s insert by %s at %s allocated a used id: %s",
+					req.Kind, res.Session, op.Time.Format(time.RFC3339Nano), res.Error))
+			}
+			if res.Ok {
+				allocs = append(allocs, &allocation{kind: req.Kind, id: res.ID, session: res.Session, start: invoke.Time, end: op.Time})
+			}
+		case core.InvokeNemesis:
+			if record, ok := op.Data.(core.NemesisGeneratorRecord); ok {
+				events = append(events, event{name: "nemesis " + record.Name, time: op.Time})
+			}
+		case core.PodRestart:
+			if record, ok := op.Data.(core.PodRestartRecord); ok {
+				events = append(events, event{name: fmt.Sprintf("restart of %s %s", record.Component, record.Pod), time: record.Time})
+			}
+		}
+	}
+	return allocs, events, violations, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testcase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pingcap/tipocket/pkg/core"
+)
+
+func TestChecker(t *testing.T) {
+	now := time.Now()
+	at := func(ms int) time.Time {
+		return now.Add(time.Duration(ms) * time.Millisecond)
+	}
+	insert := func(proc int64, kind, session string, id int64, start, end int) []core.Operation {
+		return []core.Operation{
+			{Action: core.InvokeOperation, Proc: proc, Time: at(start), Data: request{Kind: kind}},
+			{Action: core.ReturnOperation, Proc: proc, Time: at(end), Data: response{Ok: true, Session: session, ID: id}},
+		}
+	}
+	concat := func(groups ...[]core.Operation) []core.Operation {
+		var ops []core.Operation
+		for _, g := range groups {
+			ops = append(ops, g...)
+		}
+		return ops
+	}
+	for i, c := range []struct {
+		ops []core.Operation
+		ok  bool
+	}{
+		{concat(insert(1, AutoIncrement, "a", 1, 0, 10), insert(1, AutoIncrement, "a", 30001, 20, 30), insert(2, AutoIncrement, "b", 2, 25, 35)), true},
+		// the default cache is not monotonic across sessions
+		{concat(insert(1, AutoIncrement, "a", 30001, 0, 10), insert(2, AutoIncrement, "b", 2, 20, 30)), true},
+		{concat(insert(1, AutoIncrement, "a", 5, 0, 10), insert(1, AutoIncrement, "a", 3, 20, 30)), false},
+		{concat(insert(1, AutoIncrement, "a", 5, 0, 10), insert(2, AutoIncrement, "b", 5, 20, 30)), false},
+		{concat(insert(1, AutoIDCache1, "a", 5, 0, 10), insert(2, AutoIDCache1, "b", 3, 20, 30)), false},
+		// concurrent inserts can be in any order
+		{concat(insert(1, AutoIDCache1, "a", 5, 0, 20), insert(2, AutoIDCache1, "b", 3, 10, 30)), true},
+		{concat(insert(1, AutoRandom, "a", 5, 0, 10), insert(1, AutoRandom, "a", 3, 20, 30)), true},
+		{concat(insert(1, Sequence, "a", 5, 0, 10), insert(1, Sequence, "a", 3, 20, 30)), false},
+		{[]core.Operation{
+			{Action: core.InvokeOperation, Proc: 1, Time: at(0), Data: request{Kind: Sequence}},
+			{Action: core.ReturnOperation, Proc: 1, Time: at(10), Data: response{Duplicate: true, Session: "a", Error: "Duplicate entry"}},
+		}, false},
+	} {
+		ok, err := Checker().Check(nil, c.ops)
+		if ok != c.ok {
+			t.Fatalf("case %d: expect %t, got %t, %v", i, c.ok, ok, err)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testcase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/ngaut/log"
+
+	"github.com/pingcap/tipocket/pkg/cluster"
+	"github.com/pingcap/tipocket/pkg/core"
+	"github.com/pingcap/tipocket/pkg/history"
+	"github.com/pingcap/tipocket/pkg/test-infra/fixture"
+	"github.com/pingcap/tipocket/util"
+)
+
+// The ways to allocate the ids
+const (
+	// AutoIncrement is AUTO_INCREMENT with the default id cache
+	AutoIncrement = "auto-increment"
+	// AutoIDCache1 is AUTO_INCREMENT with AUTO_ID_CACHE 1, the ids are allocated by a central service
+	AutoIDCache1 = "auto-id-cache-1"
+	// AutoRandom is AUTO_RANDOM
+	AutoRandom = "auto-random"
+	// Sequence is NEXTVAL of a sequence
+	Sequence = "sequence"
+)
+
+// Kinds are all the ways to allocate the ids
+var Kinds = []string{AutoIncrement, AutoIDCache1, AutoRandom, Sequence}
+
+type kindDef struct {
+	table string
+	// stmts create the table
+	stmts []string
+	// insert inserts a row with the session, query returns the id inserted in the session
+	insert string
+	query  string
+}
+
+var kindDefs = map[string]kindDef{
+	AutoIncrement: {
+		table:  "uid_auto_increment",
+		stmts:  []string{"CREATE TABLE uid_auto_increment (id BIGINT AUTO_INCREMENT PRIMARY KEY, session VARCHAR(64) NOT NULL)"},
+		insert: "INSERT INTO uid_auto_increment (session) VALUES (?)",
+		query:  "SELECT LAST_INSERT_ID()",
+	},
+	AutoIDCache1: {
+		table:  "uid_auto_id_cache_1",
+		stmts:  []string{"CREATE TABLE uid_auto_id_cache_1 (id BIGINT AUTO_INCREMENT PRIMARY KEY, session VARCHAR(64) NOT NULL) AUTO_ID_CACHE 1"},
+		insert: "INSERT INTO uid_auto_id_cache_1 (session) VALUES (?)",
+		query:  "SELECT LAST_INSERT_ID()",
+	},
+	AutoRandom: {
+		table:  "uid_auto_random",
+		stmts:  []string{"CREATE TABLE uid_auto_random (id BIGINT AUTO_RANDOM, session VARCHAR(64) NOT NULL, PRIMARY KEY (id) /*T![clustered_index] CLUSTERED */)"},
+		insert: "INSERT INTO uid_auto_random (session) VALUES (?)",
+		query:  "SELECT LAST_INSERT_ID()",
+	},
+	Sequence: {
+		table: "uid_sequence",
+		stmts: []string{
+			"CREATE SEQUENCE uid_seq",
+			"CREATE TABLE uid_sequence (id BIGINT PRIMARY KEY, session VARCHAR(64) NOT NULL)",
+		},
+		insert: "INSERT INTO uid_sequence (id, session) VALUES (NEXTVAL(uid_seq), ?)",
+		query:  "SELECT LASTVAL(uid_seq)",
+	},
+}
+
+type request struct {
+	Kind string `json:"kind"`
+}
+
+type response struct {
+	Ok      bool   `json:"ok"`
+	Unknown bool   `json:"unknown"`
+	Error   string `json:"error,omitempty"`
+	// Duplicate means the insert failed since the allocated id is used
+	Duplicate bool  `json:"duplicate,omitempty"`
+	ID        int64 `json:"id,omitempty"`
+	// Session identifies the connection, a reconnected client has a new session
+	Session string `json:"session"`
+}
+
+// IsUnknown implements core.UnknownResponse
+func (r response) IsUnknown() bool {
+	return r.Unknown
+}
+
+func (r response) String() string {
+	if !r.Ok {
+		return fmt.Sprintf("ok: %t, unknown: %t, duplicate: %t, session: %s, error: %s", r.Ok, r.Unknown, r.Duplicate, r.Session, r.Error)
+	}
+	return fmt.Sprintf("ok: true, session: %s, id: %d", r.Session, r.ID)
+}
+
+// CaseCreator is a creator of test client
+type CaseCreator struct {
+	// Kinds are the ways to allocate the ids, chosen randomly for each request
+	Kinds []string
+}
+
+// Create creates a test client
+func (c CaseCreator) Create(node cluster.ClientNode) core.Client {
+	return &Client{
+		kinds: c.Kinds,
+		r:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Client inserts rows with the ids allocated by TiDB and records the ids
+type Client struct {
+	kinds []string
+	r     *rand.Rand
+	db    *sql.DB
+	// conn keeps the session, LAST_INSERT_ID and LASTVAL are per session
+	conn    *sql.Conn
+	idx     int
+	session int
+}
+
+// SetUp implements the core.Client interface.
+func (c *Client) SetUp(ctx context.Context, _ []cluster.Node, clientNodes []cluster.ClientNode, idx int) error {
+	node := clientNodes[idx]
+	dsn := fmt.Sprintf("root@tcp(%s:%d)/test", node.IP, node.Port)
+	util.SetMySQLProxy(fixture.Context.MySQLProxy)
+	db, err := util.OpenDB(dsn, 1)
+	if err != nil {
+		return err
+	}
+	c.db = db
+	c.idx = idx
+	if c.conn, err = db.Conn(ctx); err != nil {
+		return err
+	}
+
+	if idx != 0 {
+		return nil
+	}
+	log.Infof("begin to create tables for %v on node %s", c.kinds, node)
+	for _, kind := range c.kinds {
+		def := kindDefs[kind]
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", def.table)); err != nil {
+			return err
+		}
+		if kind == Sequence {
+			if _, err := db.ExecContext(ctx, "DROP SEQUENCE IF EXISTS uid_seq"); err != nil {
+				return err
+			}
+		}
+		for _, stmt := range def.stmts {
+			if _, err := db.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// TearDown implements the core.Client interface.
+func (c *Client) TearDown(ctx context.Context, nodes []cluster.ClientNode, idx int) error {
+	c.conn.Close()
+	return c.db.Close()
+}
+
+// Invoke implements the core.OnScheduleClientExtensions interface.
+func (c *Client) Invoke(ctx context.Context, node cluster.ClientNode, r interface{}) core.UnknownResponse {
+	req := r.(request)
+	def := kindDefs[req.Kind]
+	session := c.sessionName()
+	if _, err := c.conn.ExecContext(ctx, def.insert, session); err != nil {
+		res := response{Session: session, Error: err.Error()}
+		switch {
+		case util.IsErrDupEntry(err):
+			res.Duplicate = true
+		case isBadConn(err):
+			res.Unknown = true
+			c.reconnect(ctx)
+		}
+		return res
+	}
+	var id int64
+	if err := c.conn.QueryRowContext(ctx, def.query).Scan(&id); err != nil {
+		// the row is inserted, but the id is unknown
+		if isBadConn(err) {
+			c.reconnect(ctx)
+		}
+		return response{Session: session, Unknown: true, Error: err.Error()}
+	}
+	return response{Ok: true, Session: session, ID: id}
+}
+
+// NextRequest implements the core.OnScheduleClientExtensions interface.
+func (c *Client) NextRequest() interface{} {
+	return request{Kind: c.kinds[c.r.Intn(len(c.kinds))]}
+}
+
+// DumpState implements the core.OnScheduleClientExtensions interface.
+func (c *Client) DumpState(ctx context.Context) (interface{}, error) {
+	return nil, nil
+}
+
+func (c *Client) sessionName() string {
+	return fmt.Sprintf("client-%d-session-%d", c.idx, c.session)
+}
+
+// reconnect starts a new session
+func (c *Client) reconnect(ctx context.Context) {
+	c.conn.Close()
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		log.Fatalf("Failed to establish connection, error: %v", err)
+	}
+	c.conn = conn
+	c.session++
+}
+
+func isBadConn(err error) bool {
+	msg := err.Error()
+	return err == sql.ErrConnDone || strings.Contains(msg, "bad connection") || strings.Contains(msg, "invalid connection")
+}
+
+type parser struct{}
+
+// OnRequest implements history.RecordParser
+func (parser) OnRequest(data json.RawMessage) (interface{}, error) {
+	r := request{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+// OnResponse implements history.RecordParser
+func (parser) OnResponse(data json.RawMessage) (interface{}, error) {
+	r := response{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+// OnNoopResponse implements history.RecordParser
+func (parser) OnNoopResponse() interface{} {
+	return response{Unknown: true}
+}
+
+// OnState implements history.RecordParser
+func (parser) OnState(state json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+// Parser parses a history of unique id test
+func Parser() history.RecordParser {
+	return parser{}
+}
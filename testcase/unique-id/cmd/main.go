@@ -0,0 +1,83 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"strings"
+
+	// use mysql
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/ngaut/log"
+
+	"github.com/pingcap/tipocket/cmd/util"
+	logs "github.com/pingcap/tipocket/logsearch/pkg/logs"
+	"github.com/pingcap/tipocket/pkg/cluster"
+	"github.com/pingcap/tipocket/pkg/control"
+	test_infra "github.com/pingcap/tipocket/pkg/test-infra"
+	"github.com/pingcap/tipocket/pkg/test-infra/fixture"
+	"github.com/pingcap/tipocket/pkg/verify"
+
+	testcase "github.com/pingcap/tipocket/testcase/unique-id"
+)
+
+var (
+	kinds = flag.String("kinds", strings.Join(testcase.Kinds, ","),
+		"the ways to allocate ids separated by comma, any of 'auto-increment', 'auto-id-cache-1', 'auto-random' and 'sequence'")
+)
+
+func main() {
+	flag.Parse()
+	var selected []string
+	for _, kind := range strings.Split(*kinds, ",") {
+		if kind = strings.TrimSpace(kind); kind == "" {
+			continue
+		}
+		valid := false
+		for _, k := range testcase.Kinds {
+			valid = valid || k == kind
+		}
+		if !valid {
+			log.Fatalf("unknown kind %s", kind)
+		}
+		selected = append(selected, kind)
+	}
+	if len(selected) == 0 {
+		log.Fatal("no kind is selected")
+	}
+
+	c := fixture.Context
+	suit := util.Suit{
+		Config: &control.Config{
+			Mode:         control.ModeOnSchedule,
+			ClientCount:  c.ClientCount,
+			RequestCount: c.RequestCount,
+			RunRound:     c.RunRound,
+			RunTime:      c.RunTime,
+			History:      c.HistoryFile,
+		},
+		Provider:         cluster.NewDefaultClusterProvider(),
+		ClientCreator:    testcase.CaseCreator{Kinds: selected},
+		NemesisGens:      util.ParseNemesisGenerators(c.Nemesis),
+		ClientRequestGen: util.OnClientLoop,
+		VerifySuit: verify.Suit{
+			Checker: testcase.Checker(),
+			Parser:  testcase.Parser(),
+		},
+		ClusterDefs: test_infra.NewDefaultCluster(c.Namespace, c.ClusterName, c.TiDBClusterConfig),
+		LogsClient:  logs.NewDiagnosticLogClient(),
+	}
+	suit.Run(context.Background())
+}
@@ -0,0 +1,94 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"strings"
+	"time"
+
+	// use mysql
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/ngaut/log"
+
+	"github.com/pingcap/tipocket/cmd/util"
+	logs "github.com/pingcap/tipocket/logsearch/pkg/logs"
+	"github.com/pingcap/tipocket/pkg/cluster"
+	"github.com/pingcap/tipocket/pkg/control"
+	test_infra "github.com/pingcap/tipocket/pkg/test-infra"
+	"github.com/pingcap/tipocket/pkg/test-infra/fixture"
+
+	testcase "github.com/pingcap/tipocket/testcase/workload"
+)
+
+var (
+	workloadName = flag.String("workload", "bank", "workload name, any of "+strings.Join(testcase.Names(), ","))
+	checkerNames = flag.String("checkers", "", "checker names separated by comma, eg. porcupine,admin_check, the workload's default checkers if empty")
+	// list-append and rw-register
+	tableCount = flag.Int("table-count", 7, "Table count of list-append and rw-register")
+	readLock   = flag.String("read-lock", "FOR UPDATE", "Maybe empty or 'FOR UPDATE'")
+	txnMode    = flag.String("txn-mode", "pessimistic", "Must be 'pessimistic', 'optimistic' or 'mixed'")
+	// tpcc
+	warehouses = flag.Int("warehouses", 10, "tpcc warehouses")
+	ticker     = flag.Duration("ticker", time.Second, "ticker control request emitting freq of tpcc")
+	qosFile    = flag.String("qos-file", "./qos.log", "qos file of tpcc")
+)
+
+func main() {
+	flag.Parse()
+	workload, ok := testcase.Lookup(*workloadName)
+	if !ok {
+		log.Fatalf("invalid workload %s, the available workloads are %s", *workloadName, strings.Join(testcase.Names(), ","))
+	}
+	var names []string
+	for _, name := range strings.Split(*checkerNames, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	c := fixture.Context
+	cfg := control.Config{
+		ClientCount:  c.ClientCount,
+		RequestCount: c.RequestCount,
+		RunRound:     c.RunRound,
+		RunTime:      c.RunTime,
+		History:      c.HistoryFile,
+	}
+	opt := testcase.Options{
+		TableCount:  *tableCount,
+		ReadLock:    *readLock,
+		TxnMode:     *txnMode,
+		ReplicaRead: c.ReplicaRead,
+		Warehouses:  *warehouses,
+		Ticker:      *ticker,
+		QoSFile:     *qosFile,
+	}
+	creator, requestGen, verifySuit, err := workload.Build(&cfg, names, &opt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	suit := util.Suit{
+		Config:           &cfg,
+		Provider:         cluster.NewDefaultClusterProvider(),
+		ClientCreator:    creator,
+		NemesisGens:      workload.NemesisGens(util.ParseNemesisGenerators(c.Nemesis)),
+		ClientRequestGen: requestGen,
+		VerifySuit:       verifySuit,
+		ClusterDefs:      test_infra.NewDefaultCluster(c.Namespace, c.ClusterName, c.TiDBClusterConfig),
+		LogsClient:       logs.NewDiagnosticLogClient(),
+	}
+	suit.Run(context.Background())
+}
@@ -0,0 +1,167 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testcase
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pingcap/tipocket/cmd/util"
+	adminChecker "github.com/pingcap/tipocket/pkg/check/admin-check"
+	"github.com/pingcap/tipocket/pkg/check/porcupine"
+	"github.com/pingcap/tipocket/pkg/control"
+	"github.com/pingcap/tipocket/pkg/core"
+	"github.com/pingcap/tipocket/pkg/history"
+	"github.com/pingcap/tipocket/pkg/verify"
+)
+
+// Options are the options of the workloads, each workload uses the ones it needs
+type Options struct {
+	// TableCount, ReadLock, TxnMode and ReplicaRead are for list-append and rw-register
+	TableCount  int
+	ReadLock    string
+	TxnMode     string
+	ReplicaRead string
+	// Warehouses, Ticker and QoSFile are for tpcc
+	Warehouses int
+	Ticker     time.Duration
+	QoSFile    string
+}
+
+// CheckerFactory creates a checker, the control config and the client creator are for the checkers which need them
+type CheckerFactory func(cfg *control.Config, creator core.ClientCreator, opt *Options) core.Checker
+
+// Workload is a SQL workload which can run with any nemesis
+type Workload struct {
+	Name string
+	// NewCreator creates the client creator
+	NewCreator func(opt *Options) core.ClientCreator
+	Parser     history.RecordParser
+	// Model is for porcupine, nil means the workload can't be checked by porcupine
+	Model core.Model
+	// Checkers are the checkers of this workload by name, besides the common ones
+	Checkers        map[string]CheckerFactory
+	DefaultCheckers []string
+	// Mode is the control mode, ModeOnSchedule by default
+	Mode control.Mode
+	// NewRequestGen creates the request loop of the clients, util.OnClientLoop if it's nil
+	NewRequestGen func(opt *Options) util.ClientLoopFunc
+	// NemesisDelay delays the nemesis for warming up
+	NemesisDelay time.Duration
+}
+
+var workloads = make(map[string]*Workload)
+
+// commonCheckers can check any workload
+var commonCheckers = map[string]CheckerFactory{
+	"admin_check": func(cfg *control.Config, _ core.ClientCreator, _ *Options) core.Checker {
+		return adminChecker.AdminChecker(cfg)
+	},
+}
+
+// Register registers a workload, it panics if the name is registered
+func Register(w *Workload) {
+	if _, ok := workloads[w.Name]; ok {
+		panic(fmt.Sprintf("workload %s is registered twice", w.Name))
+	}
+	workloads[w.Name] = w
+}
+
+// Lookup returns the registered workload by name
+func Lookup(name string) (*Workload, bool) {
+	w, ok := workloads[name]
+	return w, ok
+}
+
+// Names returns the names of all the registered workloads in order
+func Names() []string {
+	var names []string
+	for name := range workloads {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckerNames returns the names of the checkers which can check the workload in order
+func (w *Workload) CheckerNames() []string {
+	var names []string
+	for name := range w.Checkers {
+		names = append(names, name)
+	}
+	for name := range commonCheckers {
+		names = append(names, name)
+	}
+	if w.Model != nil {
+		names = append(names, "porcupine")
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (w *Workload) checker(name string, cfg *control.Config, creator core.ClientCreator, opt *Options) (core.Checker, error) {
+	if f, ok := w.Checkers[name]; ok {
+		return f(cfg, creator, opt), nil
+	}
+	if f, ok := commonCheckers[name]; ok {
+		return f(cfg, creator, opt), nil
+	}
+	if name == "porcupine" && w.Model != nil {
+		return porcupine.Checker{}, nil
+	}
+	return nil, fmt.Errorf("checker %s can't check workload %s, the available checkers are %s",
+		name, w.Name, strings.Join(w.CheckerNames(), ","))
+}
+
+// Build creates the client creator, the request loop and the verify suit of the workload,
+// the checkers are composed by core.MultiChecker, empty checkers means the default ones
+func (w *Workload) Build(cfg *control.Config, checkerNames []string, opt *Options) (core.ClientCreator, util.ClientLoopFunc, verify.Suit, error) {
+	if len(checkerNames) == 0 {
+		checkerNames = w.DefaultCheckers
+	}
+	cfg.Mode = w.Mode
+	creator := w.NewCreator(opt)
+	var checkers []core.Checker
+	for _, name := range checkerNames {
+		checker, err := w.checker(name, cfg, creator, opt)
+		if err != nil {
+			return nil, nil, verify.Suit{}, err
+		}
+		checkers = append(checkers, checker)
+	}
+	requestGen := util.OnClientLoop
+	if w.NewRequestGen != nil {
+		requestGen = w.NewRequestGen(opt)
+	}
+	suit := verify.Suit{
+		Model:   w.Model,
+		Checker: core.MultiChecker(w.Name+" checkers", checkers...),
+		Parser:  w.Parser,
+	}
+	return creator, requestGen, suit, nil
+}
+
+// NemesisGens wraps the nemesis generators with the delay of the workload
+func (w *Workload) NemesisGens(gens []core.NemesisGenerator) []core.NemesisGenerator {
+	if w.NemesisDelay == 0 {
+		return gens
+	}
+	delayed := make([]core.NemesisGenerator, 0, len(gens))
+	for _, gen := range gens {
+		delayed = append(delayed, core.DelayNemesisGenerator{Gen: gen, Delay: w.NemesisDelay})
+	}
+	return delayed
+}
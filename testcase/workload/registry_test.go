@@ -0,0 +1,49 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testcase
+
+import (
+	"testing"
+
+	"github.com/pingcap/tipocket/pkg/control"
+)
+
+func TestBuild(t *testing.T) {
+	for _, name := range []string{"bank", "multi_bank", "long_fork", "tpcc", "list-append", "rw-register"} {
+		w, ok := Lookup(name)
+		if !ok {
+			t.Fatalf("workload %s isn't registered", name)
+		}
+		opt := &Options{TableCount: 7, Warehouses: 1}
+		// every checker of the workload can be composed
+		cfg := &control.Config{}
+		_, _, suit, err := w.Build(cfg, w.CheckerNames(), opt)
+		if err != nil {
+			t.Fatalf("build %s: %v", name, err)
+		}
+		if suit.Checker == nil || suit.Parser == nil {
+			t.Fatalf("workload %s has no checker or parser", name)
+		}
+		if cfg.Mode != w.Mode {
+			t.Fatalf("workload %s: expect mode %d, got %d", name, w.Mode, cfg.Mode)
+		}
+		if _, _, _, err := w.Build(&control.Config{}, nil, opt); err != nil {
+			t.Fatalf("build %s with the default checkers: %v", name, err)
+		}
+	}
+	w, _ := Lookup("long_fork")
+	if _, _, _, err := w.Build(&control.Config{}, []string{"porcupine"}, &Options{}); err == nil {
+		t.Fatal("long_fork has no model for porcupine")
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testcase
+
+import (
+	"time"
+
+	"github.com/pingcap/go-tpc/tpcc"
+
+	"github.com/pingcap/tipocket/cmd/util"
+	"github.com/pingcap/tipocket/db/tidb"
+	"github.com/pingcap/tipocket/pkg/control"
+	"github.com/pingcap/tipocket/pkg/core"
+	listappend "github.com/pingcap/tipocket/testcase/list-append"
+	rwregister "github.com/pingcap/tipocket/testcase/rw-register"
+)
+
+func init() {
+	bankCheckers := map[string]CheckerFactory{
+		"tidb_bank_tso": func(*control.Config, core.ClientCreator, *Options) core.Checker {
+			return tidb.BankTsoChecker()
+		},
+	}
+	Register(&Workload{
+		Name: "bank",
+		NewCreator: func(*Options) core.ClientCreator {
+			return tidb.BankClientCreator{}
+		},
+		Parser:          tidb.BankParser(),
+		Model:           tidb.BankModel(),
+		Checkers:        bankCheckers,
+		DefaultCheckers: []string{"porcupine"},
+	})
+	Register(&Workload{
+		Name: "multi_bank",
+		NewCreator: func(*Options) core.ClientCreator {
+			return tidb.MultiBankClientCreator{}
+		},
+		Parser:          tidb.BankParser(),
+		Model:           tidb.BankModel(),
+		Checkers:        bankCheckers,
+		DefaultCheckers: []string{"porcupine"},
+	})
+	Register(&Workload{
+		Name: "long_fork",
+		NewCreator: func(*Options) core.ClientCreator {
+			return tidb.LongForkClientCreator{}
+		},
+		Parser: tidb.LongForkParser(),
+		Checkers: map[string]CheckerFactory{
+			"long_fork_checker": func(*control.Config, core.ClientCreator, *Options) core.Checker {
+				return tidb.LongForkChecker()
+			},
+		},
+		DefaultCheckers: []string{"long_fork_checker"},
+	})
+	Register(&Workload{
+		Name: "tpcc",
+		NewCreator: func(opt *Options) core.ClientCreator {
+			return &tidb.TPCCClientCreator{
+				Config: &tpcc.Config{
+					// Must 1 here, we use `ClientCount` clients to launch `ClientCount` terminals
+					Threads:    1,
+					Warehouses: opt.Warehouses,
+					Parts:      1,
+				},
+			}
+		},
+		Parser: tidb.TPCCParser(),
+		Checkers: map[string]CheckerFactory{
+			"tpcc_consistency": func(_ *control.Config, creator core.ClientCreator, _ *Options) core.Checker {
+				return &tidb.TPCCChecker{CreatorRef: creator.(*tidb.TPCCClientCreator)}
+			},
+			"tpcc_qos": func(_ *control.Config, _ core.ClientCreator, opt *Options) core.Checker {
+				return tidb.TPCCQosChecker(time.Minute, opt.QoSFile)
+			},
+		},
+		DefaultCheckers: []string{"tpcc_consistency"},
+		Mode:            control.ModeNemesisSequential,
+		NewRequestGen: func(opt *Options) util.ClientLoopFunc {
+			return util.BuildClientLoopThrottle(opt.Ticker)
+		},
+		NemesisDelay: 2 * time.Minute,
+	})
+	Register(&Workload{
+		Name: "list-append",
+		NewCreator: func(opt *Options) core.ClientCreator {
+			return listappend.NewClientCreator(opt.TableCount, opt.ReadLock, opt.TxnMode, opt.ReplicaRead)
+		},
+		Parser: listappend.AppendParser{},
+		Checkers: map[string]CheckerFactory{
+			"append_checker": func(*control.Config, core.ClientCreator, *Options) core.Checker {
+				return listappend.AppendChecker{}
+			},
+		},
+		DefaultCheckers: []string{"append_checker"},
+	})
+	Register(&Workload{
+		Name: "rw-register",
+		NewCreator: func(opt *Options) core.ClientCreator {
+			return rwregister.NewClientCreator(opt.TableCount, opt.ReadLock, opt.TxnMode, opt.ReplicaRead)
+		},
+		Parser: rwregister.RegisterParser{},
+		Checkers: map[string]CheckerFactory{
+			"register_checker": func(*control.Config, core.ClientCreator, *Options) core.Checker {
+				return rwregister.RegisterChecker{}
+			},
+		},
+		DefaultCheckers: []string{"register_checker"},
+	})
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/ngaut/log"
 	"github.com/pingcap/errors"
 
+	syncdiff "github.com/pingcap/tipocket/pkg/check/sync-diff"
 	"github.com/pingcap/tipocket/pkg/cluster"
 	"github.com/pingcap/tipocket/pkg/core"
 	"github.com/pingcap/tipocket/util"
@@ -74,6 +75,9 @@ var stmtsCreate = []string{
 	`TRUNCATE TABLE transaction_leg;`,
 }
 
+// tables are checked after restore
+var tables = []string{"accounts", "transaction", "transaction_leg"}
+
 // Features means the feature on TiDB we can turn on and off
 type Features struct {
 	Pessimistic bool
@@ -95,6 +99,19 @@ type Config struct {
 	RetryLimit      int
 	// will backup to BackupURI/full-$nextBackupIndex
 	BackupURI url.URL
+	// GCLifeTime keeps the snapshots of the backups readable until they are restored and checked
+	GCLifeTime string
+}
+
+// snapshotRecord is the expected state of a backup, which is read at the backup ts
+type snapshotRecord struct {
+	backupTS  uint64
+	checksums map[string]tableChecksum
+}
+
+type tableChecksum struct {
+	count    int
+	checksum string
 }
 
 type backupClient struct {
@@ -105,6 +122,9 @@ type backupClient struct {
 	lastBackupTs     uint64
 	nextRestoreIndex int
 	nextBackupIndex  int
+	dsn              string
+	// records are the expected snapshots of the backups by index
+	records map[int]*snapshotRecord
 }
 
 func (c *backupClient) SetUp(ctx context.Context, _ []cluster.Node, clientNodes []cluster.ClientNode, idx int) error {
@@ -114,6 +134,8 @@ func (c *backupClient) SetUp(ctx context.Context, _ []cluster.Node, clientNodes
 	var err error
 	node := clientNodes[idx]
 	dsn := fmt.Sprintf("root@tcp(%s:%d)/%s", node.IP, node.Port, c.config.DbName)
+	c.dsn = dsn
+	c.records = make(map[int]*snapshotRecord)
 	log.Infof("[%s] start to init...", c)
 	c.db, err = util.OpenDB(dsn, c.config.Concurrency)
 	if err != nil {
@@ -189,6 +211,11 @@ func (c *backupClient) applyConfig() {
 	if err != nil {
 		log.Fatalf("[%s] set txn_mode failed: %v", c, err)
 	}
+	if c.config.GCLifeTime != "" {
+		if _, err = c.db.Exec(fmt.Sprintf("set @@global.tidb_gc_life_time = '%s';", c.config.GCLifeTime)); err != nil {
+			log.Fatalf("[%s] set gc life time failed: %v", c, err)
+		}
+	}
 	time.Sleep(5 * time.Second)
 }
 
@@ -234,6 +261,13 @@ func (c *backupClient) backup() error {
 		return err
 	}
 	log.Infof("[%s] Back up %d success, this increment include updates from %d to %d", c, c.nextBackupIndex, c.lastBackupTs, lastBackupTs)
+	// the backup is done, so record the snapshot until success rather than backup again
+	err = util.RunWithRetry(context.Background(), c.config.RetryLimit, 5*time.Second, func() error {
+		return c.recordSnapshot(c.nextBackupIndex, lastBackupTs)
+	})
+	if err != nil {
+		log.Fatalf("[%s] failed to record the snapshot of backup %d at %d, err: %v", c, c.nextBackupIndex, lastBackupTs, err)
+	}
 	c.lastBackupTs = lastBackupTs
 	c.nextBackupIndex++
 	return nil
@@ -258,11 +292,79 @@ func (c *backupClient) restore() {
 		} else {
 			log.Infof("[%s] Success", c)
 		}
+		// every prefix of the chain restores the snapshot of its last backup,
+		// and the rows are compared after the whole chain is restored
+		c.checkRestoredSnapshot(c.nextRestoreIndex, c.nextRestoreIndex == c.nextBackupIndex-1)
+		delete(c.records, c.nextRestoreIndex)
 	}
 	c.lastBackupTs = 0
 	log.Infof("[%s] Restore success", c)
 }
 
+// openSnapshot opens the database at the ts by tidb_snapshot, which is set on every connection
+func (c *backupClient) openSnapshot(ts uint64) (*sql.DB, error) {
+	return util.OpenDB(fmt.Sprintf("%s?tidb_snapshot=%d", c.dsn, ts), 1)
+}
+
+// recordSnapshot records the checksum of every table at the backup ts, which is the expected state after restore
+func (c *backupClient) recordSnapshot(index int, backupTS uint64) error {
+	db, err := c.openSnapshot(backupTS)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer db.Close()
+	record := &snapshotRecord{backupTS: backupTS, checksums: make(map[string]tableChecksum)}
+	for _, table := range tables {
+		count, checksum, err := syncdiff.Checksum(db, c.config.DbName, table)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		record.checksums[table] = tableChecksum{count: count, checksum: checksum}
+	}
+	log.Infof("[%s] Backup %d at %d has the snapshot %v", c, index, backupTS, record.checksums)
+	c.records[index] = record
+	return nil
+}
+
+// checkRestoredSnapshot checks the checksums of the restored tables match the snapshot of the backup,
+// the rows are compared with the snapshot if the checksums mismatch or compareRows is set
+func (c *backupClient) checkRestoredSnapshot(index int, compareRows bool) {
+	record, ok := c.records[index]
+	if !ok {
+		log.Fatalf("[%s] No snapshot recorded for backup %d", c, index)
+	}
+	for _, table := range tables {
+		count, checksum, err := syncdiff.Checksum(c.db, c.config.DbName, table)
+		if err != nil {
+			log.Fatalf("[%s] Checksum restored table %s err %v", c, table, err)
+		}
+		if expected := record.checksums[table]; count != expected.count || checksum != expected.checksum {
+			log.Errorf("[%s] Table %s restored from backup %d has %d rows with checksum %s, expect %d rows with checksum %s at %d",
+				c, table, index, count, checksum, expected.count, expected.checksum, record.backupTS)
+			compareRows = true
+		}
+	}
+	if !compareRows {
+		log.Infof("[%s] Restored backup %d matches the snapshot at %d", c, index, record.backupTS)
+		return
+	}
+	db, err := c.openSnapshot(record.backupTS)
+	if err != nil {
+		log.Fatalf("[%s] Open snapshot at %d err %v", c, record.backupTS, err)
+	}
+	defer db.Close()
+	diffs, err := syncdiff.CompareTables(db, c.db, c.config.DbName, tables, syncdiff.DefaultConfig)
+	if err != nil {
+		log.Fatalf("[%s] Compare restored tables with the snapshot at %d err %v", c, record.backupTS, err)
+	}
+	for _, diff := range diffs {
+		if !diff.Equal() {
+			log.Fatalf("[%s] Restored backup %d does not match the snapshot at %d\n%s", c, index, record.backupTS, syncdiff.Report(diffs))
+		}
+	}
+	log.Infof("[%s] Restored backup %d matches the rows of the snapshot at %d", c, index, record.backupTS)
+}
+
 func (c *backupClient) transferOnce() error {
 	from, to := rand.Intn(c.config.NumAccounts), rand.Intn(c.config.NumAccounts)
 	if c.config.Contention == "high" {
@@ -27,6 +27,7 @@ import (
 
 	"github.com/ngaut/log"
 
+	syncdiff "github.com/pingcap/tipocket/pkg/check/sync-diff"
 	"github.com/pingcap/tipocket/pkg/cluster"
 	"github.com/pingcap/tipocket/pkg/core"
 	"github.com/pingcap/tipocket/util"
@@ -278,6 +279,18 @@ func (c *client) Start(ctx context.Context, cfg interface{}, clientNodes []clust
 	}
 	wg.Wait()
 
+	// all the transfers are done, compare the whole table after the downstream catches up with the upstream
+	finishCtx, finishCancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer finishCancel()
+	mustExec(finishCtx, upstream, "CREATE TABLE IF NOT EXISTS finishmark_final (foo BIGINT PRIMARY KEY)")
+	waitTable(finishCtx, downstream, "finishmark_final")
+	diff, err := syncdiff.CompareTable(upstream, downstream, "test", "accounts", syncdiff.Config{ChunkSize: c.Accounts/regions + 1})
+	if err != nil {
+		log.Errorf("[cdc-bank] compare accounts error %v", err)
+	} else if !diff.Equal() {
+		log.Fatalf("[cdc-bank] accounts are different in upstream and downstream\n%s", diff.Report())
+	}
+
 	if total == 0 {
 		log.Warn("[cdc-bank] finished, but total check round is 0")
 	} else {